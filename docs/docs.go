@@ -23,9 +23,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/messages": {
-            "get": {
-                "description": "Retrieves a paginated list of all messages with optional status filter",
+        "/api/v1/admin/archive": {
+            "post": {
+                "description": "Streams messages sent more than olderThanDays ago to the configured S3-compatible bucket as one JSON Lines file. With purge, the archived rows are deleted after the upload succeeded.",
                 "consumes": [
                     "application/json"
                 ],
@@ -33,41 +33,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Get all messages",
+                "summary": "Archive old sent messages",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key for messages",
+                        "description": "API key for scheduler",
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Page number (default: 1)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page size (default: 20, max: 100)",
-                        "name": "pageSize",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by status (pending, sent, failed)",
-                        "name": "status",
-                        "in": "query"
+                        "description": "Age of the messages to archive",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ArchiveRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.PaginatedResponse"
+                            "$ref": "#/definitions/response.SuccessResponse"
                         }
                     },
                     "400": {
@@ -76,6 +67,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -83,9 +80,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/v1/admin/backfill-sent-at": {
             "post": {
-                "description": "Creates a new message to be sent by the scheduler",
+                "description": "Sets sent_at from updated_at for sent messages that have no sent_at (e.g. legacy imports)",
                 "consumes": [
                     "application/json"
                 ],
@@ -93,40 +92,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Create a new message",
+                "summary": "Backfill missing sent_at values",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key for messages",
+                        "description": "API key for scheduler",
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "description": "Message to create",
-                        "name": "message",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CreateMessageRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -136,23 +120,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/messages/cached": {
+        "/api/v1/admin/config": {
             "get": {
-                "description": "Returns all messages cached in Redis (bonus feature)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the config loaded on boot, for spotting config drift. Passwords and keys are replaced by \"[REDACTED]\" when set, webhook URLs are reduced to their host, and durations are in nanoseconds.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Get cached messages from Redis",
+                "summary": "Get the effective config",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key for messages",
+                        "description": "API key for scheduler",
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
@@ -164,89 +145,53 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/messages/sent": {
+        "/api/v1/admin/diagnostics": {
             "get": {
-                "description": "Retrieves a paginated list of all sent messages",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Reports which optional subsystems are active (Redis, alerts, quiet hours, queues, database driver) as loaded on boot. Secrets are redacted.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Get sent messages",
+                "summary": "Get startup diagnostics",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key for messages",
+                        "description": "API key for scheduler",
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number (default: 1)",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page size (default: 20, max: 100)",
-                        "name": "pageSize",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.PaginatedResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
+                            "$ref": "#/definitions/response.SuccessResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/messages/stats": {
+        "/api/v1/admin/metrics.json": {
             "get": {
-                "description": "Returns count of messages by status",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns counters (runs, messages sent, failed, deduped, suppressed) and gauges (average provider latency in ms, messages in the last run) recorded since startup. Recording is opt-in via IN_MEMORY_METRICS_ENABLED.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Get message statistics",
+                "summary": "Get in-memory metrics",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "API key for messages",
+                        "description": "API key for scheduler",
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
@@ -258,19 +203,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/response.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/scheduler/start": {
+        "/api/v1/admin/normalize-phones": {
             "post": {
-                "description": "Starts the automatic message sending process with optional parameters",
+                "description": "Normalizes the phone numbers of pending messages (e.g. rows created before normalization on create) and reports how many were changed and which could not be fixed. With failRejected, those are moved to failed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -278,9 +217,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scheduler"
+                    "admin"
                 ],
-                "summary": "Start the message scheduler",
+                "summary": "Normalize phone numbers of pending messages",
                 "parameters": [
                     {
                         "type": "string",
@@ -290,11 +229,11 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Scheduler parameters (optional)",
+                        "description": "Options",
                         "name": "request",
                         "in": "body",
                         "schema": {
-                            "$ref": "#/definitions/handlers.StartSchedulerRequest"
+                            "$ref": "#/definitions/handlers.NormalizePhonesRequest"
                         }
                     }
                 ],
@@ -305,8 +244,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.ErrorResponse"
                         }
@@ -320,9 +259,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/scheduler/status": {
+        "/api/v1/admin/overview": {
             "get": {
-                "description": "Returns the current status of the message scheduler",
+                "description": "Aggregates message stats, scheduler status, oldest pending age and component health",
                 "consumes": [
                     "application/json"
                 ],
@@ -330,9 +269,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scheduler"
+                    "admin"
                 ],
-                "summary": "Get scheduler status",
+                "summary": "Get admin overview",
                 "parameters": [
                     {
                         "type": "string",
@@ -348,13 +287,48 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/scheduler/stop": {
+        "/api/v1/admin/recent-errors": {
+            "get": {
+                "description": "Returns the latest 4xx/5xx responses (status, code, method, route, request id, timestamp), newest first. Recording is opt-in via RECENT_ERRORS_SIZE.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List recent API errors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/sending": {
             "post": {
-                "description": "Stops the automatic message sending process",
+                "description": "Global kill switch for incidents. While sending is disabled, runs leave every message pending without calling the provider; schedulers keep running and resume sending once it is enabled again. The switch is stored in the database, so it applies to all instances and survives restarts.",
                 "consumes": [
                     "application/json"
                 ],
@@ -362,9 +336,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scheduler"
+                    "admin"
                 ],
-                "summary": "Stop the message scheduler",
+                "summary": "Turn sending on or off",
                 "parameters": [
                     {
                         "type": "string",
@@ -372,6 +346,15 @@ const docTemplate = `{
                         "name": "x-ins-auth-key",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "Whether sending is enabled",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SendingSwitchRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -381,6 +364,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.SuccessResponse"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -390,9 +385,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/api/v1/messages": {
             "get": {
-                "description": "Returns overall status with DB and Redis connectivity results",
+                "description": "Retrieves a paginated list of all messages with optional status filter",
                 "consumes": [
                     "application/json"
                 ],
@@ -400,35 +395,2653 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "messages"
+                ],
+                "summary": "Get all messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)",
+                        "name": "fields",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
-            }
-        }
+            },
+            "post": {
+                "description": "Creates a new message to be sent by the scheduler. Set sendAt, or delaySeconds relative to now (at most 31536000, i.e. a year), to hold it back until then; setting both is a 400. A clientMessageId already used with the same API key returns the earlier message with 200 instead of creating another. The response includes a preview with the normalized phone number, SMS segment count and whether the content will be truncated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Create a new message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Message to create",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Moves the given messages to one status in a single transaction, for migrations and manual corrections. The whole update is rejected if any id is unknown or any transition is not allowed (e.g. to sent without a provider message id). Failed messages moved back to pending get their retryCount and firstFailedAt reset.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Bulk-update message statuses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Message ids and target status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkStatusUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/cached": {
+            "get": {
+                "description": "Returns all messages cached in Redis (bonus feature). If the Redis scan fails partway, the entries read so far are returned with a ` + "`" + `warning` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get cached messages from Redis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/cached/batch": {
+            "post": {
+                "description": "Looks up the Redis cache entries of up to 1000 message ids in pipelined MGETs. Ids without a cache entry are left out of the result.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get cached messages by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Message ids",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CachedMessagesBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/campaign": {
+            "post": {
+                "description": "Renders a text/template once per recipient with the recipient's variables and stores every result as a pending message under a shared campaignId, in one transaction. A template that does not parse, a missing variable or an invalid recipient rejects the whole campaign with 422.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Create a campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Template and recipients",
+                        "name": "campaign",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CampaignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/campaign/{campaignId}": {
+            "get": {
+                "description": "Retrieves a paginated list of the messages created by one campaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get the messages of a campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Campaign id returned on creation",
+                        "name": "campaignId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/cancel": {
+            "post": {
+                "description": "Moves pending messages matching tag, phone prefix and/or created-before to cancelled. An empty filter requires all=true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Bulk-cancel pending messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancel filter",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CancelMessagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/changes": {
+            "get": {
+                "description": "Returns messages changed after the cursor (` + "`" + `since` + "`" + `, and ` + "`" + `afterId` + "`" + ` for rows changed in that same second), oldest first, with the ` + "`" + `cursor` + "`" + ` and ` + "`" + `cursorId` + "`" + ` to pass as ` + "`" + `since` + "`" + ` and ` + "`" + `afterId` + "`" + ` on the next call",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get messages changed since a timestamp",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp; only rows updated after it are returned",
+                        "name": "since",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Also return rows updated exactly at since with a higher id (the cursorId of the previous call)",
+                        "name": "afterId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max rows to return (default: 100, max: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/cost-estimate": {
+            "get": {
+                "description": "Projects the cost of sending all pending messages as SMS segments times MESSAGE_SEGMENT_PRICE, broken down by destination calling code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Estimate the cost of pending messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/export.jsonl": {
+            "get": {
+                "description": "Streams messages (oldest first) as one JSON object per line. The stream stops at ` + "`" + `limit` + "`" + ` rows and never exceeds the server ceiling (MESSAGE_EXPORT_MAX_ROWS); the X-Export-Truncated trailer is \"true\" if matching messages were left out.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Export messages as JSON Lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max rows to emit (default and max: server ceiling)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JSON Lines",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/failure-reasons": {
+            "get": {
+                "description": "Groups failed messages by normalized error and returns the most common reasons",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get top failure reasons",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max reasons to return (default: 10, max: 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/head": {
+            "get": {
+                "description": "Returns the oldest due pending messages of a queue in the order the scheduler picks them (newest first with MESSAGE_PROCESS_ORDER=lifo), without sending or changing them. For debugging a backlog.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Peek at the head of the queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Queue (default: default)",
+                        "name": "queue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max messages to return (default: 10, max: 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/replay": {
+            "post": {
+                "description": "Sets status='pending' for failed messages so the scheduler can resend them. With from/to, only messages that first failed in [from, to) are replayed; olderThan/newerThan bound the replay by failure age instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Replay failed messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional failure window",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReplayMessagesRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages that failed at least this long ago, e.g. 1h",
+                        "name": "olderThan",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages that failed less than this long ago, e.g. 24h",
+                        "name": "newerThan",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/search": {
+            "get": {
+                "description": "Finds messages whose phone number starts with q or whose content contains q. Phone matches come first, newest first within each group. With content encryption on, only phone number prefixes (digits with an optional leading +) can be searched; other queries get 400.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Search messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone number prefix or content text (max 100 characters)",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/sent": {
+            "get": {
+                "description": "Retrieves a paginated list of all sent messages",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get sent messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only messages carrying this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/sla": {
+            "get": {
+                "description": "Reports the percentage of messages sent in the last ` + "`" + `hours` + "`" + ` hours that were sent within ` + "`" + `targetMinutes` + "`" + ` of being due (creation, or sendAt if later), how many breached it, and whether the 95% objective is met. With no sends in the window, compliance is 100%.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get delivery SLA compliance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Hours to cover (default: 24, max: 168)",
+                        "name": "hours",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max minutes from being due to send (default: 5, max: 1440)",
+                        "name": "targetMinutes",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/stats": {
+            "get": {
+                "description": "Returns count of messages by status, plus counts of truncated and skipped messages since the service started",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get message statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/stats/grouped": {
+            "get": {
+                "description": "Returns a queue -\u003e status matrix of message counts plus per-status totals, from a single grouped query",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get message counts grouped by queue and status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/throughput": {
+            "get": {
+                "description": "Counts sent messages per hour of sentAt over the last ` + "`" + `hours` + "`" + ` hours (oldest first, current hour last), with hours without sends reported as zero. For capacity planning.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get hourly send throughput",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Hours to cover (default: 24, max: 168)",
+                        "name": "hours",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/truncated": {
+            "get": {
+                "description": "Retrieves a paginated list of messages whose content exceeded the max length and was truncated when sent, with their original length",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get truncated messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/{id}": {
+            "get": {
+                "description": "Returns a message by its DB id. The response carries an ETag that changes with any field of the message; send it back in If-None-Match to get a 304 while the message is unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a single message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/{id}/fail": {
+            "post": {
+                "description": "Moves a pending message to failed with a manual reason, e.g. for a number the provider always rejects. Only pending messages can be force-failed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Force-fail a stuck pending message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Failure reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ForceFailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/{id}/replay": {
+            "post": {
+                "description": "Sets status='pending' for a specific failed message so the scheduler can resend it. A message already replayed MESSAGE_MAX_REPLAYS times is refused with 409.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Replay a single failed message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/messages/{id}/reset-retries": {
+            "post": {
+                "description": "Clears firstFailedAt, replayCount and retryCount of a failed message so it counts as freshly failed (e.g. for the age bounds of a replay), can be replayed up to MESSAGE_MAX_REPLAYS times again and gets MESSAGE_MAX_RETRIES attempts once replayed, without re-pending it. Only failed messages can be reset.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Reset the retry state of a failed message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/history.csv": {
+            "get": {
+                "description": "Streams the most recent scheduler runs (oldest first) as CSV: timestamp, processed, succeeded, failed",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Export scheduler run history as CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/next-batch": {
+            "get": {
+                "description": "Returns the pending messages the next scheduler run would pick, without sending or changing them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Preview the next batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/runs/{runNumber}": {
+            "get": {
+                "description": "Returns the per-message send results of a recent run by run number, and the retries it drew from the retry budget. Only runs that processed messages are stored, and only the most recent SCHEDULER_RUNS_KEPT runs per queue are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get the results of a scheduler run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Run number",
+                        "name": "runNumber",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/simulate": {
+            "post": {
+                "description": "Predicts per message what the next run would do (send; stay pending for quiet hours, the kill switch or the per-run send cap; or skip it as suppressed or deduped) and how it would be sent after content transforms. Nothing is sent and no rows change; every send is assumed to succeed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Simulate the next scheduler run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/start": {
+            "post": {
+                "description": "Starts the automatic message sending process with optional parameters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Start the message scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Scheduler parameters (optional)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StartSchedulerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/status": {
+            "get": {
+                "description": "Returns the current status of the message scheduler",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get scheduler status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/stop": {
+            "post": {
+                "description": "Stops the automatic message sending process",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Stop the message scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/test-alert": {
+            "post": {
+                "description": "Posts a synthetic alert to the configured alert webhook (ALERT_WEBHOOK_URL) once and reports the status it answered with. The webhook's failures are reported in the body, not as an error status.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Send a test alert",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/history.csv": {
+            "get": {
+                "description": "Streams the most recent scheduler runs (oldest first) as CSV: timestamp, processed, succeeded, failed",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Export scheduler run history as CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/next-batch": {
+            "get": {
+                "description": "Returns the pending messages the next scheduler run would pick, without sending or changing them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Preview the next batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/runs/{runNumber}": {
+            "get": {
+                "description": "Returns the per-message send results of a recent run by run number, and the retries it drew from the retry budget. Only runs that processed messages are stored, and only the most recent SCHEDULER_RUNS_KEPT runs per queue are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get the results of a scheduler run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Run number",
+                        "name": "runNumber",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/simulate": {
+            "post": {
+                "description": "Predicts per message what the next run would do (send; stay pending for quiet hours, the kill switch or the per-run send cap; or skip it as suppressed or deduped) and how it would be sent after content transforms. Nothing is sent and no rows change; every send is assumed to succeed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Simulate the next scheduler run",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/start": {
+            "post": {
+                "description": "Starts the automatic message sending process with optional parameters",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Start the message scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Scheduler parameters (optional)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StartSchedulerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/status": {
+            "get": {
+                "description": "Returns the current status of the message scheduler",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get scheduler status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scheduler/{name}/stop": {
+            "post": {
+                "description": "Stops the automatic message sending process",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Stop the message scheduler",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for scheduler",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/suppressions": {
+            "post": {
+                "description": "Adds a phone number to the suppression list. Its pending messages are moved to suppressed right away, and messages created for it later are suppressed instead of sent. Suppressing a number again only updates the reason.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "suppressions"
+                ],
+                "summary": "Suppress a phone number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Number to suppress",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SuppressionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/suppressions/{phone}": {
+            "delete": {
+                "description": "Lets messages created for the number be sent again. Messages already suppressed stay suppressed. A leading + must be URL-encoded as %2B.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "suppressions"
+                ],
+                "summary": "Remove a phone number from the suppression list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for messages",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Phone number",
+                        "name": "phone",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/dlr": {
+            "post": {
+                "description": "Records the provider's final delivery state for a sent message, looked up by its provider message id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Receive a delivery receipt (DLR)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key for delivery receipts",
+                        "name": "x-ins-auth-key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Delivery receipt",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeliveryReceiptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns overall status with DB and Redis connectivity results",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Outcome counters (truncated messages, runs skipped for quiet hours, messages left pending by the per-run send cap, deduplicated messages, messages to suppressed numbers) in the Prometheus text format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get metrics",
+                "responses": {
+                    "200": {
+                        "description": "Prometheus metrics",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Returns the version, commit and build time the running binary was built with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Build information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
+        "handlers.ArchiveRequest": {
+            "type": "object",
+            "required": [
+                "olderThanDays"
+            ],
+            "properties": {
+                "olderThanDays": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "purge": {
+                    "description": "Delete the archived rows after a successful upload",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.BulkStatusUpdateRequest": {
+            "type": "object",
+            "required": [
+                "ids",
+                "status"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "status": {
+                    "description": "One of domain.MessageStatuses",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CachedMessagesBatchRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "handlers.CampaignRecipientRequest": {
+            "type": "object",
+            "required": [
+                "phoneNumber",
+                "variables"
+            ],
+            "properties": {
+                "phoneNumber": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.CampaignRequest": {
+            "type": "object",
+            "required": [
+                "recipients",
+                "template"
+            ],
+            "properties": {
+                "queue": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "recipients": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/handlers.CampaignRecipientRequest"
+                    }
+                },
+                "template": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "handlers.CancelMessagesRequest": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "createdBefore": {
+                    "type": "string"
+                },
+                "phonePrefix": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "tag": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
         "handlers.CreateMessageRequest": {
             "type": "object",
             "required": [
                 "content",
-                "phoneNumber"
+                "phoneNumber",
+                "tags"
             ],
             "properties": {
+                "clientMessageId": {
+                    "description": "ClientMessageID is the client's own unique id for the message. Creating a message with an\nid the same API key used before returns the earlier message with 200 instead of creating a\nnew one.",
+                    "type": "string",
+                    "maxLength": 100
+                },
                 "content": {
                     "type": "string",
                     "maxLength": 1000
                 },
+                "delaySeconds": {
+                    "type": "integer",
+                    "maximum": 31536000,
+                    "minimum": 1
+                },
                 "phoneNumber": {
                     "type": "string"
+                },
+                "queue": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "sendAt": {
+                    "description": "SendAt holds the message back until the given time; DelaySeconds does the same relative to\nnow, for up to a year. At most one of them may be set.",
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeoutSeconds": {
+                    "description": "TimeoutSeconds overrides the webhook timeout for this message (capped by the server).",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "handlers.DeliveryReceiptRequest": {
+            "type": "object",
+            "required": [
+                "messageId",
+                "status"
+            ],
+            "properties": {
+                "messageId": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "delivered",
+                        "undelivered"
+                    ]
+                }
+            }
+        },
+        "handlers.ForceFailRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "handlers.NormalizePhonesRequest": {
+            "type": "object",
+            "properties": {
+                "failRejected": {
+                    "description": "Move messages with unfixable numbers to failed",
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.ReplayMessagesRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SendingSwitchRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 }
             }
         },
@@ -441,11 +3054,28 @@ const docTemplate = `{
                     "minimum": 0
                 },
                 "interval": {
+                    "description": "Interval in minutes, up to MESSAGE_MAX_SEND_INTERVAL_MINUTES (checked by the handler).",
                     "type": "integer",
                     "minimum": 1
                 }
             }
         },
+        "handlers.SuppressionRequest": {
+            "type": "object",
+            "required": [
+                "phoneNumber"
+            ],
+            "properties": {
+                "phoneNumber": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
         "response.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -464,6 +3094,10 @@ const docTemplate = `{
                 "page": {
                     "type": "integer"
                 },
+                "pageOutOfRange": {
+                    "description": "PageOutOfRange is set when page is past the last page, so clients paging until an empty\ndata array can tell the end of the list from an empty page.",
+                    "type": "boolean"
+                },
                 "pageSize": {
                     "type": "integer"
                 },
@@ -487,6 +3121,9 @@ const docTemplate = `{
                 },
                 "success": {
                     "type": "boolean"
+                },
+                "warning": {
+                    "type": "string"
                 }
             }
         }
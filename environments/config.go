@@ -3,6 +3,7 @@ package environments
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,10 +15,21 @@ type Config struct {
 	Message  MessageConfig
 	Alert    AlertConfig
 	Auth     AuthConfig
+	Archive  ArchiveConfig
 }
 
 type ServerConfig struct {
-	Port string
+	Port                  string
+	ShutdownTimeout       time.Duration // Max time to drain HTTP connections on shutdown
+	SchedulerStopTimeout  time.Duration // Max time to wait for the scheduler to stop on shutdown
+	SchedulerStartDelay   time.Duration // Grace period before the scheduler's first run (0 = immediately)
+	SchedulerIdleStopRuns int           // Auto-stop after this many consecutive empty runs (0 = never)
+	SchedulerFailureRetry time.Duration // Shortened wait before the next run after an all-failed run (0 = off)
+	SchedulerRunsKept     int           // Runs per queue whose per-message results are kept
+	JSONFieldNaming       string        // Response field naming: "camelCase" (default) or "snake_case"
+	HealthPendingBacklog  int64         // /health reports degraded above this many pending messages (0 = no check)
+	RecentErrorsSize      int           // Error responses kept for /admin/recent-errors (0 = not recorded)
+	InMemoryMetrics       bool          // Record counters and gauges for /admin/metrics.json
 }
 
 type DatabaseConfig struct {
@@ -26,6 +38,14 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	DBName   string
+
+	ReadRetryAttempts int // Attempts for read queries that hit a transient error (1 = no retry)
+
+	// DSN of a read replica for the list and stats queries (empty = all reads hit the primary).
+	ReplicaDSN string
+
+	// Base64 AES key (16, 24 or 32 bytes) message content is encrypted with at rest (empty = plaintext).
+	ContentEncryptionKey string
 }
 
 type RedisConfig struct {
@@ -33,34 +53,133 @@ type RedisConfig struct {
 	Port     string
 	Password string
 	DB       int
+	Required bool // Fail startup if Redis is unavailable instead of disabling caching
+
+	BatchCacheWrites bool // Pipeline a run's sent-message cache writes at the end of the run
 }
 
 type WebhookConfig struct {
 	URL     string
 	AuthKey string
-	Timeout time.Duration
+	Timeout time.Duration // Total budget of one webhook call, including the client's HTTP retries
+
+	// AuthRequired makes an empty AuthKey a startup error. Turn it off for providers without
+	// auth; the auth header is then left out.
+	AuthRequired bool
+
+	// Max concurrent SendMessage calls across the whole service (0 = unlimited).
+	MaxConcurrency int
+
+	// Optional body-based success check for providers that always answer 200.
+	SuccessField string
+	SuccessValue string
+
+	// Unexpected status codes worth retrying (empty = the client's defaults); any other is terminal.
+	RetryableStatusCodes []int
 }
 
 type MessageConfig struct {
 	BatchSize        int
 	SendInterval     time.Duration
+	MinSendInterval  time.Duration // Floor applied to any scheduler interval
+	MaxSendInterval  time.Duration // Longest interval a scheduler may be started with
 	MaxContentLength int
+	SendAttempts     int // Webhook attempts per message within a single run
+	RetryBudget      int // Max retries across a single run (0 = unlimited)
+	MaxSendsPerRun   int // Successful sends after which a run stops, unlike BatchSize which bounds the fetch (0 = unlimited)
+	FailureSeed      int // Seed for failure simulation (0 = seeded from the clock)
+	MaxReplays       int // Replays after which a failed message stays failed (0 = unlimited)
+	MaxRetries       int // Failed runs after which a message is marked failed; before that it stays pending (0 or 1 = fail on the first)
+
+	// Pause between two sends within a run, for providers that throttle bursts (0 = no pause).
+	SendDelay time.Duration
+
+	// Same content to the same number within this window is skipped as a duplicate (0 = off; needs Redis).
+	DedupWindow time.Duration
+
+	// Order pending messages are picked in: "fifo" (oldest first, default) or "lifo" (newest first).
+	ProcessOrder string
+
+	// What happens to content with control characters other than newline, CRLF and tab:
+	// "reject" (default) fails validation, "strip" removes them.
+	ControlChars string
+
+	// Upper bound for per-message webhook timeout overrides.
+	MaxWebhookTimeout time.Duration
+
+	// Max rows a single export stream emits, whatever limit the client asks for.
+	ExportMaxRows int
+
+	// Price of one SMS segment, used by the cost estimate (0 = estimate segments only).
+	SegmentPrice float64
+
+	// Base URL long links in content are shortened to before sending (empty = links sent as written).
+	URLShortenerBase      string
+	URLShortenerMinLength int // Links at or below this length are left alone
+
+	// Named queues that get their own scheduler, in addition to the default queue.
+	Queues []string
+
+	// Destination country codes messages may be created for, e.g. "+90" (empty = all allowed).
+	AllowedCountryCodes []string
+
+	// Daily window ("HH:MM", in QuietHoursTimezone) during which no messages are sent.
+	// Both empty = no quiet hours; an end before the start wraps past midnight.
+	QuietHoursStart    string
+	QuietHoursEnd      string
+	QuietHoursTimezone string
 }
 
 type AlertConfig struct {
 	WebhookURL     string
 	IterationCount int
+	Timeout        time.Duration
+	Retries        int           // Extra attempts after a failed alert call (0 = send once)
+	RetryBackoff   time.Duration // Base delay before the first retry, doubled for each further one
+
+	// Optional webhook receiving every message marked failed (empty = not sent).
+	DeadLetterWebhookURL string
+
+	// text/template for the alert's message text, e.g. "{{.ConsecutiveFailures}} failed runs"
+	// (empty = built-in text).
+	MessageTemplate string
+}
+
+// ArchiveConfig points at the S3-compatible bucket old sent messages are archived to.
+type ArchiveConfig struct {
+	Endpoint  string // e.g. "https://s3.eu-west-1.amazonaws.com" (empty = archiving disabled)
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // Prepended to archive object keys, e.g. "archive/"
+	Timeout   time.Duration
 }
 
 type AuthConfig struct {
 	MessagesAPIKey  string
 	SchedulerAPIKey string
+	DLRAPIKey       string // Used by the provider to post delivery receipts
+
+	// Optional extra key for message endpoints (e.g. internal ETL) that may request larger pages.
+	TrustedAPIKey      string
+	TrustedMaxPageSize int
 }
 
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: GetEnv("SERVER_PORT", "8080"),
+			Port:                  GetEnv("SERVER_PORT", "8080"),
+			ShutdownTimeout:       time.Duration(GetEnvAsInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 10)) * time.Second,
+			SchedulerStopTimeout:  time.Duration(GetEnvAsInt("SCHEDULER_STOP_TIMEOUT_SECONDS", 5)) * time.Second,
+			SchedulerStartDelay:   time.Duration(GetEnvAsInt("SCHEDULER_START_DELAY_SECONDS", 0)) * time.Second,
+			SchedulerIdleStopRuns: GetEnvAsInt("SCHEDULER_IDLE_STOP_RUNS", 0),
+			SchedulerFailureRetry: time.Duration(GetEnvAsInt("SCHEDULER_FAILURE_RETRY_SECONDS", 0)) * time.Second,
+			SchedulerRunsKept:     GetEnvAsInt("SCHEDULER_RUNS_KEPT", 100),
+			JSONFieldNaming:       GetEnv("API_JSON_FIELD_NAMING", "camelCase"),
+			HealthPendingBacklog:  int64(GetEnvAsInt("HEALTH_PENDING_BACKLOG_THRESHOLD", 0)),
+			RecentErrorsSize:      GetEnvAsInt("RECENT_ERRORS_SIZE", 0),
+			InMemoryMetrics:       GetEnvAsBool("IN_MEMORY_METRICS_ENABLED", false),
 		},
 		Database: DatabaseConfig{
 			Host:     GetEnv("DB_HOST", "localhost"),
@@ -68,30 +187,94 @@ func Load() *Config {
 			User:     GetEnv("DB_USER", "insider"),
 			Password: GetEnv("DB_PASSWORD", "insider123"),
 			DBName:   GetEnv("DB_NAME", "insider_messages"),
+
+			ReadRetryAttempts: GetEnvAsInt("DB_READ_RETRY_ATTEMPTS", 3),
+			ReplicaDSN:        GetEnv("DB_REPLICA_DSN", ""),
+
+			ContentEncryptionKey: GetEnv("DB_CONTENT_ENCRYPTION_KEY", ""),
 		},
 		Redis: RedisConfig{
 			Host:     GetEnv("REDIS_HOST", "localhost"),
 			Port:     GetEnv("REDIS_PORT", "6379"),
 			Password: GetEnv("REDIS_PASSWORD", ""),
 			DB:       GetEnvAsInt("REDIS_DB", 0),
+			Required: GetEnvAsBool("REDIS_REQUIRED", false),
+
+			BatchCacheWrites: GetEnvAsBool("REDIS_BATCH_CACHE_WRITES", false),
 		},
 		Webhook: WebhookConfig{
 			URL:     GetEnv("WEBHOOK_URL", "https://webhook.site/your-unique-id"),
 			AuthKey: GetEnv("WEBHOOK_AUTH_KEY", ""),
 			Timeout: time.Duration(GetEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 30)) * time.Second,
+
+			AuthRequired: GetEnvAsBool("WEBHOOK_AUTH_REQUIRED", true),
+
+			MaxConcurrency: GetEnvAsInt("WEBHOOK_MAX_CONCURRENCY", 0),
+
+			SuccessField: GetEnv("WEBHOOK_SUCCESS_FIELD", ""),
+			SuccessValue: GetEnv("WEBHOOK_SUCCESS_VALUE", ""),
+
+			RetryableStatusCodes: GetEnvAsIntSlice("WEBHOOK_RETRYABLE_STATUS_CODES", nil),
 		},
 		Message: MessageConfig{
 			BatchSize:        GetEnvAsInt("MESSAGE_BATCH_SIZE", 2),
 			SendInterval:     time.Duration(GetEnvAsInt("MESSAGE_SEND_INTERVAL_MINUTES", 2)) * time.Minute,
+			MinSendInterval:  time.Duration(GetEnvAsInt("MESSAGE_MIN_SEND_INTERVAL_SECONDS", 60)) * time.Second,
+			MaxSendInterval:  time.Duration(GetEnvAsInt("MESSAGE_MAX_SEND_INTERVAL_MINUTES", 1440)) * time.Minute,
 			MaxContentLength: GetEnvAsInt("MESSAGE_MAX_CONTENT_LENGTH", 1000),
+			SendAttempts:     GetEnvAsInt("MESSAGE_SEND_ATTEMPTS", 1),
+			RetryBudget:      GetEnvAsInt("MESSAGE_RETRY_BUDGET", 10),
+			MaxSendsPerRun:   GetEnvAsInt("MESSAGE_MAX_SENDS_PER_RUN", 0),
+			FailureSeed:      GetEnvAsInt("MESSAGE_FAILURE_SEED", 0),
+			MaxReplays:       GetEnvAsInt("MESSAGE_MAX_REPLAYS", 0),
+			MaxRetries:       GetEnvAsInt("MESSAGE_MAX_RETRIES", 0),
+
+			SendDelay:   GetEnvAsDuration("MESSAGE_SEND_DELAY", 0),
+			DedupWindow: GetEnvAsDuration("MESSAGE_DEDUP_WINDOW", 0),
+
+			ProcessOrder: GetEnv("MESSAGE_PROCESS_ORDER", "fifo"),
+			ControlChars: GetEnv("MESSAGE_CONTROL_CHARS", "reject"),
+
+			MaxWebhookTimeout: time.Duration(GetEnvAsInt("MESSAGE_MAX_WEBHOOK_TIMEOUT_SECONDS", 120)) * time.Second,
+			ExportMaxRows:     GetEnvAsInt("MESSAGE_EXPORT_MAX_ROWS", 10000),
+			SegmentPrice:      GetEnvAsFloat("MESSAGE_SEGMENT_PRICE", 0),
+
+			URLShortenerBase:      GetEnv("MESSAGE_URL_SHORTENER_BASE", ""),
+			URLShortenerMinLength: GetEnvAsInt("MESSAGE_URL_SHORTENER_MIN_LENGTH", 40),
+
+			Queues:              GetEnvAsSlice("MESSAGE_QUEUES", nil),
+			AllowedCountryCodes: GetEnvAsSlice("MESSAGE_ALLOWED_COUNTRY_CODES", nil),
+
+			QuietHoursStart:    GetEnv("MESSAGE_QUIET_HOURS_START", ""),
+			QuietHoursEnd:      GetEnv("MESSAGE_QUIET_HOURS_END", ""),
+			QuietHoursTimezone: GetEnv("MESSAGE_QUIET_HOURS_TIMEZONE", "UTC"),
 		},
 		Alert: AlertConfig{
 			WebhookURL:     GetEnv("ALERT_WEBHOOK_URL", ""),
 			IterationCount: GetEnvAsInt("ALERT_ITERATION_COUNT", 0),
+			Timeout:        time.Duration(GetEnvAsInt("ALERT_TIMEOUT_SECONDS", 10)) * time.Second,
+			Retries:        GetEnvAsInt("ALERT_RETRIES", 2),
+			RetryBackoff:   GetEnvAsDuration("ALERT_RETRY_BACKOFF", 500*time.Millisecond),
+
+			DeadLetterWebhookURL: GetEnv("DEAD_LETTER_WEBHOOK_URL", ""),
+			MessageTemplate:      GetEnv("ALERT_MESSAGE_TEMPLATE", ""),
 		},
 		Auth: AuthConfig{
 			MessagesAPIKey:  GetEnv("MESSAGES_API_KEY", ""),
 			SchedulerAPIKey: GetEnv("SCHEDULER_API_KEY", ""),
+			DLRAPIKey:       GetEnv("DLR_API_KEY", ""),
+
+			TrustedAPIKey:      GetEnv("TRUSTED_API_KEY", ""),
+			TrustedMaxPageSize: GetEnvAsInt("TRUSTED_MAX_PAGE_SIZE", 1000),
+		},
+		Archive: ArchiveConfig{
+			Endpoint:  GetEnv("ARCHIVE_S3_ENDPOINT", ""),
+			Bucket:    GetEnv("ARCHIVE_S3_BUCKET", ""),
+			Region:    GetEnv("ARCHIVE_S3_REGION", "us-east-1"),
+			AccessKey: GetEnv("ARCHIVE_S3_ACCESS_KEY", ""),
+			SecretKey: GetEnv("ARCHIVE_S3_SECRET_KEY", ""),
+			Prefix:    GetEnv("ARCHIVE_S3_PREFIX", "archive/"),
+			Timeout:   GetEnvAsDuration("ARCHIVE_S3_TIMEOUT", 5*time.Minute),
 		},
 	}
 }
@@ -112,6 +295,47 @@ func GetEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// GetEnvAsSlice splits a comma-separated value, trimming spaces and dropping empty entries.
+func GetEnvAsSlice(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetEnvAsIntSlice splits a comma-separated list of integers, skipping entries that do not parse.
+func GetEnvAsIntSlice(key string, defaultValue []int) []int {
+	items := GetEnvAsSlice(key, nil)
+	if items == nil {
+		return defaultValue
+	}
+
+	values := make([]int, 0, len(items))
+	for _, item := range items {
+		if value, err := strconv.Atoi(item); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func GetEnvAsFloat(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func GetEnvAsBool(key string, defaultValue bool) bool {
 	if value, exists := os.LookupEnv(key); exists {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -0,0 +1,55 @@
+package environments
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad_ShutdownTimeoutsDefault(t *testing.T) {
+	// Unparseable values fall back to the defaults, regardless of the caller's environment.
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT_SECONDS", "")
+	t.Setenv("SCHEDULER_STOP_TIMEOUT_SECONDS", "")
+
+	cfg := Load()
+
+	if cfg.Server.ShutdownTimeout != 10*time.Second {
+		t.Fatalf("expected default shutdown timeout 10s, got %v", cfg.Server.ShutdownTimeout)
+	}
+	if cfg.Server.SchedulerStopTimeout != 5*time.Second {
+		t.Fatalf("expected default scheduler stop timeout 5s, got %v", cfg.Server.SchedulerStopTimeout)
+	}
+}
+
+func TestLoad_ShutdownTimeoutsFromEnv(t *testing.T) {
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT_SECONDS", "30")
+	t.Setenv("SCHEDULER_STOP_TIMEOUT_SECONDS", "2")
+
+	cfg := Load()
+
+	if cfg.Server.ShutdownTimeout != 30*time.Second {
+		t.Fatalf("expected shutdown timeout 30s, got %v", cfg.Server.ShutdownTimeout)
+	}
+	if cfg.Server.SchedulerStopTimeout != 2*time.Second {
+		t.Fatalf("expected scheduler stop timeout 2s, got %v", cfg.Server.SchedulerStopTimeout)
+	}
+}
+
+func TestGetEnvAsSlice_TrimsAndDropsEmpty(t *testing.T) {
+	t.Setenv("MESSAGE_QUEUES", " transactional, ,promotional ")
+
+	queues := Load().Message.Queues
+
+	if len(queues) != 2 || queues[0] != "transactional" || queues[1] != "promotional" {
+		t.Fatalf("expected [transactional promotional], got %q", queues)
+	}
+}
+
+func TestGetEnvAsIntSlice_SkipsInvalidEntries(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRYABLE_STATUS_CODES", "429, abc,503")
+
+	codes := Load().Webhook.RetryableStatusCodes
+
+	if len(codes) != 2 || codes[0] != 429 || codes[1] != 503 {
+		t.Fatalf("expected [429 503], got %v", codes)
+	}
+}
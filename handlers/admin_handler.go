@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/internal/scheduler"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+	"github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+// Small internal interfaces so the admin endpoints can be tested with fakes.
+type adminMessageService interface {
+	GetStats(ctx context.Context) (pending, sent, failed int64, err error)
+	GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error)
+	BackfillSentAt(ctx context.Context) (int64, error)
+	NormalizePendingPhones(ctx context.Context, failRejected bool) (domain.PhoneNormalizationResult, error)
+	BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error)
+	ArchiveSentMessages(ctx context.Context, olderThan time.Duration, purge bool) (*domain.ArchiveResult, error)
+	SetSendingEnabled(ctx context.Context, enabled bool) error
+}
+
+type schedulerStatusProvider interface {
+	GetStatus() scheduler.SchedulerStatus
+}
+
+// AdminHandler serves operator-facing endpoints that aggregate data from several components.
+type AdminHandler struct {
+	service   adminMessageService
+	scheduler schedulerStatusProvider
+	health    *HealthHandler
+}
+
+// NormalizePhonesRequest controls what happens to numbers that cannot be fixed.
+type NormalizePhonesRequest struct {
+	FailRejected bool `json:"failRejected"` // Move messages with unfixable numbers to failed
+}
+
+// ArchiveRequest selects the sent messages to archive.
+type ArchiveRequest struct {
+	OlderThanDays int  `json:"olderThanDays" validate:"required,min=1"`
+	Purge         bool `json:"purge"` // Delete the archived rows after a successful upload
+}
+
+// BulkStatusUpdateRequest moves up to 1000 messages to one status.
+type BulkStatusUpdateRequest struct {
+	IDs    []int64 `json:"ids" validate:"required,min=1,max=1000,dive,gt=0"`
+	Status string  `json:"status" validate:"required"` // One of domain.MessageStatuses
+}
+
+// SendingSwitchRequest turns the global sending kill switch on or off.
+type SendingSwitchRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+func NewAdminHandler(
+	service adminMessageService,
+	sched schedulerStatusProvider,
+	health *HealthHandler,
+) *AdminHandler {
+	return &AdminHandler{
+		service:   service,
+		scheduler: sched,
+		health:    health,
+	}
+}
+
+// GetOverview godoc
+// @Summary Get admin overview
+// @Description Aggregates message stats, scheduler status, oldest pending age and component health
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/overview [get]
+func (h *AdminHandler) GetOverview(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	pending, sent, failed, err := h.service.GetStats(ctx)
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	oldestPendingAt, err := h.service.GetOldestPendingCreatedAt(ctx)
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	oldestPending := map[string]any{
+		"createdAt":  nil,
+		"ageSeconds": 0,
+	}
+	if oldestPendingAt != nil {
+		oldestPending["createdAt"] = oldestPendingAt.Format(time.RFC3339)
+		oldestPending["ageSeconds"] = int64(time.Since(*oldestPendingAt).Seconds())
+	}
+
+	healthCtx, cancel := context.WithTimeout(ctx, h.health.checkTimeout)
+	defer cancel()
+
+	healthStatus, components := h.health.Check(healthCtx)
+
+	return response.Ok(c, map[string]any{
+		"messages": map[string]any{
+			"pending": pending,
+			"sent":    sent,
+			"failed":  failed,
+			"total":   pending + sent + failed,
+		},
+		"oldestPending": oldestPending,
+		"scheduler":     h.scheduler.GetStatus(),
+		"health": map[string]any{
+			"status":     healthStatus,
+			"components": components,
+		},
+	})
+}
+
+// BackfillSentAt godoc
+// @Summary Backfill missing sent_at values
+// @Description Sets sent_at from updated_at for sent messages that have no sent_at (e.g. legacy imports)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/backfill-sent-at [post]
+func (h *AdminHandler) BackfillSentAt(c echo.Context) error {
+	count, err := h.service.BackfillSentAt(c.Request().Context())
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"backfilled": count,
+	})
+}
+
+// NormalizePhones godoc
+// @Summary Normalize phone numbers of pending messages
+// @Description Normalizes the phone numbers of pending messages (e.g. rows created before normalization on create) and reports how many were changed and which could not be fixed. With failRejected, those are moved to failed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Param request body NormalizePhonesRequest false "Options"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/normalize-phones [post]
+func (h *AdminHandler) NormalizePhones(c echo.Context) error {
+	var req NormalizePhonesRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	result, err := h.service.NormalizePendingPhones(c.Request().Context(), req.FailRejected)
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, result)
+}
+
+// ArchiveSentMessages godoc
+// @Summary Archive old sent messages
+// @Description Streams messages sent more than olderThanDays ago to the configured S3-compatible bucket as one JSON Lines file. With purge, the archived rows are deleted after the upload succeeded.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Param request body ArchiveRequest true "Age of the messages to archive"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/archive [post]
+func (h *AdminHandler) ArchiveSentMessages(c echo.Context) error {
+	var req ArchiveRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	olderThan := time.Duration(req.OlderThanDays) * 24 * time.Hour
+	result, err := h.service.ArchiveSentMessages(c.Request().Context(), olderThan, req.Purge)
+	if err != nil {
+		if errors.Is(err, domain.ErrArchiveNotConfigured) {
+			return response.UnprocessableEntity(c, fmt.Errorf("%w; set ARCHIVE_S3_ENDPOINT and ARCHIVE_S3_BUCKET", err))
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, result)
+}
+
+// BulkUpdateStatus godoc
+// @Summary Bulk-update message statuses
+// @Description Moves the given messages to one status in a single transaction, for migrations and manual corrections. The whole update is rejected if any id is unknown or any transition is not allowed (e.g. to sent without a provider message id). Failed messages moved back to pending get their retryCount and firstFailedAt reset.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Param request body BulkStatusUpdateRequest true "Message ids and target status"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages [patch]
+func (h *AdminHandler) BulkUpdateStatus(c echo.Context) error {
+	var req BulkStatusUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	// Checked against the status registry rather than a oneof tag, so new statuses need no tag edits.
+	status, err := domain.ParseMessageStatus(req.Status)
+	if err != nil {
+		return response.UnprocessableEntity(c, err)
+	}
+
+	count, err := h.service.BulkUpdateStatus(c.Request().Context(), req.IDs, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMessageNotFound):
+			return response.NotFound(c, err.Error())
+		case errors.Is(err, domain.ErrInvalidTransition):
+			return response.UnprocessableEntity(c, err)
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"updated": count,
+	})
+}
+
+// SetSending godoc
+// @Summary Turn sending on or off
+// @Description Global kill switch for incidents. While sending is disabled, runs leave every message pending without calling the provider; schedulers keep running and resume sending once it is enabled again. The switch is stored in the database, so it applies to all instances and survives restarts.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Param request body SendingSwitchRequest true "Whether sending is enabled"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/sending [post]
+func (h *AdminHandler) SetSending(c echo.Context) error {
+	var req SendingSwitchRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	if err := h.service.SetSendingEnabled(c.Request().Context(), *req.Enabled); err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	message := "Sending disabled"
+	if *req.Enabled {
+		message = "Sending enabled"
+	}
+	return response.OkWithMessage(c, message, map[string]any{
+		"sendingEnabled": *req.Enabled,
+	})
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/internal/scheduler"
+	validatorpkg "github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+type fakeAdminService struct {
+	pending, sent, failed int64
+	oldestPendingAt       *time.Time
+
+	// statuses backs BulkUpdateStatus, keyed by message id.
+	statuses map[int64]domain.MessageStatus
+
+	sendingEnabled *bool // Last value passed to SetSendingEnabled
+}
+
+func (f *fakeAdminService) GetStats(ctx context.Context) (int64, int64, int64, error) {
+	return f.pending, f.sent, f.failed, nil
+}
+
+func (f *fakeAdminService) GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error) {
+	return f.oldestPendingAt, nil
+}
+
+func (f *fakeAdminService) BackfillSentAt(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeAdminService) NormalizePendingPhones(ctx context.Context, failRejected bool) (domain.PhoneNormalizationResult, error) {
+	return domain.PhoneNormalizationResult{}, nil
+}
+
+func (f *fakeAdminService) ArchiveSentMessages(ctx context.Context, olderThan time.Duration, purge bool) (*domain.ArchiveResult, error) {
+	return nil, domain.ErrArchiveNotConfigured
+}
+
+// BulkUpdateStatus applies the same all-or-nothing rules as the repository.
+func (f *fakeAdminService) BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error) {
+	for _, id := range ids {
+		current, ok := f.statuses[id]
+		if !ok {
+			return 0, domain.ErrMessageNotFound
+		}
+		if !domain.CanTransition(current, status, false) {
+			return 0, domain.ErrInvalidTransition
+		}
+	}
+	for _, id := range ids {
+		f.statuses[id] = status
+	}
+	return int64(len(ids)), nil
+}
+
+func (f *fakeAdminService) SetSendingEnabled(ctx context.Context, enabled bool) error {
+	f.sendingEnabled = &enabled
+	return nil
+}
+
+type fakeSchedulerStatus struct {
+	status scheduler.SchedulerStatus
+}
+
+func (f *fakeSchedulerStatus) GetStatus() scheduler.SchedulerStatus {
+	return f.status
+}
+
+func TestGetOverview_ContainsAllSections(t *testing.T) {
+	e := echo.New()
+
+	oldest := time.Now().Add(-10 * time.Minute)
+	svc := &fakeAdminService{pending: 3, sent: 5, failed: 1, oldestPendingAt: &oldest}
+	sched := &fakeSchedulerStatus{status: scheduler.SchedulerStatus{Running: true, RunsCount: 4}}
+
+	// No DB or Redis: health reports the database as down, which is fine for this test.
+	handler := NewAdminHandler(svc, sched, NewHealthHandler(nil, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/overview", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.GetOverview(c); err != nil {
+		t.Fatalf("GetOverview returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Messages struct {
+				Pending int64 `json:"pending"`
+				Total   int64 `json:"total"`
+			} `json:"messages"`
+			OldestPending struct {
+				AgeSeconds int64 `json:"ageSeconds"`
+			} `json:"oldestPending"`
+			Scheduler *scheduler.SchedulerStatus `json:"scheduler"`
+			Health    *struct {
+				Status     string         `json:"status"`
+				Components map[string]any `json:"components"`
+			} `json:"health"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if body.Data.Messages.Pending != 3 || body.Data.Messages.Total != 9 {
+		t.Errorf("unexpected message stats: %+v", body.Data.Messages)
+	}
+	if body.Data.OldestPending.AgeSeconds < 599 {
+		t.Errorf("expected oldest pending age around 600s, got %d", body.Data.OldestPending.AgeSeconds)
+	}
+	if body.Data.Scheduler == nil || !body.Data.Scheduler.Running || body.Data.Scheduler.RunsCount != 4 {
+		t.Errorf("unexpected scheduler section: %+v", body.Data.Scheduler)
+	}
+	if body.Data.Health == nil {
+		t.Fatalf("expected health section to be present")
+	}
+	if body.Data.Health.Status != "down" {
+		t.Errorf("expected health status 'down' without a database, got %q", body.Data.Health.Status)
+	}
+	if _, ok := body.Data.Health.Components["database"]; !ok {
+		t.Errorf("expected database component in health section")
+	}
+}
+
+func patchMessages(t *testing.T, handler *AdminHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/messages", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.BulkUpdateStatus(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("BulkUpdateStatus returned error: %v", err)
+	}
+	return rec
+}
+
+func TestBulkUpdateStatus_ValidTransition(t *testing.T) {
+	svc := &fakeAdminService{statuses: map[int64]domain.MessageStatus{
+		1: domain.StatusPending,
+		2: domain.StatusFailed,
+	}}
+	handler := NewAdminHandler(svc, &fakeSchedulerStatus{}, nil)
+
+	rec := patchMessages(t, handler, `{"ids": [1, 2], "status": "cancelled"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data struct {
+			Updated int64 `json:"updated"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body.Data.Updated != 2 {
+		t.Fatalf("expected 2 updated messages, got %d", body.Data.Updated)
+	}
+	if svc.statuses[1] != domain.StatusCancelled || svc.statuses[2] != domain.StatusCancelled {
+		t.Fatalf("expected both messages to be cancelled, got %v", svc.statuses)
+	}
+}
+
+func TestBulkUpdateStatus_InvalidTransitionIsRejected(t *testing.T) {
+	svc := &fakeAdminService{statuses: map[int64]domain.MessageStatus{
+		1: domain.StatusPending,
+		2: domain.StatusFailed,
+	}}
+	handler := NewAdminHandler(svc, &fakeSchedulerStatus{}, nil)
+
+	rec := patchMessages(t, handler, `{"ids": [1, 2], "status": "sent"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.statuses[1] != domain.StatusPending || svc.statuses[2] != domain.StatusFailed {
+		t.Fatalf("expected no changes, got %v", svc.statuses)
+	}
+}
+
+func TestBulkUpdateStatus_UnknownStatusFailsValidation(t *testing.T) {
+	handler := NewAdminHandler(&fakeAdminService{}, &fakeSchedulerStatus{}, nil)
+
+	rec := patchMessages(t, handler, `{"ids": [1], "status": "delivered"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestSetSending_TogglesKillSwitch(t *testing.T) {
+	svc := &fakeAdminService{}
+	handler := NewAdminHandler(svc, &fakeSchedulerStatus{}, nil)
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/sending", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		if err := handler.SetSending(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("SetSending returned error: %v", err)
+		}
+		return rec
+	}
+
+	if rec := post(`{"enabled": false}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.sendingEnabled == nil || *svc.sendingEnabled {
+		t.Fatalf("expected sending to be disabled, got %v", svc.sendingEnabled)
+	}
+
+	// Omitting enabled must not silently turn sending off.
+	svc.sendingEnabled = nil
+	if rec := post(`{}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.sendingEnabled != nil {
+		t.Fatalf("expected the switch to be left alone, got %v", *svc.sendingEnabled)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/url"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+)
+
+// Diagnostics describes which optional subsystems are active. Secrets are never included;
+// keys and passwords are only reported as configured or not.
+type Diagnostics struct {
+	Database  DatabaseDiagnostics  `json:"database"`
+	Redis     RedisDiagnostics     `json:"redis"`
+	Webhook   WebhookDiagnostics   `json:"webhook"`
+	Alerts    AlertDiagnostics     `json:"alerts"`
+	Messages  MessageDiagnostics   `json:"messages"`
+	Scheduler SchedulerDiagnostics `json:"scheduler"`
+	Auth      AuthDiagnostics      `json:"auth"`
+}
+
+type DatabaseDiagnostics struct {
+	Driver            string `json:"driver"`
+	Host              string `json:"host"`
+	Name              string `json:"name"`
+	ReadRetryAttempts int    `json:"readRetryAttempts"`
+	ContentEncrypted  bool   `json:"contentEncrypted"`
+	ReplicaConfigured bool   `json:"replicaConfigured"`
+}
+
+type RedisDiagnostics struct {
+	Enabled bool   `json:"enabled"` // False when Redis was unreachable on boot and caching is disabled
+	Host    string `json:"host"`
+}
+
+type WebhookDiagnostics struct {
+	Host              string `json:"host"` // Only the host; the path may carry a token
+	TimeoutSeconds    int    `json:"timeoutSeconds"`
+	MaxConcurrency    int    `json:"maxConcurrency"`
+	BodySuccessCheck  bool   `json:"bodySuccessCheck"`
+	AuthKeyConfigured bool   `json:"authKeyConfigured"`
+}
+
+type AlertDiagnostics struct {
+	Configured           bool `json:"configured"`
+	IterationCount       int  `json:"iterationCount"`
+	DeadLetterConfigured bool `json:"deadLetterConfigured"`
+}
+
+type MessageDiagnostics struct {
+	Queues              []string `json:"queues"`
+	AllowedCountryCodes []string `json:"allowedCountryCodes"`
+	QuietHoursEnabled   bool     `json:"quietHoursEnabled"`
+	FixedFailureSeed    bool     `json:"fixedFailureSeed"`
+	MaxSendsPerRun      int      `json:"maxSendsPerRun"`
+}
+
+type SchedulerDiagnostics struct {
+	IntervalSeconds     int `json:"intervalSeconds"`
+	StartDelaySeconds   int `json:"startDelaySeconds"`
+	IdleStopRuns        int `json:"idleStopRuns"`
+	StopTimeoutSeconds  int `json:"stopTimeoutSeconds"`
+	MinIntervalSeconds  int `json:"minIntervalSeconds"`
+	FailureRetrySeconds int `json:"failureRetrySeconds"`
+}
+
+type AuthDiagnostics struct {
+	DLRAPIKeyConfigured     bool `json:"dlrApiKeyConfigured"`
+	TrustedAPIKeyConfigured bool `json:"trustedApiKeyConfigured"`
+	TrustedMaxPageSize      int  `json:"trustedMaxPageSize"`
+}
+
+// DiagnosticsHandler reports the startup configuration, which is otherwise only visible in the boot logs.
+type DiagnosticsHandler struct {
+	diagnostics Diagnostics
+	config      environments.Config // Sanitized copy served by GetConfig
+}
+
+// NewDiagnosticsHandler captures the loaded config once; driver is the database driver in use and
+// redisEnabled whether the Redis client connected on boot.
+func NewDiagnosticsHandler(cfg *environments.Config, driver string, redisEnabled bool) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		diagnostics: buildDiagnostics(cfg, driver, redisEnabled),
+		config:      sanitizeConfig(*cfg),
+	}
+}
+
+// redacted replaces secrets that are set, so the output still shows whether they are configured.
+const redacted = "[REDACTED]"
+
+// sanitizeConfig returns a copy of cfg with passwords and keys redacted and webhook URLs cut down
+// to their host, since their path may carry a token.
+func sanitizeConfig(cfg environments.Config) environments.Config {
+	cfg.Database.Password = redact(cfg.Database.Password)
+	cfg.Database.ContentEncryptionKey = redact(cfg.Database.ContentEncryptionKey)
+	cfg.Database.ReplicaDSN = redact(cfg.Database.ReplicaDSN) // Carries the replica password
+	cfg.Redis.Password = redact(cfg.Redis.Password)
+	cfg.Webhook.URL = urlHost(cfg.Webhook.URL)
+	cfg.Webhook.AuthKey = redact(cfg.Webhook.AuthKey)
+	cfg.Alert.WebhookURL = urlHost(cfg.Alert.WebhookURL)
+	cfg.Alert.DeadLetterWebhookURL = urlHost(cfg.Alert.DeadLetterWebhookURL)
+	cfg.Auth.MessagesAPIKey = redact(cfg.Auth.MessagesAPIKey)
+	cfg.Auth.SchedulerAPIKey = redact(cfg.Auth.SchedulerAPIKey)
+	cfg.Auth.DLRAPIKey = redact(cfg.Auth.DLRAPIKey)
+	cfg.Auth.TrustedAPIKey = redact(cfg.Auth.TrustedAPIKey)
+	cfg.Archive.AccessKey = redact(cfg.Archive.AccessKey)
+	cfg.Archive.SecretKey = redact(cfg.Archive.SecretKey)
+
+	// Slices are the only fields shared with the original; copy them so the handler owns its data.
+	cfg.Message.Queues = slices.Clone(cfg.Message.Queues)
+	cfg.Message.AllowedCountryCodes = slices.Clone(cfg.Message.AllowedCountryCodes)
+	return cfg
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+func buildDiagnostics(cfg *environments.Config, driver string, redisEnabled bool) Diagnostics {
+	return Diagnostics{
+		Database: DatabaseDiagnostics{
+			Driver:            driver,
+			Host:              cfg.Database.Host + ":" + cfg.Database.Port,
+			Name:              cfg.Database.DBName,
+			ReadRetryAttempts: cfg.Database.ReadRetryAttempts,
+			ContentEncrypted:  cfg.Database.ContentEncryptionKey != "",
+			ReplicaConfigured: cfg.Database.ReplicaDSN != "",
+		},
+		Redis: RedisDiagnostics{
+			Enabled: redisEnabled,
+			Host:    cfg.Redis.Host + ":" + cfg.Redis.Port,
+		},
+		Webhook: WebhookDiagnostics{
+			Host:              urlHost(cfg.Webhook.URL),
+			TimeoutSeconds:    int(cfg.Webhook.Timeout.Seconds()),
+			MaxConcurrency:    cfg.Webhook.MaxConcurrency,
+			BodySuccessCheck:  cfg.Webhook.SuccessField != "",
+			AuthKeyConfigured: cfg.Webhook.AuthKey != "",
+		},
+		Alerts: AlertDiagnostics{
+			Configured:     cfg.Alert.WebhookURL != "" && cfg.Alert.IterationCount > 0,
+			IterationCount: cfg.Alert.IterationCount,
+
+			DeadLetterConfigured: cfg.Alert.DeadLetterWebhookURL != "",
+		},
+		Messages: MessageDiagnostics{
+			Queues:              cfg.Message.Queues,
+			AllowedCountryCodes: cfg.Message.AllowedCountryCodes,
+			QuietHoursEnabled:   cfg.Message.QuietHoursStart != "" && cfg.Message.QuietHoursEnd != "",
+			FixedFailureSeed:    cfg.Message.FailureSeed != 0,
+			MaxSendsPerRun:      cfg.Message.MaxSendsPerRun,
+		},
+		Scheduler: SchedulerDiagnostics{
+			IntervalSeconds:     int(cfg.Message.SendInterval.Seconds()),
+			StartDelaySeconds:   int(cfg.Server.SchedulerStartDelay.Seconds()),
+			IdleStopRuns:        cfg.Server.SchedulerIdleStopRuns,
+			StopTimeoutSeconds:  int(cfg.Server.SchedulerStopTimeout.Seconds()),
+			MinIntervalSeconds:  int(cfg.Message.MinSendInterval.Seconds()),
+			FailureRetrySeconds: int(cfg.Server.SchedulerFailureRetry.Seconds()),
+		},
+		Auth: AuthDiagnostics{
+			DLRAPIKeyConfigured:     cfg.Auth.DLRAPIKey != "",
+			TrustedAPIKeyConfigured: cfg.Auth.TrustedAPIKey != "",
+			TrustedMaxPageSize:      cfg.Auth.TrustedMaxPageSize,
+		},
+	}
+}
+
+// urlHost returns only the host of rawURL, or an empty string if it cannot be parsed.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// GetDiagnostics godoc
+// @Summary Get startup diagnostics
+// @Description Reports which optional subsystems are active (Redis, alerts, quiet hours, queues, database driver) as loaded on boot. Secrets are redacted.
+// @Tags admin
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Router /api/v1/admin/diagnostics [get]
+func (h *DiagnosticsHandler) GetDiagnostics(c echo.Context) error {
+	return response.Ok(c, h.diagnostics)
+}
+
+// GetConfig godoc
+// @Summary Get the effective config
+// @Description Returns the config loaded on boot, for spotting config drift. Passwords and keys are replaced by "[REDACTED]" when set, webhook URLs are reduced to their host, and durations are in nanoseconds.
+// @Tags admin
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Router /api/v1/admin/config [get]
+func (h *DiagnosticsHandler) GetConfig(c echo.Context) error {
+	return response.Ok(c, h.config)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/environments"
+)
+
+func TestGetDiagnostics_ReflectsConfigWithoutSecrets(t *testing.T) {
+	cfg := &environments.Config{
+		Database: environments.DatabaseConfig{Host: "db", Port: "3306", DBName: "insider", Password: "db-secret"},
+		Redis:    environments.RedisConfig{Host: "cache", Port: "6379", Password: "redis-secret"},
+		Webhook: environments.WebhookConfig{
+			URL:     "https://webhook.site/token-in-path",
+			AuthKey: "webhook-secret",
+			Timeout: 30 * time.Second,
+		},
+		Alert: environments.AlertConfig{WebhookURL: "https://alerts.example.com/hook", IterationCount: 3},
+		Message: environments.MessageConfig{
+			Queues:          []string{"transactional"},
+			QuietHoursStart: "22:00",
+			QuietHoursEnd:   "08:00",
+		},
+		Auth: environments.AuthConfig{SchedulerAPIKey: "scheduler-secret"},
+	}
+	handler := NewDiagnosticsHandler(cfg, "mysql", false)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/diagnostics", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetDiagnostics(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetDiagnostics returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	for _, secret := range []string{"db-secret", "redis-secret", "webhook-secret", "scheduler-secret", "token-in-path"} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Fatalf("response leaks %q: %s", secret, rec.Body.String())
+		}
+	}
+
+	var body struct {
+		Data Diagnostics `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	got := body.Data
+	if got.Database.Driver != "mysql" || got.Redis.Enabled {
+		t.Errorf("unexpected database/redis diagnostics: %+v %+v", got.Database, got.Redis)
+	}
+	if !got.Alerts.Configured || !got.Messages.QuietHoursEnabled {
+		t.Errorf("expected alerts and quiet hours to be reported as active: %+v %+v", got.Alerts, got.Messages)
+	}
+	if !got.Webhook.AuthKeyConfigured || got.Webhook.Host != "webhook.site" || got.Webhook.BodySuccessCheck {
+		t.Errorf("unexpected webhook diagnostics: %+v", got.Webhook)
+	}
+	if got.Auth.DLRAPIKeyConfigured {
+		t.Errorf("expected DLR key to be reported as missing")
+	}
+}
+
+func TestGetConfig_RedactsSecretsAndKeepsSettings(t *testing.T) {
+	cfg := &environments.Config{
+		Database: environments.DatabaseConfig{
+			Host:                 "db",
+			Password:             "db-secret",
+			ContentEncryptionKey: "content-key",
+			ReplicaDSN:           "reader:replica-secret@tcp(replica:3306)/insider?parseTime=true",
+		},
+		Redis: environments.RedisConfig{Password: "redis-secret"},
+		Webhook: environments.WebhookConfig{
+			URL:     "https://webhook.site/token-in-path",
+			AuthKey: "webhook-secret",
+			Timeout: 30 * time.Second,
+		},
+		Message: environments.MessageConfig{BatchSize: 25, SendInterval: 2 * time.Minute, MaxContentLength: 160},
+		Alert:   environments.AlertConfig{WebhookURL: "https://alerts.example.com/alert-token"},
+		Auth: environments.AuthConfig{
+			MessagesAPIKey:  "messages-secret",
+			SchedulerAPIKey: "scheduler-secret",
+			TrustedAPIKey:   "trusted-secret",
+		},
+	}
+	handler := NewDiagnosticsHandler(cfg, "mysql", true)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetConfig(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetConfig returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	for _, secret := range []string{
+		"db-secret", "redis-secret", "webhook-secret", "messages-secret", "scheduler-secret",
+		"trusted-secret", "token-in-path", "alert-token", "content-key", "replica-secret",
+	} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Fatalf("response leaks %q: %s", secret, rec.Body.String())
+		}
+	}
+
+	var body struct {
+		Data environments.Config `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	got := body.Data
+	if got.Message.BatchSize != 25 || got.Message.SendInterval != 2*time.Minute || got.Message.MaxContentLength != 160 {
+		t.Errorf("expected message settings to be reported, got %+v", got.Message)
+	}
+	if got.Webhook.Timeout != 30*time.Second || got.Webhook.URL != "webhook.site" {
+		t.Errorf("expected webhook timeout and host to be reported, got %+v", got.Webhook)
+	}
+	if got.Auth.SchedulerAPIKey != redacted || got.Auth.DLRAPIKey != "" {
+		t.Errorf("expected set keys to show as redacted and unset ones as empty, got %+v", got.Auth)
+	}
+	if cfg.Auth.SchedulerAPIKey != "scheduler-secret" {
+		t.Errorf("expected the loaded config to be left untouched")
+	}
+}
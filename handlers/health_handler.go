@@ -11,11 +11,20 @@ import (
 	"github.com/onurcolak/insider-message-service/pkg/redis"
 )
 
+// pendingCounter is the stats source for the backlog check; MessageService implements it.
+type pendingCounter interface {
+	GetStats(ctx context.Context) (pending, sent, failed int64, err error)
+}
+
 // HealthHandler handles health checks.
 type HealthHandler struct {
 	db           *sqlx.DB
 	redis        *redis.Client
 	checkTimeout time.Duration
+
+	// Reports degraded when more than backlogThreshold messages are pending (nil = no check).
+	backlog          pendingCounter
+	backlogThreshold int64
 }
 
 func NewHealthHandler(db *sqlx.DB, redisClient *redis.Client) *HealthHandler {
@@ -26,6 +35,17 @@ func NewHealthHandler(db *sqlx.DB, redisClient *redis.Client) *HealthHandler {
 	}
 }
 
+// SetBacklogCheck makes the health check report degraded while more than threshold messages are
+// pending, i.e. the scheduler is not keeping up. A threshold of 0 or less disables the check.
+func (h *HealthHandler) SetBacklogCheck(stats pendingCounter, threshold int64) {
+	if threshold <= 0 {
+		h.backlog = nil
+		return
+	}
+	h.backlog = stats
+	h.backlogThreshold = threshold
+}
+
 // Health returns overall status and basic component statuses (DB and Redis).
 // @Summary Health check
 // @Description Returns overall status with DB and Redis connectivity results
@@ -38,6 +58,17 @@ func (h *HealthHandler) Health(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), h.checkTimeout)
 	defer cancel()
 
+	overallStatus, components := h.Check(ctx)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"status":     overallStatus,
+		"timestamp":  time.Now().Format(time.RFC3339),
+		"components": components,
+	})
+}
+
+// Check pings each dependency and returns the overall status with per-component details.
+func (h *HealthHandler) Check(ctx context.Context) (string, map[string]any) {
 	overallStatus := "ok"
 
 	dbStatus := "up"
@@ -59,16 +90,63 @@ func (h *HealthHandler) Health(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]any{
-		"status":    overallStatus,
-		"timestamp": time.Now().Format(time.RFC3339),
-		"components": map[string]any{
-			"database": map[string]any{
-				"status": dbStatus,
-			},
-			"redis": map[string]any{
-				"status": redisStatus,
-			},
+	database := map[string]any{
+		"status": dbStatus,
+	}
+	if dbStatus == "up" {
+		database["pool"] = poolStats(h.db)
+	}
+
+	components := map[string]any{
+		"database": database,
+		"redis": map[string]any{
+			"status": redisStatus,
 		},
-	})
+	}
+
+	// The backlog is counted in the database, so there is nothing to check while it is down.
+	if h.backlog != nil && dbStatus == "up" {
+		backlog := h.checkBacklog(ctx)
+		if backlog["status"] == "over_threshold" && overallStatus == "ok" {
+			overallStatus = "degraded"
+		}
+		components["backlog"] = backlog
+	}
+
+	return overallStatus, components
+}
+
+// checkBacklog compares the pending count with the configured threshold. A failed count is
+// reported as unknown rather than degrading the overall status.
+func (h *HealthHandler) checkBacklog(ctx context.Context) map[string]any {
+	backlog := map[string]any{
+		"threshold": h.backlogThreshold,
+	}
+
+	pending, _, _, err := h.backlog.GetStats(ctx)
+	if err != nil {
+		backlog["status"] = "unknown"
+		return backlog
+	}
+
+	backlog["pending"] = pending
+	backlog["status"] = "ok"
+	if pending > h.backlogThreshold {
+		backlog["status"] = "over_threshold"
+	}
+	return backlog
+}
+
+// poolStats exposes connection pool saturation so latency can be traced to a starved pool.
+func poolStats(db *sqlx.DB) map[string]any {
+	stats := db.Stats()
+
+	return map[string]any{
+		"maxOpenConnections": stats.MaxOpenConnections,
+		"openConnections":    stats.OpenConnections,
+		"inUse":              stats.InUse,
+		"idle":               stats.Idle,
+		"waitCount":          stats.WaitCount,
+		"waitDuration":       stats.WaitDuration.String(),
+	}
 }
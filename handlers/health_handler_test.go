@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestHealthCheck_IncludesPoolStatsWhenDatabaseIsUp(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(10)
+
+	mock.ExpectPing()
+
+	status, components := NewHealthHandler(sqlx.NewDb(db, "mysql"), nil).Check(context.Background())
+	if status != "ok" {
+		t.Fatalf("expected status ok, got %q", status)
+	}
+
+	database := components["database"].(map[string]any)
+	pool, ok := database["pool"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected pool stats in database component, got %v", database)
+	}
+
+	if pool["maxOpenConnections"] != 10 {
+		t.Fatalf("expected maxOpenConnections 10, got %v", pool["maxOpenConnections"])
+	}
+	open, inUse, idle := pool["openConnections"].(int), pool["inUse"].(int), pool["idle"].(int)
+	if open < 1 || inUse+idle != open {
+		t.Fatalf("implausible pool stats: open=%d inUse=%d idle=%d", open, inUse, idle)
+	}
+	if _, ok := pool["waitCount"].(int64); !ok {
+		t.Fatalf("expected waitCount to be reported, got %v", pool["waitCount"])
+	}
+}
+
+func TestHealthCheck_OmitsPoolStatsWhenDatabaseIsDown(t *testing.T) {
+	_, components := NewHealthHandler(nil, nil).Check(context.Background())
+
+	database := components["database"].(map[string]any)
+	if _, ok := database["pool"]; ok {
+		t.Fatalf("expected no pool stats for a down database, got %v", database)
+	}
+}
+
+// fakePendingCounter reports a fixed pending count.
+type fakePendingCounter struct {
+	pending int64
+}
+
+func (f *fakePendingCounter) GetStats(ctx context.Context) (int64, int64, int64, error) {
+	return f.pending, 0, 0, nil
+}
+
+func TestHealthCheck_DegradedWhenBacklogExceedsThreshold(t *testing.T) {
+	cases := []struct {
+		name    string
+		pending int64
+		want    string
+	}{
+		{name: "under threshold", pending: 100, want: "ok"},
+		{name: "at threshold", pending: 500, want: "ok"},
+		{name: "over threshold", pending: 501, want: "degraded"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+			mock.ExpectPing()
+
+			handler := NewHealthHandler(sqlx.NewDb(db, "mysql"), nil)
+			handler.SetBacklogCheck(&fakePendingCounter{pending: tc.pending}, 500)
+
+			status, components := handler.Check(context.Background())
+			if status != tc.want {
+				t.Fatalf("expected status %q, got %q", tc.want, status)
+			}
+
+			backlog := components["backlog"].(map[string]any)
+			if backlog["pending"] != tc.pending {
+				t.Fatalf("expected pending %d in backlog component, got %v", tc.pending, backlog)
+			}
+		})
+	}
+}
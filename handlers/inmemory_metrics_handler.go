@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/pkg/metrics"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+)
+
+type metricsSnapshotter interface {
+	Snapshot() metrics.Snapshot
+}
+
+// InMemoryMetricsHandler serves the in-memory metrics registry as JSON, for deployments that
+// cannot scrape /metrics.
+type InMemoryMetricsHandler struct {
+	registry metricsSnapshotter
+}
+
+// NewInMemoryMetricsHandler serves the given registry; pass nil when recording is disabled.
+func NewInMemoryMetricsHandler(registry metricsSnapshotter) *InMemoryMetricsHandler {
+	return &InMemoryMetricsHandler{registry: registry}
+}
+
+// GetMetrics godoc
+// @Summary Get in-memory metrics
+// @Description Returns counters (runs, messages sent, failed, deduped, suppressed) and gauges (average provider latency in ms, messages in the last run) recorded since startup. Recording is opt-in via IN_MEMORY_METRICS_ENABLED.
+// @Tags admin
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Router /api/v1/admin/metrics.json [get]
+func (h *InMemoryMetricsHandler) GetMetrics(c echo.Context) error {
+	snapshot := metrics.Snapshot{Counters: map[string]int64{}, Gauges: map[string]float64{}}
+	if h.registry != nil {
+		snapshot = h.registry.Snapshot()
+	}
+
+	return response.Ok(c, map[string]any{
+		"enabled":  h.registry != nil,
+		"counters": snapshot.Counters,
+		"gauges":   snapshot.Gauges,
+	})
+}
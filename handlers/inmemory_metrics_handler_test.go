@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/pkg/metrics"
+)
+
+func TestGetInMemoryMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.Add("messages_sent", 4)
+	registry.Observe("send_latency_avg_ms", 20)
+	registry.Observe("send_latency_avg_ms", 40)
+
+	for _, tc := range []struct {
+		name      string
+		handler   *InMemoryMetricsHandler
+		enabled   bool
+		sent      int64
+		latencyMs float64
+	}{
+		{name: "enabled", handler: NewInMemoryMetricsHandler(registry), enabled: true, sent: 4, latencyMs: 30},
+		{name: "disabled", handler: NewInMemoryMetricsHandler(nil)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/metrics.json", nil)
+			rec := httptest.NewRecorder()
+
+			if err := tc.handler.GetMetrics(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("GetMetrics returned error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+
+			var body struct {
+				Data struct {
+					Enabled  bool               `json:"enabled"`
+					Counters map[string]int64   `json:"counters"`
+					Gauges   map[string]float64 `json:"gauges"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to unmarshal response body: %v", err)
+			}
+			if body.Data.Enabled != tc.enabled || body.Data.Counters["messages_sent"] != tc.sent ||
+				body.Data.Gauges["send_latency_avg_ms"] != tc.latencyMs {
+				t.Fatalf("unexpected response: %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,28 +1,158 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/onurcolak/insider-message-service/internal/domain"
-	"github.com/onurcolak/insider-message-service/internal/service"
+	"github.com/onurcolak/insider-message-service/internal/middlewares"
 	"github.com/onurcolak/insider-message-service/pkg/response"
 	"github.com/onurcolak/insider-message-service/pkg/validator"
 )
 
+// messageService is the subset of service.MessageService used by the handlers,
+// kept small so handlers can be tested with a fake.
+type messageService interface {
+	GetSentMessages(ctx context.Context, filter domain.MessageFilter, page, pageSize int) ([]domain.Message, int64, error)
+	GetAllMessages(ctx context.Context, filter domain.MessageFilter, page, pageSize int) ([]domain.Message, int64, error)
+	SearchMessages(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error)
+	ExportMessages(ctx context.Context, filter domain.MessageFilter, limit int, fn func(domain.Message) error) (bool, error)
+	CreateMessage(ctx context.Context, msg domain.NewMessage) (*domain.Message, error)
+	CreateCampaign(ctx context.Context, campaign domain.NewCampaign) (*domain.CampaignResult, error)
+	SendPreview(msg *domain.Message) domain.SendPreview
+	GetStats(ctx context.Context) (pending, sent, failed int64, err error)
+	EstimateCost(ctx context.Context) (*domain.CostEstimate, error)
+	OutcomeCounts() domain.OutcomeCounts
+	GetCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error)
+	GetCachedMessagesByIDs(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error)
+	ReplayFailedMessage(ctx context.Context, id int64) error
+	ReplayAllFailedMessages(ctx context.Context, age domain.ReplayAge) (int64, error)
+	ReplayFailedMessagesInWindow(ctx context.Context, from, to time.Time) (int64, error)
+	GetChangesSince(ctx context.Context, after domain.ChangeCursor, limit int) ([]domain.Message, domain.ChangeCursor, error)
+	GetFailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error)
+	GetThroughput(ctx context.Context, window time.Duration) ([]domain.HourlySends, error)
+	GetSLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error)
+	GetGroupedStats(ctx context.Context) (map[string]map[domain.MessageStatus]int64, error)
+	CancelPendingMessages(ctx context.Context, filter domain.CancelFilter, all bool) (int64, error)
+	GetMessage(ctx context.Context, id int64) (*domain.Message, error)
+	ForceFailMessage(ctx context.Context, id int64, reason string) (*domain.Message, error)
+	ResetRetries(ctx context.Context, id int64) (*domain.Message, error)
+	GetQueueHead(ctx context.Context, queue string, limit int) ([]domain.Message, error)
+}
+
 type MessageHandler struct {
-	service *service.MessageService
+	service            messageService
+	trustedMaxPageSize int
+	stripControlChars  bool
 }
 
-func NewMessageHandler(service *service.MessageService) *MessageHandler {
+func NewMessageHandler(service messageService) *MessageHandler {
 	return &MessageHandler{service: service}
 }
 
+// SetTrustedMaxPageSize lets requests authenticated with a trusted key ask for up to n
+// items per page instead of defaultMaxPageSize.
+func (h *MessageHandler) SetTrustedMaxPageSize(n int) {
+	h.trustedMaxPageSize = n
+}
+
+// SetStripControlChars makes CreateMessage remove disallowed control characters from the
+// content instead of rejecting it.
+func (h *MessageHandler) SetStripControlChars(strip bool) {
+	h.stripControlChars = strip
+}
+
+// maxPageSize returns the pageSize cap for the key that authenticated the request.
+func (h *MessageHandler) maxPageSize(c echo.Context) int {
+	if middlewares.IsTrustedKey(c) && h.trustedMaxPageSize > defaultMaxPageSize {
+		return h.trustedMaxPageSize
+	}
+	return defaultMaxPageSize
+}
+
+// CreateMessageResponse is the created message plus the server-computed send preview, so
+// clients see exactly what will be sent.
+type CreateMessageResponse struct {
+	*domain.Message
+	Preview domain.SendPreview `json:"preview"`
+}
+
 type CreateMessageRequest struct {
-	Content     string `json:"content" validate:"required,max=1000"`
-	PhoneNumber string `json:"phoneNumber" validate:"required"`
+	Content     string   `json:"content" validate:"required,max=1000,nocontrol"`
+	PhoneNumber string   `json:"phoneNumber" validate:"required"`
+	Tags        []string `json:"tags,omitempty" validate:"omitempty,max=10,dive,required,max=50,excludesall=0x2C"`
+	Queue       string   `json:"queue,omitempty" validate:"omitempty,max=50"`
+
+	// TimeoutSeconds overrides the webhook timeout for this message (capped by the server).
+	TimeoutSeconds *int `json:"timeoutSeconds,omitempty" validate:"omitempty,min=1"`
+
+	// SendAt holds the message back until the given time; DelaySeconds does the same relative to
+	// now, for up to a year. At most one of them may be set.
+	SendAt       *time.Time `json:"sendAt,omitempty"`
+	DelaySeconds *int       `json:"delaySeconds,omitempty" validate:"omitempty,min=1,max=31536000"`
+
+	// ClientMessageID is the client's own unique id for the message. Creating a message with an
+	// id the same API key used before returns the earlier message with 200 instead of creating a
+	// new one.
+	ClientMessageID string `json:"clientMessageId,omitempty" validate:"omitempty,max=100,nocontrol"`
+}
+
+// CampaignRequest creates one message per recipient from Template, a text/template rendered
+// with the recipient's variables, e.g. "Hi {{.name}}, your code is {{.code}}".
+type CampaignRequest struct {
+	Template   string                     `json:"template" validate:"required,max=1000,nocontrol"`
+	Queue      string                     `json:"queue,omitempty" validate:"omitempty,max=50"`
+	Recipients []CampaignRecipientRequest `json:"recipients" validate:"required,min=1,max=1000,dive"`
+}
+
+type CampaignRecipientRequest struct {
+	PhoneNumber string            `json:"phoneNumber" validate:"required"`
+	Variables   map[string]string `json:"variables,omitempty" validate:"omitempty,max=20,dive,keys,required,max=50,endkeys,max=200,nocontrol"`
+}
+
+// CachedMessagesBatchRequest lists the message ids to look up in the cache.
+type CachedMessagesBatchRequest struct {
+	IDs []int64 `json:"ids" validate:"required,min=1,max=1000,dive,gt=0"`
+}
+
+// CancelMessagesRequest selects pending messages to cancel. Filters are combined with AND;
+// with no filter set, All must be true.
+type CancelMessagesRequest struct {
+	Tag           string     `json:"tag,omitempty" validate:"omitempty,max=50"`
+	PhonePrefix   string     `json:"phonePrefix,omitempty" validate:"omitempty,max=20"`
+	CreatedBefore *time.Time `json:"createdBefore,omitempty"`
+	All           bool       `json:"all,omitempty"`
+}
+
+// ForceFailRequest carries the manual reason stored as the message's last error.
+type ForceFailRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ReplayMessagesRequest optionally limits a replay to messages that failed in [from, to).
+// An empty body replays every failed message.
+type ReplayMessagesRequest struct {
+	From *time.Time `json:"from,omitempty" validate:"required_with=To"`
+	To   *time.Time `json:"to,omitempty" validate:"required_with=From"`
+}
+
+// serviceError maps a service error to a response: timeouts become 504, everything else 500.
+func serviceError(c echo.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return response.GatewayTimeout(c)
+	}
+	return response.InternalServerError(c, err)
 }
 
 // GetSentMessages godoc
@@ -34,24 +164,158 @@ type CreateMessageRequest struct {
 // @Param x-ins-auth-key header string true "API key for messages"
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Page size (default: 20, max: 100)"
+// @Param tag query string false "Only messages carrying this tag"
+// @Param fields query string false "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)"
 // @Success 200 {object} response.PaginatedResponse
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/messages/sent [get]
 func (h *MessageHandler) GetSentMessages(c echo.Context) error {
-	page, pageSize, err := parsePaginationParams(c)
+	page, pageSize, err := parsePaginationParams(c, h.maxPageSize(c))
 	if err != nil {
 		return response.BadRequest(c, err)
 	}
 
-	messages, totalCount, err := h.service.GetSentMessages(c.Request().Context(), page, pageSize)
+	fields, err := domain.ParseMessageFields(c.QueryParam("fields"))
 	if err != nil {
-		return response.InternalServerError(c, err)
+		return response.BadRequest(c, err)
+	}
+
+	filter := domain.MessageFilter{Tag: c.QueryParam("tag"), Fields: fields}
+
+	messages, totalCount, err := h.service.GetSentMessages(c.Request().Context(), filter, page, pageSize)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return paginatedMessages(c, messages, fields, page, pageSize, totalCount)
+}
+
+// GetTruncatedMessages godoc
+// @Summary Get truncated messages
+// @Description Retrieves a paginated list of messages whose content exceeded the max length and was truncated when sent, with their original length
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 20, max: 100)"
+// @Param fields query string false "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)"
+// @Success 200 {object} response.PaginatedResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/truncated [get]
+func (h *MessageHandler) GetTruncatedMessages(c echo.Context) error {
+	page, pageSize, err := parsePaginationParams(c, h.maxPageSize(c))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	fields, err := domain.ParseMessageFields(c.QueryParam("fields"))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	filter := domain.MessageFilter{TruncatedOnly: true, Fields: fields}
+
+	messages, totalCount, err := h.service.GetAllMessages(c.Request().Context(), filter, page, pageSize)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return paginatedMessages(c, messages, fields, page, pageSize, totalCount)
+}
+
+// maxSearchQueryLength bounds the q parameter of SearchMessages.
+const maxSearchQueryLength = 100
+
+// SearchMessages godoc
+// @Summary Search messages
+// @Description Finds messages whose phone number starts with q or whose content contains q. Phone matches come first, newest first within each group. With content encryption on, only phone number prefixes (digits with an optional leading +) can be searched; other queries get 400.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param q query string true "Phone number prefix or content text (max 100 characters)"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 20, max: 100)"
+// @Success 200 {object} response.PaginatedResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/search [get]
+func (h *MessageHandler) SearchMessages(c echo.Context) error {
+	page, pageSize, err := parsePaginationParams(c, h.maxPageSize(c))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return response.BadRequest(c, fmt.Errorf("q is required"))
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		return response.BadRequest(c, fmt.Errorf("q must be at most %d characters", maxSearchQueryLength))
+	}
+
+	messages, totalCount, err := h.service.SearchMessages(c.Request().Context(), q, page, pageSize)
+	if errors.Is(err, domain.ErrContentSearchUnavailable) {
+		return response.BadRequest(c, err)
+	}
+	if err != nil {
+		return serviceError(c, err)
 	}
 
 	return response.Paginated(c, messages, page, pageSize, totalCount)
 }
 
+// GetCampaignMessages godoc
+// @Summary Get the messages of a campaign
+// @Description Retrieves a paginated list of the messages created by one campaign
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param campaignId path string true "Campaign id returned on creation"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Page size (default: 20, max: 100)"
+// @Param status query string false "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)"
+// @Param fields query string false "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)"
+// @Success 200 {object} response.PaginatedResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/campaign/{campaignId} [get]
+func (h *MessageHandler) GetCampaignMessages(c echo.Context) error {
+	page, pageSize, err := parsePaginationParams(c, h.maxPageSize(c))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	fields, err := domain.ParseMessageFields(c.QueryParam("fields"))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	filter := domain.MessageFilter{CampaignID: c.Param("campaignId"), Fields: fields}
+	if statusStr := c.QueryParam("status"); statusStr != "" {
+		parsedStatus, err := domain.ParseMessageStatus(statusStr)
+		if err != nil {
+			return response.BadRequest(c, err)
+		}
+		filter.Status = &parsedStatus
+	}
+
+	messages, totalCount, err := h.service.GetAllMessages(c.Request().Context(), filter, page, pageSize)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return paginatedMessages(c, messages, fields, page, pageSize, totalCount)
+}
+
 // GetAllMessages godoc
 // @Summary Get all messages
 // @Description Retrieves a paginated list of all messages with optional status filter
@@ -61,44 +325,150 @@ func (h *MessageHandler) GetSentMessages(c echo.Context) error {
 // @Param x-ins-auth-key header string true "API key for messages"
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Page size (default: 20, max: 100)"
-// @Param status query string false "Filter by status (pending, sent, failed)"
+// @Param status query string false "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)"
+// @Param tag query string false "Only messages carrying this tag"
+// @Param fields query string false "Comma-separated message fields to return, e.g. id,status,phoneNumber (default: all)"
 // @Success 200 {object} response.PaginatedResponse
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/messages [get]
 func (h *MessageHandler) GetAllMessages(c echo.Context) error {
-	page, pageSize, err := parsePaginationParams(c)
+	page, pageSize, err := parsePaginationParams(c, h.maxPageSize(c))
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	fields, err := domain.ParseMessageFields(c.QueryParam("fields"))
 	if err != nil {
 		return response.BadRequest(c, err)
 	}
 
 	statusStr := c.QueryParam("status")
+	filter := domain.MessageFilter{Tag: c.QueryParam("tag"), Fields: fields}
 
 	// Convert status string to pointer (optional filter).
-	var status *domain.MessageStatus
 	if statusStr != "" {
-		parsedStatus := domain.MessageStatus(statusStr)
-		status = &parsedStatus
+		parsedStatus, err := domain.ParseMessageStatus(statusStr)
+		if err != nil {
+			return response.BadRequest(c, err)
+		}
+		filter.Status = &parsedStatus
+	}
+
+	messages, totalCount, err := h.service.GetAllMessages(c.Request().Context(), filter, page, pageSize)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return paginatedMessages(c, messages, fields, page, pageSize, totalCount)
+}
+
+// paginatedMessages responds with a page of messages, reduced to the requested fields if any.
+func paginatedMessages(c echo.Context, messages []domain.Message, fields []string, page, pageSize int, totalCount int64) error {
+	if len(fields) == 0 {
+		return response.Paginated(c, messages, page, pageSize, totalCount)
 	}
 
-	messages, totalCount, err := h.service.GetAllMessages(c.Request().Context(), status, page, pageSize)
+	projected, err := projectMessages(messages, fields)
 	if err != nil {
 		return response.InternalServerError(c, err)
 	}
+	return response.Paginated(c, projected, page, pageSize, totalCount)
+}
 
-	return response.Paginated(c, messages, page, pageSize, totalCount)
+// projectMessages encodes each message with only the given fields; fields that would be omitted
+// when empty stay omitted.
+func projectMessages(messages []domain.Message, fields []string) ([]response.FieldMap, error) {
+	projected := make([]response.FieldMap, 0, len(messages))
+	for _, msg := range messages {
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message %d: %w", msg.ID, err)
+		}
+
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &all); err != nil {
+			return nil, fmt.Errorf("failed to decode message %d: %w", msg.ID, err)
+		}
+
+		selected := make(response.FieldMap, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				selected[field] = value
+			}
+		}
+		projected = append(projected, selected)
+	}
+	return projected, nil
+}
+
+// ExportTruncatedTrailer is the HTTP trailer sent after an export stream; "true" means the row cap
+// was reached and matching messages were left out.
+const ExportTruncatedTrailer = "X-Export-Truncated"
+
+// ExportMessages godoc
+// @Summary Export messages as JSON Lines
+// @Description Streams messages (oldest first) as one JSON object per line. The stream stops at `limit` rows and never exceeds the server ceiling (MESSAGE_EXPORT_MAX_ROWS); the X-Export-Truncated trailer is "true" if matching messages were left out.
+// @Tags messages
+// @Produce application/x-ndjson
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param status query string false "Filter by status (pending, sent, failed, cancelled, deduped, suppressed)"
+// @Param tag query string false "Only messages carrying this tag"
+// @Param limit query int false "Max rows to emit (default and max: server ceiling)"
+// @Success 200 {string} string "JSON Lines"
+// @Failure 400 {object} response.ErrorResponse
+// @Router /api/v1/messages/export.jsonl [get]
+func (h *MessageHandler) ExportMessages(c echo.Context) error {
+	limit := 0
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			return response.BadRequest(c, fmt.Errorf("limit must be a positive integer"))
+		}
+		limit = l
+	}
+
+	filter := domain.MessageFilter{Tag: c.QueryParam("tag")}
+	if statusStr := c.QueryParam("status"); statusStr != "" {
+		parsedStatus, err := domain.ParseMessageStatus(statusStr)
+		if err != nil {
+			return response.BadRequest(c, err)
+		}
+		filter.Status = &parsedStatus
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.Header().Set("Trailer", ExportTruncatedTrailer)
+	res.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(res)
+	truncated, err := h.service.ExportMessages(c.Request().Context(), filter, limit, func(msg domain.Message) error {
+		return enc.Encode(msg)
+	})
+	if err != nil {
+		// The status is already sent, so the client only sees the stream end without the
+		// trailer; returning the error gets it logged.
+		return err
+	}
+
+	res.Header().Set(ExportTruncatedTrailer, strconv.FormatBool(truncated))
+	return nil
 }
 
 // CreateMessage godoc
 // @Summary Create a new message
-// @Description Creates a new message to be sent by the scheduler
+// @Description Creates a new message to be sent by the scheduler. Set sendAt, or delaySeconds relative to now (at most 31536000, i.e. a year), to hold it back until then; setting both is a 400. A clientMessageId already used with the same API key returns the earlier message with 200 instead of creating another. The response includes a preview with the normalized phone number, SMS segment count and whether the content will be truncated.
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param x-ins-auth-key header string true "API key for messages"
 // @Param message body CreateMessageRequest true "Message to create"
+// @Success 200 {object} response.SuccessResponse
 // @Success 201 {object} response.SuccessResponse
 // @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /api/v1/messages [post]
 func (h *MessageHandler) CreateMessage(c echo.Context) error {
@@ -107,45 +477,186 @@ func (h *MessageHandler) CreateMessage(c echo.Context) error {
 		return response.BadRequest(c, err)
 	}
 
+	if h.stripControlChars {
+		req.Content = validator.StripControlChars(req.Content)
+	}
+
 	if err := c.Validate(&req); err != nil {
 		return validator.HandleValidationError(c, err)
 	}
 
-	message, err := h.service.CreateMessage(c.Request().Context(), req.Content, req.PhoneNumber)
+	message, err := h.service.CreateMessage(c.Request().Context(), domain.NewMessage{
+		Content:     req.Content,
+		PhoneNumber: req.PhoneNumber,
+		Tags:        req.Tags,
+		Queue:       req.Queue,
+
+		TimeoutSeconds: req.TimeoutSeconds,
+		SendAt:         req.SendAt,
+		DelaySeconds:   req.DelaySeconds,
+
+		ClientMessageID: req.ClientMessageID,
+		Client:          middlewares.KeyIdentity(c),
+	})
+	if errors.Is(err, domain.ErrDuplicateClientMessageID) && message != nil {
+		return response.OkWithMessage(c, "Message already exists", CreateMessageResponse{
+			Message: message,
+			Preview: h.service.SendPreview(message),
+		})
+	}
 	if err != nil {
+		if errors.Is(err, domain.ErrConflictingSchedule) {
+			return response.BadRequest(c, err)
+		}
+		if isInvalidNewMessage(err) {
+			return response.UnprocessableEntity(c, err)
+		}
 		return response.InternalServerError(c, err)
 	}
 
-	return response.Created(c, "Message created successfully", message)
+	return response.Created(c, "Message created successfully", CreateMessageResponse{
+		Message: message,
+		Preview: h.service.SendPreview(message),
+	})
+}
+
+// isInvalidNewMessage reports whether a create failed on the message itself rather than on storage.
+func isInvalidNewMessage(err error) bool {
+	return errors.Is(err, domain.ErrUnknownQueue) ||
+		errors.Is(err, domain.ErrCountryNotAllowed) ||
+		errors.Is(err, domain.ErrPhoneNumberTooLong) ||
+		errors.Is(err, domain.ErrContentTooLong)
+}
+
+// CreateCampaign godoc
+// @Summary Create a campaign
+// @Description Renders a text/template once per recipient with the recipient's variables and stores every result as a pending message under a shared campaignId, in one transaction. A template that does not parse, a missing variable or an invalid recipient rejects the whole campaign with 422.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param campaign body CampaignRequest true "Template and recipients"
+// @Success 201 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/campaign [post]
+func (h *MessageHandler) CreateCampaign(c echo.Context) error {
+	var req CampaignRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if h.stripControlChars {
+		req.Template = validator.StripControlChars(req.Template)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	campaign := domain.NewCampaign{
+		Template:   req.Template,
+		Queue:      req.Queue,
+		Recipients: make([]domain.CampaignRecipient, 0, len(req.Recipients)),
+	}
+	for _, r := range req.Recipients {
+		campaign.Recipients = append(campaign.Recipients, domain.CampaignRecipient{
+			PhoneNumber: r.PhoneNumber,
+			Variables:   r.Variables,
+		})
+	}
+
+	result, err := h.service.CreateCampaign(c.Request().Context(), campaign)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTemplate) || isInvalidNewMessage(err) {
+			return response.UnprocessableEntity(c, err)
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Created(c, "Campaign created successfully", result)
 }
 
 // GetStats godoc
 // @Summary Get message statistics
-// @Description Returns count of messages by status
+// @Description Returns count of messages by status, plus counts of truncated and skipped messages since the service started
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param x-ins-auth-key header string true "API key for messages"
 // @Success 200 {object} response.SuccessResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/messages/stats [get]
 func (h *MessageHandler) GetStats(c echo.Context) error {
 	pending, sent, failed, err := h.service.GetStats(c.Request().Context())
 	if err != nil {
-		return response.InternalServerError(c, err)
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"pending":  pending,
+		"sent":     sent,
+		"failed":   failed,
+		"total":    pending + sent + failed,
+		"outcomes": h.service.OutcomeCounts(),
+	})
+}
+
+// GetCostEstimate godoc
+// @Summary Estimate the cost of pending messages
+// @Description Projects the cost of sending all pending messages as SMS segments times MESSAGE_SEGMENT_PRICE, broken down by destination calling code
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/cost-estimate [get]
+func (h *MessageHandler) GetCostEstimate(c echo.Context) error {
+	estimate, err := h.service.EstimateCost(c.Request().Context())
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, estimate)
+}
+
+// GetGroupedStats godoc
+// @Summary Get message counts grouped by queue and status
+// @Description Returns a queue -> status matrix of message counts plus per-status totals, from a single grouped query
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/stats/grouped [get]
+func (h *MessageHandler) GetGroupedStats(c echo.Context) error {
+	queues, err := h.service.GetGroupedStats(c.Request().Context())
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	totals := make(map[domain.MessageStatus]int64)
+	for _, statuses := range queues {
+		for status, count := range statuses {
+			totals[status] += count
+		}
 	}
 
 	return response.Ok(c, map[string]any{
-		"pending": pending,
-		"sent":    sent,
-		"failed":  failed,
-		"total":   pending + sent + failed,
+		"queues": queues,
+		"totals": totals,
 	})
 }
 
 // GetCachedMessages godoc
 // @Summary Get cached messages from Redis
-// @Description Returns all messages cached in Redis (bonus feature)
+// @Description Returns all messages cached in Redis (bonus feature). If the Redis scan fails partway, the entries read so far are returned with a `warning`.
 // @Tags messages
 // @Accept json
 // @Produce json
@@ -155,6 +666,40 @@ func (h *MessageHandler) GetStats(c echo.Context) error {
 // @Router /api/v1/messages/cached [get]
 func (h *MessageHandler) GetCachedMessages(c echo.Context) error {
 	cached, err := h.service.GetCachedMessages(c.Request().Context())
+	if errors.Is(err, domain.ErrPartialCacheRead) {
+		return response.OkWithWarning(c, err.Error(), cached)
+	}
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, cached)
+}
+
+// GetCachedMessagesBatch godoc
+// @Summary Get cached messages by id
+// @Description Looks up the Redis cache entries of up to 1000 message ids in pipelined MGETs. Ids without a cache entry are left out of the result.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param request body CachedMessagesBatchRequest true "Message ids"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/cached/batch [post]
+func (h *MessageHandler) GetCachedMessagesBatch(c echo.Context) error {
+	var req CachedMessagesBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	cached, err := h.service.GetCachedMessagesByIDs(c.Request().Context(), req.IDs)
 	if err != nil {
 		return response.InternalServerError(c, err)
 	}
@@ -162,11 +707,240 @@ func (h *MessageHandler) GetCachedMessages(c echo.Context) error {
 	return response.Ok(c, cached)
 }
 
-func parsePaginationParams(c echo.Context) (int, int, error) {
+// GetChanges godoc
+// @Summary Get messages changed since a timestamp
+// @Description Returns messages changed after the cursor (`since`, and `afterId` for rows changed in that same second), oldest first, with the `cursor` and `cursorId` to pass as `since` and `afterId` on the next call
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param since query string true "RFC3339 timestamp; only rows updated after it are returned"
+// @Param afterId query int false "Also return rows updated exactly at since with a higher id (the cursorId of the previous call)"
+// @Param limit query int false "Max rows to return (default: 100, max: 1000)"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/changes [get]
+func (h *MessageHandler) GetChanges(c echo.Context) error {
+	const (
+		defaultLimit = 100
+		maxLimit     = 1000
+	)
+
+	since, err := time.Parse(time.RFC3339, c.QueryParam("since"))
+	if err != nil {
+		return response.BadRequest(c, fmt.Errorf("since must be an RFC3339 timestamp"))
+	}
+
+	limit := defaultLimit
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > maxLimit {
+			return response.BadRequest(c, fmt.Errorf("limit must be between 1 and %d", maxLimit))
+		}
+		limit = l
+	}
+
+	after := domain.ChangeCursor{UpdatedAt: since}
+	if afterIDStr := c.QueryParam("afterId"); afterIDStr != "" {
+		id, err := strconv.ParseInt(afterIDStr, 10, 64)
+		if err != nil || id < 0 {
+			return response.BadRequest(c, fmt.Errorf("afterId must be a non-negative integer"))
+		}
+		after.ID = id
+	}
+
+	messages, cursor, err := h.service.GetChangesSince(c.Request().Context(), after, limit)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"messages": messages,
+		"cursor":   cursor.UpdatedAt.Format(time.RFC3339),
+		"cursorId": cursor.ID,
+	})
+}
+
+// GetFailureReasons godoc
+// @Summary Get top failure reasons
+// @Description Groups failed messages by normalized error and returns the most common reasons
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param limit query int false "Max reasons to return (default: 10, max: 100)"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/failure-reasons [get]
+func (h *MessageHandler) GetFailureReasons(c echo.Context) error {
+	const (
+		defaultLimit = 10
+		maxLimit     = 100
+	)
+
+	limit := defaultLimit
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > maxLimit {
+			return response.BadRequest(c, fmt.Errorf("limit must be between 1 and %d", maxLimit))
+		}
+		limit = l
+	}
+
+	reasons, err := h.service.GetFailureReasons(c.Request().Context(), limit)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, reasons)
+}
+
+// GetQueueHead godoc
+// @Summary Peek at the head of the queue
+// @Description Returns the oldest due pending messages of a queue in the order the scheduler picks them (newest first with MESSAGE_PROCESS_ORDER=lifo), without sending or changing them. For debugging a backlog.
+// @Tags messages
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param queue query string false "Queue (default: default)"
+// @Param limit query int false "Max messages to return (default: 10, max: 100)"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/head [get]
+func (h *MessageHandler) GetQueueHead(c echo.Context) error {
+	const (
+		defaultLimit = 10
+		maxLimit     = 100
+	)
+
+	limit := defaultLimit
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > maxLimit {
+			return response.BadRequest(c, fmt.Errorf("limit must be between 1 and %d", maxLimit))
+		}
+		limit = l
+	}
+
+	queue := c.QueryParam("queue")
+	messages, err := h.service.GetQueueHead(c.Request().Context(), queue, limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnknownQueue) {
+			return response.BadRequest(c, err)
+		}
+		return serviceError(c, err)
+	}
+
+	if queue == "" {
+		queue = domain.DefaultQueue
+	}
+	return response.Ok(c, map[string]any{
+		"queue":    queue,
+		"count":    len(messages),
+		"messages": messages,
+	})
+}
+
+// GetThroughput godoc
+// @Summary Get hourly send throughput
+// @Description Counts sent messages per hour of sentAt over the last `hours` hours (oldest first, current hour last), with hours without sends reported as zero. For capacity planning.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param hours query int false "Hours to cover (default: 24, max: 168)"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/throughput [get]
+func (h *MessageHandler) GetThroughput(c echo.Context) error {
+	const (
+		defaultHours = 24
+		maxHours     = 7 * 24
+	)
+
+	hours := defaultHours
+	if hoursStr := c.QueryParam("hours"); hoursStr != "" {
+		n, err := strconv.Atoi(hoursStr)
+		if err != nil || n <= 0 || n > maxHours {
+			return response.BadRequest(c, fmt.Errorf("hours must be between 1 and %d", maxHours))
+		}
+		hours = n
+	}
+
+	buckets, err := h.service.GetThroughput(c.Request().Context(), time.Duration(hours)*time.Hour)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, buckets)
+}
+
+// GetSLACompliance godoc
+// @Summary Get delivery SLA compliance
+// @Description Reports the percentage of messages sent in the last `hours` hours that were sent within `targetMinutes` of being due (creation, or sendAt if later), how many breached it, and whether the 95% objective is met. With no sends in the window, compliance is 100%.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param hours query int false "Hours to cover (default: 24, max: 168)"
+// @Param targetMinutes query int false "Max minutes from being due to send (default: 5, max: 1440)"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/messages/sla [get]
+func (h *MessageHandler) GetSLACompliance(c echo.Context) error {
+	const (
+		defaultHours         = 24
+		maxHours             = 7 * 24
+		defaultTargetMinutes = 5
+		maxTargetMinutes     = 24 * 60
+	)
+
+	hours := defaultHours
+	if hoursStr := c.QueryParam("hours"); hoursStr != "" {
+		n, err := strconv.Atoi(hoursStr)
+		if err != nil || n <= 0 || n > maxHours {
+			return response.BadRequest(c, fmt.Errorf("hours must be between 1 and %d", maxHours))
+		}
+		hours = n
+	}
+
+	targetMinutes := defaultTargetMinutes
+	if targetStr := c.QueryParam("targetMinutes"); targetStr != "" {
+		n, err := strconv.Atoi(targetStr)
+		if err != nil || n <= 0 || n > maxTargetMinutes {
+			return response.BadRequest(c, fmt.Errorf("targetMinutes must be between 1 and %d", maxTargetMinutes))
+		}
+		targetMinutes = n
+	}
+
+	compliance, err := h.service.GetSLACompliance(
+		c.Request().Context(),
+		time.Duration(hours)*time.Hour,
+		time.Duration(targetMinutes)*time.Minute,
+	)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return response.Ok(c, compliance)
+}
+
+// defaultMaxPageSize caps pageSize for keys without a higher trusted limit.
+const defaultMaxPageSize = 100
+
+func parsePaginationParams(c echo.Context, maxPageSize int) (int, int, error) {
 	const (
 		defaultPage     = 1
 		defaultPageSize = 20
-		maxPageSize     = 100
 	)
 
 	pageStr := c.QueryParam("page")
@@ -197,17 +971,47 @@ func parsePaginationParams(c echo.Context) (int, int, error) {
 }
 
 // ReplayAllFailedMessages godoc
-// @Summary Replay all failed messages
-// @Description Sets status='pending' for all failed messages so the scheduler can resend them
+// @Summary Replay failed messages
+// @Description Sets status='pending' for failed messages so the scheduler can resend them. With from/to, only messages that first failed in [from, to) are replayed; olderThan/newerThan bound the replay by failure age instead.
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param x-ins-auth-key header string true "API key for messages"
+// @Param request body ReplayMessagesRequest false "Optional failure window"
+// @Param olderThan query string false "Only messages that failed at least this long ago, e.g. 1h"
+// @Param newerThan query string false "Only messages that failed less than this long ago, e.g. 24h"
 // @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /api/v1/messages/replay [post]
 func (h *MessageHandler) ReplayAllFailedMessages(c echo.Context) error {
-	count, err := h.service.ReplayAllFailedMessages(c.Request().Context())
+	var req ReplayMessagesRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	age, err := parseReplayAge(c)
+	if err != nil {
+		return response.BadRequest(c, err)
+	}
+	if req.From != nil && age != (domain.ReplayAge{}) {
+		return response.BadRequest(c, fmt.Errorf("use either from/to or olderThan/newerThan, not both"))
+	}
+
+	var count int64
+	if req.From != nil {
+		if !req.To.After(*req.From) {
+			return response.BadRequest(c, fmt.Errorf("to must be after from"))
+		}
+		count, err = h.service.ReplayFailedMessagesInWindow(c.Request().Context(), *req.From, *req.To)
+	} else {
+		count, err = h.service.ReplayAllFailedMessages(c.Request().Context(), age)
+	}
 	if err != nil {
 		return response.InternalServerError(c, err)
 	}
@@ -217,9 +1021,64 @@ func (h *MessageHandler) ReplayAllFailedMessages(c echo.Context) error {
 	})
 }
 
+// parseReplayAge reads the olderThan/newerThan query params as Go durations, e.g. "24h".
+func parseReplayAge(c echo.Context) (domain.ReplayAge, error) {
+	var age domain.ReplayAge
+	for param, target := range map[string]*time.Duration{"olderThan": &age.OlderThan, "newerThan": &age.NewerThan} {
+		raw := c.QueryParam(param)
+		if raw == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return domain.ReplayAge{}, fmt.Errorf("%s must be a positive duration such as 24h", param)
+		}
+		*target = d
+	}
+
+	if age.OlderThan > 0 && age.NewerThan > 0 && age.NewerThan <= age.OlderThan {
+		return domain.ReplayAge{}, fmt.Errorf("newerThan must be longer than olderThan")
+	}
+	return age, nil
+}
+
+// ResetRetries godoc
+// @Summary Reset the retry state of a failed message
+// @Description Clears firstFailedAt, replayCount and retryCount of a failed message so it counts as freshly failed (e.g. for the age bounds of a replay), can be replayed up to MESSAGE_MAX_REPLAYS times again and gets MESSAGE_MAX_RETRIES attempts once replayed, without re-pending it. Only failed messages can be reset.
+// @Tags messages
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param id path int true "Message ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/{id}/reset-retries [post]
+func (h *MessageHandler) ResetRetries(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, fmt.Errorf("invalid message id"))
+	}
+
+	msg, err := h.service.ResetRetries(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMessageNotFound):
+			return response.NotFound(c, "Message not found")
+		case errors.Is(err, domain.ErrMessageNotFailed):
+			return response.Conflict(c, err)
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.OkWithMessage(c, "Message retries reset", msg)
+}
+
 // ReplayFailedMessage godoc
 // @Summary Replay a single failed message
-// @Description Sets status='pending' for a specific failed message so the scheduler can resend it
+// @Description Sets status='pending' for a specific failed message so the scheduler can resend it. A message already replayed MESSAGE_MAX_REPLAYS times is refused with 409.
 // @Tags messages
 // @Accept json
 // @Produce json
@@ -227,6 +1086,7 @@ func (h *MessageHandler) ReplayAllFailedMessages(c echo.Context) error {
 // @Param id path int true "Message ID"
 // @Success 200 {object} response.SuccessResponse
 // @Failure 400 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /api/v1/messages/{id}/replay [post]
 func (h *MessageHandler) ReplayFailedMessage(c echo.Context) error {
@@ -237,6 +1097,9 @@ func (h *MessageHandler) ReplayFailedMessage(c echo.Context) error {
 	}
 
 	if err := h.service.ReplayFailedMessage(c.Request().Context(), id); err != nil {
+		if errors.Is(err, domain.ErrReplayLimitReached) {
+			return response.Conflict(c, err)
+		}
 		// We treat "no failed message found" as a 400 here to avoid adding a new NotFound helper.
 		return response.BadRequest(c, err)
 	}
@@ -245,3 +1108,154 @@ func (h *MessageHandler) ReplayFailedMessage(c echo.Context) error {
 		"replayed": 1,
 	})
 }
+
+// CancelMessages godoc
+// @Summary Bulk-cancel pending messages
+// @Description Moves pending messages matching tag, phone prefix and/or created-before to cancelled. An empty filter requires all=true.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param request body CancelMessagesRequest true "Cancel filter"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/cancel [post]
+func (h *MessageHandler) CancelMessages(c echo.Context) error {
+	var req CancelMessagesRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	filter := domain.CancelFilter{
+		Tag:           req.Tag,
+		PhonePrefix:   req.PhonePrefix,
+		CreatedBefore: req.CreatedBefore,
+	}
+
+	count, err := h.service.CancelPendingMessages(c.Request().Context(), filter, req.All)
+	if err != nil {
+		if errors.Is(err, domain.ErrEmptyCancelFilter) {
+			return response.BadRequest(c, err)
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"cancelled": count,
+	})
+}
+
+// GetMessage godoc
+// @Summary Get a single message
+// @Description Returns a message by its DB id. The response carries an ETag that changes with any field of the message; send it back in If-None-Match to get a 304 while the message is unchanged.
+// @Tags messages
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Param id path int true "Message ID"
+// @Success 200 {object} response.SuccessResponse
+// @Success 304
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/{id} [get]
+func (h *MessageHandler) GetMessage(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, fmt.Errorf("invalid message id"))
+	}
+
+	msg, err := h.service.GetMessage(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			return response.NotFound(c, "Message not found")
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	etag, err := messageETag(msg)
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+	c.Response().Header().Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return response.Ok(c, msg)
+}
+
+// messageETag identifies a version of a message by hashing its JSON form. updated_at only has
+// second precision, so two changes within one second would otherwise share a tag.
+func messageETag(msg *domain.Message) (string, error) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode message %d: %w", msg.ID, err)
+	}
+
+	sum := sha256.Sum256(encoded)
+	return fmt.Sprintf(`"%d-%s"`, msg.ID, hex.EncodeToString(sum[:16])), nil
+}
+
+// etagMatches reports whether an If-None-Match header names etag. Weak tags compare equal to
+// their strong form, as If-None-Match uses weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ForceFailMessage godoc
+// @Summary Force-fail a stuck pending message
+// @Description Moves a pending message to failed with a manual reason, e.g. for a number the provider always rejects. Only pending messages can be force-failed.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param id path int true "Message ID"
+// @Param request body ForceFailRequest true "Failure reason"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/messages/{id}/fail [post]
+func (h *MessageHandler) ForceFailMessage(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, fmt.Errorf("invalid message id"))
+	}
+
+	var req ForceFailRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	msg, err := h.service.ForceFailMessage(c.Request().Context(), id, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrMessageNotFound):
+			return response.NotFound(c, "Message not found")
+		case errors.Is(err, domain.ErrMessageNotPending):
+			return response.Conflict(c, err)
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.OkWithMessage(c, "Message marked as failed", msg)
+}
@@ -1,17 +1,292 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/internal/middlewares"
 	"github.com/onurcolak/insider-message-service/pkg/response"
 	validatorpkg "github.com/onurcolak/insider-message-service/pkg/validator"
 )
 
+// fakeMessageService is a configurable test double for messageService.
+type fakeMessageService struct {
+	messages []domain.Message
+	err      error
+
+	lastSince   domain.ChangeCursor
+	lastLimit   int
+	lastFilter  domain.MessageFilter
+	lastCreated domain.NewMessage
+	campaign    domain.NewCampaign
+	lastSearch  string
+	lastPage    int
+	lastCancel  domain.CancelFilter
+
+	replayedAll          bool
+	replayAge            domain.ReplayAge
+	replayFrom, replayTo time.Time
+
+	preview domain.SendPreview
+	cached  map[int64]*domain.SentMessageCache
+
+	lastCachedIDs []int64
+}
+
+func (f *fakeMessageService) GetSentMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	page, pageSize int,
+) ([]domain.Message, int64, error) {
+	f.lastFilter = filter
+	return f.messages, int64(len(f.messages)), f.err
+}
+
+func (f *fakeMessageService) CreateCampaign(ctx context.Context, campaign domain.NewCampaign) (*domain.CampaignResult, error) {
+	f.campaign = campaign
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &domain.CampaignResult{CampaignID: "c1", Created: int64(len(campaign.Recipients))}, nil
+}
+
+// SearchMessages returns a page of f.messages as the matches; ranking is the repository's job.
+func (f *fakeMessageService) SearchMessages(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error) {
+	f.lastSearch = query
+	f.lastPage = page
+
+	start := min((page-1)*pageSize, len(f.messages))
+	end := min(start+pageSize, len(f.messages))
+	return f.messages[start:end], int64(len(f.messages)), f.err
+}
+
+func (f *fakeMessageService) GetAllMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	page, pageSize int,
+) ([]domain.Message, int64, error) {
+	f.lastFilter = filter
+
+	var matched []domain.Message
+	for _, m := range f.messages {
+		if filter.Tag != "" && !slices.Contains(m.Tags, filter.Tag) {
+			continue
+		}
+		matched = append(matched, m)
+	}
+	return matched, int64(len(matched)), f.err
+}
+
+func (f *fakeMessageService) CreateMessage(ctx context.Context, msg domain.NewMessage) (*domain.Message, error) {
+	f.lastCreated = msg
+	if msg.ClientMessageID != "" {
+		for i := range f.messages {
+			if id := f.messages[i].ClientMessageID; id != nil && *id == msg.ClientMessageID {
+				return &f.messages[i], domain.ErrDuplicateClientMessageID
+			}
+		}
+	}
+	return &domain.Message{
+		ID:          1,
+		Content:     msg.Content,
+		PhoneNumber: msg.PhoneNumber,
+		Status:      domain.StatusPending,
+		Tags:        msg.Tags,
+	}, f.err
+}
+
+func (f *fakeMessageService) SendPreview(msg *domain.Message) domain.SendPreview {
+	return f.preview
+}
+
+func (f *fakeMessageService) OutcomeCounts() domain.OutcomeCounts {
+	return domain.OutcomeCounts{}
+}
+
+func (f *fakeMessageService) EstimateCost(ctx context.Context) (*domain.CostEstimate, error) {
+	return &domain.CostEstimate{}, f.err
+}
+
+func (f *fakeMessageService) GetStats(ctx context.Context) (int64, int64, int64, error) {
+	return 0, 0, 0, f.err
+}
+
+func (f *fakeMessageService) GetCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error) {
+	return f.cached, f.err
+}
+
+func (f *fakeMessageService) GetCachedMessagesByIDs(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error) {
+	f.lastCachedIDs = ids
+	return f.cached, f.err
+}
+
+func (f *fakeMessageService) ReplayFailedMessage(ctx context.Context, id int64) error {
+	return f.err
+}
+
+func (f *fakeMessageService) ReplayAllFailedMessages(ctx context.Context, age domain.ReplayAge) (int64, error) {
+	f.replayedAll = true
+	f.replayAge = age
+	return 0, f.err
+}
+
+func (f *fakeMessageService) GetMessage(ctx context.Context, id int64) (*domain.Message, error) {
+	for i := range f.messages {
+		if f.messages[i].ID == id {
+			return &f.messages[i], nil
+		}
+	}
+	return nil, domain.ErrMessageNotFound
+}
+
+// ForceFailMessage only fails messages that are pending, like the repository does.
+func (f *fakeMessageService) ForceFailMessage(ctx context.Context, id int64, reason string) (*domain.Message, error) {
+	for i := range f.messages {
+		msg := &f.messages[i]
+		if msg.ID != id {
+			continue
+		}
+		if msg.Status != domain.StatusPending {
+			return nil, domain.ErrMessageNotPending
+		}
+		msg.Status = domain.StatusFailed
+		msg.LastError = &reason
+		return msg, nil
+	}
+	return nil, domain.ErrMessageNotFound
+}
+
+func (f *fakeMessageService) ResetRetries(ctx context.Context, id int64) (*domain.Message, error) {
+	for i := range f.messages {
+		msg := &f.messages[i]
+		if msg.ID != id {
+			continue
+		}
+		if msg.Status != domain.StatusFailed {
+			return nil, domain.ErrMessageNotFailed
+		}
+		msg.FirstFailedAt = nil
+		return msg, nil
+	}
+	return nil, domain.ErrMessageNotFound
+}
+
+func (f *fakeMessageService) GetGroupedStats(ctx context.Context) (map[string]map[domain.MessageStatus]int64, error) {
+	return nil, f.err
+}
+
+func (f *fakeMessageService) ReplayFailedMessagesInWindow(ctx context.Context, from, to time.Time) (int64, error) {
+	f.replayFrom, f.replayTo = from, to
+	return 3, f.err
+}
+
+// CancelPendingMessages mirrors the service contract: empty filters need all=true.
+func (f *fakeMessageService) CancelPendingMessages(
+	ctx context.Context,
+	filter domain.CancelFilter,
+	all bool,
+) (int64, error) {
+	f.lastCancel = filter
+	if filter.IsEmpty() && !all {
+		return 0, domain.ErrEmptyCancelFilter
+	}
+
+	var count int64
+	for _, m := range f.messages {
+		if m.Status != domain.StatusPending {
+			continue
+		}
+		if filter.Tag != "" && !slices.Contains(m.Tags, filter.Tag) {
+			continue
+		}
+		if filter.PhonePrefix != "" && !strings.HasPrefix(m.PhoneNumber, filter.PhonePrefix) {
+			continue
+		}
+		count++
+	}
+	return count, f.err
+}
+
+// ExportMessages emits up to limit messages and reports truncation if any are left.
+func (f *fakeMessageService) ExportMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	limit int,
+	fn func(domain.Message) error,
+) (bool, error) {
+	f.lastFilter = filter
+	f.lastLimit = limit
+
+	for i, m := range f.messages {
+		if limit > 0 && i == limit {
+			return true, nil
+		}
+		if err := fn(m); err != nil {
+			return false, err
+		}
+	}
+	return false, f.err
+}
+
+func (f *fakeMessageService) GetThroughput(ctx context.Context, window time.Duration) ([]domain.HourlySends, error) {
+	return nil, f.err
+}
+
+func (f *fakeMessageService) GetSLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error) {
+	return domain.SLACompliance{}, f.err
+}
+
+// GetQueueHead returns pending messages of the queue oldest first, like the repository's GetUnsent.
+func (f *fakeMessageService) GetQueueHead(ctx context.Context, queue string, limit int) ([]domain.Message, error) {
+	f.lastLimit = limit
+	if queue != "" && queue != domain.DefaultQueue {
+		return nil, domain.ErrUnknownQueue
+	}
+
+	var pending []domain.Message
+	for _, m := range f.messages {
+		if m.Status == domain.StatusPending {
+			pending = append(pending, m)
+		}
+	}
+	slices.SortStableFunc(pending, func(a, b domain.Message) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return pending[:min(limit, len(pending))], f.err
+}
+
+func (f *fakeMessageService) GetFailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error) {
+	return nil, f.err
+}
+
+// GetChangesSince returns the messages after the cursor; messages are given in change order.
+func (f *fakeMessageService) GetChangesSince(
+	ctx context.Context,
+	after domain.ChangeCursor,
+	limit int,
+) ([]domain.Message, domain.ChangeCursor, error) {
+	f.lastSince = after
+	f.lastLimit = limit
+
+	cursor := after
+	var changed []domain.Message
+	for _, m := range f.messages {
+		if m.UpdatedAt.After(after.UpdatedAt) || (m.UpdatedAt.Equal(after.UpdatedAt) && m.ID > after.ID) {
+			changed = append(changed, m)
+			cursor = domain.ChangeCursor{UpdatedAt: m.UpdatedAt, ID: m.ID}
+		}
+	}
+	return changed, cursor, f.err
+}
+
 // TestCreateMessage_BadJSON verifies that invalid JSON returns 400 Bad Request.
 func TestCreateMessage_BadJSON(t *testing.T) {
 	e := echo.New()
@@ -95,3 +370,1376 @@ func TestCreateMessage_TooLongContent(t *testing.T) {
 		t.Fatalf("expected Details to contain 'content' key")
 	}
 }
+
+func TestGetChanges_ReturnsChangedRowsAndAdvancesCursor(t *testing.T) {
+	e := echo.New()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeMessageService{
+		messages: []domain.Message{
+			{ID: 1, UpdatedAt: base.Add(-time.Minute)},
+			{ID: 2, UpdatedAt: base.Add(time.Minute)},
+			{ID: 3, UpdatedAt: base.Add(2 * time.Minute)},
+		},
+	}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/changes?since="+base.Format(time.RFC3339), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.GetChanges(c); err != nil {
+		t.Fatalf("GetChanges returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Data struct {
+			Messages []domain.Message `json:"messages"`
+			Cursor   string           `json:"cursor"`
+			CursorID int64            `json:"cursorId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(body.Data.Messages) != 2 {
+		t.Fatalf("expected 2 changed messages, got %d", len(body.Data.Messages))
+	}
+	if body.Data.Messages[0].ID != 2 || body.Data.Messages[1].ID != 3 {
+		t.Errorf("unexpected messages returned: %+v", body.Data.Messages)
+	}
+
+	expectedCursor := base.Add(2 * time.Minute).Format(time.RFC3339)
+	if body.Data.Cursor != expectedCursor || body.Data.CursorID != 3 {
+		t.Errorf("expected cursor %q/3, got %q/%d", expectedCursor, body.Data.Cursor, body.Data.CursorID)
+	}
+	if svc.lastLimit != 100 {
+		t.Errorf("expected default limit 100, got %d", svc.lastLimit)
+	}
+}
+
+func TestGetQueueHead_ReturnsOldestPendingInUnsentOrder(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeMessageService{
+		messages: []domain.Message{
+			{ID: 1, Status: domain.StatusPending, CreatedAt: base.Add(2 * time.Minute)},
+			{ID: 2, Status: domain.StatusSent, CreatedAt: base},
+			{ID: 3, Status: domain.StatusPending, CreatedAt: base.Add(time.Minute)},
+			{ID: 4, Status: domain.StatusPending, CreatedAt: base.Add(3 * time.Minute)},
+		},
+	}
+	handler := NewMessageHandler(svc)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/head"+query, nil)
+		rec := httptest.NewRecorder()
+
+		if err := handler.GetQueueHead(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("GetQueueHead returned error: %v", err)
+		}
+		return rec
+	}
+
+	rec := get("?limit=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data struct {
+			Queue    string           `json:"queue"`
+			Count    int              `json:"count"`
+			Messages []domain.Message `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body.Data.Queue != domain.DefaultQueue || body.Data.Count != 2 ||
+		len(body.Data.Messages) != 2 || body.Data.Messages[0].ID != 3 || body.Data.Messages[1].ID != 1 {
+		t.Fatalf("expected pending messages 3 and 1 in creation order, got %s", rec.Body.String())
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=101", "?queue=unknown"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestGetChanges_ResumesInsideASecondWithAfterID(t *testing.T) {
+	second := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeMessageService{
+		messages: []domain.Message{
+			{ID: 2, UpdatedAt: second},
+			{ID: 3, UpdatedAt: second},
+		},
+	}
+	handler := NewMessageHandler(svc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/changes?since="+second.Format(time.RFC3339)+"&afterId=2", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetChanges(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetChanges returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if svc.lastSince != (domain.ChangeCursor{UpdatedAt: second, ID: 2}) {
+		t.Fatalf("expected the cursor since/afterId to reach the service, got %+v", svc.lastSince)
+	}
+
+	var body struct {
+		Data struct {
+			Messages []domain.Message `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(body.Data.Messages) != 1 || body.Data.Messages[0].ID != 3 {
+		t.Fatalf("expected only message 3 from the same second, got %+v", body.Data.Messages)
+	}
+}
+
+func TestGetChanges_InvalidSinceReturns400(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/changes?since=yesterday", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.GetChanges(c); err != nil {
+		t.Fatalf("GetChanges returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateMessage_WithTags(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	reqBody := `{"content": "Summer sale", "phoneNumber": "+905551234567", "tags": ["summer-sale", "promo"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.CreateMessage(c); err != nil {
+		t.Fatalf("CreateMessage returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+
+	if !slices.Equal(svc.lastCreated.Tags, []string{"summer-sale", "promo"}) {
+		t.Fatalf("expected tags to be passed to the service, got %v", svc.lastCreated.Tags)
+	}
+
+	var body struct {
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(body.Data.Tags) != 2 {
+		t.Errorf("expected tags in response, got %v", body.Data.Tags)
+	}
+}
+
+func TestCreateMessage_DuplicateClientMessageIDReturnsEarlierMessage(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	clientID := "order-42"
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 7, Content: "Your order shipped", Status: domain.StatusSent, ClientMessageID: &clientID},
+	}}
+	handler := NewMessageHandler(svc)
+
+	post := func(clientMessageID string) *httptest.ResponseRecorder {
+		reqBody := `{"content": "Your order shipped", "phoneNumber": "+905551234567", "clientMessageId": "` + clientMessageID + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		if err := handler.CreateMessage(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("CreateMessage returned error: %v", err)
+		}
+		return rec
+	}
+
+	rec := post("order-42")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for a known client message id, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body.Data.ID != 7 || body.Data.Status != domain.StatusSent {
+		t.Fatalf("expected the earlier message, got %+v", body.Data)
+	}
+
+	if rec := post("order-43"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 for a new client message id, got %d", rec.Code)
+	}
+	if svc.lastCreated.ClientMessageID != "order-43" {
+		t.Fatalf("expected the client message id to reach the service, got %q", svc.lastCreated.ClientMessageID)
+	}
+}
+
+func TestCreateMessage_ScopesClientMessageIDToTheAPIKey(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	reqBody := `{"content": "Your order shipped", "phoneNumber": "+905551234567", "clientMessageId": "order-42"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(middlewares.KeyIdentityContextKey, middlewares.KeyIdentityTrusted)
+
+	if err := handler.CreateMessage(c); err != nil {
+		t.Fatalf("CreateMessage returned error: %v", err)
+	}
+	if svc.lastCreated.Client != middlewares.KeyIdentityTrusted {
+		t.Fatalf("expected the message to be created for the trusted key, got %q", svc.lastCreated.Client)
+	}
+}
+
+func TestCreateMessage_ResponseIncludesSendPreview(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	svc := &fakeMessageService{preview: domain.SendPreview{NormalizedPhone: "+905551234567", SegmentCount: 2}}
+	handler := NewMessageHandler(svc)
+
+	reqBody := `{"content": "Hello", "phoneNumber": "0090 555 123 45 67"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.CreateMessage(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("CreateMessage returned error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+
+	var body struct {
+		Data struct {
+			ID      int64          `json:"id"`
+			Preview map[string]any `json:"preview"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if body.Data.ID != 1 {
+		t.Errorf("expected the created message fields alongside the preview, got id %d", body.Data.ID)
+	}
+	want := map[string]any{"normalizedPhone": "+905551234567", "segmentCount": float64(2), "truncated": false}
+	for field, value := range want {
+		if body.Data.Preview[field] != value {
+			t.Errorf("expected preview.%s=%v, got %v", field, value, body.Data.Preview[field])
+		}
+	}
+}
+
+func TestCreateMessage_DelaySecondsCappedAtAYear(t *testing.T) {
+	for delay, want := range map[string]int{
+		"31536000":            http.StatusCreated,
+		"31536001":            http.StatusUnprocessableEntity,
+		"9223372036854775807": http.StatusUnprocessableEntity,
+	} {
+		e := echo.New()
+		e.Validator = validatorpkg.New()
+		handler := NewMessageHandler(&fakeMessageService{})
+
+		reqBody := `{"content": "Hi", "phoneNumber": "+905551234567", "delaySeconds": ` + delay + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		if err := handler.CreateMessage(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("CreateMessage returned error: %v", err)
+		}
+		if rec.Code != want {
+			t.Errorf("delaySeconds %s: expected status %d, got %d: %s", delay, want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCreateMessage_TagWithCommaRejected(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	reqBody := `{"content": "Hi", "phoneNumber": "+905551234567", "tags": ["a,b"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.CreateMessage(c); err != nil {
+		t.Fatalf("CreateMessage returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestCreateMessage_ControlCharactersRejectedOrStripped(t *testing.T) {
+	for _, strip := range []bool{false, true} {
+		e := echo.New()
+		e.Validator = validatorpkg.New()
+
+		svc := &fakeMessageService{}
+		handler := NewMessageHandler(svc)
+		handler.SetStripControlChars(strip)
+
+		reqBody := `{"content": "Hi\u0000 there\nbye", "phoneNumber": "+905551234567"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		if err := handler.CreateMessage(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("CreateMessage returned error: %v", err)
+		}
+
+		if !strip {
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("reject: expected status 422, got %d", rec.Code)
+			}
+			continue
+		}
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("strip: expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if svc.lastCreated.Content != "Hi there\nbye" {
+			t.Errorf("strip: expected the null byte removed and the newline kept, got %q", svc.lastCreated.Content)
+		}
+	}
+}
+
+func TestGetAllMessages_FiltersByTag(t *testing.T) {
+	e := echo.New()
+
+	svc := &fakeMessageService{
+		messages: []domain.Message{
+			{ID: 1, Tags: domain.Tags{"summer-sale"}},
+			{ID: 2, Tags: domain.Tags{"welcome"}},
+			{ID: 3, Tags: domain.Tags{"promo", "summer-sale"}},
+		},
+	}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?tag=summer-sale", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.GetAllMessages(c); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+
+	if svc.lastFilter.Tag != "summer-sale" {
+		t.Fatalf("expected tag filter %q, got %q", "summer-sale", svc.lastFilter.Tag)
+	}
+
+	var body struct {
+		Data       []domain.Message `json:"data"`
+		TotalCount int64            `json:"totalCount"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if body.TotalCount != 2 || len(body.Data) != 2 {
+		t.Fatalf("expected 2 tagged messages, got %d (total %d)", len(body.Data), body.TotalCount)
+	}
+}
+
+func TestGetAllMessages_ProjectsRequestedFields(t *testing.T) {
+	e := echo.New()
+
+	svc := &fakeMessageService{
+		messages: []domain.Message{{ID: 1, Status: domain.StatusSent, PhoneNumber: "+905551234567"}},
+	}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?fields=id,status,phone_number", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	if !slices.Equal(svc.lastFilter.Fields, []string{"id", "status", "phoneNumber"}) {
+		t.Fatalf("expected the fields to reach the service, got %v", svc.lastFilter.Fields)
+	}
+
+	var body struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	want := map[string]any{"id": float64(1), "status": "sent", "phoneNumber": "+905551234567"}
+	if len(body.Data) != 1 || !reflect.DeepEqual(body.Data[0], want) {
+		t.Fatalf("expected only the requested fields, got %v", body.Data)
+	}
+}
+
+func TestGetAllMessages_UnknownFieldIsBadRequest(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?fields=id,secret", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetAllMessages_UnknownStatusIsBadRequest(t *testing.T) {
+	e := echo.New()
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?status=queued", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "queued") {
+		t.Errorf("expected the error to name the rejected status, got %s", rec.Body.String())
+	}
+	if svc.lastFilter.Status != nil {
+		t.Errorf("expected the service not to be queried")
+	}
+}
+
+func TestGetAllMessages_TrustedKeyGetsLargerPageSize(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+	handler.SetTrustedMaxPageSize(1000)
+	e.GET("/api/v1/messages", handler.GetAllMessages, middlewares.APIKeyAuth("normal-key", "etl-key"))
+
+	for _, tc := range []struct {
+		key  string
+		want int
+	}{
+		{key: "etl-key", want: http.StatusOK},
+		{key: "normal-key", want: http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?pageSize=500", nil)
+		req.Header.Set(middlewares.APIKeyHeader, tc.key)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("key %q: expected status %d, got %d: %s", tc.key, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestGetAllMessages_EnvelopeFollowsHeader(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{messages: []domain.Message{{ID: 1}, {ID: 2}}})
+
+	// Default: the {success, data} envelope with pagination fields.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+
+	var enveloped response.PaginatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &enveloped); err != nil {
+		t.Fatalf("expected an envelope, got %s: %v", rec.Body.String(), err)
+	}
+	if !enveloped.Success || enveloped.TotalCount != 2 {
+		t.Fatalf("unexpected envelope: %+v", enveloped)
+	}
+
+	// Opted out: the bare list, with pagination in headers.
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+	req.Header.Set(response.EnvelopeHeader, "false")
+	rec = httptest.NewRecorder()
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+
+	var raw []domain.Message
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("expected a bare list, got %s: %v", rec.Body.String(), err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(raw))
+	}
+	if got := rec.Header().Get(response.TotalCountHeader); got != "2" {
+		t.Fatalf("expected %s: 2, got %q", response.TotalCountHeader, got)
+	}
+}
+
+func TestCancelMessages_CancelsMatchingPendingMessages(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 1, PhoneNumber: "+905551234567", Status: domain.StatusPending, Tags: domain.Tags{"summer-sale"}},
+		{ID: 2, PhoneNumber: "+905551234568", Status: domain.StatusPending, Tags: domain.Tags{"summer-sale"}},
+		{ID: 3, PhoneNumber: "+905551234569", Status: domain.StatusSent, Tags: domain.Tags{"summer-sale"}},
+		{ID: 4, PhoneNumber: "+441234567890", Status: domain.StatusPending, Tags: domain.Tags{"summer-sale"}},
+	}}
+	handler := NewMessageHandler(svc)
+
+	reqBody := `{"tag": "summer-sale", "phonePrefix": "+90"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/cancel", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.CancelMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("CancelMessages returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.lastCancel.Tag != "summer-sale" || svc.lastCancel.PhonePrefix != "+90" || svc.lastCancel.CreatedBefore != nil {
+		t.Fatalf("unexpected filter passed to service: %+v", svc.lastCancel)
+	}
+
+	var body struct {
+		Data struct {
+			Cancelled int64 `json:"cancelled"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.Cancelled != 2 {
+		t.Fatalf("expected 2 cancelled messages, got %d", body.Data.Cancelled)
+	}
+}
+
+func TestCancelMessages_EmptyFilterWithoutAllIsRejected(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/cancel", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.CancelMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("CancelMessages returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetAllMessages_DeadlineExceededReturns504(t *testing.T) {
+	e := echo.New()
+
+	svc := &fakeMessageService{err: fmt.Errorf("failed to count messages: %w", context.DeadlineExceeded)}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetAllMessages returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d", rec.Code)
+	}
+
+	var resp response.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if strings.Contains(resp.Error, "failed to count") {
+		t.Fatalf("expected raw error to be hidden, got %q", resp.Error)
+	}
+}
+
+func postReplay(t *testing.T, handler *MessageHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/replay", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+
+	if err := handler.ReplayAllFailedMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ReplayAllFailedMessages returned error: %v", err)
+	}
+	return rec
+}
+
+func TestReplayAllFailedMessages_PassesFailureAgeBounds(t *testing.T) {
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	rec := postReplayQuery(t, handler, "olderThan=1h&newerThan=24h")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if want := (domain.ReplayAge{OlderThan: time.Hour, NewerThan: 24 * time.Hour}); svc.replayAge != want {
+		t.Fatalf("expected age bounds %+v, got %+v", want, svc.replayAge)
+	}
+}
+
+func TestReplayAllFailedMessages_RejectsInvalidAgeBounds(t *testing.T) {
+	for _, query := range []string{"olderThan=soon", "newerThan=-1h", "olderThan=24h&newerThan=1h"} {
+		svc := &fakeMessageService{}
+		handler := NewMessageHandler(svc)
+
+		rec := postReplayQuery(t, handler, query)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+		if svc.replayedAll {
+			t.Errorf("%s: expected no replay", query)
+		}
+	}
+}
+
+func postReplayQuery(t *testing.T, handler *MessageHandler, query string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/replay?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.ReplayAllFailedMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ReplayAllFailedMessages returned error: %v", err)
+	}
+	return rec
+}
+
+func TestReplay_WithWindowReplaysOnlyThatWindow(t *testing.T) {
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	rec := postReplay(t, handler, `{"from": "2025-03-01T10:00:00Z", "to": "2025-03-01T12:00:00Z"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.replayedAll {
+		t.Fatal("expected windowed replay, got replay-all")
+	}
+
+	wantFrom := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if !svc.replayFrom.Equal(wantFrom) || !svc.replayTo.Equal(wantTo) {
+		t.Fatalf("unexpected window passed to service: %v - %v", svc.replayFrom, svc.replayTo)
+	}
+
+	var body struct {
+		Data struct {
+			Replayed int64 `json:"replayed"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.Replayed != 3 {
+		t.Fatalf("expected 3 replayed messages, got %d", body.Data.Replayed)
+	}
+}
+
+func TestReplay_WithoutBodyReplaysAll(t *testing.T) {
+	svc := &fakeMessageService{}
+
+	rec := postReplay(t, NewMessageHandler(svc), "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !svc.replayedAll {
+		t.Fatal("expected replay-all when no window is given")
+	}
+}
+
+func TestReplay_InvalidWindowIsRejected(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		code int
+	}{
+		"only from":      {`{"from": "2025-03-01T10:00:00Z"}`, http.StatusUnprocessableEntity},
+		"to before from": {`{"from": "2025-03-01T12:00:00Z", "to": "2025-03-01T10:00:00Z"}`, http.StatusBadRequest},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := &fakeMessageService{}
+
+			rec := postReplay(t, NewMessageHandler(svc), tc.body)
+
+			if rec.Code != tc.code {
+				t.Fatalf("expected status %d, got %d: %s", tc.code, rec.Code, rec.Body.String())
+			}
+			if svc.replayedAll || !svc.replayFrom.IsZero() {
+				t.Fatal("expected service not to be called")
+			}
+		})
+	}
+}
+
+func getMessage(t *testing.T, handler *MessageHandler, id, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/"+id, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	if err := handler.GetMessage(c); err != nil {
+		t.Fatalf("GetMessage returned error: %v", err)
+	}
+	return rec
+}
+
+func TestGetMessage_ReturnsNotModifiedForMatchingETag(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, Status: domain.StatusPending, UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}}
+	handler := NewMessageHandler(svc)
+
+	first := getMessage(t, handler, "9", "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", first.Code, first.Body.String())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	rec := getMessage(t, handler, "9", etag)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected status 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty body, got %s", rec.Body.String())
+	}
+
+	if rec := getMessage(t, handler, "9", "W/"+etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected status 304 for the weak form, got %d", rec.Code)
+	}
+}
+
+func TestGetMessage_ETagChangesWithinTheSameSecond(t *testing.T) {
+	updatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, Status: domain.StatusPending, UpdatedAt: updatedAt},
+	}}
+	handler := NewMessageHandler(svc)
+
+	etag := getMessage(t, handler, "9", "").Header().Get("ETag")
+
+	// A failed send kept for retry in the same second leaves updated_at unchanged.
+	svc.messages[0].RetryCount = 1
+	if rec := getMessage(t, handler, "9", etag); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after a change within the same second, got %d", rec.Code)
+	}
+}
+
+func TestGetMessage_ReturnsMessageWhenChanged(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, Status: domain.StatusPending, UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}}
+	handler := NewMessageHandler(svc)
+
+	etag := getMessage(t, handler, "9", "").Header().Get("ETag")
+
+	svc.messages[0].Status = domain.StatusSent
+	svc.messages[0].UpdatedAt = svc.messages[0].UpdatedAt.Add(time.Second)
+
+	rec := getMessage(t, handler, "9", etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("expected the ETag to change with updatedAt")
+	}
+
+	var body struct {
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.Status != domain.StatusSent {
+		t.Fatalf("expected the updated message, got %+v", body.Data)
+	}
+}
+
+func TestGetMessage_UnknownIDReturnsNotFound(t *testing.T) {
+	rec := getMessage(t, NewMessageHandler(&fakeMessageService{}), "42", "")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func postForceFail(t *testing.T, handler *MessageHandler, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/"+id+"/fail", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	if err := handler.ForceFailMessage(c); err != nil {
+		t.Fatalf("ForceFailMessage returned error: %v", err)
+	}
+	return rec
+}
+
+func TestForceFailMessage_FailsPendingMessage(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, PhoneNumber: "+905551234567", Status: domain.StatusPending},
+	}}
+
+	rec := postForceFail(t, NewMessageHandler(svc), "9", `{"reason": "number rejected by provider"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.Status != domain.StatusFailed || body.Data.LastError == nil ||
+		*body.Data.LastError != "number rejected by provider" {
+		t.Fatalf("unexpected response data: %+v", body.Data)
+	}
+}
+
+func TestForceFailMessage_RefusesNonPendingMessage(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, PhoneNumber: "+905551234567", Status: domain.StatusSent},
+	}}
+
+	rec := postForceFail(t, NewMessageHandler(svc), "9", `{"reason": "stuck"}`)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.messages[0].Status != domain.StatusSent {
+		t.Fatalf("expected status to stay sent, got %q", svc.messages[0].Status)
+	}
+}
+
+func TestForceFailMessage_RequiresReason(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, PhoneNumber: "+905551234567", Status: domain.StatusPending},
+	}}
+
+	rec := postForceFail(t, NewMessageHandler(svc), "9", `{}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestGetAllMessages_FieldNamingFollowsConfig(t *testing.T) {
+	cases := map[string]struct{ present, absent string }{
+		response.FieldNamingCamelCase: {present: `"phoneNumber"`, absent: `"phone_number"`},
+		response.FieldNamingSnakeCase: {present: `"phone_number"`, absent: `"phoneNumber"`},
+	}
+
+	for naming, want := range cases {
+		t.Run(naming, func(t *testing.T) {
+			e := echo.New()
+			e.JSONSerializer = response.JSONSerializer(naming)
+
+			handler := NewMessageHandler(&fakeMessageService{messages: []domain.Message{
+				{ID: 1, PhoneNumber: "+905551234567", Status: domain.StatusPending},
+			}})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+			rec := httptest.NewRecorder()
+
+			if err := handler.GetAllMessages(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("GetAllMessages returned error: %v", err)
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, want.present) || strings.Contains(body, want.absent) {
+				t.Fatalf("expected %s and no %s in body: %s", want.present, want.absent, body)
+			}
+		})
+	}
+}
+
+func TestExportMessages_StopsAtLimitAndSignalsTruncation(t *testing.T) {
+	e := echo.New()
+
+	svc := &fakeMessageService{messages: []domain.Message{{ID: 1}, {ID: 2}, {ID: 3}}}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/export.jsonl?limit=2&status=sent", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.ExportMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ExportMessages returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d: %q", len(lines), rec.Body.String())
+	}
+	var first domain.Message
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != 1 {
+		t.Fatalf("expected the first line to be message 1, got %q (err %v)", lines[0], err)
+	}
+
+	if got := rec.Result().Trailer.Get(ExportTruncatedTrailer); got != "true" {
+		t.Fatalf("expected truncation trailer %q, got %q", "true", got)
+	}
+	if svc.lastFilter.Status == nil || *svc.lastFilter.Status != domain.StatusSent {
+		t.Errorf("expected status filter to be passed through, got %+v", svc.lastFilter)
+	}
+}
+
+func TestExportMessages_RejectsInvalidLimit(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/export.jsonl?limit=0", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.ExportMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ExportMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestGetCachedMessages_ReturnsPartialEntriesWithWarning(t *testing.T) {
+	svc := &fakeMessageService{
+		cached: map[int64]*domain.SentMessageCache{7: {MessageID: "msg-7"}},
+		err:    fmt.Errorf("%w: failed to scan cache keys: connection reset", domain.ErrPartialCacheRead),
+	}
+	handler := NewMessageHandler(svc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/cached", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetCachedMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetCachedMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Warning string                             `json:"warning"`
+		Data    map[string]domain.SentMessageCache `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if !strings.Contains(body.Warning, "connection reset") {
+		t.Errorf("expected the scan failure as a warning, got %q", body.Warning)
+	}
+	if body.Data["7"].MessageID != "msg-7" {
+		t.Errorf("expected the partial entries to be returned, got %+v", body.Data)
+	}
+}
+
+func TestGetCachedMessages_FailsWithoutPartialEntries(t *testing.T) {
+	handler := NewMessageHandler(&fakeMessageService{err: fmt.Errorf("redis client not configured")})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/cached", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetCachedMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetCachedMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+}
+
+func TestGetCachedMessagesBatch_PassesIDsToService(t *testing.T) {
+	svc := &fakeMessageService{cached: map[int64]*domain.SentMessageCache{3: {MessageID: "msg-3"}}}
+	handler := NewMessageHandler(svc)
+
+	rec := postCachedBatch(t, handler, `{"ids": [3, 4]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !slices.Equal(svc.lastCachedIDs, []int64{3, 4}) {
+		t.Errorf("expected ids [3 4] to be looked up, got %v", svc.lastCachedIDs)
+	}
+
+	var body struct {
+		Data map[string]domain.SentMessageCache `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if len(body.Data) != 1 || body.Data["3"].MessageID != "msg-3" {
+		t.Errorf("expected only the cached entry, got %+v", body.Data)
+	}
+}
+
+func TestGetCachedMessagesBatch_RequiresIDs(t *testing.T) {
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	rec := postCachedBatch(t, handler, `{"ids": []}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+	if svc.lastCachedIDs != nil {
+		t.Errorf("expected no cache lookup")
+	}
+}
+
+func postCachedBatch(t *testing.T, handler *MessageHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/cached/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetCachedMessagesBatch(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetCachedMessagesBatch returned error: %v", err)
+	}
+	return rec
+}
+
+func postCampaign(t *testing.T, handler *MessageHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/campaign", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.CreateCampaign(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("CreateCampaign returned error: %v", err)
+	}
+	return rec
+}
+
+func TestCreateCampaign_CreatesMessagePerRecipient(t *testing.T) {
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	rec := postCampaign(t, handler, `{
+		"template": "Hi {{.name}}",
+		"queue": "promotional",
+		"recipients": [
+			{"phoneNumber": "+905551234567", "variables": {"name": "Ada"}},
+			{"phoneNumber": "+905559876543", "variables": {"name": "Alan"}}
+		]
+	}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data domain.CampaignResult `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body.Data.CampaignID != "c1" || body.Data.Created != 2 {
+		t.Fatalf("unexpected campaign result %+v", body.Data)
+	}
+
+	got := svc.campaign
+	if got.Template != "Hi {{.name}}" || got.Queue != "promotional" || len(got.Recipients) != 2 ||
+		got.Recipients[1].PhoneNumber != "+905559876543" || got.Recipients[1].Variables["name"] != "Alan" {
+		t.Fatalf("unexpected campaign passed to the service: %+v", got)
+	}
+}
+
+func TestCreateCampaign_InvalidTemplateIs422(t *testing.T) {
+	svc := &fakeMessageService{err: fmt.Errorf("%w: recipient 1: missing key", domain.ErrInvalidTemplate)}
+	handler := NewMessageHandler(svc)
+
+	rec := postCampaign(t, handler, `{"template": "Hi {{.name}}", "recipients": [{"phoneNumber": "+905551234567"}]}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateCampaign_RequiresRecipients(t *testing.T) {
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	rec := postCampaign(t, handler, `{"template": "Hi", "recipients": []}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetCampaignMessages_FiltersByCampaignID(t *testing.T) {
+	e := echo.New()
+	svc := &fakeMessageService{}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/campaign/c1?status=pending", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("campaignId")
+	c.SetParamValues("c1")
+
+	if err := handler.GetCampaignMessages(c); err != nil {
+		t.Fatalf("GetCampaignMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.lastFilter.CampaignID != "c1" || svc.lastFilter.Status == nil || *svc.lastFilter.Status != domain.StatusPending {
+		t.Fatalf("expected campaign and status filters, got %+v", svc.lastFilter)
+	}
+}
+
+func TestSearchMessages_PassesQueryAndPaginates(t *testing.T) {
+	e := echo.New()
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 2, Content: "hello", PhoneNumber: "+905551234567"},
+		{ID: 4, Content: "hi", PhoneNumber: "+905559876543"},
+		{ID: 1, Content: "call +90555 back", PhoneNumber: "+441234567890"},
+	}}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/search?q=%2B90555%20&page=2&pageSize=2", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.SearchMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("SearchMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Data       []domain.Message `json:"data"`
+		Page       int              `json:"page"`
+		TotalCount int64            `json:"totalCount"`
+		TotalPages int              `json:"totalPages"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if svc.lastSearch != "+90555" || svc.lastPage != 2 {
+		t.Fatalf("expected the trimmed query for page 2, got q=%q page=%d", svc.lastSearch, svc.lastPage)
+	}
+	if body.Page != 2 || body.TotalCount != 3 || body.TotalPages != 2 || len(body.Data) != 1 || body.Data[0].ID != 1 {
+		t.Fatalf("unexpected page: %+v", body)
+	}
+}
+
+func TestSearchMessages_RejectsContentSearchWhenEncrypted(t *testing.T) {
+	e := echo.New()
+	svc := &fakeMessageService{err: fmt.Errorf("%w: %q is not a phone number prefix", domain.ErrContentSearchUnavailable, "hello")}
+	handler := NewMessageHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/search?q=hello", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.SearchMessages(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("SearchMessages returned error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSearchMessages_FlagsPageBeyondTheLast(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 1, Content: "hello", PhoneNumber: "+905551234567"},
+		{ID: 2, Content: "hi", PhoneNumber: "+905559876543"},
+	}}
+	handler := NewMessageHandler(svc)
+
+	search := func(query string, envelope bool) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/search?q=%2B90555&pageSize=2"+query, nil)
+		if !envelope {
+			req.Header.Set(response.EnvelopeHeader, "false")
+		}
+		rec := httptest.NewRecorder()
+
+		if err := handler.SearchMessages(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("SearchMessages returned error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	for _, tc := range []struct {
+		page       string
+		outOfRange bool
+	}{
+		{page: "&page=1", outOfRange: false},
+		{page: "&page=1000", outOfRange: true},
+	} {
+		var body struct {
+			Data           []domain.Message `json:"data"`
+			TotalPages     int              `json:"totalPages"`
+			PageOutOfRange bool             `json:"pageOutOfRange"`
+		}
+		if err := json.Unmarshal(search(tc.page, true).Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if body.TotalPages != 1 || body.PageOutOfRange != tc.outOfRange {
+			t.Errorf("%s: expected totalPages 1 and pageOutOfRange %v, got %+v", tc.page, tc.outOfRange, body)
+		}
+
+		header := search(tc.page, false).Header().Get(response.OutOfRangeHeader)
+		if (header == "true") != tc.outOfRange {
+			t.Errorf("%s: unexpected %s header %q", tc.page, response.OutOfRangeHeader, header)
+		}
+	}
+}
+
+func TestSearchMessages_RequiresQuery(t *testing.T) {
+	e := echo.New()
+	handler := NewMessageHandler(&fakeMessageService{})
+
+	for _, target := range []string{
+		"/api/v1/messages/search",
+		"/api/v1/messages/search?q=%20%20",
+		"/api/v1/messages/search?q=" + strings.Repeat("a", maxSearchQueryLength+1),
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+
+		if err := handler.SearchMessages(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("SearchMessages returned error: %v", err)
+		}
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func postResetRetries(t *testing.T, handler *MessageHandler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/"+id+"/reset-retries", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+
+	if err := handler.ResetRetries(c); err != nil {
+		t.Fatalf("ResetRetries returned error: %v", err)
+	}
+	return rec
+}
+
+func TestResetRetries_ClearsFirstFailedAtOfFailedMessage(t *testing.T) {
+	failedAt := time.Now().Add(-48 * time.Hour)
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, Status: domain.StatusFailed, FirstFailedAt: &failedAt},
+	}}
+
+	rec := postResetRetries(t, NewMessageHandler(svc), "9")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.messages[0].FirstFailedAt != nil || svc.messages[0].Status != domain.StatusFailed {
+		t.Fatalf("expected firstFailedAt cleared and status kept, got %+v", svc.messages[0])
+	}
+}
+
+func TestResetRetries_RefusesMessageThatIsNotFailed(t *testing.T) {
+	svc := &fakeMessageService{messages: []domain.Message{
+		{ID: 9, Status: domain.StatusPending},
+	}}
+
+	rec := postResetRetries(t, NewMessageHandler(svc), "9")
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+type outcomeCounter interface {
+	OutcomeCounts() domain.OutcomeCounts
+}
+
+// MetricsHandler exposes the service's outcome counters in the Prometheus text format.
+type MetricsHandler struct {
+	counter outcomeCounter
+}
+
+func NewMetricsHandler(counter outcomeCounter) *MetricsHandler {
+	return &MetricsHandler{counter: counter}
+}
+
+// GetMetrics godoc
+// @Summary Get metrics
+// @Description Outcome counters (truncated messages, runs skipped for quiet hours, messages left pending by the per-run send cap, deduplicated messages, messages to suppressed numbers) in the Prometheus text format
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "Prometheus metrics"
+// @Router /metrics [get]
+func (h *MetricsHandler) GetMetrics(c echo.Context) error {
+	counts := h.counter.OutcomeCounts()
+
+	var b strings.Builder
+	writeCounter(&b, "insider_messages_truncated_total", "Messages sent with content truncated to the max length.", counts.Truncated)
+	writeCounter(&b, "insider_runs_quiet_hours_skipped_total", "Scheduler runs skipped because quiet hours were in effect.", counts.QuietHoursSkippedRuns)
+	writeCounter(&b, "insider_messages_send_cap_skipped_total", "Fetched messages left pending because the per-run send cap was reached.", counts.SendCapSkipped)
+	writeCounter(&b, "insider_messages_deduped_total", "Messages skipped as repeats of a recent send to the same number.", counts.Deduped)
+	writeCounter(&b, "insider_messages_suppressed_total", "Messages not sent because their number is on the suppression list.", counts.Suppressed)
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+func writeCounter(b *strings.Builder, name, help string, value int64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+type fakeOutcomeCounter struct {
+	counts domain.OutcomeCounts
+}
+
+func (f *fakeOutcomeCounter) OutcomeCounts() domain.OutcomeCounts { return f.counts }
+
+func TestGetMetrics_ExposesOutcomeCounters(t *testing.T) {
+	handler := NewMetricsHandler(&fakeOutcomeCounter{counts: domain.OutcomeCounts{Truncated: 4, QuietHoursSkippedRuns: 2, SendCapSkipped: 7}})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetMetrics(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetMetrics returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	for _, line := range []string{
+		"insider_messages_truncated_total 4",
+		"insider_runs_quiet_hours_skipped_total 2",
+		"insider_messages_send_cap_skipped_total 7",
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("expected %q in metrics output:\n%s", line, rec.Body.String())
+		}
+	}
+}
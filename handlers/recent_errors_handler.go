@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/middlewares"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+)
+
+type recentErrorLister interface {
+	Entries() []middlewares.ErrorEntry
+}
+
+// RecentErrorsHandler lists recent error responses so clients can self-diagnose failed calls.
+type RecentErrorsHandler struct {
+	errors recentErrorLister
+}
+
+// NewRecentErrorsHandler serves the given buffer; pass nil when recording is disabled.
+func NewRecentErrorsHandler(errors recentErrorLister) *RecentErrorsHandler {
+	return &RecentErrorsHandler{errors: errors}
+}
+
+// GetRecentErrors godoc
+// @Summary List recent API errors
+// @Description Returns the latest 4xx/5xx responses (status, code, method, route, request id, timestamp), newest first. Recording is opt-in via RECENT_ERRORS_SIZE.
+// @Tags admin
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Router /api/v1/admin/recent-errors [get]
+func (h *RecentErrorsHandler) GetRecentErrors(c echo.Context) error {
+	entries := []middlewares.ErrorEntry{}
+	if h.errors != nil {
+		entries = h.errors.Entries()
+	}
+
+	return response.Ok(c, map[string]any{
+		"enabled": h.errors != nil,
+		"errors":  entries,
+	})
+}
@@ -2,38 +2,86 @@ package handlers
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/internal/domain"
 	"github.com/onurcolak/insider-message-service/internal/scheduler"
 	"github.com/onurcolak/insider-message-service/pkg/response"
 	"github.com/onurcolak/insider-message-service/pkg/validator"
 )
 
+// Small internal interfaces so the handler can be tested with fakes.
+type nextBatchProvider interface {
+	PreviewNextBatch(ctx context.Context, queue string) ([]domain.Message, error)
+	SimulateRun(ctx context.Context, queue string) (*domain.SimulatedRun, error)
+}
+
+type queueScheduler interface {
+	StartWithParams(ctx context.Context, intervalMinutes int, failureRate float64, alertWebhook string, alertThreshold int) error
+	Stop() error
+	IsRunning() bool
+	GetStatus() scheduler.SchedulerStatus
+	History() []scheduler.RunRecord
+	GetRun(ctx context.Context, runNumber int64) (*domain.SchedulerRun, error)
+	Queue() string
+}
+
 type SchedulerHandler struct {
-	scheduler *scheduler.Scheduler
-	ctx       context.Context
-	config    *environments.Config
+	schedulers map[string]queueScheduler // keyed by queue name
+	service    nextBatchProvider
+	ctx        context.Context
+	config     *environments.Config
 }
 
 type StartSchedulerRequest struct {
+	// Interval in minutes, up to MESSAGE_MAX_SEND_INTERVAL_MINUTES (checked by the handler).
 	Interval    *int     `json:"interval,omitempty" validate:"omitempty,min=1"`
 	FailureRate *float64 `json:"failureRate,omitempty" validate:"omitempty,min=0,max=1"`
 }
 
 func NewSchedulerHandler(
-	sched *scheduler.Scheduler,
+	schedulers []*scheduler.Scheduler,
+	service nextBatchProvider,
 	ctx context.Context,
 	cfg *environments.Config,
 ) *SchedulerHandler {
+	byQueue := make(map[string]queueScheduler, len(schedulers))
+	for _, sched := range schedulers {
+		byQueue[sched.Queue()] = sched
+	}
+
 	return &SchedulerHandler{
-		scheduler: sched,
-		ctx:       ctx,
-		config:    cfg,
+		schedulers: byQueue,
+		service:    service,
+		ctx:        ctx,
+		config:     cfg,
 	}
 }
 
+// lookup returns the scheduler for the :name route param, or the default queue's
+// scheduler on the unnamed routes.
+func (h *SchedulerHandler) lookup(c echo.Context) (queueScheduler, bool) {
+	name := c.Param("name")
+	if name == "" {
+		name = domain.DefaultQueue
+	}
+
+	sched, ok := h.schedulers[name]
+	return sched, ok
+}
+
+func unknownQueue(c echo.Context) error {
+	return response.NotFound(c, fmt.Sprintf("No scheduler configured for queue %q", c.Param("name")))
+}
+
 // StartScheduler godoc
 // @Summary Start the message scheduler
 // @Description Starts the automatic message sending process with optional parameters
@@ -45,10 +93,17 @@ func NewSchedulerHandler(
 // @Success 200 {object} response.SuccessResponse
 // @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
 // @Router /api/v1/scheduler/start [post]
+// @Router /api/v1/scheduler/{name}/start [post]
 func (h *SchedulerHandler) StartScheduler(c echo.Context) error {
-	if h.scheduler.IsRunning() {
-		return response.OkWithMessage(c, "Scheduler is already running", h.scheduler.GetStatus())
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	if sched.IsRunning() {
+		return response.OkWithMessage(c, "Scheduler is already running", sched.GetStatus())
 	}
 
 	var req StartSchedulerRequest
@@ -60,6 +115,15 @@ func (h *SchedulerHandler) StartScheduler(c echo.Context) error {
 		return validator.HandleValidationError(c, err)
 	}
 
+	// The upper bound is configurable, so it is checked here rather than in a struct tag; a huge
+	// interval would otherwise quietly stop sending.
+	if maxMinutes := h.maxIntervalMinutes(); req.Interval != nil && *req.Interval > maxMinutes {
+		return validator.HandleValidationError(c, &validator.ValidationError{
+			Errors: map[string]string{"interval": fmt.Sprintf("interval must be %d or less", maxMinutes)},
+			Rules:  map[string]string{"interval": "max"},
+		})
+	}
+
 	// Default interval from configuration (e.g. 2 minutes per spec).
 	intervalMinutes := int(h.config.Message.SendInterval.Minutes())
 	if intervalMinutes <= 0 {
@@ -77,7 +141,7 @@ func (h *SchedulerHandler) StartScheduler(c echo.Context) error {
 	alertWebhook := h.config.Alert.WebhookURL
 	alertThreshold := h.config.Alert.IterationCount
 
-	if err := h.scheduler.StartWithParams(
+	if err := sched.StartWithParams(
 		h.ctx,
 		intervalMinutes,
 		failureRate,
@@ -87,7 +151,17 @@ func (h *SchedulerHandler) StartScheduler(c echo.Context) error {
 		return response.InternalServerError(c, err)
 	}
 
-	return response.OkWithMessage(c, "Scheduler started successfully", h.scheduler.GetStatus())
+	return response.OkWithMessage(c, "Scheduler started successfully", sched.GetStatus())
+}
+
+// defaultMaxIntervalMinutes caps the interval when MaxSendInterval is not configured.
+const defaultMaxIntervalMinutes = 24 * 60
+
+func (h *SchedulerHandler) maxIntervalMinutes() int {
+	if m := int(h.config.Message.MaxSendInterval.Minutes()); m > 0 {
+		return m
+	}
+	return defaultMaxIntervalMinutes
 }
 
 // StopScheduler godoc
@@ -99,17 +173,25 @@ func (h *SchedulerHandler) StartScheduler(c echo.Context) error {
 // @Param x-ins-auth-key header string true "API key for scheduler"
 // @Success 200 {object} response.SuccessResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
 // @Router /api/v1/scheduler/stop [post]
+// @Router /api/v1/scheduler/{name}/stop [post]
 func (h *SchedulerHandler) StopScheduler(c echo.Context) error {
-	if !h.scheduler.IsRunning() {
-		return response.OkWithMessage(c, "Scheduler is already stopped", h.scheduler.GetStatus())
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	// An idle-stopped scheduler is still stopped, so it does not wake up for new messages.
+	if !sched.IsRunning() && !sched.GetStatus().IdleStopped {
+		return response.OkWithMessage(c, "Scheduler is already stopped", sched.GetStatus())
 	}
 
-	if err := h.scheduler.Stop(); err != nil {
+	if err := sched.Stop(); err != nil {
 		return response.InternalServerError(c, err)
 	}
 
-	return response.OkWithMessage(c, "Scheduler stopped successfully", h.scheduler.GetStatus())
+	return response.OkWithMessage(c, "Scheduler stopped successfully", sched.GetStatus())
 }
 
 // GetSchedulerStatus godoc
@@ -120,7 +202,178 @@ func (h *SchedulerHandler) StopScheduler(c echo.Context) error {
 // @Produce json
 // @Param x-ins-auth-key header string true "API key for scheduler"
 // @Success 200 {object} response.SuccessResponse
+// @Failure 404 {object} response.ErrorResponse
 // @Router /api/v1/scheduler/status [get]
+// @Router /api/v1/scheduler/{name}/status [get]
 func (h *SchedulerHandler) GetSchedulerStatus(c echo.Context) error {
-	return response.Ok(c, h.scheduler.GetStatus())
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	return response.Ok(c, sched.GetStatus())
+}
+
+// GetNextBatch godoc
+// @Summary Preview the next batch
+// @Description Returns the pending messages the next scheduler run would pick, without sending or changing them
+// @Tags scheduler
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/scheduler/next-batch [get]
+// @Router /api/v1/scheduler/{name}/next-batch [get]
+func (h *SchedulerHandler) GetNextBatch(c echo.Context) error {
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	messages, err := h.service.PreviewNextBatch(c.Request().Context(), sched.Queue())
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, map[string]any{
+		"queue":     sched.Queue(),
+		"batchSize": h.config.Message.BatchSize,
+		"count":     len(messages),
+		"messages":  messages,
+	})
+}
+
+// SimulateRun godoc
+// @Summary Simulate the next scheduler run
+// @Description Predicts per message what the next run would do (send; stay pending for quiet hours, the kill switch or the per-run send cap; or skip it as suppressed or deduped) and how it would be sent after content transforms. Nothing is sent and no rows change; every send is assumed to succeed.
+// @Tags scheduler
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/scheduler/simulate [post]
+// @Router /api/v1/scheduler/{name}/simulate [post]
+func (h *SchedulerHandler) SimulateRun(c echo.Context) error {
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	run, err := h.service.SimulateRun(c.Request().Context(), sched.Queue())
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, run)
+}
+
+// TestAlertResult reports how the alert webhook answered a test alert.
+type TestAlertResult struct {
+	StatusCode int    `json:"statusCode"` // 0 when no response was received
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TestAlert godoc
+// @Summary Send a test alert
+// @Description Posts a synthetic alert to the configured alert webhook (ALERT_WEBHOOK_URL) once and reports the status it answered with. The webhook's failures are reported in the body, not as an error status.
+// @Tags scheduler
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Router /api/v1/scheduler/test-alert [post]
+func (h *SchedulerHandler) TestAlert(c echo.Context) error {
+	webhookURL := h.config.Alert.WebhookURL
+	if webhookURL == "" {
+		return response.UnprocessableEntity(c, errors.New("no alert webhook configured (ALERT_WEBHOOK_URL)"))
+	}
+
+	status, err := scheduler.SendTestAlert(c.Request().Context(), webhookURL, h.config.Alert.Timeout)
+
+	result := TestAlertResult{StatusCode: status, Delivered: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return response.Ok(c, result)
+}
+
+// GetHistoryCSV godoc
+// @Summary Export scheduler run history as CSV
+// @Description Streams the most recent scheduler runs (oldest first) as CSV: timestamp, processed, succeeded, failed
+// @Tags scheduler
+// @Produce text/csv
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Success 200 {string} string "CSV file"
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/scheduler/history.csv [get]
+// @Router /api/v1/scheduler/{name}/history.csv [get]
+func (h *SchedulerHandler) GetHistoryCSV(c echo.Context) error {
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	runs := sched.History()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="scheduler-history.csv"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	if err := w.Write([]string{"timestamp", "processed", "succeeded", "failed"}); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if err := w.Write([]string{
+			run.StartedAt.UTC().Format(time.RFC3339),
+			strconv.Itoa(run.Processed),
+			strconv.Itoa(run.Succeeded),
+			strconv.Itoa(run.Failed),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// GetRunResults godoc
+// @Summary Get the results of a scheduler run
+// @Description Returns the per-message send results of a recent run by run number, and the retries it drew from the retry budget. Only runs that processed messages are stored, and only the most recent SCHEDULER_RUNS_KEPT runs per queue are kept.
+// @Tags scheduler
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for scheduler"
+// @Param runNumber path int true "Run number"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/scheduler/runs/{runNumber} [get]
+// @Router /api/v1/scheduler/{name}/runs/{runNumber} [get]
+func (h *SchedulerHandler) GetRunResults(c echo.Context) error {
+	sched, ok := h.lookup(c)
+	if !ok {
+		return unknownQueue(c)
+	}
+
+	runNumber, err := strconv.ParseInt(c.Param("runNumber"), 10, 64)
+	if err != nil || runNumber <= 0 {
+		return response.BadRequest(c, fmt.Errorf("runNumber must be a positive integer"))
+	}
+
+	run, err := sched.GetRun(c.Request().Context(), runNumber)
+	if err != nil {
+		if errors.Is(err, domain.ErrRunNotFound) {
+			return response.NotFound(c, fmt.Sprintf("no results stored for run %d", runNumber))
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Ok(c, run)
 }
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/internal/repository"
+	"github.com/onurcolak/insider-message-service/internal/scheduler"
+	"github.com/onurcolak/insider-message-service/internal/service"
+	validatorpkg "github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+type fakeNextBatchProvider struct {
+	batch     []domain.Message
+	calls     int
+	lastQueue string
+}
+
+func (f *fakeNextBatchProvider) PreviewNextBatch(ctx context.Context, queue string) ([]domain.Message, error) {
+	f.calls++
+	f.lastQueue = queue
+	return f.batch, nil
+}
+
+func (f *fakeNextBatchProvider) SimulateRun(ctx context.Context, queue string) (*domain.SimulatedRun, error) {
+	return &domain.SimulatedRun{Queue: queue}, nil
+}
+
+// fakeQueueScheduler is a queueScheduler that only tracks running state and history.
+type fakeQueueScheduler struct {
+	queue        string
+	running      bool
+	lastInterval int
+	runs         []scheduler.RunRecord
+	results      map[int64]*domain.SchedulerRun
+}
+
+func (f *fakeQueueScheduler) StartWithParams(_ context.Context, intervalMinutes int, _ float64, _ string, _ int) error {
+	f.running = true
+	f.lastInterval = intervalMinutes
+	return nil
+}
+
+func (f *fakeQueueScheduler) Stop() error {
+	f.running = false
+	return nil
+}
+
+func (f *fakeQueueScheduler) IsRunning() bool { return f.running }
+
+func (f *fakeQueueScheduler) GetStatus() scheduler.SchedulerStatus {
+	return scheduler.SchedulerStatus{Queue: f.queue, Running: f.running}
+}
+
+func (f *fakeQueueScheduler) History() []scheduler.RunRecord { return f.runs }
+
+func (f *fakeQueueScheduler) GetRun(_ context.Context, runNumber int64) (*domain.SchedulerRun, error) {
+	run, ok := f.results[runNumber]
+	if !ok {
+		return nil, domain.ErrRunNotFound
+	}
+	return run, nil
+}
+
+func (f *fakeQueueScheduler) Queue() string { return f.queue }
+
+func newTestSchedulerHandler(service nextBatchProvider, cfg *environments.Config, schedulers ...*fakeQueueScheduler) *SchedulerHandler {
+	byQueue := make(map[string]queueScheduler, len(schedulers))
+	for _, s := range schedulers {
+		byQueue[s.queue] = s
+	}
+	return &SchedulerHandler{schedulers: byQueue, service: service, ctx: context.Background(), config: cfg}
+}
+
+func TestGetNextBatch_ReturnsPreviewFromService(t *testing.T) {
+	e := echo.New()
+
+	svc := &fakeNextBatchProvider{batch: []domain.Message{
+		{ID: 1, Content: "first", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+		{ID: 2, Content: "second", PhoneNumber: "+905551234568", Status: domain.StatusPending},
+	}}
+	cfg := &environments.Config{Message: environments.MessageConfig{BatchSize: 2}}
+
+	handler := newTestSchedulerHandler(svc, cfg, &fakeQueueScheduler{queue: domain.DefaultQueue})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scheduler/next-batch", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetNextBatch(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetNextBatch returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if svc.calls != 1 || svc.lastQueue != domain.DefaultQueue {
+		t.Fatalf("expected exactly one preview call for the default queue, got %d (%q)", svc.calls, svc.lastQueue)
+	}
+
+	var body struct {
+		Data struct {
+			BatchSize int              `json:"batchSize"`
+			Count     int              `json:"count"`
+			Messages  []domain.Message `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	if body.Data.BatchSize != 2 || body.Data.Count != 2 {
+		t.Fatalf("unexpected batchSize/count: %+v", body.Data)
+	}
+	for i, msg := range body.Data.Messages {
+		want := svc.batch[i]
+		if msg.ID != want.ID || msg.Content != want.Content || msg.Status != domain.StatusPending {
+			t.Fatalf("message %d: expected %+v, got %+v", i, want, msg)
+		}
+	}
+}
+
+func TestGetHistoryCSV_MatchesBufferContents(t *testing.T) {
+	e := echo.New()
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := &fakeQueueScheduler{queue: domain.DefaultQueue, runs: []scheduler.RunRecord{
+		{StartedAt: base, Processed: 2, Succeeded: 2, Failed: 0},
+		{StartedAt: base.Add(2 * time.Minute), Processed: 2, Succeeded: 1, Failed: 1},
+		{StartedAt: base.Add(4 * time.Minute), Processed: 0},
+	}}
+	handler := newTestSchedulerHandler(nil, nil, history)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scheduler/history.csv", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.GetHistoryCSV(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("GetHistoryCSV returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected text/csv content type, got %q", ct)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if lines[0] != "timestamp,processed,succeeded,failed" {
+		t.Fatalf("unexpected CSV header: %q", lines[0])
+	}
+	if len(lines)-1 != len(history.runs) {
+		t.Fatalf("expected %d data rows, got %d", len(history.runs), len(lines)-1)
+	}
+	if lines[2] != "2025-01-01T12:02:00Z,2,1,1" {
+		t.Fatalf("unexpected second row: %q", lines[2])
+	}
+}
+
+func TestSchedulerRoutes_NamedQueuesStartIndependently(t *testing.T) {
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	transactional := &fakeQueueScheduler{queue: "transactional"}
+	promotional := &fakeQueueScheduler{queue: "promotional"}
+	cfg := &environments.Config{Message: environments.MessageConfig{SendInterval: 2 * time.Minute}}
+	handler := newTestSchedulerHandler(nil, cfg, transactional, promotional)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduler/transactional/start", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues("transactional")
+
+	if err := handler.StartScheduler(c); err != nil {
+		t.Fatalf("StartScheduler returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !transactional.running || promotional.running {
+		t.Fatalf("expected only the transactional scheduler to run (transactional=%v, promotional=%v)",
+			transactional.running, promotional.running)
+	}
+}
+
+func TestSchedulerRoutes_UnknownQueueReturns404(t *testing.T) {
+	e := echo.New()
+
+	handler := newTestSchedulerHandler(nil, nil, &fakeQueueScheduler{queue: domain.DefaultQueue})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scheduler/nope/status", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues("nope")
+
+	if err := handler.GetSchedulerStatus(c); err != nil {
+		t.Fatalf("GetSchedulerStatus returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+// failingWebhook fails the test if the simulation reaches the provider.
+type failingWebhook struct{ t *testing.T }
+
+func (w failingWebhook) SendMessage(context.Context, string, string) (*domain.WebhookResponse, error) {
+	w.t.Fatalf("simulation must not call the webhook")
+	return nil, nil
+}
+
+func TestSimulateRun_PredictsOutcomesWithoutWriting(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Only the batch read is expected; any UPDATE or INSERT fails the mock.
+	now := time.Now()
+	mock.ExpectQuery("FROM messages").
+		WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "phone_number", "status", "queue", "created_at", "updated_at"}).
+			AddRow(1, "hello", "+905551234567", "pending", domain.DefaultQueue, now, now).
+			AddRow(2, strings.Repeat("a", 20), "+905551234568", "pending", domain.DefaultQueue, now, now).
+			AddRow(3, "later", "+905551234569", "pending", domain.DefaultQueue, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT enabled FROM sending_switch")).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}))
+	// The send cap holds message 3 before its suppression would be checked.
+	for _, phone := range []string{"+905551234567", "+905551234568"} {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM suppressions WHERE phone_number = ?")).
+			WithArgs(phone).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	}
+
+	msgCfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 10, MaxSendsPerRun: 2}
+	svc := service.NewMessageService(repository.NewMessageRepository(sqlx.NewDb(db, "mysql")), failingWebhook{t}, nil, msgCfg)
+	handler := newTestSchedulerHandler(svc, &environments.Config{Message: msgCfg}, &fakeQueueScheduler{queue: domain.DefaultQueue})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduler/simulate", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.SimulateRun(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("SimulateRun returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected database activity: %v", err)
+	}
+
+	var body struct {
+		Data domain.SimulatedRun `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	got := body.Data.Messages
+	if len(got) != 3 {
+		t.Fatalf("expected a prediction per message, got %+v", got)
+	}
+	if got[0].Outcome != domain.SimulatedSendOutcome || got[0].SegmentCount != 1 || got[0].Truncated {
+		t.Errorf("unexpected prediction for message 1: %+v", got[0])
+	}
+	if got[1].Outcome != domain.SimulatedSendOutcome || !got[1].Truncated {
+		t.Errorf("expected message 2 to be sent truncated, got %+v", got[1])
+	}
+	if got[2].Outcome != domain.SimulatedSendCapOutcome {
+		t.Errorf("expected message 3 to be held by the send cap, got %+v", got[2])
+	}
+}
+
+func TestTestAlert_ReportsWebhookStatus(t *testing.T) {
+	var received map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := &environments.Config{Alert: environments.AlertConfig{WebhookURL: srv.URL, Timeout: time.Second}}
+	handler := newTestSchedulerHandler(&fakeNextBatchProvider{}, cfg)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduler/test-alert", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.TestAlert(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("TestAlert returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Data TestAlertResult `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	if body.Data.StatusCode != http.StatusServiceUnavailable || body.Data.Delivered || body.Data.Error == "" {
+		t.Fatalf("expected the webhook's 503 to be surfaced, got %+v", body.Data)
+	}
+	if received["alert"] != "test" {
+		t.Fatalf("expected a test alert payload, got %v", received)
+	}
+}
+
+func TestTestAlert_WithoutWebhookIsUnprocessable(t *testing.T) {
+	handler := newTestSchedulerHandler(&fakeNextBatchProvider{}, &environments.Config{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduler/test-alert", nil)
+	rec := httptest.NewRecorder()
+
+	if err := handler.TestAlert(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("TestAlert returned error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "ALERT_WEBHOOK_URL") {
+		t.Fatalf("expected 422 naming ALERT_WEBHOOK_URL, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStartScheduler_RejectsIntervalAboveMax(t *testing.T) {
+	cfg := &environments.Config{Message: environments.MessageConfig{MaxSendInterval: 60 * time.Minute}}
+
+	for _, tt := range []struct {
+		interval int
+		want     int
+	}{
+		{interval: 61, want: http.StatusUnprocessableEntity},
+		{interval: 60, want: http.StatusOK},
+	} {
+		sched := &fakeQueueScheduler{queue: domain.DefaultQueue}
+		handler := newTestSchedulerHandler(&fakeNextBatchProvider{}, cfg, sched)
+
+		e := echo.New()
+		e.Validator = validatorpkg.New()
+
+		body := fmt.Sprintf(`{"interval": %d}`, tt.interval)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduler/start", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		if err := handler.StartScheduler(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("StartScheduler returned error: %v", err)
+		}
+		if rec.Code != tt.want {
+			t.Fatalf("interval %d: expected status %d, got %d: %s", tt.interval, tt.want, rec.Code, rec.Body.String())
+		}
+		if started := tt.want == http.StatusOK; sched.running != started {
+			t.Errorf("interval %d: expected running=%v", tt.interval, started)
+		}
+		if tt.want == http.StatusOK && sched.lastInterval != tt.interval {
+			t.Errorf("expected interval %d to be used, got %d", tt.interval, sched.lastInterval)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+	"github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+// Small internal interface so the suppression list endpoints can be tested with fakes.
+type suppressionService interface {
+	SuppressPhone(ctx context.Context, phoneNumber, reason string) (*domain.SuppressionResult, error)
+	UnsuppressPhone(ctx context.Context, phoneNumber string) error
+}
+
+// SuppressionHandler manages the list of phone numbers that must not be messaged.
+type SuppressionHandler struct {
+	service suppressionService
+}
+
+type SuppressionRequest struct {
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=20"`
+	Reason      string `json:"reason,omitempty" validate:"omitempty,max=255,nocontrol"`
+}
+
+func NewSuppressionHandler(service suppressionService) *SuppressionHandler {
+	return &SuppressionHandler{
+		service: service,
+	}
+}
+
+// AddSuppression godoc
+// @Summary Suppress a phone number
+// @Description Adds a phone number to the suppression list. Its pending messages are moved to suppressed right away, and messages created for it later are suppressed instead of sent. Suppressing a number again only updates the reason.
+// @Tags suppressions
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param request body SuppressionRequest true "Number to suppress"
+// @Success 201 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/suppressions [post]
+func (h *SuppressionHandler) AddSuppression(c echo.Context) error {
+	var req SuppressionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	result, err := h.service.SuppressPhone(c.Request().Context(), req.PhoneNumber, req.Reason)
+	if err != nil {
+		return response.InternalServerError(c, err)
+	}
+
+	return response.Created(c, "Phone number suppressed", result)
+}
+
+// RemoveSuppression godoc
+// @Summary Remove a phone number from the suppression list
+// @Description Lets messages created for the number be sent again. Messages already suppressed stay suppressed. A leading + must be URL-encoded as %2B.
+// @Tags suppressions
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for messages"
+// @Param phone path string true "Phone number"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/suppressions/{phone} [delete]
+func (h *SuppressionHandler) RemoveSuppression(c echo.Context) error {
+	phoneNumber, err := url.PathUnescape(c.Param("phone"))
+	if err != nil || phoneNumber == "" {
+		return response.BadRequest(c, fmt.Errorf("invalid phone number"))
+	}
+
+	if err := h.service.UnsuppressPhone(c.Request().Context(), phoneNumber); err != nil {
+		if errors.Is(err, domain.ErrSuppressionNotFound) {
+			return response.NotFound(c, "Phone number is not suppressed")
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.OkWithMessage(c, "Suppression removed", nil)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	validatorpkg "github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+// fakeSuppressionService keeps suppressed numbers as given, without normalizing them.
+type fakeSuppressionService struct {
+	suppressed map[string]string
+}
+
+func (f *fakeSuppressionService) SuppressPhone(ctx context.Context, phoneNumber, reason string) (*domain.SuppressionResult, error) {
+	f.suppressed[phoneNumber] = reason
+	return &domain.SuppressionResult{
+		Suppression: &domain.Suppression{PhoneNumber: phoneNumber, Reason: &reason},
+		Suppressed:  2,
+	}, nil
+}
+
+func (f *fakeSuppressionService) UnsuppressPhone(ctx context.Context, phoneNumber string) error {
+	if _, ok := f.suppressed[phoneNumber]; !ok {
+		return domain.ErrSuppressionNotFound
+	}
+	delete(f.suppressed, phoneNumber)
+	return nil
+}
+
+func TestAddSuppression_Returns201WithStoppedCount(t *testing.T) {
+	svc := &fakeSuppressionService{suppressed: map[string]string{}}
+	handler := NewSuppressionHandler(svc)
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/suppressions",
+		strings.NewReader(`{"phoneNumber": "+905551234567", "reason": "STOP reply"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.AddSuppression(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("AddSuppression returned error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if svc.suppressed["+905551234567"] != "STOP reply" {
+		t.Fatalf("expected the number to be suppressed with its reason, got %v", svc.suppressed)
+	}
+
+	var body struct {
+		Data domain.SuppressionResult `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.Suppressed != 2 || body.Data.Suppression == nil || body.Data.Suppression.PhoneNumber != "+905551234567" {
+		t.Fatalf("unexpected response data: %s", rec.Body.String())
+	}
+}
+
+func TestRemoveSuppression_DecodesPhoneAndReturns404WhenUnknown(t *testing.T) {
+	svc := &fakeSuppressionService{suppressed: map[string]string{"+905551234567": ""}}
+	handler := NewSuppressionHandler(svc)
+
+	remove := func(phone string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/suppressions/"+phone, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("phone")
+		c.SetParamValues(phone)
+
+		if err := handler.RemoveSuppression(c); err != nil {
+			t.Fatalf("RemoveSuppression returned error: %v", err)
+		}
+		return rec
+	}
+
+	if rec := remove("%2B905551234567"); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(svc.suppressed) != 0 {
+		t.Fatalf("expected the number to be removed, got %v", svc.suppressed)
+	}
+	if rec := remove("%2B905551234567"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 once removed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
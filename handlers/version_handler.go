@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/pkg/response"
+	"github.com/onurcolak/insider-message-service/pkg/version"
+)
+
+// Version godoc
+// @Summary Build information
+// @Description Returns the version, commit and build time the running binary was built with
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.SuccessResponse
+// @Router /version [get]
+func Version(c echo.Context) error {
+	return response.Ok(c, version.Get())
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestVersion_ReturnsBuildInfoFields(t *testing.T) {
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	rec := httptest.NewRecorder()
+
+	if err := Version(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("Version returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+
+	// Not injected in tests, so the placeholders are expected.
+	expected := map[string]string{"version": "dev", "commit": "unknown", "buildTime": "unknown"}
+	for field, want := range expected {
+		if got, ok := body.Data[field]; !ok || got != want {
+			t.Errorf("expected %s=%q, got %q (present: %v)", field, want, got, ok)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+	"github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+// Small internal interface so inbound provider webhooks can be tested with fakes.
+type deliveryReceiptService interface {
+	RecordDeliveryReceipt(ctx context.Context, messageID string, status domain.DeliveryStatus) (*domain.Message, error)
+}
+
+// WebhookHandler serves callbacks sent to us by the SMS provider.
+type WebhookHandler struct {
+	service deliveryReceiptService
+}
+
+type DeliveryReceiptRequest struct {
+	MessageID string `json:"messageId" validate:"required,max=100"`
+	Status    string `json:"status" validate:"required,oneof=delivered undelivered"`
+}
+
+func NewWebhookHandler(service deliveryReceiptService) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+// ReceiveDeliveryReceipt godoc
+// @Summary Receive a delivery receipt (DLR)
+// @Description Records the provider's final delivery state for a sent message, looked up by its provider message id
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param x-ins-auth-key header string true "API key for delivery receipts"
+// @Param request body DeliveryReceiptRequest true "Delivery receipt"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/webhooks/dlr [post]
+func (h *WebhookHandler) ReceiveDeliveryReceipt(c echo.Context) error {
+	var req DeliveryReceiptRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, err)
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return validator.HandleValidationError(c, err)
+	}
+
+	msg, err := h.service.RecordDeliveryReceipt(c.Request().Context(), req.MessageID, domain.DeliveryStatus(req.Status))
+	if err != nil {
+		if errors.Is(err, domain.ErrMessageNotFound) {
+			return response.NotFound(c, "No message found with the given messageId")
+		}
+		return response.InternalServerError(c, err)
+	}
+
+	return response.OkWithMessage(c, "Delivery receipt recorded", msg)
+}
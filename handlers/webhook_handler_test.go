@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	validatorpkg "github.com/onurcolak/insider-message-service/pkg/validator"
+)
+
+// fakeDeliveryReceiptService keeps messages keyed by provider message id.
+type fakeDeliveryReceiptService struct {
+	byMessageID map[string]*domain.Message
+}
+
+func (f *fakeDeliveryReceiptService) RecordDeliveryReceipt(
+	ctx context.Context,
+	messageID string,
+	status domain.DeliveryStatus,
+) (*domain.Message, error) {
+	msg, ok := f.byMessageID[messageID]
+	if !ok {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	now := time.Now()
+	msg.DeliveryStatus = &status
+	msg.DeliveryUpdatedAt = &now
+	return msg, nil
+}
+
+func postDeliveryReceipt(t *testing.T, handler *WebhookHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = validatorpkg.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/dlr", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler.ReceiveDeliveryReceipt(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ReceiveDeliveryReceipt returned error: %v", err)
+	}
+	return rec
+}
+
+func TestReceiveDeliveryReceipt_UpdatesKnownMessage(t *testing.T) {
+	msg := &domain.Message{ID: 7, Status: domain.StatusSent}
+	svc := &fakeDeliveryReceiptService{byMessageID: map[string]*domain.Message{"provider-7": msg}}
+	handler := NewWebhookHandler(svc)
+
+	rec := postDeliveryReceipt(t, handler, `{"messageId": "provider-7", "status": "delivered"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if msg.DeliveryStatus == nil || *msg.DeliveryStatus != domain.DeliveryDelivered {
+		t.Fatalf("expected delivery status %q, got %v", domain.DeliveryDelivered, msg.DeliveryStatus)
+	}
+
+	var body struct {
+		Data domain.Message `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Data.ID != 7 || body.Data.DeliveryStatus == nil || *body.Data.DeliveryStatus != domain.DeliveryDelivered {
+		t.Fatalf("unexpected response data: %+v", body.Data)
+	}
+}
+
+func TestReceiveDeliveryReceipt_UnknownMessageIDReturns404(t *testing.T) {
+	handler := NewWebhookHandler(&fakeDeliveryReceiptService{})
+
+	rec := postDeliveryReceipt(t, handler, `{"messageId": "does-not-exist", "status": "undelivered"}`)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestReceiveDeliveryReceipt_InvalidStatusReturns422(t *testing.T) {
+	// service is nil on purpose; validation must fail before it is called.
+	handler := NewWebhookHandler(nil)
+
+	rec := postDeliveryReceipt(t, handler, `{"messageId": "provider-7", "status": "read"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
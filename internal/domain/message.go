@@ -1,13 +1,143 @@
 package domain
 
-import "time"
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
 
 type MessageStatus string
 
 const (
-	StatusPending MessageStatus = "pending"
-	StatusSent    MessageStatus = "sent"
-	StatusFailed  MessageStatus = "failed"
+	StatusPending   MessageStatus = "pending"
+	StatusSent      MessageStatus = "sent"
+	StatusFailed    MessageStatus = "failed"
+	StatusCancelled MessageStatus = "cancelled"
+	StatusDeduped   MessageStatus = "deduped" // Skipped as a repeat of a recent send to the same number
+
+	StatusSuppressed MessageStatus = "suppressed" // Not sent because the number is on the suppression list
+)
+
+// messageStatuses is the registry of known statuses. A new status is added here (and, if
+// operators may move messages to it, in allowedTransitions); everything that validates a
+// status goes through it.
+var messageStatuses = []MessageStatus{
+	StatusPending, StatusSent, StatusFailed, StatusCancelled, StatusDeduped, StatusSuppressed,
+}
+
+// ErrUnknownStatus is returned when a status is not in the registry.
+var ErrUnknownStatus = errors.New("unknown message status")
+
+// MessageStatuses returns every known status, in registry order.
+func MessageStatuses() []MessageStatus {
+	return slices.Clone(messageStatuses)
+}
+
+// IsValid reports whether s is a known status.
+func (s MessageStatus) IsValid() bool {
+	return slices.Contains(messageStatuses, s)
+}
+
+// ParseMessageStatus validates raw against the registry.
+func ParseMessageStatus(raw string) (MessageStatus, error) {
+	status := MessageStatus(raw)
+	if !status.IsValid() {
+		names := make([]string, len(messageStatuses))
+		for i, s := range messageStatuses {
+			names[i] = string(s)
+		}
+		return "", fmt.Errorf("%w %q (allowed: %s)", ErrUnknownStatus, raw, strings.Join(names, ", "))
+	}
+	return status, nil
+}
+
+// allowedTransitions lists the statuses an operator may move a message to from each status.
+var allowedTransitions = map[MessageStatus][]MessageStatus{
+	StatusPending:   {StatusSent, StatusFailed, StatusCancelled},
+	StatusFailed:    {StatusPending, StatusSent, StatusCancelled},
+	StatusCancelled: {StatusPending},
+	StatusDeduped:   {StatusPending, StatusCancelled},
+
+	StatusSuppressed: {StatusPending, StatusCancelled},
+}
+
+// CanTransition reports whether a message may be moved from one status to another by hand.
+// Moving to sent requires a provider message id, since sent messages are tracked by it.
+func CanTransition(from, to MessageStatus, hasMessageID bool) bool {
+	if to == StatusSent && !hasMessageID {
+		return false
+	}
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultQueue is used for messages created without a queue and by the default scheduler.
+const DefaultQueue = "default"
+
+// DefaultClient owns messages created without a client, e.g. by a campaign.
+const DefaultClient = "default"
+
+// Orders in which the scheduler picks pending messages.
+const (
+	ProcessOrderFIFO = "fifo" // Oldest first (default)
+	ProcessOrderLIFO = "lifo" // Newest first, e.g. when the latest notifications matter most
+)
+
+// DeliveryStatus is the final state reported by the provider in a delivery receipt.
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered   DeliveryStatus = "delivered"
+	DeliveryUndelivered DeliveryStatus = "undelivered"
+)
+
+var (
+	ErrMessageNotFound   = errors.New("message not found")
+	ErrEmptyCancelFilter = errors.New("cancel filter is empty; set all=true to cancel every pending message")
+	ErrUnknownQueue      = errors.New("unknown queue")
+	ErrMessageNotPending = errors.New("message is not pending")
+	ErrMessageNotFailed  = errors.New("message is not failed")
+	ErrInvalidTransition = errors.New("invalid status transition")
+
+	// ErrMissingMessageID means the provider accepted the request but returned no message id,
+	// so delivery cannot be tracked. Retrying could send the message twice.
+	ErrMissingMessageID = errors.New("webhook accepted the message but returned no messageId")
+	// ErrTerminalSend means the provider rejected the message in a way retrying cannot fix,
+	// e.g. an invalid number.
+	ErrTerminalSend = errors.New("webhook rejected the message permanently")
+	// ErrBatchAborted is returned by a batch send that stopped at its first failed message.
+	ErrBatchAborted = errors.New("batch send aborted on first error")
+	// ErrBatchInProgress means another batch is already sending from the same queue.
+	ErrBatchInProgress = errors.New("a batch is already being processed for this queue")
+	// ErrSendingDisabled means the global kill switch is off, so messages are left pending.
+	ErrSendingDisabled = errors.New("sending is disabled")
+	// ErrQuietHours means a run was skipped because quiet hours are in effect.
+	ErrQuietHours = errors.New("quiet hours in effect")
+	// ErrNotDue means nothing was sent because every pending message is scheduled for later.
+	ErrNotDue = errors.New("pending messages are scheduled for later")
+	// ErrCountryNotAllowed means the destination number is outside the configured country allowlist.
+	ErrCountryNotAllowed = errors.New("destination country code is not allowed")
+	// ErrPhoneNumberTooLong means the number does not fit the phone_number column.
+	ErrPhoneNumberTooLong = errors.New("phone number is too long")
+	// ErrContentTooLong means the content is longer than MESSAGE_MAX_CONTENT_LENGTH.
+	ErrContentTooLong = errors.New("content exceeds maximum length")
+	// ErrConflictingSchedule means a message was given both an absolute and a relative send time.
+	ErrConflictingSchedule = errors.New("sendAt and delaySeconds cannot both be set")
+	// ErrDuplicateClientMessageID is returned together with the message created earlier under
+	// the same client message id.
+	ErrDuplicateClientMessageID = errors.New("a message with this client message id already exists")
+	// ErrContentSearchUnavailable means a search needs message content, which is encrypted at rest.
+	ErrContentSearchUnavailable = errors.New("content search is unavailable while content is encrypted")
+	// ErrReplayLimitReached means a failed message was replayed the maximum number of times.
+	ErrReplayLimitReached = errors.New("message reached the replay limit")
 )
 
 type Message struct {
@@ -17,8 +147,310 @@ type Message struct {
 	Status      MessageStatus `db:"status" json:"status"`
 	MessageID   *string       `db:"message_id" json:"messageId,omitempty"`
 	SentAt      *time.Time    `db:"sent_at" json:"sentAt,omitempty"`
-	CreatedAt   time.Time     `db:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time     `db:"updated_at" json:"updatedAt"`
+	Tags        Tags          `db:"tags" json:"tags,omitempty"`
+	Queue       string        `db:"queue" json:"queue"`
+	LastError   *string       `db:"last_error" json:"lastError,omitempty"`
+
+	// TimeoutSeconds overrides the webhook timeout for this message, e.g. for known-slow destinations.
+	// Like the global timeout, it bounds a whole webhook call including the client's HTTP retries.
+	TimeoutSeconds *int `db:"timeout_seconds" json:"timeoutSeconds,omitempty"`
+
+	// SendAt is when the message becomes due; the scheduler skips it until then. Nil means right away.
+	SendAt *time.Time `db:"send_at" json:"sendAt,omitempty"`
+
+	// CampaignID groups the messages created together by one campaign request.
+	CampaignID *string `db:"campaign_id" json:"campaignId,omitempty"`
+
+	// ClientMessageID is the client's own unique id for the message, used to dedupe creates.
+	ClientMessageID *string `db:"client_message_id" json:"clientMessageId,omitempty"`
+
+	// Truncated is set when the content exceeded the max length and was cut before sending;
+	// OriginalLength is the content length at that point. The stored content is not changed.
+	Truncated      bool `db:"truncated" json:"truncated"`
+	OriginalLength *int `db:"original_length" json:"originalLength,omitempty"`
+
+	// FirstFailedAt is when the message first failed; cleared on replay.
+	FirstFailedAt *time.Time `db:"first_failed_at" json:"firstFailedAt,omitempty"`
+
+	// ReplayCount is how often the message was replayed after failing.
+	ReplayCount int `db:"replay_count" json:"replayCount"`
+
+	// RetryCount is how many runs failed to send the message; it stays pending until MaxRetries.
+	RetryCount int `db:"retry_count" json:"retryCount"`
+
+	DeliveryStatus    *DeliveryStatus `db:"delivery_status" json:"deliveryStatus,omitempty"`
+	DeliveryUpdatedAt *time.Time      `db:"delivery_updated_at" json:"deliveryUpdatedAt,omitempty"`
+
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// NewMessage holds the client-provided fields used to create a message.
+type NewMessage struct {
+	Content     string
+	PhoneNumber string
+	Tags        []string
+	Queue       string // Empty means DefaultQueue
+
+	TimeoutSeconds *int // Nil means the global webhook timeout
+
+	// SendAt holds the message back until the given time; DelaySeconds does the same relative to
+	// creation. At most one of them may be set.
+	SendAt       *time.Time
+	DelaySeconds *int
+
+	CampaignID string // Empty for messages not created by a campaign
+
+	// ClientMessageID, if set, must be unique per Client; creating a message with one the client
+	// already used returns the earlier message instead.
+	ClientMessageID string
+	Client          string // API client creating the message; empty means DefaultClient
+}
+
+// NewCampaign renders Template once per recipient with the recipient's variables, e.g.
+// "Hi {{.name}}", and creates one pending message for each.
+type NewCampaign struct {
+	Template   string
+	Queue      string // Empty means DefaultQueue
+	Recipients []CampaignRecipient
+}
+
+type CampaignRecipient struct {
+	PhoneNumber string
+	Variables   map[string]string
+}
+
+// CampaignResult identifies the messages created by a campaign.
+type CampaignResult struct {
+	CampaignID string `json:"campaignId"`
+	Created    int64  `json:"created"`
+}
+
+// ErrInvalidTemplate means a campaign template does not parse or cannot be rendered for a recipient.
+var ErrInvalidTemplate = errors.New("invalid campaign template")
+
+// MessageFilter narrows list queries. Zero values mean "no filter".
+type MessageFilter struct {
+	Status        *MessageStatus
+	Tag           string
+	TruncatedOnly bool       // Only messages whose content was truncated when sent
+	SentBefore    *time.Time // Only messages with a sent_at (or updated_at, if unset) before this time
+	CampaignID    string     // Only messages created by this campaign
+
+	// Fields limits the columns read to these message fields, as returned by ParseMessageFields
+	// (empty = all columns).
+	Fields []string
+}
+
+// ErrUnknownField means a requested field is not a message field.
+var ErrUnknownField = errors.New("unknown message field")
+
+// messageFieldColumns maps the JSON name of every Message field to its column.
+var messageFieldColumns = func() map[string]string {
+	columns := make(map[string]string)
+	t := reflect.TypeFor[Message]()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		column := field.Tag.Get("db")
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if column != "" && name != "" {
+			columns[name] = column
+		}
+	}
+	return columns
+}()
+
+// ParseMessageFields turns a comma-separated list of message fields, given by their JSON name
+// ("phoneNumber") or column ("phone_number"), into JSON names without duplicates.
+func ParseMessageFields(raw string) ([]string, error) {
+	var fields []string
+	for _, item := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(item)
+		if name == "" {
+			continue
+		}
+
+		if _, ok := messageFieldColumns[name]; !ok {
+			name = fieldForColumn(name)
+			if name == "" {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownField, strings.TrimSpace(item))
+			}
+		}
+
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+func fieldForColumn(column string) string {
+	for name, c := range messageFieldColumns {
+		if c == column {
+			return name
+		}
+	}
+	return ""
+}
+
+// MessageFieldColumn returns the column a message field (by JSON name) is stored in.
+func MessageFieldColumn(field string) (string, bool) {
+	column, ok := messageFieldColumns[field]
+	return column, ok
+}
+
+// ReplayAge bounds a replay of failed messages by how long ago they failed (zero = unbounded).
+type ReplayAge struct {
+	OlderThan time.Duration // Only messages that failed at least this long ago
+	NewerThan time.Duration // Only messages that failed less than this long ago
+}
+
+// CancelFilter selects pending messages to cancel. Set fields are combined with AND.
+type CancelFilter struct {
+	Tag           string
+	PhonePrefix   string
+	CreatedBefore *time.Time
+}
+
+func (f CancelFilter) IsEmpty() bool {
+	return f.Tag == "" && f.PhonePrefix == "" && f.CreatedBefore == nil
+}
+
+// Tags is a list of labels, stored in the database as a comma-separated string.
+type Tags []string
+
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return strings.Join(t, ","), nil
+}
+
+func (t *Tags) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", src)
+	}
+
+	if raw == "" {
+		*t = nil
+		return nil
+	}
+	*t = strings.Split(raw, ",")
+	return nil
+}
+
+// PhoneNormalizationResult reports a pass over the phone numbers of pending messages.
+type PhoneNormalizationResult struct {
+	Scanned     int64   `json:"scanned"`
+	Changed     int64   `json:"changed"`
+	RejectedIDs []int64 `json:"rejectedIds"` // Numbers that could not be fixed
+	Failed      int64   `json:"failed"`      // Rejected messages moved to failed, if requested
+}
+
+// SendPreview describes how a message will be sent, as computed by the server.
+type SendPreview struct {
+	NormalizedPhone string `json:"normalizedPhone"`
+	SegmentCount    int    `json:"segmentCount"`
+	Truncated       bool   `json:"truncated"` // Content exceeds the max length and will be cut
+}
+
+// SimulatedOutcome is what a scheduler run would do with a pending message.
+type SimulatedOutcome string
+
+const (
+	SimulatedSendOutcome            SimulatedOutcome = "send"                  // Sent to the webhook
+	SimulatedQuietHoursOutcome      SimulatedOutcome = "held_quiet_hours"      // Left pending, the run falls in quiet hours
+	SimulatedSendingDisabledOutcome SimulatedOutcome = "held_sending_disabled" // Left pending, the kill switch is off
+	SimulatedSendCapOutcome         SimulatedOutcome = "held_send_cap"         // Left pending, MaxSendsPerRun would be reached first
+	SimulatedSuppressedOutcome      SimulatedOutcome = "suppressed"            // Not sent, the number is on the suppression list
+	SimulatedDedupedOutcome         SimulatedOutcome = "deduped"               // Not sent, an identical send is within the dedup window
+)
+
+// SimulatedRun predicts what the next run of a queue would do, without sending or changing anything.
+type SimulatedRun struct {
+	Queue      string          `json:"queue"`
+	QuietHours bool            `json:"quietHours"`
+	Messages   []SimulatedSend `json:"messages"`
+}
+
+// SimulatedSend is the predicted handling of one message, with how it would be sent.
+type SimulatedSend struct {
+	ID      int64            `json:"id"`
+	Outcome SimulatedOutcome `json:"outcome"`
+	SendPreview
+}
+
+// CostEstimate projects what sending the pending messages will cost, in SMS segments times the
+// configured price per segment.
+type CostEstimate struct {
+	PricePerSegment float64       `json:"pricePerSegment"`
+	Messages        int64         `json:"messages"`
+	Segments        int64         `json:"segments"`
+	TotalCost       float64       `json:"totalCost"`
+	Countries       []CountryCost `json:"countries"` // Sorted by country code
+}
+
+// CountryCost is the part of a CostEstimate for one destination calling code ("unknown" for
+// numbers without an international prefix).
+type CountryCost struct {
+	CountryCode string  `json:"countryCode"`
+	Messages    int64   `json:"messages"`
+	Segments    int64   `json:"segments"`
+	Cost        float64 `json:"cost"`
+}
+
+// ErrArchiveNotConfigured is returned when archiving is requested without an object store.
+var ErrArchiveNotConfigured = errors.New("archive object store is not configured")
+
+// ArchiveResult describes one archive of sent messages to the object store.
+type ArchiveResult struct {
+	Cutoff   time.Time `json:"cutoff"`        // Messages sent before this time were archived
+	Key      string    `json:"key,omitempty"` // Object key of the upload (empty when nothing matched)
+	Archived int       `json:"archived"`
+	Purged   int64     `json:"purged"` // Rows deleted after the upload (0 unless purge was requested)
+}
+
+// HourlySends is the number of messages sent in the hour starting at Hour (UTC).
+type HourlySends struct {
+	Hour  time.Time `json:"hour"`
+	Count int64     `json:"count"`
+}
+
+// SLAObjectivePercent is the share of messages that must be sent within the SLA target.
+const SLAObjectivePercent = 95.0
+
+// SLACompliance is the share of messages sent within Target of their creation, over the messages
+// sent in the last Window.
+type SLACompliance struct {
+	Window            string  `json:"window"`
+	Target            string  `json:"target"`
+	Sent              int64   `json:"sent"`
+	Breaching         int64   `json:"breaching"` // Sent Target or more after creation
+	CompliancePercent float64 `json:"compliancePercent"`
+	ObjectivePercent  float64 `json:"objectivePercent"`
+	Met               bool    `json:"met"`
+}
+
+// FailureReason is a normalized failure message and how many failed messages share it.
+type FailureReason struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// StatusCount is the number of messages of one queue in one status.
+type StatusCount struct {
+	Queue  string        `db:"queue" json:"queue"`
+	Status MessageStatus `db:"status" json:"status"`
+	Count  int64         `db:"count" json:"count"`
 }
 
 type SentMessageCache struct {
@@ -26,6 +458,10 @@ type SentMessageCache struct {
 	SentAt    time.Time `json:"sentAt"`
 }
 
+// ErrPartialCacheRead marks a cache listing that stopped early; the entries read before the
+// failure are returned alongside it.
+var ErrPartialCacheRead = errors.New("cache read was incomplete")
+
 type WebhookRequest struct {
 	To      string `json:"to"`
 	Content string `json:"content"`
@@ -36,10 +472,124 @@ type WebhookResponse struct {
 	MessageID string `json:"messageId"`
 }
 
+// WebhookStatusError is a webhook response with a status code other than the expected one.
+// Retryable is set by the webhook client from its list of transient status codes; any other
+// status matches ErrTerminalSend.
+type WebhookStatusError struct {
+	StatusCode int
+	Body       string
+	Retryable  bool
+}
+
+func (e *WebhookStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+func (e *WebhookStatusError) Is(target error) bool {
+	return target == ErrTerminalSend && !e.Retryable
+}
+
+// ChangeCursor is the position of a message in the change feed, ordered by updated_at and then
+// id. updated_at has second precision, so the id is needed to resume inside a second.
+type ChangeCursor struct {
+	UpdatedAt time.Time
+	ID        int64
+}
+
+// UnsentCursor is the position of a pending message in the order GetUnsent returns them, for
+// fetching the messages after it.
+type UnsentCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
 type SendResult struct {
 	MessageDBID int64
 	MessageID   string
 	Success     bool
 	Error       error
 	SentAt      time.Time
+	Attempts    int           // Webhook attempts made for this message (retries = Attempts - 1)
+	Deduped     bool          // Skipped without calling the provider as a repeat of a recent send
+	Suppressed  bool          // Skipped without calling the provider because the number is suppressed
+	Latency     time.Duration // Time spent calling the provider, retries included (0 = not called)
+	Retrying    bool          // Failed, but left pending for a later run because retries remain
+
+	// The message as stored, so failures can be reported without another lookup.
+	PhoneNumber string
+	Content     string
 }
+
+// SendOutcome is the machine-readable outcome of processing one message, as logged per message.
+type SendOutcome string
+
+const (
+	OutcomeSent       SendOutcome = "sent"
+	OutcomeFailed     SendOutcome = "failed"
+	OutcomeDeduped    SendOutcome = "deduped"
+	OutcomeSuppressed SendOutcome = "suppressed"
+	OutcomeRetrying   SendOutcome = "retrying" // Failed and left pending for another run
+)
+
+// Outcome returns the outcome code of the result.
+func (r SendResult) Outcome() SendOutcome {
+	switch {
+	case r.Success:
+		return OutcomeSent
+	case r.Suppressed:
+		return OutcomeSuppressed
+	case r.Deduped:
+		return OutcomeDeduped
+	case r.Retrying:
+		return OutcomeRetrying
+	default:
+		return OutcomeFailed
+	}
+}
+
+// DeadLetter is posted to the dead-letter webhook for a message that was marked failed.
+type DeadLetter struct {
+	ID          int64     `json:"id"`
+	PhoneNumber string    `json:"phoneNumber"`
+	Content     string    `json:"content"`
+	LastError   string    `json:"lastError"`
+	FailedAt    time.Time `json:"failedAt"`
+}
+
+// NewDeadLetter builds the dead-letter payload of a failed send.
+func NewDeadLetter(r SendResult) DeadLetter {
+	dl := DeadLetter{
+		ID:          r.MessageDBID,
+		PhoneNumber: r.PhoneNumber,
+		Content:     r.Content,
+		FailedAt:    r.SentAt,
+	}
+	if r.Error != nil {
+		dl.LastError = r.Error.Error()
+	}
+	return dl
+}
+
+// OutcomeCounts counts send outcomes other than sent/failed since the process started.
+type OutcomeCounts struct {
+	Truncated             int64 `json:"truncated"`             // Messages sent with content cut to the max length
+	QuietHoursSkippedRuns int64 `json:"quietHoursSkippedRuns"` // Runs skipped because quiet hours were in effect
+	SendCapSkipped        int64 `json:"sendCapSkipped"`        // Fetched messages left pending because MaxSendsPerRun was reached
+	Deduped               int64 `json:"deduped"`               // Messages skipped as repeats of a recent send to the same number
+	Suppressed            int64 `json:"suppressed"`            // Messages skipped because their number is on the suppression list
+}
+
+// Suppression stops all messages to a phone number, e.g. after the recipient complained.
+type Suppression struct {
+	PhoneNumber string    `db:"phone_number" json:"phoneNumber"`
+	Reason      *string   `db:"reason" json:"reason,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"createdAt"`
+}
+
+// SuppressionResult reports a new suppression and how many pending messages it stopped.
+type SuppressionResult struct {
+	Suppression *Suppression `json:"suppression"`
+	Suppressed  int64        `json:"suppressedMessages"` // Pending messages moved to suppressed
+}
+
+var ErrSuppressionNotFound = errors.New("phone number is not suppressed")
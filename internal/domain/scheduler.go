@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// SchedulerState is the part of a queue's scheduler state that survives restarts.
+type SchedulerState struct {
+	Queue           string    `db:"queue"`
+	Running         bool      `db:"running"` // Desired state: false once an operator stopped the scheduler
+	IntervalSeconds int64     `db:"interval_seconds"`
+	FailureRate     float64   `db:"failure_rate"`
+	RunsCount       int64     `db:"runs_count"`
+	MessagesSent    int64     `db:"messages_sent"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+// ErrRunNotFound means no results are stored for the requested run, e.g. because it is older
+// than the retained runs.
+var ErrRunNotFound = errors.New("scheduler run not found")
+
+// SchedulerRun holds the per-message outcomes of one scheduler run.
+type SchedulerRun struct {
+	Queue           string      `json:"queue"`
+	RunNumber       int64       `json:"runNumber"`
+	StartedAt       time.Time   `json:"startedAt"`
+	RetryBudgetUsed int         `json:"retryBudgetUsed"` // Webhook retries the run drew from MESSAGE_RETRY_BUDGET
+	Results         []RunResult `json:"results"`
+}
+
+// RunResult is the stored form of a SendResult.
+type RunResult struct {
+	MessageDBID int64     `json:"messageDbId"`
+	MessageID   string    `json:"messageId,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	SentAt      time.Time `json:"sentAt"`
+	Attempts    int       `json:"attempts"`
+	Deduped     bool      `json:"deduped,omitempty"`
+	Suppressed  bool      `json:"suppressed,omitempty"`
+}
+
+// NewRunResults converts send results for storage.
+func NewRunResults(results []SendResult) []RunResult {
+	stored := make([]RunResult, 0, len(results))
+	for _, r := range results {
+		result := RunResult{
+			MessageDBID: r.MessageDBID,
+			MessageID:   r.MessageID,
+			Success:     r.Success,
+			SentAt:      r.SentAt,
+			Attempts:    r.Attempts,
+			Deduped:     r.Deduped,
+			Suppressed:  r.Suppressed,
+		}
+		if r.Error != nil {
+			result.Error = r.Error.Error()
+		}
+		stored = append(stored, result)
+	}
+	return stored
+}
+
+// RetryBudgetUsed returns how many retries the results drew from their run's retry budget. Every
+// attempt after a message's first one is a retry taken from the budget.
+func RetryBudgetUsed(results []RunResult) int {
+	used := 0
+	for _, r := range results {
+		if r.Attempts > 1 {
+			used += r.Attempts - 1
+		}
+	}
+	return used
+}
@@ -11,6 +11,15 @@ import (
 
 const (
 	APIKeyHeader = "x-ins-auth-key"
+
+	// KeyIdentityContextKey holds which configured key authenticated the request.
+	KeyIdentityContextKey = "apiKeyIdentity"
+)
+
+// Key identities stored under KeyIdentityContextKey.
+const (
+	KeyIdentityDefault = "default"
+	KeyIdentityTrusted = "trusted"
 )
 
 // secureCompare compares two strings in a way that is safer against timing attacks.
@@ -18,7 +27,9 @@ func secureCompare(a, b string) bool {
 	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
 }
 
-func APIKeyAuth(apiKey string) echo.MiddlewareFunc {
+// APIKeyAuth accepts requests carrying apiKey or one of trustedKeys (empty trusted keys are
+// ignored) and records which one matched under KeyIdentityContextKey.
+func APIKeyAuth(apiKey string, trustedKeys ...string) echo.MiddlewareFunc {
 	// If the API key is not configured, treat this as a server-side misconfiguration.
 	if apiKey == "" {
 		return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -35,7 +46,16 @@ func APIKeyAuth(apiKey string) echo.MiddlewareFunc {
 		return func(c echo.Context) error {
 			// Get API key from x-ins-auth-key header.
 			token := c.Request().Header.Get(APIKeyHeader)
-			if token == "" || !secureCompare(token, apiKey) {
+			if token == "" {
+				return response.Unauthorized(c)
+			}
+
+			switch {
+			case matchesAny(token, trustedKeys):
+				c.Set(KeyIdentityContextKey, KeyIdentityTrusted)
+			case secureCompare(token, apiKey):
+				c.Set(KeyIdentityContextKey, KeyIdentityDefault)
+			default:
 				return response.Unauthorized(c)
 			}
 
@@ -43,3 +63,25 @@ func APIKeyAuth(apiKey string) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// matchesAny reports whether token equals one of the non-empty keys.
+func matchesAny(token string, keys []string) bool {
+	for _, key := range keys {
+		if key != "" && secureCompare(token, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyIdentity returns which configured key authenticated the request, or "" if none did.
+func KeyIdentity(c echo.Context) string {
+	identity, _ := c.Get(KeyIdentityContextKey).(string)
+	return identity
+}
+
+// IsTrustedKey reports whether the request was authenticated with a trusted key.
+func IsTrustedKey(c echo.Context) bool {
+	identity, _ := c.Get(KeyIdentityContextKey).(string)
+	return identity == KeyIdentityTrusted
+}
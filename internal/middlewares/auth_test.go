@@ -122,3 +122,28 @@ func TestAPIKeyAuth_ValidKeyPassesThrough(t *testing.T) {
 		t.Fatalf("expected next handler to be called")
 	}
 }
+
+func TestAPIKeyAuth_RecordsMatchedKeyIdentity(t *testing.T) {
+	mw := APIKeyAuth("secret", "", "trusted-secret")
+
+	for key, wantTrusted := range map[string]bool{"secret": false, "trusted-secret": true} {
+		c, rec := newEchoContext(http.MethodGet, "/test")
+		c.Request().Header.Set(APIKeyHeader, key)
+
+		var trusted bool
+		handler := mw(func(c echo.Context) error {
+			trusted = IsTrustedKey(c)
+			return c.NoContent(http.StatusOK)
+		})
+
+		if err := handler(c); err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("key %q: expected status 200, got %d", key, rec.Code)
+		}
+		if trusted != wantTrusted {
+			t.Errorf("key %q: expected trusted=%v, got %v", key, wantTrusted, trusted)
+		}
+	}
+}
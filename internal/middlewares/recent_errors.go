@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRecentErrors caps the ring buffer whatever size is configured.
+const maxRecentErrors = 1000
+
+// ErrorEntry is one recorded error response. Only the route pattern is kept, never the query,
+// headers or body, so ids, phone numbers and API keys do not end up in the buffer.
+type ErrorEntry struct {
+	Status    int       `json:"status"`
+	Code      string    `json:"code"` // HTTP status text, e.g. "Unprocessable Entity"
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	RequestID string    `json:"requestId,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecentErrors keeps the latest 4xx/5xx responses in a fixed-size ring buffer, for clients
+// debugging their own failed calls.
+type RecentErrors struct {
+	mu      sync.Mutex
+	entries []ErrorEntry
+	next    int
+	size    int
+}
+
+// NewRecentErrors creates a buffer holding the last size errors (at most maxRecentErrors).
+func NewRecentErrors(size int) *RecentErrors {
+	return &RecentErrors{size: min(max(size, 1), maxRecentErrors)}
+}
+
+// Middleware records every response with a 4xx or 5xx status.
+func (r *RecentErrors) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil && !c.Response().Committed {
+				// The error handler has not written the response yet.
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+
+			if status >= http.StatusBadRequest {
+				path := c.Path()
+				if path == "" {
+					path = c.Request().URL.Path
+				}
+
+				r.add(ErrorEntry{
+					Status:    status,
+					Code:      http.StatusText(status),
+					Method:    c.Request().Method,
+					Path:      path,
+					RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+					Timestamp: time.Now(),
+				})
+			}
+
+			return err
+		}
+	}
+}
+
+func (r *RecentErrors) add(entry ErrorEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) < r.size {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % r.size
+}
+
+// Entries returns the recorded errors, newest first.
+func (r *RecentErrors) Entries() []ErrorEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]ErrorEntry, 0, len(r.entries))
+	for i := range len(r.entries) {
+		// The newest entry sits just before next (or at the end while the buffer is filling).
+		idx := (r.next - 1 - i + 2*len(r.entries)) % len(r.entries)
+		entries = append(entries, r.entries[idx])
+	}
+	return entries
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/onurcolak/insider-message-service/pkg/response"
+)
+
+func TestRecentErrors_RecordsFailedRequests(t *testing.T) {
+	recent := NewRecentErrors(10)
+
+	e := echo.New()
+	e.Use(recent.Middleware())
+	e.GET("/messages/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return response.BadRequest(c, fmt.Errorf("id must be positive"))
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, target := range []string{"/messages/1", "/messages/0?phone=%2B905551234567"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(APIKeyHeader, "secret")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	entries := recent.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected only the failed request to be recorded, got %+v", entries)
+	}
+	got := entries[0]
+	if got.Status != http.StatusBadRequest || got.Code != "Bad Request" || got.Method != http.MethodGet {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+	// The route pattern is stored, not the raw URL with its query.
+	if got.Path != "/messages/:id" {
+		t.Errorf("expected route path /messages/:id, got %q", got.Path)
+	}
+}
+
+func TestRecentErrors_KeepsNewestFirstWithinCap(t *testing.T) {
+	recent := NewRecentErrors(2)
+	for _, status := range []int{400, 401, 404} {
+		recent.add(ErrorEntry{Status: status})
+	}
+
+	entries := recent.Entries()
+	if len(entries) != 2 || entries[0].Status != 404 || entries[1].Status != 401 {
+		t.Fatalf("expected [404 401], got %+v", entries)
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+// encryptedContentPrefix marks content stored encrypted, so rows written before encryption was
+// turned on can still be read as plaintext.
+const encryptedContentPrefix = "enc:v1:"
+
+// contentCipher encrypts message content with AES-GCM. The stored form is the prefix followed by
+// base64 of the nonce and the sealed content.
+type contentCipher struct {
+	aead cipher.AEAD
+}
+
+// newContentCipher builds a cipher from a base64-encoded 16, 24 or 32 byte AES key.
+func newContentCipher(encodedKey string) (*contentCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content encryption key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content encryption key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content cipher: %w", err)
+	}
+
+	return &contentCipher{aead: aead}, nil
+}
+
+func (c *contentCipher) seal(content string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(content), nil)
+	return encryptedContentPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts stored content; content without the prefix is returned as is.
+func (c *contentCipher) open(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedContentPrefix)
+	if !ok {
+		return stored, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted content: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("encrypted content is too short")
+	}
+
+	content, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt content: %w", err)
+	}
+
+	return string(content), nil
+}
+
+// SetContentKey turns on encryption of message content at rest with the given base64-encoded AES
+// key. An empty key leaves content stored as plaintext.
+func (r *MessageRepository) SetContentKey(encodedKey string) error {
+	if encodedKey == "" {
+		r.cipher = nil
+		return nil
+	}
+
+	c, err := newContentCipher(encodedKey)
+	if err != nil {
+		return err
+	}
+
+	r.cipher = c
+	return nil
+}
+
+// sealContent returns content as it should be stored.
+func (r *MessageRepository) sealContent(content string) (string, error) {
+	if r.cipher == nil {
+		return content, nil
+	}
+	return r.cipher.seal(content)
+}
+
+// openContent decrypts the content of a message read from the database in place.
+func (r *MessageRepository) openContent(msg *domain.Message) error {
+	content, err := r.openStored(msg.Content)
+	if err != nil {
+		return fmt.Errorf("message %d: %w", msg.ID, err)
+	}
+	msg.Content = content
+	return nil
+}
+
+// openStored decrypts a stored content value. Encrypted content read without a key is an error
+// rather than ciphertext handed on as if it were the message.
+func (r *MessageRepository) openStored(stored string) (string, error) {
+	if r.cipher == nil {
+		if strings.HasPrefix(stored, encryptedContentPrefix) {
+			return "", errors.New("content is encrypted but no content encryption key is set")
+		}
+		return stored, nil
+	}
+	return r.cipher.open(stored)
+}
+
+// openContents decrypts a slice of messages read from the database in place.
+func (r *MessageRepository) openContents(messages []domain.Message) error {
+	for i := range messages {
+		if err := r.openContent(&messages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,47 +4,157 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jmoiron/sqlx"
 
 	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/logger"
 )
 
+// messageColumns is the column list selected into domain.Message.
+const messageColumns = "id, content, phone_number, status, message_id, sent_at, tags, queue, timeout_seconds, send_at, campaign_id, client_message_id, truncated, original_length, last_error, first_failed_at, replay_count, retry_count, delivery_status, delivery_updated_at, created_at, updated_at"
+
+// maxLastErrorLength matches the width of the last_error column.
+const maxLastErrorLength = 1000
+
 // MessageRepository handles database operations for messages.
 type MessageRepository struct {
+	// ReadAttempts is how often GetUnsent, GetAll and GetSent are tried when they hit a
+	// transient error such as a dropped connection (values below 1 mean a single attempt).
+	ReadAttempts int
+
+	// ProcessOrder is domain.ProcessOrderLIFO to have GetUnsent return the newest pending
+	// messages first; anything else keeps the oldest first.
+	ProcessOrder string
+
+	// MaxReplays is how often a failed message may be replayed; messages at the limit stay
+	// failed (0 = unlimited).
+	MaxReplays int
+
+	// cipher encrypts content at rest when a key is set via SetContentKey (nil = plaintext).
+	cipher *contentCipher
+
+	// now is the clock used to place time buckets and pick due messages; nil means time.Now.
+	now func() time.Time
+
 	db *sqlx.DB
+	// replica serves the read-heavy list and stats queries when set via SetReplica (nil = primary).
+	replica *sqlx.DB
 }
 
 func NewMessageRepository(db *sqlx.DB) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
-func (r *MessageRepository) GetUnsent(ctx context.Context, limit int) ([]domain.Message, error) {
+// SetReplica routes GetAll, GetSent and GetStats to a read replica. Writes and every other read
+// stay on the primary, so reads that must see a write made just before are not affected by lag.
+func (r *MessageRepository) SetReplica(replica *sqlx.DB) {
+	r.replica = replica
+}
+
+// reader returns the connection for replica-safe reads.
+func (r *MessageRepository) reader() *sqlx.DB {
+	if r.replica != nil {
+		return r.replica
+	}
+	return r.db
+}
+
+// clock returns the clock set for tests, or time.Now.
+func (r *MessageRepository) clock() func() time.Time {
+	if r.now != nil {
+		return r.now
+	}
+	return time.Now
+}
+
+// GetUnsent returns the oldest pending messages of the given queue that are due, or the newest
+// with ProcessOrder lifo. Messages with a send_at in the future are left for a later run.
+func (r *MessageRepository) GetUnsent(ctx context.Context, queue string, limit int) ([]domain.Message, error) {
+	return r.GetUnsentAfter(ctx, queue, nil, limit)
+}
+
+// GetUnsentAfter is GetUnsent continuing after the message at cursor (nil = from the start), so a
+// run can page through the queue without fetching messages it already handled but left pending.
+func (r *MessageRepository) GetUnsentAfter(
+	ctx context.Context,
+	queue string,
+	after *domain.UnsentCursor,
+	limit int,
+) ([]domain.Message, error) {
+	direction, comparison := "ASC", ">"
+	if r.ProcessOrder == domain.ProcessOrderLIFO {
+		direction, comparison = "DESC", "<"
+	}
+
+	now := r.clock()()
+	args := []any{queue, now}
+
+	// Ties on created_at are broken by id, so the cursor never skips or repeats a message.
+	keyset := ""
+	if after != nil {
+		keyset = " AND (created_at " + comparison + " ? OR (created_at = ? AND id " + comparison + " ?))"
+		args = append(args, after.CreatedAt, after.CreatedAt, after.ID)
+	}
+	args = append(args, limit)
+
 	query := `
-		SELECT id, content, phone_number, status, message_id, sent_at, created_at, updated_at
+		SELECT ` + messageColumns + `
 		FROM messages
-		WHERE status = 'pending'
-		ORDER BY created_at ASC
+		WHERE status = 'pending' AND queue = ? AND (send_at IS NULL OR send_at <= ?)
+		  AND NOT EXISTS (SELECT 1 FROM suppressions WHERE suppressions.phone_number = messages.phone_number)` + keyset + `
+		ORDER BY created_at ` + direction + `, id ` + direction + `
 		LIMIT ?
 	`
 
 	var messages []domain.Message
-	if err := r.db.SelectContext(ctx, &messages, query, limit); err != nil {
+	err := r.retryRead(ctx, func() error {
+		messages = nil
+		return r.db.SelectContext(ctx, &messages, query, args...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsent messages: %w", err)
+	}
+	if err := r.openContents(messages); err != nil {
 		return nil, fmt.Errorf("failed to get unsent messages: %w", err)
 	}
 
 	return messages, nil
 }
 
-func (r *MessageRepository) MarkAsSent(ctx context.Context, id int64, messageID string, sentAt time.Time) error {
+// MarkAsSent records a successful send. originalLength is the content length before truncation,
+// or nil if the content was sent unchanged.
+//
+// message_id is unique. If the provider returns an id already stored on another row, the message
+// is still marked sent, under the id suffixed with "-dup-<id>", so the send is not lost and
+// GetByMessageID stays unambiguous.
+func (r *MessageRepository) MarkAsSent(
+	ctx context.Context,
+	id int64,
+	messageID string,
+	sentAt time.Time,
+	originalLength *int,
+) error {
 	query := `
 		UPDATE messages
-		SET status = 'sent', message_id = ?, sent_at = ?, updated_at = CURRENT_TIMESTAMP
+		SET status = 'sent', message_id = ?, sent_at = ?, truncated = ?, original_length = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	result, err := r.db.ExecContext(ctx, query, messageID, sentAt, id)
+	result, err := r.db.ExecContext(ctx, query, messageID, sentAt, originalLength != nil, originalLength, id)
+	if isDuplicateEntry(err) {
+		storedID := fmt.Sprintf("%s-dup-%d", messageID, id)
+		logger.Warnf("Provider returned message id %q for message %d, but it is already stored on another message; storing it as %q",
+			messageID, id, storedID)
+
+		result, err = r.db.ExecContext(ctx, query, storedID, sentAt, originalLength != nil, originalLength, id)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to mark message as sent: %w", err)
 	}
@@ -61,14 +171,25 @@ func (r *MessageRepository) MarkAsSent(ctx context.Context, id int64, messageID
 	return nil
 }
 
-func (r *MessageRepository) MarkAsFailed(ctx context.Context, id int64) error {
+// MarkAsFailed moves a message to failed, counting the failed send and recording why.
+// first_failed_at is only set on the first failure and survives later ones, until the message
+// is replayed.
+func (r *MessageRepository) MarkAsFailed(ctx context.Context, id int64, reason string) error {
 	query := `
 		UPDATE messages
-		SET status = 'failed', updated_at = CURRENT_TIMESTAMP
+		SET status = 'failed',
+		    last_error = ?,
+		    retry_count = retry_count + 1,
+		    first_failed_at = COALESCE(first_failed_at, CURRENT_TIMESTAMP),
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	if len(reason) > maxLastErrorLength {
+		reason = strings.ToValidUTF8(reason[:maxLastErrorLength], "")
+	}
+
+	_, err := r.db.ExecContext(ctx, query, reason, id)
 	if err != nil {
 		return fmt.Errorf("failed to mark message as failed: %w", err)
 	}
@@ -76,34 +197,249 @@ func (r *MessageRepository) MarkAsFailed(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *MessageRepository) GetSent(ctx context.Context, page, pageSize int) ([]domain.Message, int64, error) {
-	offset := (page - 1) * pageSize
+// MarkForRetry counts a failed send of a pending message and records why, leaving it pending so
+// the next run tries it again.
+func (r *MessageRepository) MarkForRetry(ctx context.Context, id int64, reason string) error {
+	query := `
+		UPDATE messages
+		SET retry_count = retry_count + 1,
+		    last_error = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'
+	`
 
-	var totalCount int64
-	countQuery := "SELECT COUNT(*) FROM messages WHERE status = 'sent'"
-	if err := r.db.GetContext(ctx, &totalCount, countQuery); err != nil {
-		return nil, 0, fmt.Errorf("failed to count sent messages: %w", err)
+	if len(reason) > maxLastErrorLength {
+		reason = strings.ToValidUTF8(reason[:maxLastErrorLength], "")
 	}
 
+	if _, err := r.db.ExecContext(ctx, query, reason, id); err != nil {
+		return fmt.Errorf("failed to mark message for retry: %w", err)
+	}
+
+	return nil
+}
+
+// MarkAsDeduped records that a pending message was skipped as a repeat of a recent send. A
+// message sent or cancelled in the meantime is left untouched and reported as an error.
+func (r *MessageRepository) MarkAsDeduped(ctx context.Context, id int64) error {
 	query := `
-		SELECT id, content, phone_number, status, message_id, sent_at, created_at, updated_at
-		FROM messages
-		WHERE status = 'sent'
-		ORDER BY sent_at DESC
-		LIMIT ? OFFSET ?
+		UPDATE messages
+		SET status = 'deduped',
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'
 	`
 
-	var messages []domain.Message
-	if err := r.db.SelectContext(ctx, &messages, query, pageSize, offset); err != nil {
-		return nil, 0, fmt.Errorf("failed to get sent messages: %w", err)
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark message as deduped: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("no pending message found with id %d", id)
+	}
+
+	return nil
+}
+
+// ForceFail moves a pending message to failed with a manual reason, e.g. for numbers the
+// provider will never accept. Messages in any other status are left untouched.
+func (r *MessageRepository) ForceFail(ctx context.Context, id int64, reason string) (*domain.Message, error) {
+	query := `
+		UPDATE messages
+		SET status = 'failed',
+		    last_error = ?,
+		    first_failed_at = COALESCE(first_failed_at, CURRENT_TIMESTAMP),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'
+	`
+
+	if len(reason) > maxLastErrorLength {
+		reason = strings.ToValidUTF8(reason[:maxLastErrorLength], "")
+	}
+
+	result, err := r.db.ExecContext(ctx, query, reason, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to force-fail message: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	msg, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, domain.ErrMessageNotFound
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("%w (status: %s)", domain.ErrMessageNotPending, msg.Status)
+	}
+
+	return msg, nil
+}
+
+// ResetRetries clears first_failed_at, replay_count and retry_count of a failed message so it
+// starts a fresh failure age and may be replayed MaxReplays times again. The status is left
+// untouched.
+func (r *MessageRepository) ResetRetries(ctx context.Context, id int64) (*domain.Message, error) {
+	query := `
+		UPDATE messages
+		SET first_failed_at = NULL,
+		    replay_count = 0,
+		    retry_count = 0,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'failed'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset retries: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	msg, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, domain.ErrMessageNotFound
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("%w (status: %s)", domain.ErrMessageNotFailed, msg.Status)
+	}
+
+	return msg, nil
+}
+
+// GetSent returns sent messages, newest first. filter.Status is ignored.
+func (r *MessageRepository) GetSent(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	page, pageSize int,
+) ([]domain.Message, int64, error) {
+	offset := (page - 1) * pageSize
+
+	sentStatus := domain.StatusSent
+	filter.Status = &sentStatus
+	where, args := buildMessageFilter(filter)
+
+	columns, err := selectedColumns(filter.Fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countQuery := "SELECT COUNT(*) FROM messages" + where
+	query := "SELECT " + columns + " FROM messages" + where + " ORDER BY sent_at DESC LIMIT ? OFFSET ?"
+
+	var (
+		totalCount int64
+		messages   []domain.Message
+	)
+	err = r.retryRead(ctx, func() error {
+		if err := r.reader().GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+			return fmt.Errorf("failed to count sent messages: %w", err)
+		}
+
+		messages = nil
+		if err := r.reader().SelectContext(ctx, &messages, query, append(args, pageSize, offset)...); err != nil {
+			return fmt.Errorf("failed to get sent messages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.openContents(messages); err != nil {
+		return nil, 0, fmt.Errorf("failed to read message content: %w", err)
 	}
 
 	return messages, totalCount, nil
 }
 
+// ForEachPending calls fn with the phone number and content of every pending message, scanning
+// rows one at a time. It stops at the first error fn returns.
+func (r *MessageRepository) ForEachPending(ctx context.Context, fn func(phoneNumber, content string) error) error {
+	query := "SELECT phone_number, content FROM messages WHERE status = 'pending'"
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read pending messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var phoneNumber, content string
+		if err := rows.Scan(&phoneNumber, &content); err != nil {
+			return fmt.Errorf("failed to scan pending message: %w", err)
+		}
+		content, err := r.openStored(content)
+		if err != nil {
+			return fmt.Errorf("failed to read pending message content: %w", err)
+		}
+		if err := fn(phoneNumber, content); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read pending messages: %w", err)
+	}
+
+	return nil
+}
+
+// StreamMessages calls fn for up to limit messages matching the filter, oldest first, scanning
+// rows one at a time instead of loading them all. It stops at the first error fn returns.
+func (r *MessageRepository) StreamMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	limit int,
+	fn func(domain.Message) error,
+) error {
+	where, args := buildMessageFilter(filter)
+	query := "SELECT " + messageColumns + " FROM messages" + where + " ORDER BY id ASC LIMIT ?"
+
+	rows, err := r.db.QueryxContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return fmt.Errorf("failed to stream messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg domain.Message
+		if err := rows.StructScan(&msg); err != nil {
+			return fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := r.openContent(&msg); err != nil {
+			return fmt.Errorf("failed to read message content: %w", err)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to stream messages: %w", err)
+	}
+
+	return nil
+}
+
 func (r *MessageRepository) GetByID(ctx context.Context, id int64) (*domain.Message, error) {
 	query := `
-		SELECT id, content, phone_number, status, message_id, sent_at, created_at, updated_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE id = ?
 	`
@@ -115,18 +451,92 @@ func (r *MessageRepository) GetByID(ctx context.Context, id int64) (*domain.Mess
 		}
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
+	if err := r.openContent(&message); err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	return &message, nil
+}
+
+func (r *MessageRepository) GetByMessageID(ctx context.Context, messageID string) (*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE message_id = ?
+		LIMIT 1
+	`
+
+	var message domain.Message
+	if err := r.db.GetContext(ctx, &message, query, messageID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get message by message id: %w", err)
+	}
+	if err := r.openContent(&message); err != nil {
+		return nil, fmt.Errorf("failed to get message by message id: %w", err)
+	}
 
 	return &message, nil
 }
 
-func (r *MessageRepository) Create(ctx context.Context, content, phoneNumber string) (*domain.Message, error) {
+// UpdateDeliveryStatus records the provider's delivery receipt for a message.
+func (r *MessageRepository) UpdateDeliveryStatus(ctx context.Context, id int64, status domain.DeliveryStatus) error {
 	query := `
-		INSERT INTO messages (content, phone_number, status, created_at, updated_at)
-		VALUES (?, ?, 'pending', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		UPDATE messages
+		SET delivery_status = ?,
+		    delivery_updated_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
 	`
 
-	result, err := r.db.ExecContext(ctx, query, content, phoneNumber)
+	if _, err := r.db.ExecContext(ctx, query, status, id); err != nil {
+		return fmt.Errorf("failed to update delivery status: %w", err)
+	}
+
+	return nil
+}
+
+// maxPhoneNumberLength is the width of messages.phone_number, in characters.
+const maxPhoneNumberLength = 20
+
+// Create inserts a pending message. Numbers wider than the phone_number column are rejected with
+// domain.ErrPhoneNumberTooLong instead of a driver error.
+func (r *MessageRepository) Create(ctx context.Context, msg domain.NewMessage) (*domain.Message, error) {
+	if n := utf8.RuneCountInString(msg.PhoneNumber); n > maxPhoneNumberLength {
+		return nil, fmt.Errorf("%w: %d characters (max %d)", domain.ErrPhoneNumberTooLong, n, maxPhoneNumberLength)
+	}
+
+	query := `
+		INSERT INTO messages (content, phone_number, status, tags, queue, timeout_seconds, send_at, client, client_message_id, created_at, updated_at)
+		VALUES (?, ?, 'pending', ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`
+
+	queue := msg.Queue
+	if queue == "" {
+		queue = domain.DefaultQueue
+	}
+	client := msg.Client
+	if client == "" {
+		client = domain.DefaultClient
+	}
+
+	content, err := r.sealContent(msg.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message content: %w", err)
+	}
+
+	var clientMessageID *string
+	if msg.ClientMessageID != "" {
+		clientMessageID = &msg.ClientMessageID
+	}
+
+	result, err := r.db.ExecContext(ctx, query,
+		content, msg.PhoneNumber, domain.Tags(msg.Tags), queue, msg.TimeoutSeconds, msg.SendAt, client, clientMessageID)
 	if err != nil {
+		if clientMessageID != nil && isDuplicateEntry(err) {
+			return r.existingClientMessage(ctx, client, msg.ClientMessageID)
+		}
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
 
@@ -138,51 +548,271 @@ func (r *MessageRepository) Create(ctx context.Context, content, phoneNumber str
 	return r.GetByID(ctx, id)
 }
 
+// existingClientMessage returns the message client stored under clientMessageID together with
+// domain.ErrDuplicateClientMessageID.
+func (r *MessageRepository) existingClientMessage(ctx context.Context, client, clientMessageID string) (*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE client = ? AND client_message_id = ?
+	`
+
+	var message domain.Message
+	if err := r.db.GetContext(ctx, &message, query, client, clientMessageID); err != nil {
+		return nil, fmt.Errorf("failed to get message by client message id: %w", err)
+	}
+	if err := r.openContent(&message); err != nil {
+		return nil, fmt.Errorf("failed to get message by client message id: %w", err)
+	}
+
+	return &message, fmt.Errorf("%w: %q", domain.ErrDuplicateClientMessageID, clientMessageID)
+}
+
+// campaignInsertChunk bounds the rows written by one INSERT of CreateCampaign.
+const campaignInsertChunk = 500
+
+// CreateCampaign inserts the messages of one campaign as pending under campaignID, in a single
+// transaction: either every message is stored or none is.
+func (r *MessageRepository) CreateCampaign(ctx context.Context, campaignID string, msgs []domain.NewMessage) (int64, error) {
+	for i, msg := range msgs {
+		if n := utf8.RuneCountInString(msg.PhoneNumber); n > maxPhoneNumberLength {
+			return 0, fmt.Errorf("%w: recipient %d: %d characters (max %d)", domain.ErrPhoneNumberTooLong, i, n, maxPhoneNumberLength)
+		}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var created int64
+	for chunk := range slices.Chunk(msgs, campaignInsertChunk) {
+		values := make([]string, 0, len(chunk))
+		args := make([]any, 0, len(chunk)*6)
+		for _, msg := range chunk {
+			queue := msg.Queue
+			if queue == "" {
+				queue = domain.DefaultQueue
+			}
+
+			content, err := r.sealContent(msg.Content)
+			if err != nil {
+				return 0, fmt.Errorf("failed to encrypt message content: %w", err)
+			}
+
+			values = append(values, "(?, ?, 'pending', ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+			args = append(args, content, msg.PhoneNumber, domain.Tags(msg.Tags), queue, msg.SendAt, campaignID)
+		}
+
+		query := `
+			INSERT INTO messages (content, phone_number, status, tags, queue, send_at, campaign_id, created_at, updated_at)
+			VALUES ` + strings.Join(values, ", ")
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create campaign messages: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		created += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit campaign: %w", err)
+	}
+
+	return created, nil
+}
+
 func (r *MessageRepository) GetAll(
 	ctx context.Context,
-	status *domain.MessageStatus,
+	filter domain.MessageFilter,
 	page, pageSize int,
 ) ([]domain.Message, int64, error) {
 	offset := (page - 1) * pageSize
-	var totalCount int64
-	var messages []domain.Message
+	where, args := buildMessageFilter(filter)
+
+	columns, err := selectedColumns(filter.Fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countQuery := "SELECT COUNT(*) FROM messages" + where
+	query := "SELECT " + columns + " FROM messages" + where + " ORDER BY created_at DESC LIMIT ? OFFSET ?"
 
-	if status != nil {
-		countQuery := "SELECT COUNT(*) FROM messages WHERE status = ?"
-		if err := r.db.GetContext(ctx, &totalCount, countQuery, *status); err != nil {
-			return nil, 0, fmt.Errorf("failed to count messages: %w", err)
+	var (
+		totalCount int64
+		messages   []domain.Message
+	)
+	err = r.retryRead(ctx, func() error {
+		if err := r.reader().GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+			return fmt.Errorf("failed to count messages: %w", err)
 		}
 
-		query := `
-			SELECT id, content, phone_number, status, message_id, sent_at, created_at, updated_at
-			FROM messages
-			WHERE status = ?
-			ORDER BY created_at DESC
-			LIMIT ? OFFSET ?
-		`
-		if err := r.db.SelectContext(ctx, &messages, query, *status, pageSize, offset); err != nil {
-			return nil, 0, fmt.Errorf("failed to get messages: %w", err)
+		messages = nil
+		if err := r.reader().SelectContext(ctx, &messages, query, append(args, pageSize, offset)...); err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
 		}
-	} else {
-		countQuery := "SELECT COUNT(*) FROM messages"
-		if err := r.db.GetContext(ctx, &totalCount, countQuery); err != nil {
-			return nil, 0, fmt.Errorf("failed to count messages: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.openContents(messages); err != nil {
+		return nil, 0, fmt.Errorf("failed to read message content: %w", err)
+	}
+
+	return messages, totalCount, nil
+}
+
+// selectedColumns returns the columns for the given message fields, or all of messageColumns
+// when none are given.
+func selectedColumns(fields []string) (string, error) {
+	if len(fields) == 0 {
+		return messageColumns, nil
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		column, ok := domain.MessageFieldColumn(field)
+		if !ok {
+			return "", fmt.Errorf("%w: %q", domain.ErrUnknownField, field)
 		}
+		columns = append(columns, column)
+	}
+	return strings.Join(columns, ", "), nil
+}
 
-		query := `
-			SELECT id, content, phone_number, status, message_id, sent_at, created_at, updated_at
-			FROM messages
-			ORDER BY created_at DESC
-			LIMIT ? OFFSET ?
-		`
-		if err := r.db.SelectContext(ctx, &messages, query, pageSize, offset); err != nil {
-			return nil, 0, fmt.Errorf("failed to get messages: %w", err)
+// buildMessageFilter turns a filter into a WHERE clause (empty when unfiltered) and its args.
+func buildMessageFilter(filter domain.MessageFilter) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if filter.Status != nil {
+		conditions = append(conditions, "status = ?")
+		args = append(args, *filter.Status)
+	}
+
+	if filter.Tag != "" {
+		conditions = append(conditions, "FIND_IN_SET(?, tags) > 0")
+		args = append(args, filter.Tag)
+	}
+
+	if filter.TruncatedOnly {
+		conditions = append(conditions, "truncated = TRUE")
+	}
+
+	// Rows sent before sent_at was recorded fall back to updated_at.
+	if filter.SentBefore != nil {
+		conditions = append(conditions, "COALESCE(sent_at, updated_at) < ?")
+		args = append(args, *filter.SentBefore)
+	}
+
+	if filter.CampaignID != "" {
+		conditions = append(conditions, "campaign_id = ?")
+		args = append(args, filter.CampaignID)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Search returns messages whose phone number starts with query or whose content contains it,
+// phone matches first and newest first within each group. Encrypted content cannot be searched:
+// with encryption on, a query that looks like a phone number prefix matches phone numbers only
+// and any other query fails with domain.ErrContentSearchUnavailable.
+func (r *MessageRepository) Search(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error) {
+	offset := (page - 1) * pageSize
+	phonePattern := likeEscaper.Replace(query) + "%"
+	contentPattern := "%" + likeEscaper.Replace(query) + "%"
+
+	where := " WHERE phone_number LIKE ? OR content LIKE ?"
+	matchArgs := []any{phonePattern, contentPattern}
+	if r.cipher != nil {
+		if !phonePrefixPattern.MatchString(query) {
+			return nil, 0, fmt.Errorf("%w: %q is not a phone number prefix", domain.ErrContentSearchUnavailable, query)
+		}
+		where = " WHERE phone_number LIKE ?"
+		matchArgs = []any{phonePattern}
+	}
+	countQuery := "SELECT COUNT(*) FROM messages" + where
+	selectQuery := "SELECT " + messageColumns + " FROM messages" + where +
+		" ORDER BY CASE WHEN phone_number LIKE ? THEN 0 ELSE 1 END, created_at DESC LIMIT ? OFFSET ?"
+
+	var (
+		totalCount int64
+		messages   []domain.Message
+	)
+	err := r.retryRead(ctx, func() error {
+		if err := r.db.GetContext(ctx, &totalCount, countQuery, matchArgs...); err != nil {
+			return fmt.Errorf("failed to count matching messages: %w", err)
 		}
+
+		messages = nil
+		selectArgs := append(slices.Clone(matchArgs), phonePattern, pageSize, offset)
+		if err := r.db.SelectContext(ctx, &messages, selectQuery, selectArgs...); err != nil {
+			return fmt.Errorf("failed to search messages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.openContents(messages); err != nil {
+		return nil, 0, fmt.Errorf("failed to read message content: %w", err)
 	}
 
 	return messages, totalCount, nil
 }
 
+var (
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	// phonePrefixPattern matches search queries that can only be meant as a phone number prefix.
+	phonePrefixPattern = regexp.MustCompile(`^\+?[0-9]+$`)
+)
+
+// CancelPending moves pending messages matching the filter to cancelled and returns how many
+// were changed. An empty filter matches every pending message; callers guard against that.
+func (r *MessageRepository) CancelPending(ctx context.Context, filter domain.CancelFilter) (int64, error) {
+	conditions := []string{"status = 'pending'"}
+	var args []any
+
+	if filter.Tag != "" {
+		conditions = append(conditions, "FIND_IN_SET(?, tags) > 0")
+		args = append(args, filter.Tag)
+	}
+	if filter.PhonePrefix != "" {
+		conditions = append(conditions, "phone_number LIKE ?")
+		args = append(args, likeEscaper.Replace(filter.PhonePrefix)+"%")
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.CreatedBefore)
+	}
+
+	query := "UPDATE messages SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP WHERE " +
+		strings.Join(conditions, " AND ")
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel pending messages: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows, nil
+}
+
 // GetStats returns statistics about messages.
 func (r *MessageRepository) GetStats(ctx context.Context) (pending, sent, failed int64, err error) {
 	query := `
@@ -199,24 +829,331 @@ func (r *MessageRepository) GetStats(ctx context.Context) (pending, sent, failed
 		Failed  int64 `db:"failed"`
 	}
 
-	if err := r.db.GetContext(ctx, &stats, query); err != nil {
+	if err := r.reader().GetContext(ctx, &stats, query); err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to get stats: %w", err)
 	}
 
 	return stats.Pending, stats.Sent, stats.Failed, nil
 }
 
+// GetGroupedCounts counts messages per queue and status in a single grouped query.
+// Combinations without messages are not returned.
+func (r *MessageRepository) GetGroupedCounts(ctx context.Context) ([]domain.StatusCount, error) {
+	query := `
+		SELECT queue, status, COUNT(*) AS count
+		FROM messages
+		GROUP BY queue, status
+		ORDER BY queue, status
+	`
+
+	var counts []domain.StatusCount
+	if err := r.db.SelectContext(ctx, &counts, query); err != nil {
+		return nil, fmt.Errorf("failed to get grouped counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetChangedSince returns messages after the cursor in (updated_at, id) order, oldest change
+// first. Rows sharing the cursor's second but with a higher id are included, so a full page
+// never skips the rest of its last second.
+func (r *MessageRepository) GetChangedSince(ctx context.Context, after domain.ChangeCursor, limit int) ([]domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE updated_at > ? OR (updated_at = ? AND id > ?)
+		ORDER BY updated_at ASC, id ASC
+		LIMIT ?
+	`
+
+	var messages []domain.Message
+	if err := r.db.SelectContext(ctx, &messages, query, after.UpdatedAt, after.UpdatedAt, after.ID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get changed messages: %w", err)
+	}
+	if err := r.openContents(messages); err != nil {
+		return nil, fmt.Errorf("failed to get changed messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// SendsPerHour counts sent messages per UTC hour of sent_at over the last window, oldest hour
+// first. The current, partial hour is the last bucket, and hours without sends are reported as zero.
+func (r *MessageRepository) SendsPerHour(ctx context.Context, window time.Duration) ([]domain.HourlySends, error) {
+	hours := max(int(window/time.Hour), 1)
+
+	start := r.clock()().UTC().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+
+	// Sends are bucketed by whole hours since start rather than by the hour of sent_at, which
+	// the database would read in its session time zone instead of UTC.
+	query := `
+		SELECT FLOOR(TIMESTAMPDIFF(SECOND, ?, sent_at) / 3600) AS bucket, COUNT(*) AS count
+		FROM messages
+		WHERE status = 'sent' AND sent_at >= ?
+		GROUP BY bucket
+	`
+
+	var rows []struct {
+		Bucket int   `db:"bucket"`
+		Count  int64 `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, start, start); err != nil {
+		return nil, fmt.Errorf("failed to get sends per hour: %w", err)
+	}
+
+	buckets := make([]domain.HourlySends, hours)
+	for i := range buckets {
+		buckets[i] = domain.HourlySends{Hour: start.Add(time.Duration(i) * time.Hour)}
+	}
+	for _, row := range rows {
+		// Sends stamped later than now, e.g. by a clock skewed ahead, count towards the last hour.
+		buckets[min(row.Bucket, hours-1)].Count += row.Count
+	}
+
+	return buckets, nil
+}
+
+// SLACompliance reports how many of the messages sent in the last window were sent less than
+// target after they were due: when they were created, or at send_at for scheduled messages.
+// With no sends in the window, compliance is 100%.
+func (r *MessageRepository) SLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error) {
+	query := `
+		SELECT COUNT(*) AS sent,
+			COALESCE(SUM(TIMESTAMPDIFF(MICROSECOND, GREATEST(created_at, COALESCE(send_at, created_at)), sent_at) >= ?), 0) AS breaching
+		FROM messages
+		WHERE status = 'sent' AND sent_at >= ?
+	`
+
+	var counts struct {
+		Sent      int64 `db:"sent"`
+		Breaching int64 `db:"breaching"`
+	}
+	since := r.clock()().Add(-window)
+	if err := r.db.GetContext(ctx, &counts, query, target.Microseconds(), since); err != nil {
+		return domain.SLACompliance{}, fmt.Errorf("failed to compute SLA compliance: %w", err)
+	}
+
+	compliance := 100.0
+	if counts.Sent > 0 {
+		compliance = float64(counts.Sent-counts.Breaching) / float64(counts.Sent) * 100
+	}
+
+	return domain.SLACompliance{
+		Window:            window.String(),
+		Target:            target.String(),
+		Sent:              counts.Sent,
+		Breaching:         counts.Breaching,
+		CompliancePercent: compliance,
+		ObjectivePercent:  domain.SLAObjectivePercent,
+		Met:               compliance >= domain.SLAObjectivePercent,
+	}, nil
+}
+
+// DeleteArchivedSent deletes sent messages sent before cutoff with an id up to maxID, i.e. the
+// rows an archive covered, and returns how many were deleted. Like the archive, it falls back to
+// updated_at for rows without a sent_at.
+func (r *MessageRepository) DeleteArchivedSent(ctx context.Context, cutoff time.Time, maxID int64) (int64, error) {
+	query := "DELETE FROM messages WHERE status = 'sent' AND COALESCE(sent_at, updated_at) < ? AND id <= ?"
+
+	result, err := r.db.ExecContext(ctx, query, cutoff, maxID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete archived messages: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// FailureReasons groups failed messages by their normalized last_error, most common first.
+func (r *MessageRepository) FailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error) {
+	query := `
+		SELECT last_error, COUNT(*) AS count
+		FROM messages
+		WHERE status = 'failed'
+		GROUP BY last_error
+	`
+
+	var rows []struct {
+		LastError sql.NullString `db:"last_error"`
+		Count     int64          `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to get failure reasons: %w", err)
+	}
+
+	// Raw errors differing only in ids or timestamps collapse into one reason.
+	counts := make(map[string]int64)
+	for _, row := range rows {
+		counts[normalizeFailureReason(row.LastError.String)] += row.Count
+	}
+
+	reasons := make([]domain.FailureReason, 0, len(counts))
+	for reason, count := range counts {
+		reasons = append(reasons, domain.FailureReason{Reason: reason, Count: count})
+	}
+
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Count != reasons[j].Count {
+			return reasons[i].Count > reasons[j].Count
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+
+	if limit > 0 && len(reasons) > limit {
+		reasons = reasons[:limit]
+	}
+
+	return reasons, nil
+}
+
+var (
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	uuidPattern      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	longNumPattern   = regexp.MustCompile(`\b\d{5,}\b`)
+)
+
+// normalizeFailureReason strips timestamps, UUIDs and long numeric ids so similar errors group together.
+// Short numbers such as HTTP status codes are kept.
+func normalizeFailureReason(reason string) string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "unknown"
+	}
+
+	reason = timestampPattern.ReplaceAllString(reason, "<time>")
+	reason = uuidPattern.ReplaceAllString(reason, "<id>")
+	reason = longNumPattern.ReplaceAllString(reason, "<n>")
+
+	return reason
+}
+
+// BackfillSentAt sets sent_at from updated_at for sent rows imported without it.
+// updated_at is assigned to itself so the ON UPDATE clause doesn't bump it.
+func (r *MessageRepository) BackfillSentAt(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE messages
+		SET sent_at = updated_at, updated_at = updated_at
+		WHERE status = 'sent' AND sent_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill sent_at: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// normalizePhonesBatchSize is how many pending rows NormalizePendingPhones reads per query.
+const normalizePhonesBatchSize = 500
+
+// NormalizePendingPhones passes the phone number of every pending message through normalize and
+// stores the result where it changed. Numbers normalize rejects are left as they are and
+// reported in RejectedIDs.
+func (r *MessageRepository) NormalizePendingPhones(
+	ctx context.Context,
+	normalize func(number string) (normalized string, ok bool),
+) (domain.PhoneNormalizationResult, error) {
+	selectQuery := `
+		SELECT id, phone_number
+		FROM messages
+		WHERE status = 'pending' AND id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`
+	updateQuery := "UPDATE messages SET phone_number = ? WHERE id = ? AND status = 'pending'"
+
+	result := domain.PhoneNormalizationResult{RejectedIDs: []int64{}}
+
+	var lastID int64
+	for {
+		var rows []struct {
+			ID          int64  `db:"id"`
+			PhoneNumber string `db:"phone_number"`
+		}
+		if err := r.db.SelectContext(ctx, &rows, selectQuery, lastID, normalizePhonesBatchSize); err != nil {
+			return result, fmt.Errorf("failed to get pending phone numbers: %w", err)
+		}
+
+		for _, row := range rows {
+			result.Scanned++
+
+			normalized, ok := normalize(row.PhoneNumber)
+			if !ok {
+				result.RejectedIDs = append(result.RejectedIDs, row.ID)
+				continue
+			}
+			if normalized == row.PhoneNumber {
+				continue
+			}
+
+			if _, err := r.db.ExecContext(ctx, updateQuery, normalized, row.ID); err != nil {
+				return result, fmt.Errorf("failed to update phone number of message %d: %w", row.ID, err)
+			}
+			result.Changed++
+		}
+
+		if len(rows) < normalizePhonesBatchSize {
+			return result, nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// GetOldestPendingCreatedAt returns the creation time of the oldest pending message,
+// or nil when there is nothing pending.
+func (r *MessageRepository) GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error) {
+	query := "SELECT MIN(created_at) FROM messages WHERE status = 'pending'"
+
+	var oldest sql.NullTime
+	if err := r.db.GetContext(ctx, &oldest, query); err != nil {
+		return nil, fmt.Errorf("failed to get oldest pending message: %w", err)
+	}
+
+	if !oldest.Valid {
+		return nil, nil
+	}
+
+	return &oldest.Time, nil
+}
+
+// HasScheduled reports whether the queue has pending messages with a send_at in the future.
+func (r *MessageRepository) HasScheduled(ctx context.Context, queue string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM messages WHERE status = 'pending' AND queue = ? AND send_at > ?)"
+
+	var scheduled bool
+	if err := r.db.GetContext(ctx, &scheduled, query, queue, r.clock()()); err != nil {
+		return false, fmt.Errorf("failed to check for scheduled messages: %w", err)
+	}
+	return scheduled, nil
+}
+
+// ReplayFailedByID re-pends a failed message with its retries reset. A message at MaxReplays is
+// left failed and domain.ErrReplayLimitReached is returned.
 func (r *MessageRepository) ReplayFailedByID(ctx context.Context, id int64) error {
 	query := `
 		UPDATE messages
 		SET status = 'pending',
 		    message_id = NULL,
 		    sent_at = NULL,
+		    first_failed_at = NULL,
+		    retry_count = 0,
+		    replay_count = replay_count + 1,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = ? AND status = 'failed'
-	`
+		WHERE id = ? AND status = 'failed'`
+	args := []any{id}
+	query, args = r.withinReplayLimit(query, args)
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to replay failed message: %w", err)
 	}
@@ -227,23 +1164,46 @@ func (r *MessageRepository) ReplayFailedByID(ctx context.Context, id int64) erro
 	}
 
 	if rows == 0 {
+		if r.MaxReplays > 0 {
+			var count int
+			err := r.db.GetContext(ctx, &count, "SELECT replay_count FROM messages WHERE id = ? AND status = 'failed'", id)
+			if err == nil && count >= r.MaxReplays {
+				return fmt.Errorf("%w: message %d was replayed %d times", domain.ErrReplayLimitReached, id, count)
+			}
+		}
 		return fmt.Errorf("no failed message found with id %d", id)
 	}
 
 	return nil
 }
 
-func (r *MessageRepository) ReplayAllFailed(ctx context.Context) (int64, error) {
+// ReplayAllFailed re-pends failed messages with their retries reset, optionally bounded by
+// failure age. Messages at MaxReplays are skipped.
+func (r *MessageRepository) ReplayAllFailed(ctx context.Context, age domain.ReplayAge) (int64, error) {
 	query := `
 		UPDATE messages
 		SET status = 'pending',
 		    message_id = NULL,
 		    sent_at = NULL,
+		    first_failed_at = NULL,
+		    retry_count = 0,
+		    replay_count = replay_count + 1,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE status = 'failed'
-	`
+		WHERE status = 'failed'`
 
-	result, err := r.db.ExecContext(ctx, query)
+	// Ages are measured against the database clock, like first_failed_at itself.
+	var args []any
+	if age.OlderThan > 0 {
+		query += " AND COALESCE(first_failed_at, updated_at) < CURRENT_TIMESTAMP - INTERVAL ? SECOND"
+		args = append(args, int64(age.OlderThan.Seconds()))
+	}
+	if age.NewerThan > 0 {
+		query += " AND COALESCE(first_failed_at, updated_at) >= CURRENT_TIMESTAMP - INTERVAL ? SECOND"
+		args = append(args, int64(age.NewerThan.Seconds()))
+	}
+	query, args = r.withinReplayLimit(query, args)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to replay failed messages: %w", err)
 	}
@@ -255,3 +1215,127 @@ func (r *MessageRepository) ReplayAllFailed(ctx context.Context) (int64, error)
 
 	return rows, nil
 }
+
+// ReplayFailedInWindow re-pends failed messages that first failed in [from, to), with their
+// retries reset.
+// Rows failed before first_failed_at existed fall back to updated_at. Messages at MaxReplays
+// are skipped.
+func (r *MessageRepository) ReplayFailedInWindow(ctx context.Context, from, to time.Time) (int64, error) {
+	query := `
+		UPDATE messages
+		SET status = 'pending',
+		    message_id = NULL,
+		    sent_at = NULL,
+		    first_failed_at = NULL,
+		    retry_count = 0,
+		    replay_count = replay_count + 1,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'failed'
+		  AND COALESCE(first_failed_at, updated_at) >= ?
+		  AND COALESCE(first_failed_at, updated_at) < ?`
+	query, args := r.withinReplayLimit(query, []any{from, to})
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay failed messages in window: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows, nil
+}
+
+// withinReplayLimit adds the MaxReplays condition to a replay query, if a limit is set.
+func (r *MessageRepository) withinReplayLimit(query string, args []any) (string, []any) {
+	if r.MaxReplays <= 0 {
+		return query, args
+	}
+	return query + " AND replay_count < ?", append(args, r.MaxReplays)
+}
+
+// BulkUpdateStatus moves the given messages to status in one transaction. Nothing is changed if
+// any id is unknown or any message can't make the transition (see domain.CanTransition). Failed
+// messages moved back to pending have their retry count and failure age reset.
+func (r *MessageRepository) BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	selectQuery, args, err := sqlx.In(`SELECT id, status, message_id FROM messages WHERE id IN (?) FOR UPDATE`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build status query: %w", err)
+	}
+
+	var current []struct {
+		ID        int64                `db:"id"`
+		Status    domain.MessageStatus `db:"status"`
+		MessageID *string              `db:"message_id"`
+	}
+	if err := tx.SelectContext(ctx, &current, tx.Rebind(selectQuery), args...); err != nil {
+		return 0, fmt.Errorf("failed to load message statuses: %w", err)
+	}
+
+	found := make(map[int64]bool, len(current))
+	var requeued []int64
+	for _, m := range current {
+		found[m.ID] = true
+		if !domain.CanTransition(m.Status, status, m.MessageID != nil && *m.MessageID != "") {
+			return 0, fmt.Errorf("%w: message %d from %s to %s", domain.ErrInvalidTransition, m.ID, m.Status, status)
+		}
+		if m.Status == domain.StatusFailed && status == domain.StatusPending {
+			requeued = append(requeued, m.ID)
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return 0, fmt.Errorf("%w: id %d", domain.ErrMessageNotFound, id)
+		}
+	}
+
+	updateQuery, args, err := sqlx.In(`
+		UPDATE messages
+		SET status = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (?)
+	`, status, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build status update: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(updateQuery), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update message statuses: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	// Failed messages moved back to pending get a full set of retries, as a replay gives them.
+	if len(requeued) > 0 {
+		resetQuery, args, err := sqlx.In(`
+			UPDATE messages
+			SET retry_count = 0,
+			    first_failed_at = NULL
+			WHERE id IN (?)
+		`, requeued)
+		if err != nil {
+			return 0, fmt.Errorf("failed to build retry reset: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(resetQuery), args...); err != nil {
+			return 0, fmt.Errorf("failed to reset retries: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit status update: %w", err)
+	}
+
+	return rows, nil
+}
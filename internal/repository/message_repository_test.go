@@ -0,0 +1,1291 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/phone"
+)
+
+// messageRows builds mock result rows for the given messages, in messageColumns order.
+func messageRows(msgs ...domain.Message) *sqlmock.Rows {
+	columns := strings.Split(messageColumns, ", ")
+	rows := sqlmock.NewRows(columns)
+
+	for _, m := range msgs {
+		tags, _ := m.Tags.Value()
+		values := map[string]driver.Value{
+			"id":                  m.ID,
+			"content":             m.Content,
+			"phone_number":        m.PhoneNumber,
+			"status":              string(m.Status),
+			"message_id":          ptrValue(m.MessageID),
+			"sent_at":             ptrValue(m.SentAt),
+			"tags":                tags,
+			"queue":               m.Queue,
+			"timeout_seconds":     nil,
+			"send_at":             ptrValue(m.SendAt),
+			"campaign_id":         ptrValue(m.CampaignID),
+			"client_message_id":   ptrValue(m.ClientMessageID),
+			"truncated":           m.Truncated,
+			"original_length":     nil,
+			"last_error":          ptrValue(m.LastError),
+			"first_failed_at":     ptrValue(m.FirstFailedAt),
+			"replay_count":        int64(m.ReplayCount),
+			"retry_count":         int64(m.RetryCount),
+			"delivery_status":     nil,
+			"delivery_updated_at": ptrValue(m.DeliveryUpdatedAt),
+			"created_at":          m.CreatedAt,
+			"updated_at":          m.UpdatedAt,
+		}
+
+		if m.DeliveryStatus != nil {
+			values["delivery_status"] = string(*m.DeliveryStatus)
+		}
+		if m.TimeoutSeconds != nil {
+			values["timeout_seconds"] = int64(*m.TimeoutSeconds)
+		}
+		if m.OriginalLength != nil {
+			values["original_length"] = int64(*m.OriginalLength)
+		}
+
+		row := make([]driver.Value, len(columns))
+		for i, column := range columns {
+			value, ok := values[column]
+			if !ok {
+				panic("messageRows: no value for column " + column)
+			}
+			row[i] = value
+		}
+		rows.AddRow(row...)
+	}
+
+	return rows
+}
+
+func ptrValue[T any](p *T) driver.Value {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// newMockRepository returns a repository backed by sqlmock; expectations are verified on cleanup.
+func newMockRepository(t *testing.T) (*MessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet sqlmock expectations: %v", err)
+		}
+		db.Close()
+	})
+
+	return NewMessageRepository(sqlx.NewDb(db, "mysql")), mock
+}
+
+func TestGetChangedSince_ReturnsRowsUpdatedAfterCursor(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	since := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	created := since.Add(-time.Hour)
+
+	sentAt := since.Add(time.Minute)
+	rows := messageRows(
+		domain.Message{ID: 3, Content: "first change", PhoneNumber: "+905551234567", Status: domain.StatusSent,
+			MessageID: strPtr("msg-3"), SentAt: &sentAt, CreatedAt: created, UpdatedAt: since.Add(time.Minute)},
+		domain.Message{ID: 1, Content: "second change", PhoneNumber: "+905551234568", Status: domain.StatusFailed,
+			LastError: strPtr("boom"), CreatedAt: created, UpdatedAt: since.Add(2 * time.Minute)},
+	)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE updated_at > ? OR (updated_at = ? AND id > ?)")).
+		WithArgs(since, since, int64(7), 50).
+		WillReturnRows(rows)
+
+	messages, err := repo.GetChangedSince(context.Background(), domain.ChangeCursor{UpdatedAt: since, ID: 7}, 50)
+	if err != nil {
+		t.Fatalf("GetChangedSince returned error: %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].ID != 3 || messages[1].ID != 1 {
+		t.Errorf("expected rows in updated_at order [3 1], got [%d %d]", messages[0].ID, messages[1].ID)
+	}
+	for _, m := range messages {
+		if !m.UpdatedAt.After(since) {
+			t.Errorf("message %d has updatedAt %v not after %v", m.ID, m.UpdatedAt, since)
+		}
+	}
+}
+
+func TestCreate_StoresTagsCommaSeparated(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (content, phone_number, status, tags")).
+		WithArgs("Summer sale", "+905551234567", "summer-sale,promo", domain.DefaultQueue, nil, nil, domain.DefaultClient, nil).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(10)).
+		WillReturnRows(messageRows(domain.Message{ID: 10, Content: "Summer sale", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, Tags: domain.Tags{"summer-sale", "promo"}, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.Create(context.Background(), domain.NewMessage{
+		Content:     "Summer sale",
+		PhoneNumber: "+905551234567",
+		Tags:        []string{"summer-sale", "promo"},
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if !slices.Equal(msg.Tags, domain.Tags{"summer-sale", "promo"}) {
+		t.Fatalf("expected tags to round-trip, got %v", msg.Tags)
+	}
+}
+
+func TestCreate_RejectsPhoneNumberWiderThanColumn(t *testing.T) {
+	repo, _ := newMockRepository(t)
+
+	// No INSERT is expected: the guard must reject the number before it reaches the driver.
+	_, err := repo.Create(context.Background(), domain.NewMessage{
+		Content:     "hello",
+		PhoneNumber: "+90555123456712345678",
+	})
+	if !errors.Is(err, domain.ErrPhoneNumberTooLong) {
+		t.Fatalf("expected ErrPhoneNumberTooLong, got %v", err)
+	}
+}
+
+func TestCreate_DuplicateClientMessageIDReturnsEarlierMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	clientID := "order-42"
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).
+		WithArgs("Your order shipped", "+905551234567", nil, domain.DefaultQueue, nil, nil, domain.DefaultClient, "order-42").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'default-order-42' for key 'uq_messages_client_client_message_id'"})
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE client = ? AND client_message_id = ?")).
+		WithArgs(domain.DefaultClient, "order-42").
+		WillReturnRows(messageRows(domain.Message{ID: 7, Content: "Your order shipped", PhoneNumber: "+905551234567",
+			Status: domain.StatusSent, ClientMessageID: &clientID, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.Create(context.Background(), domain.NewMessage{
+		Content:         "Your order shipped",
+		PhoneNumber:     "+905551234567",
+		ClientMessageID: "order-42",
+	})
+	if !errors.Is(err, domain.ErrDuplicateClientMessageID) {
+		t.Fatalf("expected ErrDuplicateClientMessageID, got %v", err)
+	}
+	if msg == nil || msg.ID != 7 || msg.ClientMessageID == nil || *msg.ClientMessageID != "order-42" {
+		t.Fatalf("expected the earlier message, got %+v", msg)
+	}
+}
+
+func TestCreate_ClientMessageIDIsUniquePerClient(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	clientID := "order-42"
+
+	// The default client already used the id; the trusted client's insert does not collide.
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).
+		WithArgs("Your order shipped", "+905551234567", nil, domain.DefaultQueue, nil, nil, "trusted", "order-42").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(8)).
+		WillReturnRows(messageRows(domain.Message{ID: 8, Content: "Your order shipped", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, ClientMessageID: &clientID, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.Create(context.Background(), domain.NewMessage{
+		Content:         "Your order shipped",
+		PhoneNumber:     "+905551234567",
+		ClientMessageID: "order-42",
+		Client:          "trusted",
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if msg.ID != 8 {
+		t.Fatalf("expected a new message for the second client, got %+v", msg)
+	}
+}
+
+func TestCreate_DuplicateEntryWithoutClientMessageIDIsAnError(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	msg, err := repo.Create(context.Background(), domain.NewMessage{Content: "hi", PhoneNumber: "+905551234567"})
+	if err == nil || errors.Is(err, domain.ErrDuplicateClientMessageID) || msg != nil {
+		t.Fatalf("expected a plain create error, got %+v, %v", msg, err)
+	}
+}
+
+func TestMarkAsSent_DuplicateProviderIDIsStoredSuffixed(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	sentAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE messages")).
+		WithArgs("abc-123", sentAt, false, nil, int64(9)).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'abc-123' for key 'uq_messages_message_id'"})
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE messages")).
+		WithArgs("abc-123-dup-9", sentAt, false, nil, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.MarkAsSent(context.Background(), 9, "abc-123", sentAt, nil); err != nil {
+		t.Fatalf("expected the message to be marked sent despite the duplicate id, got %v", err)
+	}
+}
+
+func TestGetAll_FiltersBySingleTag(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages WHERE FIND_IN_SET(?, tags) > 0")).
+		WithArgs("promo").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE FIND_IN_SET(?, tags) > 0 ORDER BY created_at DESC LIMIT ? OFFSET ?")).
+		WithArgs("promo", 20, 0).
+		WillReturnRows(messageRows(domain.Message{ID: 4, Content: "Promo", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, Tags: domain.Tags{"welcome", "promo"}, CreatedAt: now, UpdatedAt: now}))
+
+	messages, total, err := repo.GetAll(context.Background(), domain.MessageFilter{Tag: "promo"}, 1, 20)
+	if err != nil {
+		t.Fatalf("GetAll returned error: %v", err)
+	}
+
+	if total != 1 || len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d (total %d)", len(messages), total)
+	}
+	if !slices.Contains(messages[0].Tags, "promo") {
+		t.Errorf("expected returned message to carry the tag, got %v", messages[0].Tags)
+	}
+}
+
+func TestGetAll_SelectsOnlyRequestedFields(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("^SELECT id, status, phone_number FROM messages ORDER BY created_at DESC LIMIT \\? OFFSET \\?$").
+		WithArgs(20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "phone_number"}).AddRow(4, "sent", "+905551234567"))
+
+	filter := domain.MessageFilter{Fields: []string{"id", "status", "phoneNumber"}}
+	messages, _, err := repo.GetAll(context.Background(), filter, 1, 20)
+	if err != nil {
+		t.Fatalf("GetAll returned error: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].PhoneNumber != "+905551234567" || messages[0].Content != "" {
+		t.Fatalf("expected only the requested fields to be read, got %+v", messages)
+	}
+}
+
+func TestGetAll_TruncatedOnly(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+	originalLength := 1200
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages WHERE truncated = TRUE")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE truncated = TRUE ORDER BY created_at DESC LIMIT ? OFFSET ?")).
+		WithArgs(20, 0).
+		WillReturnRows(messageRows(domain.Message{ID: 9, Content: "Long", PhoneNumber: "+905551234567",
+			Status: domain.StatusSent, Truncated: true, OriginalLength: &originalLength, CreatedAt: now, UpdatedAt: now}))
+
+	messages, _, err := repo.GetAll(context.Background(), domain.MessageFilter{TruncatedOnly: true}, 1, 20)
+	if err != nil {
+		t.Fatalf("GetAll returned error: %v", err)
+	}
+
+	if len(messages) != 1 || !messages[0].Truncated || ptrValue(messages[0].OriginalLength) != 1200 {
+		t.Fatalf("expected the truncated message with its original length, got %+v", messages)
+	}
+}
+
+func TestCreateCampaign_InsertsAllMessagesInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (content, phone_number, status, tags, queue, send_at, campaign_id")+
+		`.*`+regexp.QuoteMeta("VALUES (?, ?, 'pending', ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP), (?, ?, 'pending'")).
+		WithArgs(
+			"Hi Ada", "+905551234567", nil, domain.DefaultQueue, nil, "c1",
+			"Hi Alan", "+905559876543", nil, "promotional", nil, "c1",
+		).
+		WillReturnResult(sqlmock.NewResult(11, 2))
+	mock.ExpectCommit()
+
+	created, err := repo.CreateCampaign(context.Background(), "c1", []domain.NewMessage{
+		{Content: "Hi Ada", PhoneNumber: "+905551234567"},
+		{Content: "Hi Alan", PhoneNumber: "+905559876543", Queue: "promotional"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign returned error: %v", err)
+	}
+	if created != 2 {
+		t.Fatalf("expected 2 created messages, got %d", created)
+	}
+}
+
+func TestCreateCampaign_RollsBackWhenInsertFails(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err := repo.CreateCampaign(context.Background(), "c1", []domain.NewMessage{
+		{Content: "Hi Ada", PhoneNumber: "+905551234567"},
+	})
+	if err == nil {
+		t.Fatalf("expected the insert error to be returned")
+	}
+}
+
+func TestGetAll_FiltersByCampaign(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+	campaignID := "c1"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages WHERE campaign_id = ?")).
+		WithArgs(campaignID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE campaign_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?")).
+		WithArgs(campaignID, 20, 0).
+		WillReturnRows(messageRows(domain.Message{ID: 11, Content: "Hi Ada", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, CampaignID: &campaignID, CreatedAt: now, UpdatedAt: now}))
+
+	messages, total, err := repo.GetAll(context.Background(), domain.MessageFilter{CampaignID: campaignID}, 1, 20)
+	if err != nil {
+		t.Fatalf("GetAll returned error: %v", err)
+	}
+	if total != 1 || len(messages) != 1 || ptrValue(messages[0].CampaignID) != campaignID {
+		t.Fatalf("expected the campaign's message, got %+v (total %d)", messages, total)
+	}
+}
+
+func TestSearch_RanksPhoneMatchesFirstAndPaginates(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages WHERE phone_number LIKE ? OR content LIKE ?")).
+		WithArgs(`+90555\_1%`, `%+90555\_1%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+	// Phone prefix matches rank before content matches, newest first within each group.
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE phone_number LIKE ? OR content LIKE ? "+
+		"ORDER BY CASE WHEN phone_number LIKE ? THEN 0 ELSE 1 END, created_at DESC LIMIT ? OFFSET ?")).
+		WithArgs(`+90555\_1%`, `%+90555\_1%`, `+90555\_1%`, 10, 20).
+		WillReturnRows(messageRows(
+			domain.Message{ID: 4, Content: "hi", PhoneNumber: "+90555_1234", Status: domain.StatusSent, CreatedAt: now, UpdatedAt: now},
+			domain.Message{ID: 3, Content: "call +90555_1", PhoneNumber: "+905559876543",
+				Status: domain.StatusSent, CreatedAt: now.Add(time.Minute), UpdatedAt: now},
+		))
+
+	messages, total, err := repo.Search(context.Background(), "+90555_1", 3, 10)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if total != 25 || len(messages) != 2 || messages[0].ID != 4 || messages[1].ID != 3 {
+		t.Fatalf("expected the third page of 25 matches in ranked order, got %+v (total %d)", messages, total)
+	}
+}
+
+func TestSearch_EncryptedContentSearchesPhonePrefixesOnly(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	if err := repo.SetContentKey(base64.StdEncoding.EncodeToString(make([]byte, 32))); err != nil {
+		t.Fatalf("SetContentKey returned error: %v", err)
+	}
+
+	if _, _, err := repo.Search(context.Background(), "hello", 1, 10); !errors.Is(err, domain.ErrContentSearchUnavailable) {
+		t.Fatalf("expected ErrContentSearchUnavailable for a content query, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages WHERE phone_number LIKE ?")).
+		WithArgs(`+90555%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM messages WHERE phone_number LIKE ? ORDER BY")).
+		WithArgs(`+90555%`, `+90555%`, 10, 0).
+		WillReturnRows(messageRows())
+
+	if _, _, err := repo.Search(context.Background(), "+90555", 1, 10); err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSendsPerHour_BucketsByHourAndFillsEmptyHours(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Date(2025, 3, 1, 12, 40, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"bucket", "count"}).
+		AddRow(0, 4).
+		AddRow(2, 2).
+		AddRow(3, 1)
+
+	// Buckets are hours since the UTC start, so the database time zone does not shift them.
+	mock.ExpectQuery(regexp.QuoteMeta("FLOOR(TIMESTAMPDIFF(SECOND, ?, sent_at) / 3600) AS bucket")+`(.|\s)+`+
+		regexp.QuoteMeta("WHERE status = 'sent' AND sent_at >= ?")).
+		WithArgs(start, start).
+		WillReturnRows(rows)
+
+	buckets, err := repo.SendsPerHour(context.Background(), 4*time.Hour)
+	if err != nil {
+		t.Fatalf("SendsPerHour returned error: %v", err)
+	}
+
+	expected := []domain.HourlySends{
+		{Hour: start, Count: 4},
+		{Hour: start.Add(time.Hour), Count: 0},
+		{Hour: start.Add(2 * time.Hour), Count: 2},
+		{Hour: start.Add(3 * time.Hour), Count: 1},
+	}
+	if len(buckets) != len(expected) {
+		t.Fatalf("expected %d buckets, got %d: %+v", len(expected), len(buckets), buckets)
+	}
+	for i, want := range expected {
+		if !buckets[i].Hour.Equal(want.Hour) || buckets[i].Count != want.Count {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want, buckets[i])
+		}
+	}
+}
+
+func TestDeleteArchivedSent_FallsBackToUpdatedAtAndStopsAtMaxID(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	cutoff := time.Date(2025, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM messages WHERE status = 'sent' AND COALESCE(sent_at, updated_at) < ? AND id <= ?")).
+		WithArgs(cutoff, int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := repo.DeleteArchivedSent(context.Background(), cutoff, 9)
+	if err != nil {
+		t.Fatalf("DeleteArchivedSent returned error: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 deleted messages, got %d", deleted)
+	}
+}
+
+func TestSLACompliance_ComputesPercentageWithinTarget(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	// 40 sends in the last day, 3 of them 5 minutes or more after creation.
+	mock.ExpectQuery(regexp.QuoteMeta("TIMESTAMPDIFF(MICROSECOND, GREATEST(created_at, COALESCE(send_at, created_at)), sent_at) >= ?")).
+		WithArgs((5 * time.Minute).Microseconds(), now.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"sent", "breaching"}).AddRow(40, 3))
+
+	sla, err := repo.SLACompliance(context.Background(), 24*time.Hour, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SLACompliance returned error: %v", err)
+	}
+
+	if sla.Sent != 40 || sla.Breaching != 3 || sla.CompliancePercent != 92.5 || sla.Met {
+		t.Fatalf("expected 92.5%% compliance with 3 of 40 breaching (objective missed), got %+v", sla)
+	}
+}
+
+func TestSLACompliance_ScheduledMessagesAreMeasuredFromSendAt(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	// A message created at 09:00 with send_at 11:00 and sent at 11:01 is a minute late, not two
+	// hours: the breach is measured from the later of created_at and send_at.
+	mock.ExpectQuery(regexp.QuoteMeta("GREATEST(created_at, COALESCE(send_at, created_at))")).
+		WithArgs((5 * time.Minute).Microseconds(), now.Add(-24*time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"sent", "breaching"}).AddRow(1, 0))
+
+	sla, err := repo.SLACompliance(context.Background(), 24*time.Hour, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SLACompliance returned error: %v", err)
+	}
+	if sla.Breaching != 0 || !sla.Met {
+		t.Fatalf("expected the scheduled send to be within target, got %+v", sla)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSLACompliance_NoSendsIsCompliant(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM messages")).
+		WillReturnRows(sqlmock.NewRows([]string{"sent", "breaching"}).AddRow(0, 0))
+
+	sla, err := repo.SLACompliance(context.Background(), time.Hour, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SLACompliance returned error: %v", err)
+	}
+	if sla.CompliancePercent != 100 || !sla.Met {
+		t.Fatalf("expected 100%% compliance without sends, got %+v", sla)
+	}
+}
+
+func TestFailureReasons_GroupsNormalizedErrorsByCount(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	rows := sqlmock.NewRows([]string{"last_error", "count"}).
+		AddRow("unexpected status code: 503 (expected 202), body: {\"requestId\":\"3f2b8c1e-1d2a-4c5b-9e8f-0a1b2c3d4e5f\"}", 2).
+		AddRow("unexpected status code: 503 (expected 202), body: {\"requestId\":\"9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d\"}", 3).
+		AddRow("unexpected status code: 400 (expected 202), body: invalid number", 1).
+		AddRow("failed to send request: timeout at 2025-01-01T10:00:00Z", 1).
+		AddRow("failed to send request: timeout at 2025-01-02T11:30:00Z", 1).
+		AddRow(nil, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY last_error")).WillReturnRows(rows)
+
+	reasons, err := repo.FailureReasons(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("FailureReasons returned error: %v", err)
+	}
+
+	if len(reasons) != 3 {
+		t.Fatalf("expected 3 reasons (limit), got %d: %+v", len(reasons), reasons)
+	}
+
+	expected := []domain.FailureReason{
+		{Reason: "unexpected status code: 503 (expected 202), body: {\"requestId\":\"<id>\"}", Count: 5},
+		{Reason: "failed to send request: timeout at <time>", Count: 2},
+		{Reason: "unexpected status code: 400 (expected 202), body: invalid number", Count: 1},
+	}
+	for i, want := range expected {
+		if reasons[i] != want {
+			t.Errorf("reason %d: expected %+v, got %+v", i, want, reasons[i])
+		}
+	}
+}
+
+func TestBackfillSentAt_OnlyTouchesSentRowsWithoutSentAt(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET sent_at = updated_at, updated_at = updated_at") +
+		`\s+` + regexp.QuoteMeta("WHERE status = 'sent' AND sent_at IS NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	count, err := repo.BackfillSentAt(context.Background())
+	if err != nil {
+		t.Fatalf("BackfillSentAt returned error: %v", err)
+	}
+
+	if count != 4 {
+		t.Fatalf("expected 4 backfilled rows, got %d", count)
+	}
+}
+
+func TestCancelPending_AppliesAllFilters(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE messages SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP "+
+		"WHERE status = 'pending' AND FIND_IN_SET(?, tags) > 0 AND phone_number LIKE ? AND created_at < ?")).
+		WithArgs("summer-sale", `+90\_5%`, before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := repo.CancelPending(context.Background(), domain.CancelFilter{
+		Tag:           "summer-sale",
+		PhonePrefix:   "+90_5",
+		CreatedBefore: &before,
+	})
+	if err != nil {
+		t.Fatalf("CancelPending returned error: %v", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("expected 3 cancelled rows, got %d", count)
+	}
+}
+
+func TestCancelPending_OnlyTouchesPendingRows(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE status = 'pending' AND FIND_IN_SET(?, tags) > 0") + "$").
+		WithArgs("promo").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := repo.CancelPending(context.Background(), domain.CancelFilter{Tag: "promo"}); err != nil {
+		t.Fatalf("CancelPending returned error: %v", err)
+	}
+}
+
+func TestFirstFailedAt_SetOnceAndClearedOnReplay(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := context.Background()
+
+	// Both failures keep an existing first_failed_at; only a NULL one gets the current time.
+	markFailed := regexp.QuoteMeta("first_failed_at = COALESCE(first_failed_at, CURRENT_TIMESTAMP)")
+	mock.ExpectExec(markFailed).WithArgs("timeout", int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(markFailed).WithArgs("still failing", int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(regexp.QuoteMeta("first_failed_at = NULL") + `(.|\s)+` + regexp.QuoteMeta("WHERE id = ? AND status = 'failed'")).
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.MarkAsFailed(ctx, 5, "timeout"); err != nil {
+		t.Fatalf("MarkAsFailed returned error: %v", err)
+	}
+	if err := repo.MarkAsFailed(ctx, 5, "still failing"); err != nil {
+		t.Fatalf("MarkAsFailed returned error: %v", err)
+	}
+	if err := repo.ReplayFailedByID(ctx, 5); err != nil {
+		t.Fatalf("ReplayFailedByID returned error: %v", err)
+	}
+}
+
+func TestMarkForRetry_CountsFailureAndKeepsMessagePending(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET retry_count = retry_count + 1")+`(.|\s)+`+regexp.QuoteMeta("WHERE id = ? AND status = 'pending'")).
+		WithArgs("timeout", int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.MarkForRetry(context.Background(), 5, "timeout"); err != nil {
+		t.Fatalf("MarkForRetry returned error: %v", err)
+	}
+}
+
+func TestMarkAsDeduped_LeavesMessagesNoLongerPendingAlone(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	// Sent or cancelled since it was fetched, so the guarded update matches nothing.
+	mock.ExpectExec(regexp.QuoteMeta("SET status = 'deduped'") + `(.|\s)+` + regexp.QuoteMeta("WHERE id = ? AND status = 'pending'")).
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.MarkAsDeduped(context.Background(), 5); err == nil {
+		t.Fatal("expected an error for a message that is no longer pending")
+	}
+}
+
+func TestGetByID_ScansFirstFailedAt(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	firstFailed := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := firstFailed.Add(time.Hour)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(5)).
+		WillReturnRows(messageRows(domain.Message{ID: 5, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusFailed, FirstFailedAt: &firstFailed, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.GetByID(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if msg.FirstFailedAt == nil || !msg.FirstFailedAt.Equal(firstFailed) {
+		t.Fatalf("expected firstFailedAt %v, got %v", firstFailed, msg.FirstFailedAt)
+	}
+}
+
+func TestGetUnsent_FiltersByQueueAndDueTime(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE status = 'pending' AND queue = ? AND (send_at IS NULL OR send_at <= ?)")).
+		WithArgs("promotional", now, 10).
+		WillReturnRows(messageRows(domain.Message{ID: 8, Content: "Sale", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, Queue: "promotional", CreatedAt: now, UpdatedAt: now}))
+
+	messages, err := repo.GetUnsent(context.Background(), "promotional", 10)
+	if err != nil {
+		t.Fatalf("GetUnsent returned error: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Queue != "promotional" {
+		t.Fatalf("expected one promotional message, got %+v", messages)
+	}
+}
+
+func TestGetUnsent_OrderFollowsProcessOrder(t *testing.T) {
+	for order, want := range map[string]string{
+		"":                      "ORDER BY created_at ASC",
+		domain.ProcessOrderFIFO: "ORDER BY created_at ASC",
+		domain.ProcessOrderLIFO: "ORDER BY created_at DESC",
+	} {
+		repo, mock := newMockRepository(t)
+		repo.ProcessOrder = order
+
+		mock.ExpectQuery(regexp.QuoteMeta(want)).
+			WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 10).
+			WillReturnRows(messageRows())
+
+		if _, err := repo.GetUnsent(context.Background(), domain.DefaultQueue, 10); err != nil {
+			t.Fatalf("order %q: GetUnsent returned error: %v", order, err)
+		}
+	}
+}
+
+func TestGetUnsentAfter_ContinuesAfterCursor(t *testing.T) {
+	for order, want := range map[string]string{
+		domain.ProcessOrderFIFO: "AND (created_at > ? OR (created_at = ? AND id > ?))",
+		domain.ProcessOrderLIFO: "AND (created_at < ? OR (created_at = ? AND id < ?))",
+	} {
+		repo, mock := newMockRepository(t)
+		repo.ProcessOrder = order
+		cursor := domain.UnsentCursor{CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), ID: 41}
+
+		mock.ExpectQuery(regexp.QuoteMeta(want)).
+			WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), cursor.CreatedAt, cursor.CreatedAt, int64(41), 10).
+			WillReturnRows(messageRows())
+
+		if _, err := repo.GetUnsentAfter(context.Background(), domain.DefaultQueue, &cursor, 10); err != nil {
+			t.Fatalf("order %q: GetUnsentAfter returned error: %v", order, err)
+		}
+	}
+}
+
+func TestHasScheduled_ChecksFuturePendingMessages(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE status = 'pending' AND queue = ? AND send_at > ?")).
+		WithArgs(domain.DefaultQueue, now).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	scheduled, err := repo.HasScheduled(context.Background(), domain.DefaultQueue)
+	if err != nil {
+		t.Fatalf("HasScheduled returned error: %v", err)
+	}
+	if !scheduled {
+		t.Fatal("expected a scheduled message to be reported")
+	}
+}
+
+func TestReplay_ResetsRetryCount(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := context.Background()
+
+	// Every replay gives the message a fresh retry budget.
+	resetsRetries := regexp.QuoteMeta("retry_count = 0,")
+	mock.ExpectExec(resetsRetries).WithArgs(int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(resetsRetries).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(resetsRetries).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	if err := repo.ReplayFailedByID(ctx, 5); err != nil {
+		t.Fatalf("ReplayFailedByID returned error: %v", err)
+	}
+	if _, err := repo.ReplayAllFailed(ctx, domain.ReplayAge{}); err != nil {
+		t.Fatalf("ReplayAllFailed returned error: %v", err)
+	}
+	from := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	if _, err := repo.ReplayFailedInWindow(ctx, from, from.Add(time.Hour)); err != nil {
+		t.Fatalf("ReplayFailedInWindow returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestReplayFailedInWindow_FiltersByFailureTime(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	from := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE status = 'failed'")+`\s+`+
+		regexp.QuoteMeta("AND COALESCE(first_failed_at, updated_at) >= ?")+`\s+`+
+		regexp.QuoteMeta("AND COALESCE(first_failed_at, updated_at) < ?")).
+		WithArgs(from, to).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	count, err := repo.ReplayFailedInWindow(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("ReplayFailedInWindow returned error: %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 replayed messages, got %d", count)
+	}
+}
+
+func TestReplayAllFailed_BoundsByFailureAge(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE status = 'failed'")+
+		regexp.QuoteMeta(" AND COALESCE(first_failed_at, updated_at) < CURRENT_TIMESTAMP - INTERVAL ? SECOND")+
+		regexp.QuoteMeta(" AND COALESCE(first_failed_at, updated_at) >= CURRENT_TIMESTAMP - INTERVAL ? SECOND")).
+		WithArgs(int64(3600), int64(86400)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	count, err := repo.ReplayAllFailed(context.Background(), domain.ReplayAge{OlderThan: time.Hour, NewerThan: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("ReplayAllFailed returned error: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 replayed messages, got %d", count)
+	}
+}
+
+func TestReplayAllFailed_SkipsMessagesAtReplayLimit(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	repo.MaxReplays = 3
+
+	mock.ExpectExec(regexp.QuoteMeta("replay_count = replay_count + 1") + `(.|\s)+` +
+		regexp.QuoteMeta("WHERE status = 'failed' AND replay_count < ?")).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	count, err := repo.ReplayAllFailed(context.Background(), domain.ReplayAge{})
+	if err != nil {
+		t.Fatalf("ReplayAllFailed returned error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 replayed message, got %d", count)
+	}
+}
+
+func TestReplayFailedByID_RefusesMessageAtReplayLimit(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	repo.MaxReplays = 3
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND status = 'failed' AND replay_count < ?")).
+		WithArgs(int64(9), 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT replay_count FROM messages WHERE id = ? AND status = 'failed'")).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"replay_count"}).AddRow(3))
+
+	err := repo.ReplayFailedByID(context.Background(), 9)
+	if !errors.Is(err, domain.ErrReplayLimitReached) {
+		t.Fatalf("expected ErrReplayLimitReached, got %v", err)
+	}
+}
+
+func TestReplayFailedByID_UnlimitedWithoutMaxReplays(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("replay_count = replay_count + 1") + `(.|\s)+` +
+		regexp.QuoteMeta("WHERE id = ? AND status = 'failed'") + `$`).
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.ReplayFailedByID(context.Background(), 9); err != nil {
+		t.Fatalf("ReplayFailedByID returned error: %v", err)
+	}
+}
+
+func TestForceFail_FailsPendingMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND status = 'pending'")).
+		WithArgs("bad number", int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(3)).
+		WillReturnRows(messageRows(domain.Message{ID: 3, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusFailed, LastError: strPtr("bad number"), FirstFailedAt: &now,
+			CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.ForceFail(context.Background(), 3, "bad number")
+	if err != nil {
+		t.Fatalf("ForceFail returned error: %v", err)
+	}
+	if msg.Status != domain.StatusFailed || msg.LastError == nil || *msg.LastError != "bad number" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestForceFail_RefusesNonPendingMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND status = 'pending'")).
+		WithArgs("bad number", int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(3)).
+		WillReturnRows(messageRows(domain.Message{ID: 3, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusSent, CreatedAt: now, UpdatedAt: now}))
+
+	_, err := repo.ForceFail(context.Background(), 3, "bad number")
+	if !errors.Is(err, domain.ErrMessageNotPending) {
+		t.Fatalf("expected ErrMessageNotPending, got %v", err)
+	}
+}
+
+func TestForceFail_UnknownMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND status = 'pending'")).
+		WithArgs("bad number", int64(404)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(404)).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.ForceFail(context.Background(), 404, "bad number")
+	if !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestReplica_ServesListAndStatsReadsWhileWritesHitPrimary(t *testing.T) {
+	repo, primary := newMockRepository(t)
+	replicaRepo, replica := newMockRepository(t)
+	repo.SetReplica(replicaRepo.db)
+
+	replica.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	replica.ExpectQuery(regexp.QuoteMeta("ORDER BY created_at DESC LIMIT ? OFFSET ?")).
+		WillReturnRows(messageRows())
+	replica.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	replica.ExpectQuery(regexp.QuoteMeta("ORDER BY sent_at DESC LIMIT ? OFFSET ?")).
+		WillReturnRows(messageRows())
+	replica.ExpectQuery(regexp.QuoteMeta("AS pending")).
+		WillReturnRows(sqlmock.NewRows([]string{"pending", "sent", "failed"}).AddRow(1, 2, 3))
+	primary.ExpectExec(regexp.QuoteMeta("SET status = 'failed'")).
+		WithArgs("boom", int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx := context.Background()
+	if _, _, err := repo.GetAll(ctx, domain.MessageFilter{}, 1, 10); err != nil {
+		t.Fatalf("GetAll returned error: %v", err)
+	}
+	if _, _, err := repo.GetSent(ctx, domain.MessageFilter{}, 1, 10); err != nil {
+		t.Fatalf("GetSent returned error: %v", err)
+	}
+	if _, sent, _, err := repo.GetStats(ctx); err != nil || sent != 2 {
+		t.Fatalf("GetStats = sent %d, err %v", sent, err)
+	}
+	if err := repo.MarkAsFailed(ctx, 7, "boom"); err != nil {
+		t.Fatalf("MarkAsFailed returned error: %v", err)
+	}
+}
+
+func TestReplica_ReadsFallBackToPrimaryWithoutReplica(t *testing.T) {
+	repo, primary := newMockRepository(t)
+
+	primary.ExpectQuery(regexp.QuoteMeta("AS pending")).
+		WillReturnRows(sqlmock.NewRows([]string{"pending", "sent", "failed"}).AddRow(1, 2, 3))
+
+	if _, _, _, err := repo.GetStats(context.Background()); err != nil {
+		t.Fatalf("GetStats returned error: %v", err)
+	}
+}
+
+func TestResetRetries_ClearsFirstFailedAt(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("SET first_failed_at = NULL") + `(.|\s)+` +
+		regexp.QuoteMeta("WHERE id = ? AND status = 'failed'")).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(3)).
+		WillReturnRows(messageRows(domain.Message{ID: 3, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusFailed, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.ResetRetries(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("ResetRetries returned error: %v", err)
+	}
+	if msg.Status != domain.StatusFailed || msg.FirstFailedAt != nil {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestResetRetries_RefusesMessageThatIsNotFailed(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND status = 'failed'")).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(3)).
+		WillReturnRows(messageRows(domain.Message{ID: 3, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, CreatedAt: now, UpdatedAt: now}))
+
+	_, err := repo.ResetRetries(context.Background(), 3)
+	if !errors.Is(err, domain.ErrMessageNotFailed) {
+		t.Fatalf("expected ErrMessageNotFailed, got %v", err)
+	}
+}
+
+func TestBulkUpdateStatus_UpdatesAllRowsInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, status, message_id FROM messages WHERE id IN (?, ?) FOR UPDATE")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "message_id"}).
+			AddRow(int64(1), "pending", nil).
+			AddRow(int64(2), "failed", nil))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE id IN (?, ?)")).
+		WithArgs(domain.StatusCancelled, int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	count, err := repo.BulkUpdateStatus(context.Background(), []int64{1, 2}, domain.StatusCancelled)
+	if err != nil {
+		t.Fatalf("BulkUpdateStatus returned error: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 updated rows, got %d", count)
+	}
+}
+
+func TestBulkUpdateStatus_FailedToPendingResetsRetries(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "message_id"}).
+			AddRow(int64(1), "failed", nil).
+			AddRow(int64(2), "cancelled", nil))
+	mock.ExpectExec(regexp.QuoteMeta("SET status = ?")).
+		WithArgs(domain.StatusPending, int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// Only the failed message gets its retries back.
+	mock.ExpectExec(regexp.QuoteMeta("SET retry_count = 0")).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := repo.BulkUpdateStatus(context.Background(), []int64{1, 2}, domain.StatusPending); err != nil {
+		t.Fatalf("BulkUpdateStatus returned error: %v", err)
+	}
+}
+
+func TestBulkUpdateStatus_RejectsSentWithoutMessageID(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "message_id"}).
+			AddRow(int64(1), "failed", "provider-1").
+			AddRow(int64(2), "failed", nil))
+	mock.ExpectRollback()
+
+	_, err := repo.BulkUpdateStatus(context.Background(), []int64{1, 2}, domain.StatusSent)
+	if !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestBulkUpdateStatus_UnknownIDChangesNothing(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs(int64(1), int64(99)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "message_id"}).
+			AddRow(int64(1), "pending", nil))
+	mock.ExpectRollback()
+
+	_, err := repo.BulkUpdateStatus(context.Background(), []int64{1, 99}, domain.StatusCancelled)
+	if !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestGetGroupedCounts_ReturnsCountsPerQueueAndStatus(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	// Seeded dataset: 3 pending + 5 sent in default, 1 failed in transactional.
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY queue, status")).
+		WillReturnRows(sqlmock.NewRows([]string{"queue", "status", "count"}).
+			AddRow("default", "pending", int64(3)).
+			AddRow("default", "sent", int64(5)).
+			AddRow("transactional", "failed", int64(1)))
+
+	counts, err := repo.GetGroupedCounts(context.Background())
+	if err != nil {
+		t.Fatalf("GetGroupedCounts returned error: %v", err)
+	}
+
+	want := []domain.StatusCount{
+		{Queue: "default", Status: domain.StatusPending, Count: 3},
+		{Queue: "default", Status: domain.StatusSent, Count: 5},
+		{Queue: "transactional", Status: domain.StatusFailed, Count: 1},
+	}
+	if !slices.Equal(counts, want) {
+		t.Fatalf("unexpected grouped counts:\n got  %+v\n want %+v", counts, want)
+	}
+}
+
+func TestStreamMessages_AppliesFilterAndLimit(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM messages WHERE status = ? ORDER BY id ASC LIMIT ?")).
+		WithArgs(domain.StatusSent, 2).
+		WillReturnRows(messageRows(
+			domain.Message{ID: 1, Status: domain.StatusSent, CreatedAt: now, UpdatedAt: now},
+			domain.Message{ID: 2, Status: domain.StatusSent, CreatedAt: now, UpdatedAt: now},
+		))
+
+	sent := domain.StatusSent
+	var ids []int64
+	err := repo.StreamMessages(context.Background(), domain.MessageFilter{Status: &sent}, 2, func(m domain.Message) error {
+		ids = append(ids, m.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamMessages returned error: %v", err)
+	}
+	if !slices.Equal(ids, []int64{1, 2}) {
+		t.Fatalf("expected messages 1 and 2, got %v", ids)
+	}
+}
+
+func TestNormalizePendingPhones_NormalizesMessyNumbers(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, phone_number FROM messages WHERE status = 'pending' AND id > ?")).
+		WithArgs(int64(0), normalizePhonesBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "phone_number"}).
+			AddRow(int64(1), "0090 (555) 123-45-67").
+			AddRow(int64(2), "+905551234567").
+			AddRow(int64(3), "+44 20 7946 0958").
+			AddRow(int64(4), "n/a"))
+
+	updatePhone := regexp.QuoteMeta("UPDATE messages SET phone_number = ? WHERE id = ? AND status = 'pending'")
+	mock.ExpectExec(updatePhone).WithArgs("+905551234567", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(updatePhone).WithArgs("+442079460958", int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	result, err := repo.NormalizePendingPhones(context.Background(), func(number string) (string, bool) {
+		normalized := phone.Normalize(number)
+		return normalized, phone.Valid(normalized)
+	})
+	if err != nil {
+		t.Fatalf("NormalizePendingPhones returned error: %v", err)
+	}
+
+	if result.Scanned != 4 || result.Changed != 2 {
+		t.Fatalf("expected 4 scanned and 2 changed, got %+v", result)
+	}
+	if !slices.Equal(result.RejectedIDs, []int64{4}) {
+		t.Fatalf("expected message 4 to be rejected, got %v", result.RejectedIDs)
+	}
+}
+
+// storedArg matches any string argument and remembers it, to inspect what was written.
+type storedArg struct{ value string }
+
+func (a *storedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	a.value = s
+	return ok
+}
+
+func TestCreate_EncryptsContentAtRest(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	if err := repo.SetContentKey(base64.StdEncoding.EncodeToString(make([]byte, 32))); err != nil {
+		t.Fatalf("SetContentKey returned error: %v", err)
+	}
+
+	const plaintext = "Your code is 123456"
+	sealed, err := repo.sealContent(plaintext)
+	if err != nil {
+		t.Fatalf("sealContent returned error: %v", err)
+	}
+
+	now := time.Now()
+	stored := &storedArg{}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (content, phone_number, status, tags")).
+		WithArgs(stored, "+905551234567", nil, domain.DefaultQueue, nil, nil, domain.DefaultClient, nil).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE id = ?")).
+		WithArgs(int64(10)).
+		WillReturnRows(messageRows(domain.Message{ID: 10, Content: sealed, PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, CreatedAt: now, UpdatedAt: now}))
+
+	msg, err := repo.Create(context.Background(), domain.NewMessage{Content: plaintext, PhoneNumber: "+905551234567"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if strings.Contains(stored.value, plaintext) || !strings.HasPrefix(stored.value, encryptedContentPrefix) {
+		t.Fatalf("expected encrypted content to be stored, got %q", stored.value)
+	}
+	if stored.value == sealed {
+		t.Fatalf("expected a fresh nonce per encryption")
+	}
+	if msg.Content != plaintext {
+		t.Fatalf("expected the created message to be read back as plaintext, got %q", msg.Content)
+	}
+
+	roundTrip, err := repo.openStored(stored.value)
+	if err != nil || roundTrip != plaintext {
+		t.Fatalf("expected the stored value to decrypt to %q, got %q (err %v)", plaintext, roundTrip, err)
+	}
+}
+
+func TestGetUnsent_ReadsPlaintextAndEncryptedRows(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	if err := repo.SetContentKey(base64.StdEncoding.EncodeToString(make([]byte, 16))); err != nil {
+		t.Fatalf("SetContentKey returned error: %v", err)
+	}
+
+	sealed, err := repo.sealContent("encrypted")
+	if err != nil {
+		t.Fatalf("sealContent returned error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE status = 'pending' AND queue = ?")).
+		WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 10).
+		WillReturnRows(messageRows(
+			domain.Message{ID: 1, Content: "written before encryption", Status: domain.StatusPending},
+			domain.Message{ID: 2, Content: sealed, Status: domain.StatusPending},
+		))
+
+	messages, err := repo.GetUnsent(context.Background(), domain.DefaultQueue, 10)
+	if err != nil {
+		t.Fatalf("GetUnsent returned error: %v", err)
+	}
+
+	if len(messages) != 2 || messages[0].Content != "written before encryption" || messages[1].Content != "encrypted" {
+		t.Fatalf("expected both rows as plaintext, got %+v", messages)
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+)
+
+// readRetryDelay is the wait before the second read attempt; it grows linearly after that.
+const readRetryDelay = 50 * time.Millisecond
+
+// MySQL error numbers worth retrying: lock wait timeout and deadlock.
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+// retryRead runs a read-only query up to ReadAttempts times while it fails with a transient error.
+// Writes must not go through here, since a failed write may still have been applied.
+func (r *MessageRepository) retryRead(ctx context.Context, query func() error) error {
+	attempts := max(r.ReadAttempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = query(); err == nil || !isTransient(err) || attempt == attempts {
+			return err
+		}
+
+		logger.Warnf("Transient database error on attempt %d/%d, retrying: %v", attempt, attempts, err)
+
+		select {
+		case <-time.After(time.Duration(attempt) * readRetryDelay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+	return err
+}
+
+// isTransient reports whether err is a connection hiccup or lock conflict that a retry may fix.
+func isTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrLockWaitTimeout || mysqlErr.Number == mysqlErrDeadlock
+	}
+	return false
+}
+
+// isDuplicateEntry reports whether err is a unique key violation.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
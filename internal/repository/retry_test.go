@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+func TestGetUnsent_RetriesTransientError(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	repo.ReadAttempts = 3
+	now := time.Now()
+
+	query := regexp.QuoteMeta("WHERE status = 'pending' AND queue = ?")
+	mock.ExpectQuery(query).WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 10).WillReturnError(mysql.ErrInvalidConn)
+	mock.ExpectQuery(query).WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 10).
+		WillReturnRows(messageRows(domain.Message{ID: 1, Content: "x", PhoneNumber: "+905551234567",
+			Status: domain.StatusPending, CreatedAt: now, UpdatedAt: now}))
+
+	messages, err := repo.GetUnsent(context.Background(), domain.DefaultQueue, 10)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+}
+
+func TestGetUnsent_DoesNotRetryPermanentError(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	repo.ReadAttempts = 3
+
+	missingTable := &mysql.MySQLError{Number: 1146, Message: "Table 'messages' doesn't exist"}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE status = 'pending' AND queue = ?")).
+		WithArgs(domain.DefaultQueue, sqlmock.AnyArg(), 10).
+		WillReturnError(missingTable)
+
+	// A second query would be an unexpected call and fail the mock.
+	_, err := repo.GetUnsent(context.Background(), domain.DefaultQueue, 10)
+	if !errors.Is(err, missingTable) {
+		t.Fatalf("expected the permanent error, got %v", err)
+	}
+}
+
+func TestGetAll_GivesUpAfterReadAttempts(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	repo.ReadAttempts = 2
+
+	count := regexp.QuoteMeta("SELECT COUNT(*) FROM messages")
+	mock.ExpectQuery(count).WillReturnError(mysql.ErrInvalidConn)
+	mock.ExpectQuery(count).WillReturnError(mysql.ErrInvalidConn)
+
+	_, _, err := repo.GetAll(context.Background(), domain.MessageFilter{}, 1, 20)
+	if !errors.Is(err, mysql.ErrInvalidConn) {
+		t.Fatalf("expected ErrInvalidConn after the last attempt, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+// defaultRunsKept is how many runs per queue RunResultRepository keeps when Keep is not set.
+const defaultRunsKept = 100
+
+// RunResultRepository stores the per-message results of recent scheduler runs.
+type RunResultRepository struct {
+	// Keep is how many of the most recent runs are kept per queue; older ones are deleted on save.
+	Keep int
+
+	db *sqlx.DB
+}
+
+func NewRunResultRepository(db *sqlx.DB) *RunResultRepository {
+	return &RunResultRepository{db: db}
+}
+
+// SaveRun stores the results of a run and deletes the runs of its queue that fall outside Keep.
+func (r *RunResultRepository) SaveRun(ctx context.Context, run domain.SchedulerRun) error {
+	results, err := json.Marshal(run.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode run results: %w", err)
+	}
+
+	insert := `
+		INSERT INTO scheduler_runs (queue, run_number, started_at, results)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE started_at = VALUES(started_at), results = VALUES(results)
+	`
+	if _, err := r.db.ExecContext(ctx, insert, run.Queue, run.RunNumber, run.StartedAt, string(results)); err != nil {
+		return fmt.Errorf("failed to save run results: %w", err)
+	}
+
+	keep := r.Keep
+	if keep <= 0 {
+		keep = defaultRunsKept
+	}
+
+	prune := "DELETE FROM scheduler_runs WHERE queue = ? AND run_number <= ?"
+	if _, err := r.db.ExecContext(ctx, prune, run.Queue, run.RunNumber-int64(keep)); err != nil {
+		return fmt.Errorf("failed to prune run results: %w", err)
+	}
+
+	return nil
+}
+
+// GetRun returns the stored results of a run, or domain.ErrRunNotFound.
+func (r *RunResultRepository) GetRun(ctx context.Context, queue string, runNumber int64) (*domain.SchedulerRun, error) {
+	query := `
+		SELECT started_at, results
+		FROM scheduler_runs
+		WHERE queue = ? AND run_number = ?
+	`
+
+	var row struct {
+		StartedAt time.Time `db:"started_at"`
+		Results   string    `db:"results"`
+	}
+	if err := r.db.GetContext(ctx, &row, query, queue, runNumber); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get run results: %w", err)
+	}
+
+	run := &domain.SchedulerRun{Queue: queue, RunNumber: runNumber, StartedAt: row.StartedAt}
+	if err := json.Unmarshal([]byte(row.Results), &run.Results); err != nil {
+		return nil, fmt.Errorf("failed to decode run results: %w", err)
+	}
+	run.RetryBudgetUsed = domain.RetryBudgetUsed(run.Results)
+
+	return run, nil
+}
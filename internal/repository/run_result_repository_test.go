@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+func newMockRunResultRepository(t *testing.T) (*RunResultRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet sqlmock expectations: %v", err)
+		}
+		db.Close()
+	})
+
+	return NewRunResultRepository(sqlx.NewDb(db, "mysql")), mock
+}
+
+func TestSaveRun_PrunesRunsOutsideKeep(t *testing.T) {
+	repo, mock := newMockRunResultRepository(t)
+	repo.Keep = 10
+
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO scheduler_runs .* ON DUPLICATE KEY UPDATE`).
+		WithArgs("default", int64(25), startedAt, `[{"messageDbId":7,"success":false,"error":"timeout","sentAt":"0001-01-01T00:00:00Z","attempts":2}]`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM scheduler_runs WHERE queue = \? AND run_number <= \?`).
+		WithArgs("default", int64(15)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SaveRun(context.Background(), domain.SchedulerRun{
+		Queue:     "default",
+		RunNumber: 25,
+		StartedAt: startedAt,
+		Results:   []domain.RunResult{{MessageDBID: 7, Error: "timeout", Attempts: 2}},
+	})
+	if err != nil {
+		t.Fatalf("SaveRun returned error: %v", err)
+	}
+}
+
+func TestGetRun_DecodesStoredResults(t *testing.T) {
+	repo, mock := newMockRunResultRepository(t)
+
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM scheduler_runs`).WithArgs("default", int64(3)).WillReturnRows(
+		sqlmock.NewRows([]string{"started_at", "results"}).
+			AddRow(startedAt, `[{"messageDbId":1,"messageId":"abc","success":true,"attempts":1},{"messageDbId":2,"success":false,"attempts":3}]`),
+	)
+
+	run, err := repo.GetRun(context.Background(), "default", 3)
+	if err != nil {
+		t.Fatalf("GetRun returned error: %v", err)
+	}
+	if run.RunNumber != 3 || len(run.Results) != 2 || run.Results[0].MessageID != "abc" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if run.RetryBudgetUsed != 2 {
+		t.Fatalf("expected 2 retries drawn from the budget, got %d", run.RetryBudgetUsed)
+	}
+}
+
+func TestGetRun_NotFound(t *testing.T) {
+	repo, mock := newMockRunResultRepository(t)
+
+	mock.ExpectQuery(`FROM scheduler_runs`).WithArgs("default", int64(1)).WillReturnError(sql.ErrNoRows)
+
+	if _, err := repo.GetRun(context.Background(), "default", 1); !errors.Is(err, domain.ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+// SchedulerStateRepository persists scheduler state per queue so it can be restored after a restart.
+type SchedulerStateRepository struct {
+	db *sqlx.DB
+}
+
+func NewSchedulerStateRepository(db *sqlx.DB) *SchedulerStateRepository {
+	return &SchedulerStateRepository{db: db}
+}
+
+// Save inserts or replaces the state of state.Queue.
+func (r *SchedulerStateRepository) Save(ctx context.Context, state domain.SchedulerState) error {
+	query := `
+		INSERT INTO scheduler_state (queue, running, interval_seconds, failure_rate, runs_count, messages_sent)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			running = VALUES(running),
+			interval_seconds = VALUES(interval_seconds),
+			failure_rate = VALUES(failure_rate),
+			runs_count = VALUES(runs_count),
+			messages_sent = VALUES(messages_sent)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		state.Queue, state.Running, state.IntervalSeconds, state.FailureRate, state.RunsCount, state.MessagesSent)
+	if err != nil {
+		return fmt.Errorf("failed to save scheduler state: %w", err)
+	}
+
+	return nil
+}
+
+// Load returns the saved state of the queue, or nil if none was saved yet.
+func (r *SchedulerStateRepository) Load(ctx context.Context, queue string) (*domain.SchedulerState, error) {
+	query := `
+		SELECT queue, running, interval_seconds, failure_rate, runs_count, messages_sent, updated_at
+		FROM scheduler_state
+		WHERE queue = ?
+	`
+
+	var state domain.SchedulerState
+	if err := r.db.GetContext(ctx, &state, query, queue); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load scheduler state: %w", err)
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+func newMockSchedulerStateRepository(t *testing.T) (*SchedulerStateRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet sqlmock expectations: %v", err)
+		}
+		db.Close()
+	})
+
+	return NewSchedulerStateRepository(sqlx.NewDb(db, "mysql")), mock
+}
+
+func TestSchedulerStateSave_Upserts(t *testing.T) {
+	repo, mock := newMockSchedulerStateRepository(t)
+
+	mock.ExpectExec(`INSERT INTO scheduler_state .* ON DUPLICATE KEY UPDATE`).
+		WithArgs("default", false, int64(300), 0.25, int64(4), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.Save(context.Background(), domain.SchedulerState{
+		Queue: "default", Running: false, IntervalSeconds: 300, FailureRate: 0.25, RunsCount: 4, MessagesSent: 7,
+	})
+	if err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+}
+
+func TestSchedulerStateLoad_ReturnsNilWhenNothingSaved(t *testing.T) {
+	repo, mock := newMockSchedulerStateRepository(t)
+
+	mock.ExpectQuery(`FROM scheduler_state`).WithArgs("transactional").WillReturnError(sql.ErrNoRows)
+
+	state, err := repo.Load(context.Background(), "transactional")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("expected no state, got %+v", state)
+	}
+}
+
+func TestSchedulerStateLoad_ReturnsSavedState(t *testing.T) {
+	repo, mock := newMockSchedulerStateRepository(t)
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM scheduler_state`).WithArgs("default").WillReturnRows(
+		sqlmock.NewRows([]string{"queue", "running", "interval_seconds", "failure_rate", "runs_count", "messages_sent", "updated_at"}).
+			AddRow("default", true, int64(120), 0.0, int64(10), int64(18), updatedAt),
+	)
+
+	state, err := repo.Load(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if state == nil || !state.Running || state.IntervalSeconds != 120 || state.MessagesSent != 18 {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+}
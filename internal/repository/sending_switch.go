@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// sendingSwitchID is the id of the single row of the sending_switch table.
+const sendingSwitchID = 1
+
+// IsSendingEnabled reports the state of the global kill switch. Sending is enabled until the
+// switch is first turned off.
+func (r *MessageRepository) IsSendingEnabled(ctx context.Context) (bool, error) {
+	var enabled bool
+	err := r.db.GetContext(ctx, &enabled, "SELECT enabled FROM sending_switch WHERE id = ?", sendingSwitchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read sending switch: %w", err)
+	}
+	return enabled, nil
+}
+
+// SetSendingEnabled turns the global kill switch on or off for every instance.
+func (r *MessageRepository) SetSendingEnabled(ctx context.Context, enabled bool) error {
+	query := `
+		INSERT INTO sending_switch (id, enabled)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, sendingSwitchID, enabled); err != nil {
+		return fmt.Errorf("failed to set sending switch: %w", err)
+	}
+
+	return nil
+}
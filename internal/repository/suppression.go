@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+// AddSuppression puts phoneNumber on the suppression list and moves its pending messages to
+// suppressed, in one transaction. Suppressing a number again updates the reason. It returns the
+// number of messages moved.
+func (r *MessageRepository) AddSuppression(ctx context.Context, phoneNumber string, reason *string) (*domain.Suppression, int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert := `
+		INSERT INTO suppressions (phone_number, reason, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE reason = VALUES(reason)
+	`
+	if _, err := tx.ExecContext(ctx, insert, phoneNumber, reason); err != nil {
+		return nil, 0, fmt.Errorf("failed to add suppression: %w", err)
+	}
+
+	update := `
+		UPDATE messages
+		SET status = 'suppressed',
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE phone_number = ? AND status = 'pending'
+	`
+	result, err := tx.ExecContext(ctx, update, phoneNumber)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to suppress pending messages: %w", err)
+	}
+	suppressed, err := result.RowsAffected()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	var suppression domain.Suppression
+	query := "SELECT phone_number, reason, created_at FROM suppressions WHERE phone_number = ?"
+	if err := tx.GetContext(ctx, &suppression, query, phoneNumber); err != nil {
+		return nil, 0, fmt.Errorf("failed to read suppression: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit suppression: %w", err)
+	}
+
+	return &suppression, suppressed, nil
+}
+
+// RemoveSuppression takes phoneNumber off the suppression list. Messages already suppressed stay
+// suppressed.
+func (r *MessageRepository) RemoveSuppression(ctx context.Context, phoneNumber string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM suppressions WHERE phone_number = ?", phoneNumber)
+	if err != nil {
+		return fmt.Errorf("failed to remove suppression: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return domain.ErrSuppressionNotFound
+	}
+
+	return nil
+}
+
+// IsSuppressed reports whether phoneNumber is on the suppression list.
+func (r *MessageRepository) IsSuppressed(ctx context.Context, phoneNumber string) (bool, error) {
+	var found int
+	err := r.db.GetContext(ctx, &found, "SELECT 1 FROM suppressions WHERE phone_number = ?", phoneNumber)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check suppression: %w", err)
+	}
+	return true, nil
+}
+
+// SuppressPending moves the pending messages of queue whose number is on the suppression list to
+// suppressed, i.e. those created after their number was suppressed. It returns how many were moved.
+func (r *MessageRepository) SuppressPending(ctx context.Context, queue string) (int64, error) {
+	query := `
+		UPDATE messages
+		SET status = 'suppressed',
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'pending' AND queue = ?
+		  AND EXISTS (SELECT 1 FROM suppressions WHERE suppressions.phone_number = messages.phone_number)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, queue)
+	if err != nil {
+		return 0, fmt.Errorf("failed to suppress pending messages: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows, nil
+}
+
+// MarkAsSuppressed records that a message was skipped because its number is suppressed.
+func (r *MessageRepository) MarkAsSuppressed(ctx context.Context, id int64) error {
+	query := `
+		UPDATE messages
+		SET status = 'suppressed',
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark message as suppressed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+func TestAddSuppression_SuppressesPendingMessagesInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	reason := "STOP reply"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO suppressions")).
+		WithArgs("+905551234567", &reason).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE phone_number = ? AND status = 'pending'")).
+		WithArgs("+905551234567").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM suppressions WHERE phone_number = ?")).
+		WithArgs("+905551234567").
+		WillReturnRows(sqlmock.NewRows([]string{"phone_number", "reason", "created_at"}).
+			AddRow("+905551234567", reason, time.Now()))
+	mock.ExpectCommit()
+
+	suppression, suppressed, err := repo.AddSuppression(context.Background(), "+905551234567", &reason)
+	if err != nil {
+		t.Fatalf("AddSuppression returned error: %v", err)
+	}
+	if suppressed != 3 {
+		t.Fatalf("expected 3 suppressed messages, got %d", suppressed)
+	}
+	if suppression.PhoneNumber != "+905551234567" || suppression.Reason == nil || *suppression.Reason != reason {
+		t.Fatalf("unexpected suppression: %+v", suppression)
+	}
+}
+
+func TestRemoveSuppression_UnknownNumber(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM suppressions WHERE phone_number = ?")).
+		WithArgs("+905551234567").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.RemoveSuppression(context.Background(), "+905551234567")
+	if !errors.Is(err, domain.ErrSuppressionNotFound) {
+		t.Fatalf("expected ErrSuppressionNotFound, got %v", err)
+	}
+}
+
+func TestSuppressPending_MovesQueuedMessagesToSuppressedNumbers(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("WHERE status = 'pending' AND queue = ?") + `\s+` +
+		regexp.QuoteMeta("AND EXISTS (SELECT 1 FROM suppressions WHERE suppressions.phone_number = messages.phone_number)")).
+		WithArgs("bulk").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	suppressed, err := repo.SuppressPending(context.Background(), "bulk")
+	if err != nil {
+		t.Fatalf("SuppressPending returned error: %v", err)
+	}
+	if suppressed != 2 {
+		t.Fatalf("expected 2 suppressed messages, got %d", suppressed)
+	}
+}
+
+func TestGetUnsent_SkipsSuppressedNumbers(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("AND NOT EXISTS (SELECT 1 FROM suppressions WHERE suppressions.phone_number = messages.phone_number)")).
+		WillReturnRows(messageRows())
+
+	if _, err := repo.GetUnsent(context.Background(), domain.DefaultQueue, 10); err != nil {
+		t.Fatalf("GetUnsent returned error: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+)
+
+// AlertTemplateData is what an alert message template is rendered with, e.g.
+// "{{.Queue}}: {{.ConsecutiveFailures}} failed runs in a row (run #{{.RunNumber}})".
+type AlertTemplateData struct {
+	Queue               string
+	RunNumber           int64
+	ConsecutiveFailures int
+	MessagesInBatch     int
+}
+
+// ParseAlertTemplate parses an alert message template and renders it once with sample data, so
+// a misspelled field is reported on startup rather than when the first alert fires.
+func ParseAlertTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("alert").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alert template: %w", err)
+	}
+
+	sample := AlertTemplateData{Queue: "default", RunNumber: 1, ConsecutiveFailures: 1, MessagesInBatch: 1}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid alert template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// alertMessage renders the alert text with AlertTemplate, or the default text if none is set or
+// rendering fails; a failed render is logged.
+func (s *Scheduler) alertMessage(data AlertTemplateData) string {
+	if s.AlertTemplate != nil {
+		var buf bytes.Buffer
+		err := s.AlertTemplate.Execute(&buf, data)
+		if err == nil {
+			return buf.String()
+		}
+		logger.Warnf("Failed to render alert template, sending the default message: %v", err)
+	}
+
+	return fmt.Sprintf(
+		"All %d messages failed for %d consecutive iterations",
+		data.MessagesInBatch,
+		data.ConsecutiveFailures,
+	)
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/onurcolak/insider-message-service/internal/domain"
@@ -18,16 +21,58 @@ import (
 // It matches the ProcessUnsentMessages method of MessageService and
 // lets us unit test the scheduler with a small fake implementation.
 type messageProcessor interface {
-	ProcessUnsentMessages(ctx context.Context, failureRate float64) ([]domain.SendResult, error)
+	ProcessUnsentMessages(ctx context.Context, queue string, failureRate float64) ([]domain.SendResult, error)
+}
+
+// RunStore keeps the per-message results of recent runs so they can be looked up by run number.
+type RunStore interface {
+	SaveRun(ctx context.Context, run domain.SchedulerRun) error
+	GetRun(ctx context.Context, queue string, runNumber int64) (*domain.SchedulerRun, error)
+}
+
+// StateStore persists scheduler state so it survives restarts.
+type StateStore interface {
+	Save(ctx context.Context, state domain.SchedulerState) error
+	Load(ctx context.Context, queue string) (*domain.SchedulerState, error)
 }
 
 type Scheduler struct {
+	// MinInterval is the lowest interval the scheduler will run at; shorter ones are clamped to it.
+	MinInterval time.Duration
+	// AlertTimeout bounds each alert webhook call (defaults to defaultAlertTimeout).
+	AlertTimeout time.Duration
+	// AlertRetries is how many more times a failed alert call is tried (0 = once). Retries wait
+	// AlertRetryBackoff (defaults to defaultAlertRetryBackoff), doubled each time, with jitter.
+	AlertRetries      int
+	AlertRetryBackoff time.Duration
+	// StartDelay postpones the first run after Start, e.g. to let a rolling deploy settle (0 = run immediately).
+	StartDelay time.Duration
+	// IdleStopRuns stops the scheduler after this many consecutive runs found nothing to send (0 = never).
+	// Runs held by quiet hours, the kill switch or messages scheduled for later do not count.
+	// An idle-stopped scheduler is restarted by Wake.
+	IdleStopRuns int
+	// FailureRetryInterval, if shorter than the interval, brings the next run forward after a run
+	// in which every message failed, so a transient outage is retried sooner. The wait is jittered
+	// between half and all of it; the normal interval resumes once a run does not all-fail (0 = off).
+	FailureRetryInterval time.Duration
+	// Store, if set, receives the scheduler state on start, stop and after every run, and is read by Restore.
+	Store StateStore
+	// Runs, if set, receives the results of every run that processed messages.
+	Runs RunStore
+	// DeadLetterURL, if set, receives a POST for every message a run marks failed, sent with the
+	// same timeout and retries as alerts. Messages left pending for a retry are not posted.
+	DeadLetterURL string
+	// AlertTemplate, if set, renders the alert's message text from AlertTemplateData; see ParseAlertTemplate.
+	AlertTemplate *template.Template
+
 	messageService  messageProcessor
+	queue           string // Queue this scheduler drains; empty means domain.DefaultQueue
 	interval        time.Duration
 	failureRate     float64 // Probability of failure (0-1)
 	alertWebhook    string
 	alertThreshold  int // Number of consecutive all-fail iterations before alert
 	lastAlertSentAt time.Time
+	lastAlertError  string // Error of the latest alert attempt; cleared once an alert lands
 
 	// Internal state
 	running  bool
@@ -35,6 +80,10 @@ type Scheduler struct {
 	doneChan chan struct{}
 	mu       sync.RWMutex
 
+	// alertCtx lives from Start to Stop so pending alert calls are cancelled on shutdown.
+	alertCtx    context.Context
+	alertCancel context.CancelFunc
+
 	// Statistics
 	lastRunAt    time.Time
 	messagesSent int64
@@ -42,16 +91,52 @@ type Scheduler struct {
 
 	// Alert tracking
 	consecutiveAllFailCount int // Count of consecutive iterations where all messages failed
+
+	// retryDelay replaces the interval before the next run after an all-failed run (0 = none).
+	retryDelay time.Duration
+
+	// Idle tracking
+	consecutiveIdleRuns int
+	idleStopped         bool // Stopped by IdleStopRuns rather than by Stop
+
+	// wantRunning is the state saved to Store: set by Start, cleared only by Stop, so idle
+	// stops and Shutdown do not count as an operator stopping the scheduler.
+	wantRunning bool
+
+	// Most recent runs, for the history endpoints
+	history runHistory
 }
 
+const (
+	defaultAlertTimeout      = 10 * time.Second
+	defaultAlertRetryBackoff = 500 * time.Millisecond
+	runHistorySize           = 100
+	stateSaveTimeout         = 5 * time.Second
+)
+
 func NewScheduler(messageService *service.MessageService, interval time.Duration) *Scheduler {
+	return NewQueueScheduler(domain.DefaultQueue, messageService, interval)
+}
+
+// NewQueueScheduler creates a scheduler that only processes messages of the named queue,
+// with its own interval, start/stop state and statistics.
+func NewQueueScheduler(queue string, messageService *service.MessageService, interval time.Duration) *Scheduler {
 	return &Scheduler{
 		messageService: messageService,
+		queue:          queue,
 		interval:       interval,
 		running:        false,
 	}
 }
 
+// Queue returns the name of the queue this scheduler processes.
+func (s *Scheduler) Queue() string {
+	if s.queue == "" {
+		return domain.DefaultQueue
+	}
+	return s.queue
+}
+
 func (s *Scheduler) StartWithParams(
 	ctx context.Context,
 	intervalMinutes int,
@@ -63,7 +148,7 @@ func (s *Scheduler) StartWithParams(
 	}
 
 	s.mu.Lock()
-	s.interval = time.Duration(intervalMinutes) * time.Minute
+	s.interval = s.clampInterval(time.Duration(intervalMinutes) * time.Minute)
 	s.failureRate = failureRate
 	s.alertWebhook = alertWebhook
 	s.alertThreshold = alertThreshold
@@ -83,11 +168,19 @@ func (s *Scheduler) Start(ctx context.Context) error {
 	}
 
 	s.running = true
+	s.wantRunning = true
+	s.idleStopped = false
+	s.consecutiveIdleRuns = 0
+	s.interval = s.clampInterval(s.interval)
 	s.stopChan = make(chan struct{})
 	s.doneChan = make(chan struct{})
+	s.alertCtx, s.alertCancel = context.WithCancel(ctx)
+	interval := s.interval
 	s.mu.Unlock()
 
-	logger.Infof("Starting scheduler with interval: %v", s.interval)
+	logger.Infof("Starting scheduler with interval: %v", interval)
+
+	s.saveState(ctx)
 
 	go s.run(ctx)
 
@@ -97,18 +190,35 @@ func (s *Scheduler) Start(ctx context.Context) error {
 func (s *Scheduler) run(ctx context.Context) {
 	defer close(s.doneChan)
 
+	if !s.waitStartDelay(ctx) {
+		return
+	}
+
 	s.processMessages(ctx)
+	if s.stopIfIdle() {
+		return
+	}
 
-	ticker := time.NewTicker(s.interval)
+	interval := s.nextDelay()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	logger.Infof("Scheduler running. Next execution in %v", s.interval)
+	logger.Infof("Scheduler running. Next execution in %v", interval)
 
 	for {
 		select {
 		case <-ticker.C:
 			s.processMessages(ctx)
-			logger.Debugf("Next execution in %v", s.interval)
+			if s.stopIfIdle() {
+				return
+			}
+
+			// Pick up interval changes made via SetInterval and retries after an all-failed run.
+			if next := s.nextDelay(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+			logger.Debugf("Next execution in %v", interval)
 
 		case <-s.stopChan:
 			logger.Warnf("Scheduler received stop signal")
@@ -121,19 +231,63 @@ func (s *Scheduler) run(ctx context.Context) {
 	}
 }
 
+// waitStartDelay blocks for StartDelay and reports whether the scheduler should go on running.
+func (s *Scheduler) waitStartDelay(ctx context.Context) bool {
+	if s.StartDelay <= 0 {
+		return true
+	}
+
+	logger.Infof("Scheduler waiting %v before its first run", s.StartDelay)
+
+	timer := time.NewTimer(s.StartDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopChan:
+		logger.Warnf("Scheduler stopped before its first run")
+		return false
+	case <-ctx.Done():
+		logger.Warnf("Scheduler context cancelled before its first run")
+		return false
+	}
+}
+
 func (s *Scheduler) processMessages(ctx context.Context) {
 	s.mu.Lock()
 	s.lastRunAt = time.Now()
 	s.runsCount++
 	runNumber := s.runsCount
+	startedAt := s.lastRunAt
 	failureRate := s.failureRate
 	alertWebhook := s.alertWebhook
 	alertThreshold := s.alertThreshold
+	alertCtx := s.alertCtx
 	s.mu.Unlock()
 
-	logger.Infof("[Run #%d] Starting message processing at %s", runNumber, s.lastRunAt.Format(time.RFC3339))
+	if alertCtx == nil {
+		alertCtx = ctx
+	}
 
-	results, err := s.messageService.ProcessUnsentMessages(ctx, failureRate)
+	defer s.saveState(ctx)
+
+	logger.Infof("[%s Run #%d] Starting message processing at %s", s.Queue(), runNumber, startedAt.Format(time.RFC3339))
+
+	results, err := s.messageService.ProcessUnsentMessages(ctx, s.Queue(), failureRate)
+	if errors.Is(err, domain.ErrBatchInProgress) {
+		logger.Warnf("[Run #%d] Skipped, another batch is still being processed for queue %q", runNumber, s.Queue())
+		return
+	}
+	// Not idle runs: messages are waiting, so the scheduler must not stop itself.
+	if errors.Is(err, domain.ErrSendingDisabled) {
+		logger.Warnf("[Run #%d] Skipped, sending is disabled by the kill switch; queue %q left pending", runNumber, s.Queue())
+		return
+	}
+	if errors.Is(err, domain.ErrQuietHours) || errors.Is(err, domain.ErrNotDue) {
+		logger.Debugf("[Run #%d] Nothing sent: %v", runNumber, err)
+		return
+	}
 	if err != nil {
 		logger.Errorf("[Run #%d] Error processing messages: %v", runNumber, err)
 		return
@@ -141,31 +295,69 @@ func (s *Scheduler) processMessages(ctx context.Context) {
 
 	if results == nil {
 		logger.Debugf("[Run #%d] No messages to process", runNumber)
+		s.mu.Lock()
+		s.retryDelay = 0
+		s.consecutiveIdleRuns++
+		s.history.add(RunRecord{StartedAt: startedAt})
+		s.mu.Unlock()
 		return
 	}
 
-	// Count successful sends
+	runResults := domain.NewRunResults(results)
+	s.saveRun(ctx, domain.SchedulerRun{
+		Queue:           s.Queue(),
+		RunNumber:       runNumber,
+		StartedAt:       startedAt,
+		RetryBudgetUsed: domain.RetryBudgetUsed(runResults),
+		Results:         runResults,
+	})
+
+	// Count successful sends; deduped and suppressed messages count as neither sent nor failed.
 	successCount := 0
-	allFailed := true
+	var failed, deadLetters []domain.SendResult
 	for _, r := range results {
-		if r.Success {
+		switch {
+		case r.Success:
 			successCount++
-			allFailed = false
+		case !r.Deduped && !r.Suppressed:
+			failed = append(failed, r)
+			// Messages left pending for another run are not dead yet.
+			if !r.Retrying {
+				deadLetters = append(deadLetters, r)
+			}
 		}
 	}
+	allFailed := successCount == 0 && len(failed) > 0
+
+	if s.DeadLetterURL != "" && len(deadLetters) > 0 {
+		go s.sendDeadLetters(alertCtx, s.DeadLetterURL, deadLetters)
+	}
 
 	s.mu.Lock()
+	s.consecutiveIdleRuns = 0
 	s.messagesSent += int64(successCount)
+	s.history.add(RunRecord{
+		StartedAt: startedAt,
+		Processed: len(results),
+		Succeeded: successCount,
+		Failed:    len(failed),
+	})
 
 	// Track consecutive all-fail iterations
-	if allFailed && len(results) > 0 {
+	s.retryDelay = 0
+	if allFailed {
 		s.consecutiveAllFailCount++
 		logger.Warnf("[Run #%d] All %d messages failed (consecutive count: %d/%d)",
 			runNumber, len(results), s.consecutiveAllFailCount, alertThreshold)
 
+		if s.FailureRetryInterval > 0 && s.FailureRetryInterval < s.interval {
+			s.retryDelay = s.FailureRetryInterval/2 + rand.N(s.FailureRetryInterval/2+1)
+			logger.Infof("[Run #%d] Retrying in %v instead of %v", runNumber, s.retryDelay, s.interval)
+		}
+
 		// Send alert if threshold reached
 		if s.consecutiveAllFailCount >= alertThreshold && alertThreshold > 0 && alertWebhook != "" {
-			go s.sendAlert(alertWebhook, runNumber, s.consecutiveAllFailCount, len(results))
+			go s.sendAlert(alertCtx, alertWebhook, runNumber, s.consecutiveAllFailCount, len(results))
 		}
 	} else {
 		// Reset counter if any message succeeded
@@ -184,18 +376,86 @@ func (s *Scheduler) processMessages(ctx context.Context) {
 		runNumber, len(results), successCount, len(results)-successCount)
 }
 
+// stopIfIdle stops the scheduler from inside its run loop once IdleStopRuns consecutive runs
+// were empty, and reports whether the loop should exit.
+func (s *Scheduler) stopIfIdle() bool {
+	s.mu.Lock()
+
+	if s.IdleStopRuns <= 0 || s.consecutiveIdleRuns < s.IdleStopRuns || !s.running {
+		s.mu.Unlock()
+		return false
+	}
+
+	s.running = false
+	s.idleStopped = true
+	idleRuns := s.consecutiveIdleRuns
+	alertCancel := s.alertCancel
+	s.mu.Unlock()
+
+	alertCancel()
+
+	logger.Warnf("[%s] Scheduler auto-stopped after %d consecutive runs without pending messages", s.Queue(), idleRuns)
+	return true
+}
+
+// Wake restarts a scheduler that stopped itself for being idle, e.g. when a new message is
+// created for its queue. Schedulers stopped via Stop stay stopped.
+func (s *Scheduler) Wake(ctx context.Context) error {
+	s.mu.RLock()
+	idleStopped := s.idleStopped
+	doneChan := s.doneChan
+	s.mu.RUnlock()
+
+	if !idleStopped {
+		return nil
+	}
+
+	// Let the previous run loop finish exiting before starting a new one.
+	<-doneChan
+
+	logger.Infof("[%s] New work enqueued, waking idle scheduler", s.Queue())
+	return s.Start(ctx)
+}
+
+// Stop stops the scheduler and records that it should stay stopped across restarts.
 func (s *Scheduler) Stop() error {
+	return s.stop(true)
+}
+
+// Shutdown stops the scheduler without changing its saved state, so it resumes after a restart.
+func (s *Scheduler) Shutdown() error {
+	return s.stop(false)
+}
+
+func (s *Scheduler) stop(persist bool) error {
 	s.mu.Lock()
 
 	if !s.running {
+		// An idle-stopped scheduler would otherwise be woken again by new work or a restart.
+		wasIdleStopped := s.idleStopped
+		s.idleStopped = false
+		if persist && wasIdleStopped {
+			s.wantRunning = false
+		}
 		s.mu.Unlock()
+
+		if persist && wasIdleStopped {
+			s.saveState(context.Background())
+			logger.Infof("Idle-stopped scheduler will stay stopped")
+			return nil
+		}
 		logger.Warnf("Scheduler is not running")
 		return nil
 	}
 
 	s.running = false
+	s.idleStopped = false
+	if persist {
+		s.wantRunning = false
+	}
 	stopChan := s.stopChan
 	doneChan := s.doneChan
+	alertCancel := s.alertCancel
 	s.mu.Unlock()
 
 	// Send stop signal
@@ -204,10 +464,145 @@ func (s *Scheduler) Stop() error {
 	// Wait for goroutine to finish
 	<-doneChan
 
+	// Abandon any alert calls still in flight.
+	alertCancel()
+
+	if persist {
+		s.saveState(context.Background())
+	}
+
 	logger.Infof("Scheduler stopped")
 	return nil
 }
 
+// Restore applies the state saved in Store (interval, failure rate and counters) and returns it,
+// so the caller can start the scheduler only if it was running. It returns nil if there is no
+// Store or nothing was saved for this queue yet.
+func (s *Scheduler) Restore(ctx context.Context) (*domain.SchedulerState, error) {
+	if s.Store == nil {
+		return nil, nil
+	}
+
+	state, err := s.Store.Load(ctx, s.Queue())
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore scheduler state: %w", err)
+	}
+	if state == nil {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	if state.IntervalSeconds > 0 {
+		s.interval = s.clampInterval(time.Duration(state.IntervalSeconds) * time.Second)
+	}
+	s.failureRate = state.FailureRate
+	s.runsCount = state.RunsCount
+	s.messagesSent = state.MessagesSent
+	s.mu.Unlock()
+
+	logger.Infof("[%s] Restored scheduler state (running: %t, runs: %d)", s.Queue(), state.Running, state.RunsCount)
+	return state, nil
+}
+
+// GetRun returns the stored results of the given run, or domain.ErrRunNotFound if they are not
+// stored (no Runs store, a run without messages, or one older than the retained runs).
+func (s *Scheduler) GetRun(ctx context.Context, runNumber int64) (*domain.SchedulerRun, error) {
+	if s.Runs == nil {
+		return nil, domain.ErrRunNotFound
+	}
+	return s.Runs.GetRun(ctx, s.Queue(), runNumber)
+}
+
+// saveRun stores a run's results, logging failures like saveState.
+func (s *Scheduler) saveRun(ctx context.Context, run domain.SchedulerRun) {
+	if s.Runs == nil {
+		return
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stateSaveTimeout)
+	defer cancel()
+
+	if err := s.Runs.SaveRun(saveCtx, run); err != nil {
+		logger.Errorf("[%s Run #%d] Failed to save run results: %v", run.Queue, run.RunNumber, err)
+	}
+}
+
+// saveState writes the current state to Store, logging rather than returning failures so a
+// store outage never stops message processing.
+func (s *Scheduler) saveState(ctx context.Context) {
+	if s.Store == nil {
+		return
+	}
+
+	s.mu.RLock()
+	state := domain.SchedulerState{
+		Queue:           s.Queue(),
+		Running:         s.wantRunning,
+		IntervalSeconds: int64(s.interval / time.Second),
+		FailureRate:     s.failureRate,
+		RunsCount:       s.runsCount,
+		MessagesSent:    s.messagesSent,
+	}
+	s.mu.RUnlock()
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stateSaveTimeout)
+	defer cancel()
+
+	if err := s.Store.Save(saveCtx, state); err != nil {
+		logger.Errorf("[%s] Failed to save scheduler state: %v", s.Queue(), err)
+	}
+}
+
+// SetInterval changes the run interval (clamped to MinInterval); a running scheduler
+// switches to it after its next run.
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interval = s.clampInterval(interval)
+}
+
+func (s *Scheduler) currentInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.interval
+}
+
+// nextDelay returns the wait before the next run: the retry delay after an all-failed run,
+// otherwise the interval.
+func (s *Scheduler) nextDelay() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.nextDelayLocked()
+}
+
+// nextDelayLocked is nextDelay for callers that hold s.mu.
+func (s *Scheduler) nextDelayLocked() time.Duration {
+	if s.retryDelay > 0 {
+		return s.retryDelay
+	}
+	return s.interval
+}
+
+// clampInterval raises intervals below MinInterval to the floor. Callers must hold s.mu.
+func (s *Scheduler) clampInterval(interval time.Duration) time.Duration {
+	if s.MinInterval > 0 && interval < s.MinInterval {
+		logger.Warnf("Interval %v is below the minimum, clamping to %v", interval, s.MinInterval)
+		return s.MinInterval
+	}
+	return interval
+}
+
+// History returns the most recent completed runs, oldest first.
+func (s *Scheduler) History() []RunRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.history.list()
+}
+
 func (s *Scheduler) IsRunning() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -219,6 +614,7 @@ func (s *Scheduler) GetStatus() SchedulerStatus {
 	defer s.mu.RUnlock()
 
 	status := SchedulerStatus{
+		Queue:                   s.Queue(),
 		Running:                 s.running,
 		LastRunAt:               s.lastRunAt,
 		MessagesSent:            s.messagesSent,
@@ -226,27 +622,42 @@ func (s *Scheduler) GetStatus() SchedulerStatus {
 		Interval:                s.interval,
 		ConsecutiveAllFailCount: s.consecutiveAllFailCount,
 		LastAlertSentAt:         s.lastAlertSentAt,
+		LastAlertError:          s.lastAlertError,
+		IdleStopped:             s.idleStopped,
 	}
 
+	// A run that took longer than the interval leaves the planned next run in the past; the next
+	// run then starts right away, so report now and flag the scheduler as behind.
 	if s.running && !s.lastRunAt.IsZero() {
-		status.NextRunAt = s.lastRunAt.Add(s.interval)
+		status.NextRunAt = s.lastRunAt.Add(s.nextDelayLocked())
+		if now := time.Now(); status.NextRunAt.Before(now) {
+			status.NextRunAt = now
+			status.BehindSchedule = true
+		}
 	}
 
 	return status
 }
 
-func (s *Scheduler) sendAlert(webhookURL string, runNumber int64, consecutiveFailures int, messagesInBatch int) {
+func (s *Scheduler) sendAlert(
+	ctx context.Context,
+	webhookURL string,
+	runNumber int64,
+	consecutiveFailures int,
+	messagesInBatch int,
+) {
 	alertPayload := map[string]any{
 		"alert":               "consecutive_all_fail",
 		"runNumber":           runNumber,
 		"consecutiveFailures": consecutiveFailures,
 		"messagesInBatch":     messagesInBatch,
 		"timestamp":           time.Now().Format(time.RFC3339),
-		"message": fmt.Sprintf(
-			"All %d messages failed for %d consecutive iterations",
-			messagesInBatch,
-			consecutiveFailures,
-		),
+		"message": s.alertMessage(AlertTemplateData{
+			Queue:               s.Queue(),
+			RunNumber:           runNumber,
+			ConsecutiveFailures: consecutiveFailures,
+			MessagesInBatch:     messagesInBatch,
+		}),
 	}
 
 	jsonData, err := json.Marshal(alertPayload)
@@ -255,35 +666,182 @@ func (s *Scheduler) sendAlert(webhookURL string, runNumber int64, consecutiveFai
 		return
 	}
 
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	err = s.postWithRetries(ctx, webhookURL, jsonData, func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err == nil {
+			s.lastAlertSentAt = time.Now()
+			s.lastAlertError = ""
+		} else {
+			s.lastAlertError = err.Error()
+		}
+	})
 	if err != nil {
 		logger.Errorf("Failed to send alert to webhook: %v", err)
 		return
 	}
 
+	logger.Infof("Alert sent successfully to %s (consecutive failures: %d)", webhookURL, consecutiveFailures)
+}
+
+// sendDeadLetters posts every message the run marked failed to DeadLetterURL, one call each.
+// Failures are only logged: the messages stay failed and can be replayed either way.
+func (s *Scheduler) sendDeadLetters(ctx context.Context, webhookURL string, failed []domain.SendResult) {
+	for _, r := range failed {
+		payload := domain.NewDeadLetter(r)
+
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			logger.Errorf("Failed to marshal dead-letter payload for message %d: %v", r.MessageDBID, err)
+			continue
+		}
+
+		if err := s.postWithRetries(ctx, webhookURL, jsonData, nil); err != nil {
+			logger.Errorf("Failed to send message %d to dead-letter webhook: %v", r.MessageDBID, err)
+			continue
+		}
+
+		logger.Infof("Sent failed message %d to dead-letter webhook", r.MessageDBID)
+	}
+}
+
+// postWithRetries posts body to webhookURL, retrying up to AlertRetries times with
+// alertRetryDelay between attempts. onAttempt, if set, is called with the outcome of each attempt.
+func (s *Scheduler) postWithRetries(ctx context.Context, webhookURL string, body []byte, onAttempt func(error)) error {
+	timeout := s.AlertTimeout
+	if timeout <= 0 {
+		timeout = defaultAlertTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	for attempt := 0; ; attempt++ {
+		_, err := postAlert(ctx, client, webhookURL, body)
+		if onAttempt != nil {
+			onAttempt(err)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= s.AlertRetries {
+			return fmt.Errorf("failed after %d attempt(s): %w", attempt+1, err)
+		}
+
+		delay := s.alertRetryDelay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("no time left to retry: %w", err)
+		}
+
+		logger.Warnf("Webhook call to %s failed (attempt %d), retrying in %v: %v", webhookURL, attempt+1, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled before retrying: %w", err)
+		}
+	}
+}
+
+// alertRetryDelay is the jittered exponential backoff before retry number attempt+1: between
+// half and all of AlertRetryBackoff * 2^attempt, so retries from several schedulers spread out.
+func (s *Scheduler) alertRetryDelay(attempt int) time.Duration {
+	base := s.AlertRetryBackoff
+	if base <= 0 {
+		base = defaultAlertRetryBackoff
+	}
+
+	delay := base << min(attempt, 10)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// SendTestAlert posts a synthetic alert to webhookURL once, without retries, so operators can
+// check the alert webhook. It returns the status code the webhook answered with (0 if none).
+func SendTestAlert(ctx context.Context, webhookURL string, timeout time.Duration) (int, error) {
+	if timeout <= 0 {
+		timeout = defaultAlertTimeout
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"alert":     "test",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"message":   "Test alert sent from the scheduler API; no action needed",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal test alert payload: %w", err)
+	}
+
+	return postAlert(ctx, &http.Client{Timeout: timeout}, webhookURL, body)
+}
+
+// postAlert makes one alert call and returns the response status; anything but 200 or 204 counts
+// as a failure.
+func postAlert(ctx context.Context, client *http.Client, webhookURL string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			logger.Warnf("Failed to close alert webhook response body: %v", err)
 		}
 	}()
 
-	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
-		s.mu.Lock()
-		s.lastAlertSentAt = time.Now()
-		s.mu.Unlock()
-		logger.Infof("Alert sent successfully to %s (consecutive failures: %d)", webhookURL, consecutiveFailures)
-	} else {
-		logger.Warnf("Alert webhook returned status %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return resp.StatusCode, fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
 	}
+
+	return resp.StatusCode, nil
 }
 
 type SchedulerStatus struct {
+	Queue                   string        `json:"queue"`
 	Running                 bool          `json:"running"`
 	LastRunAt               time.Time     `json:"lastRunAt,omitempty"`
 	NextRunAt               time.Time     `json:"nextRunAt,omitempty"`
+	BehindSchedule          bool          `json:"behindSchedule"` // The planned next run is already overdue
 	MessagesSent            int64         `json:"messagesSent"`
 	RunsCount               int64         `json:"runsCount"`
 	Interval                time.Duration `json:"interval"`
 	ConsecutiveAllFailCount int           `json:"consecutiveAllFailCount"`
 	LastAlertSentAt         time.Time     `json:"lastAlertSentAt,omitempty"`
+	LastAlertError          string        `json:"lastAlertError,omitempty"`
+	IdleStopped             bool          `json:"idleStopped"`
+}
+
+// RunRecord summarizes one completed scheduler run. Runs that fail to load messages are not recorded.
+type RunRecord struct {
+	StartedAt time.Time `json:"startedAt"`
+	Processed int       `json:"processed"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+}
+
+// runHistory is a fixed-size ring buffer of the latest runs.
+type runHistory struct {
+	records []RunRecord
+	next    int
+}
+
+func (h *runHistory) add(record RunRecord) {
+	if len(h.records) < runHistorySize {
+		h.records = append(h.records, record)
+		return
+	}
+	h.records[h.next] = record
+	h.next = (h.next + 1) % runHistorySize
+}
+
+func (h *runHistory) list() []RunRecord {
+	out := make([]RunRecord, 0, len(h.records))
+	out = append(out, h.records[h.next:]...)
+	return append(out, h.records[:h.next]...)
 }
@@ -1,8 +1,20 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/onurcolak/insider-message-service/internal/domain"
@@ -13,18 +25,34 @@ type fakeProcessor struct {
 	resultsToReturn []domain.SendResult
 	errToReturn     error
 
+	mu    sync.Mutex
 	calls []processCall
 }
 
 type processCall struct {
+	Queue       string
 	FailureRate float64
 }
 
-func (f *fakeProcessor) ProcessUnsentMessages(ctx context.Context, failureRate float64) ([]domain.SendResult, error) {
-	f.calls = append(f.calls, processCall{FailureRate: failureRate})
+func (f *fakeProcessor) ProcessUnsentMessages(
+	ctx context.Context,
+	queue string,
+	failureRate float64,
+) ([]domain.SendResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, processCall{Queue: queue, FailureRate: failureRate})
 	return f.resultsToReturn, f.errToReturn
 }
 
+func (f *fakeProcessor) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.calls)
+}
+
 func TestScheduler_ProcessMessages_MixedResults(t *testing.T) {
 	ctx := context.Background()
 
@@ -91,6 +119,46 @@ func TestScheduler_ProcessMessages_AllFailIncrementsCounter(t *testing.T) {
 	}
 }
 
+func TestScheduler_FailureRetryShortensNextDelayUntilASuccess(t *testing.T) {
+	ctx := context.Background()
+
+	processor := &fakeProcessor{resultsToReturn: []domain.SendResult{{Success: false}, {Success: false}}}
+	s := &Scheduler{
+		messageService:       processor,
+		interval:             time.Hour,
+		FailureRetryInterval: time.Minute,
+	}
+
+	if got := s.nextDelay(); got != time.Hour {
+		t.Fatalf("expected the interval before any run, got %v", got)
+	}
+
+	s.processMessages(ctx)
+	if got := s.nextDelay(); got < 30*time.Second || got > time.Minute {
+		t.Fatalf("expected a retry delay between 30s and 1m after an all-failed run, got %v", got)
+	}
+
+	processor.resultsToReturn = []domain.SendResult{{Success: true}, {Success: false}}
+	s.processMessages(ctx)
+	if got := s.nextDelay(); got != time.Hour {
+		t.Fatalf("expected the interval again after a success, got %v", got)
+	}
+}
+
+func TestScheduler_FailureRetryIgnoredWhenNotShorterThanInterval(t *testing.T) {
+	s := &Scheduler{
+		messageService:       &fakeProcessor{resultsToReturn: []domain.SendResult{{Success: false}}},
+		interval:             time.Minute,
+		FailureRetryInterval: time.Hour,
+	}
+
+	s.processMessages(context.Background())
+
+	if got := s.nextDelay(); got != time.Minute {
+		t.Fatalf("expected the interval, got %v", got)
+	}
+}
+
 func TestScheduler_StartAndStopToggleRunning(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -121,3 +189,638 @@ func TestScheduler_StartAndStopToggleRunning(t *testing.T) {
 		t.Fatalf("expected scheduler to be not running after Stop")
 	}
 }
+
+func TestScheduler_IntervalBelowFloorIsClamped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &Scheduler{
+		MinInterval:    5 * time.Minute,
+		messageService: &fakeProcessor{},
+	}
+
+	if err := s.StartWithParams(ctx, 1, 0, "", 0); err != nil {
+		t.Fatalf("StartWithParams returned error: %v", err)
+	}
+	defer s.Stop()
+
+	if got := s.GetStatus().Interval; got != 5*time.Minute {
+		t.Fatalf("expected interval to be raised to 5m, got %v", got)
+	}
+
+	s.SetInterval(time.Second)
+	if got := s.GetStatus().Interval; got != 5*time.Minute {
+		t.Fatalf("expected SetInterval to clamp to 5m, got %v", got)
+	}
+
+	s.SetInterval(10 * time.Minute)
+	if got := s.GetStatus().Interval; got != 10*time.Minute {
+		t.Fatalf("expected interval above the floor to be kept, got %v", got)
+	}
+}
+
+func TestScheduler_StatusClampsOverdueNextRun(t *testing.T) {
+	s := &Scheduler{
+		running:   true,
+		interval:  time.Minute,
+		lastRunAt: time.Now().Add(-10 * time.Minute),
+	}
+
+	before := time.Now()
+	status := s.GetStatus()
+
+	if status.NextRunAt.Before(before) {
+		t.Fatalf("expected NextRunAt to be clamped to now, got %v (now %v)", status.NextRunAt, before)
+	}
+	if !status.BehindSchedule {
+		t.Fatalf("expected the scheduler to be reported behind schedule")
+	}
+
+	s.lastRunAt = time.Now()
+	if status := s.GetStatus(); status.BehindSchedule || !status.NextRunAt.After(time.Now()) {
+		t.Fatalf("expected an on-time scheduler to report its next run, got %+v", status)
+	}
+}
+
+func TestScheduler_SendAlertTimesOutOnHangingWebhook(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	s := &Scheduler{AlertTimeout: 50 * time.Millisecond}
+
+	done := make(chan struct{})
+	go func() {
+		s.sendAlert(context.Background(), srv.URL, 1, 3, 2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("sendAlert did not return after its timeout")
+	}
+
+	if !strings.Contains(logs.String(), "Failed to send alert") {
+		t.Errorf("expected alert failure to be logged, got %q", logs.String())
+	}
+	if !s.GetStatus().LastAlertSentAt.IsZero() {
+		t.Errorf("expected LastAlertSentAt to stay zero on failure")
+	}
+}
+
+func TestScheduler_SendAlertRetriesUntilItLands(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := &Scheduler{AlertTimeout: time.Second, AlertRetries: 3, AlertRetryBackoff: time.Millisecond}
+	s.sendAlert(context.Background(), srv.URL, 1, 3, 2)
+
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected the alert to land on the third call, got %d calls", got)
+	}
+	status := s.GetStatus()
+	if status.LastAlertSentAt.IsZero() || status.LastAlertError != "" {
+		t.Fatalf("expected status to reflect the successful alert, got %+v", status)
+	}
+}
+
+func TestScheduler_SendAlertRecordsLastErrorWhenRetriesRunOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s := &Scheduler{AlertTimeout: time.Second, AlertRetries: 1, AlertRetryBackoff: time.Millisecond}
+	s.sendAlert(context.Background(), srv.URL, 1, 3, 2)
+
+	status := s.GetStatus()
+	if !strings.Contains(status.LastAlertError, "503") || !status.LastAlertSentAt.IsZero() {
+		t.Fatalf("expected the failure to be recorded, got %+v", status)
+	}
+}
+
+func TestScheduler_SendAlertRendersMessageTemplate(t *testing.T) {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tmpl, err := ParseAlertTemplate("[{{.Queue}}] run #{{.RunNumber}}: {{.MessagesInBatch}} sends failed, {{.ConsecutiveFailures}} runs in a row")
+	if err != nil {
+		t.Fatalf("ParseAlertTemplate returned error: %v", err)
+	}
+
+	s := &Scheduler{queue: "transactional", AlertTimeout: time.Second, AlertTemplate: tmpl}
+	s.sendAlert(context.Background(), srv.URL, 42, 3, 7)
+
+	want := "[transactional] run #42: 7 sends failed, 3 runs in a row"
+	if payload.Message != want {
+		t.Fatalf("expected message %q, got %q", want, payload.Message)
+	}
+}
+
+func TestParseAlertTemplate_RejectsUnknownField(t *testing.T) {
+	if _, err := ParseAlertTemplate("{{.Failures}} failed runs"); err == nil {
+		t.Fatalf("expected an unknown field to be rejected")
+	}
+	if _, err := ParseAlertTemplate("{{.RunNumber"); err == nil {
+		t.Fatalf("expected a malformed template to be rejected")
+	}
+}
+
+func TestAlertMessage_FallsBackToDefaultWhenRenderingFails(t *testing.T) {
+	// Parses, but fails to render for queue names shorter than ten bytes.
+	tmpl := template.Must(template.New("alert").Parse("{{index .Queue 9}}"))
+
+	s := &Scheduler{AlertTemplate: tmpl}
+	got := s.alertMessage(AlertTemplateData{Queue: "default", ConsecutiveFailures: 3, MessagesInBatch: 7})
+
+	if want := "All 7 messages failed for 3 consecutive iterations"; got != want {
+		t.Fatalf("expected the default message %q, got %q", want, got)
+	}
+}
+
+func TestScheduler_HistoryKeepsMostRecentRuns(t *testing.T) {
+	ctx := context.Background()
+
+	processor := &fakeProcessor{
+		resultsToReturn: []domain.SendResult{{Success: true}, {Success: false}},
+	}
+	s := &Scheduler{
+		messageService: processor,
+		interval:       time.Minute,
+	}
+
+	for i := 0; i < runHistorySize+5; i++ {
+		s.processMessages(ctx)
+	}
+
+	history := s.History()
+	if len(history) != runHistorySize {
+		t.Fatalf("expected %d runs in history, got %d", runHistorySize, len(history))
+	}
+
+	last := history[len(history)-1]
+	if last.Processed != 2 || last.Succeeded != 1 || last.Failed != 1 {
+		t.Errorf("unexpected last run record: %+v", last)
+	}
+}
+
+func TestRunHistory_WrapsOldestFirst(t *testing.T) {
+	var h runHistory
+	for i := 0; i < runHistorySize+5; i++ {
+		h.add(RunRecord{Processed: i})
+	}
+
+	records := h.list()
+	if records[0].Processed != 5 {
+		t.Errorf("expected oldest kept run to be #5, got #%d", records[0].Processed)
+	}
+	if records[len(records)-1].Processed != runHistorySize+4 {
+		t.Errorf("expected newest run to be #%d, got #%d", runHistorySize+4, records[len(records)-1].Processed)
+	}
+}
+
+func TestScheduler_NamedQueuesAreIndependent(t *testing.T) {
+	ctx := context.Background()
+
+	transactionalProcessor := &fakeProcessor{
+		resultsToReturn: []domain.SendResult{{MessageDBID: 1, Success: true}, {MessageDBID: 3, Success: true}},
+	}
+	promotionalProcessor := &fakeProcessor{
+		resultsToReturn: []domain.SendResult{{MessageDBID: 2, Success: true}},
+	}
+
+	transactional := &Scheduler{messageService: transactionalProcessor, queue: "transactional", interval: time.Minute}
+	promotional := &Scheduler{messageService: promotionalProcessor, queue: "promotional", interval: time.Hour}
+
+	transactional.processMessages(ctx)
+	transactional.processMessages(ctx)
+	promotional.processMessages(ctx)
+
+	for _, call := range transactionalProcessor.calls {
+		if call.Queue != "transactional" {
+			t.Errorf("transactional scheduler processed queue %q", call.Queue)
+		}
+	}
+	for _, call := range promotionalProcessor.calls {
+		if call.Queue != "promotional" {
+			t.Errorf("promotional scheduler processed queue %q", call.Queue)
+		}
+	}
+
+	tStatus, pStatus := transactional.GetStatus(), promotional.GetStatus()
+	if tStatus.Queue != "transactional" || tStatus.RunsCount != 2 || tStatus.MessagesSent != 4 {
+		t.Errorf("unexpected transactional status: %+v", tStatus)
+	}
+	if pStatus.Queue != "promotional" || pStatus.RunsCount != 1 || pStatus.MessagesSent != 1 {
+		t.Errorf("unexpected promotional status: %+v", pStatus)
+	}
+}
+
+func TestScheduler_DefaultQueueWhenUnnamed(t *testing.T) {
+	processor := &fakeProcessor{}
+	s := &Scheduler{messageService: processor, interval: time.Minute}
+
+	s.processMessages(context.Background())
+
+	if len(processor.calls) != 1 || processor.calls[0].Queue != domain.DefaultQueue {
+		t.Fatalf("expected one call for the default queue, got %+v", processor.calls)
+	}
+}
+
+func TestScheduler_StartDelayPostponesFirstRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processor := &fakeProcessor{}
+	s := &Scheduler{
+		StartDelay:     100 * time.Millisecond,
+		messageService: processor,
+		interval:       time.Hour,
+	}
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	time.Sleep(30 * time.Millisecond)
+	if n := processor.callCount(); n != 0 {
+		t.Fatalf("expected no processing during the start delay, got %d runs", n)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for processor.callCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the first run after the start delay elapsed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_StopDuringStartDelaySkipsFirstRun(t *testing.T) {
+	processor := &fakeProcessor{}
+	s := &Scheduler{
+		StartDelay:     time.Hour,
+		messageService: processor,
+		interval:       time.Hour,
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	if n := processor.callCount(); n != 0 {
+		t.Fatalf("expected no runs, got %d", n)
+	}
+}
+
+func waitUntil(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_IdleStopAfterConsecutiveEmptyRuns(t *testing.T) {
+	processor := &fakeProcessor{} // nil results: nothing pending
+	s := &Scheduler{
+		IdleStopRuns:   3,
+		messageService: processor,
+		interval:       5 * time.Millisecond,
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	waitUntil(t, func() bool { return !s.IsRunning() }, "expected scheduler to auto-stop when idle")
+
+	if n := processor.callCount(); n != 3 {
+		t.Fatalf("expected exactly 3 runs before auto-stop, got %d", n)
+	}
+	if !s.GetStatus().IdleStopped {
+		t.Fatal("expected status to report the idle stop")
+	}
+}
+
+func TestScheduler_HeldRunsAreNotIdle(t *testing.T) {
+	for _, heldErr := range []error{domain.ErrQuietHours, domain.ErrNotDue, domain.ErrSendingDisabled} {
+		processor := &fakeProcessor{errToReturn: fmt.Errorf("%w: queue %q", heldErr, domain.DefaultQueue)}
+		s := &Scheduler{
+			IdleStopRuns:   1,
+			messageService: processor,
+			interval:       5 * time.Millisecond,
+		}
+
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatalf("Start returned error: %v", err)
+		}
+		waitUntil(t, func() bool { return processor.callCount() >= 3 }, "expected the scheduler to keep running")
+
+		if !s.IsRunning() || s.GetStatus().IdleStopped {
+			t.Errorf("%v: expected held runs not to idle-stop the scheduler", heldErr)
+		}
+		_ = s.Stop()
+	}
+}
+
+func TestScheduler_WakeRestartsIdleStoppedScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processor := &fakeProcessor{}
+	s := &Scheduler{
+		IdleStopRuns:   1,
+		messageService: processor,
+		interval:       time.Hour,
+	}
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	waitUntil(t, func() bool { return !s.IsRunning() }, "expected scheduler to auto-stop when idle")
+
+	// New work arrives.
+	processor.mu.Lock()
+	processor.resultsToReturn = []domain.SendResult{{MessageDBID: 1, Success: true}}
+	processor.mu.Unlock()
+
+	if err := s.Wake(ctx); err != nil {
+		t.Fatalf("Wake returned error: %v", err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	if !s.IsRunning() {
+		t.Fatal("expected Wake to restart the idle scheduler")
+	}
+	waitUntil(t, func() bool { return processor.callCount() == 2 }, "expected a run right after waking")
+}
+
+func TestScheduler_WakeLeavesManuallyStoppedSchedulerAlone(t *testing.T) {
+	s := &Scheduler{
+		IdleStopRuns:   1,
+		messageService: &fakeProcessor{resultsToReturn: []domain.SendResult{{Success: true}}},
+		interval:       time.Hour,
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	if err := s.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake returned error: %v", err)
+	}
+	if s.IsRunning() {
+		t.Fatal("expected a manually stopped scheduler to stay stopped")
+	}
+}
+
+func TestScheduler_StopKeepsIdleStoppedSchedulerStopped(t *testing.T) {
+	store := &fakeStateStore{}
+	s := &Scheduler{
+		Store:          store,
+		IdleStopRuns:   1,
+		messageService: &fakeProcessor{},
+		interval:       time.Hour,
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	waitUntil(t, func() bool { return !s.IsRunning() }, "expected scheduler to auto-stop when idle")
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if s.GetStatus().IdleStopped {
+		t.Fatal("expected Stop to clear the idle stop")
+	}
+	if state, _ := store.Load(context.Background(), s.Queue()); state == nil || state.Running {
+		t.Fatalf("expected a saved, stopped state, got %+v", state)
+	}
+
+	if err := s.Wake(context.Background()); err != nil {
+		t.Fatalf("Wake returned error: %v", err)
+	}
+	if s.IsRunning() {
+		t.Fatal("expected a stopped scheduler to stay stopped when woken")
+	}
+}
+
+// fakeStateStore keeps saved scheduler states in memory, keyed by queue.
+type fakeStateStore struct {
+	mu     sync.Mutex
+	states map[string]domain.SchedulerState
+}
+
+func (f *fakeStateStore) Save(ctx context.Context, state domain.SchedulerState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.states == nil {
+		f.states = make(map[string]domain.SchedulerState)
+	}
+	f.states[state.Queue] = state
+	return nil
+}
+
+func (f *fakeStateStore) Load(ctx context.Context, queue string) (*domain.SchedulerState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.states[queue]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// fakeRunStore keeps saved runs in memory, keyed by queue and run number.
+type fakeRunStore struct {
+	mu   sync.Mutex
+	runs map[string]domain.SchedulerRun
+}
+
+func (f *fakeRunStore) SaveRun(ctx context.Context, run domain.SchedulerRun) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.runs == nil {
+		f.runs = make(map[string]domain.SchedulerRun)
+	}
+	f.runs[fmt.Sprintf("%s/%d", run.Queue, run.RunNumber)] = run
+	return nil
+}
+
+func (f *fakeRunStore) GetRun(ctx context.Context, queue string, runNumber int64) (*domain.SchedulerRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	run, ok := f.runs[fmt.Sprintf("%s/%d", queue, runNumber)]
+	if !ok {
+		return nil, domain.ErrRunNotFound
+	}
+	return &run, nil
+}
+
+func TestScheduler_CompletedRunResultsAreRetrievable(t *testing.T) {
+	ctx := context.Background()
+	processor := &fakeProcessor{resultsToReturn: []domain.SendResult{
+		{MessageDBID: 1, MessageID: "abc", Success: true, Attempts: 1},
+		{MessageDBID: 2, Success: false, Error: errors.New("webhook returned status 500"), Attempts: 3},
+	}}
+	s := &Scheduler{Runs: &fakeRunStore{}, messageService: processor, interval: time.Minute}
+
+	s.processMessages(ctx)
+
+	run, err := s.GetRun(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetRun returned error: %v", err)
+	}
+	if run.Queue != domain.DefaultQueue || len(run.Results) != 2 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if got := run.Results[0]; !got.Success || got.MessageID != "abc" {
+		t.Errorf("unexpected first result: %+v", got)
+	}
+	if got := run.Results[1]; got.Success || got.Error != "webhook returned status 500" || got.Attempts != 3 {
+		t.Errorf("unexpected second result: %+v", got)
+	}
+	if run.RetryBudgetUsed != 2 {
+		t.Errorf("expected the run to report 2 retries drawn from the budget, got %d", run.RetryBudgetUsed)
+	}
+
+	if _, err := s.GetRun(ctx, 2); !errors.Is(err, domain.ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound for a run that has not happened, got %v", err)
+	}
+}
+
+func TestScheduler_StateSurvivesRestart(t *testing.T) {
+	store := &fakeStateStore{}
+	processor := &fakeProcessor{resultsToReturn: []domain.SendResult{{Success: true}, {Success: true}}}
+
+	before := &Scheduler{Store: store, messageService: processor, interval: time.Hour}
+	if err := before.StartWithParams(context.Background(), 5, 0.25, "", 0); err != nil {
+		t.Fatalf("StartWithParams returned error: %v", err)
+	}
+	waitUntil(t, func() bool { return before.GetStatus().RunsCount == 1 }, "expected the first run")
+	if err := before.Stop(); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	// Simulated restart: a fresh scheduler backed by the same store.
+	after := &Scheduler{Store: store, messageService: processor, interval: time.Hour}
+	state, err := after.Restore(context.Background())
+	if err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	if state == nil || state.Running {
+		t.Fatalf("expected a saved, stopped state, got %+v", state)
+	}
+
+	status := after.GetStatus()
+	if status.Interval != 5*time.Minute || status.RunsCount != 1 || status.MessagesSent != 2 {
+		t.Fatalf("expected interval and counters to be restored, got %+v", status)
+	}
+	if after.failureRate != 0.25 {
+		t.Fatalf("expected failure rate 0.25, got %v", after.failureRate)
+	}
+}
+
+func TestScheduler_ShutdownKeepsRunningIntent(t *testing.T) {
+	store := &fakeStateStore{}
+	s := &Scheduler{Store: store, messageService: &fakeProcessor{}, interval: time.Hour}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	state, err := (&Scheduler{Store: store}).Restore(context.Background())
+	if err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	if state == nil || !state.Running {
+		t.Fatalf("expected the scheduler to resume after a restart, got %+v", state)
+	}
+}
+
+func TestScheduler_FailedMessagesArePostedToDeadLetterWebhook(t *testing.T) {
+	received := make(chan domain.DeadLetter, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var dl domain.DeadLetter
+		if err := json.NewDecoder(r.Body).Decode(&dl); err != nil {
+			t.Errorf("failed to decode dead-letter payload: %v", err)
+		}
+		received <- dl
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	processor := &fakeProcessor{
+		resultsToReturn: []domain.SendResult{
+			{MessageDBID: 1, Success: true},
+			{MessageDBID: 2, PhoneNumber: "+905551234567", Content: "hello", Error: errors.New("webhook returned 500")},
+		},
+	}
+	s := &Scheduler{
+		messageService: processor,
+		interval:       time.Minute,
+		AlertTimeout:   time.Second,
+		DeadLetterURL:  srv.URL,
+	}
+
+	s.processMessages(context.Background())
+
+	select {
+	case dl := <-received:
+		if dl.ID != 2 || dl.PhoneNumber != "+905551234567" || dl.Content != "hello" || dl.LastError != "webhook returned 500" {
+			t.Fatalf("unexpected dead-letter payload: %+v", dl)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the failed message to be posted to the dead-letter webhook")
+	}
+
+	select {
+	case dl := <-received:
+		t.Fatalf("expected only the failed message to be posted, also got %+v", dl)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
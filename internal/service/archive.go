@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+)
+
+// ObjectStore stores archive files, e.g. an S3-compatible bucket.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+}
+
+// SetArchiveStore enables ArchiveSentMessages, uploading to store under keys starting with prefix.
+func (s *MessageService) SetArchiveStore(store ObjectStore, prefix string) {
+	s.archive = store
+	s.archivePrefix = prefix
+}
+
+// ArchiveSentMessages uploads every message sent more than olderThan ago to the object store as
+// one JSON Lines file. Rows are streamed to a temporary file rather than held in memory, since
+// the store needs the size up front. With purge, the archived rows are deleted, but only once
+// the upload succeeded.
+func (s *MessageService) ArchiveSentMessages(ctx context.Context, olderThan time.Duration, purge bool) (*domain.ArchiveResult, error) {
+	if s.archive == nil {
+		return nil, domain.ErrArchiveNotConfigured
+	}
+
+	result := &domain.ArchiveResult{Cutoff: s.now().UTC().Add(-olderThan)}
+
+	file, err := os.CreateTemp("", "messages-archive-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+
+	// Ids are streamed in ascending order, so the last one bounds what the purge may delete.
+	var maxID int64
+	sent := domain.StatusSent
+	filter := domain.MessageFilter{Status: &sent, SentBefore: &result.Cutoff}
+	err = s.repo.StreamMessages(ctx, filter, math.MaxInt64, func(msg domain.Message) error {
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("failed to write archive line: %w", err)
+		}
+		result.Archived++
+		maxID = msg.ID
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive sent messages: %w", err)
+	}
+
+	if result.Archived == 0 {
+		return result, nil
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write archive file: %w", err)
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to size archive file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind archive file: %w", err)
+	}
+
+	key := s.archivePrefix + "messages-sent-before-" + result.Cutoff.Format("20060102T150405Z") + ".jsonl"
+	if err := s.archive.PutObject(ctx, key, file, size, "application/x-ndjson"); err != nil {
+		return nil, fmt.Errorf("failed to upload archive: %w", err)
+	}
+	result.Key = key
+
+	logger.Infof("Archived %d sent messages to %s", result.Archived, key)
+
+	if purge {
+		purged, err := s.repo.DeleteArchivedSent(ctx, result.Cutoff, maxID)
+		if err != nil {
+			return result, fmt.Errorf("archive uploaded to %s but purge failed: %w", key, err)
+		}
+		result.Purged = purged
+	}
+
+	return result, nil
+}
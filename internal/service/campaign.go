@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+// CreateCampaign renders the campaign template for every recipient and stores the results as
+// pending messages sharing a new campaign id. Nothing is stored if the template or any recipient
+// is invalid.
+func (s *MessageService) CreateCampaign(ctx context.Context, campaign domain.NewCampaign) (*domain.CampaignResult, error) {
+	tmpl, err := template.New("campaign").Option("missingkey=error").Parse(campaign.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	campaignID, err := newCampaignID()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]domain.NewMessage, 0, len(campaign.Recipients))
+	for i, recipient := range campaign.Recipients {
+		var content strings.Builder
+		if err := tmpl.Execute(&content, recipient.Variables); err != nil {
+			return nil, fmt.Errorf("%w: recipient %d: %w", domain.ErrInvalidTemplate, i, err)
+		}
+
+		msg := domain.NewMessage{
+			Content:     content.String(),
+			PhoneNumber: recipient.PhoneNumber,
+			Queue:       campaign.Queue,
+			CampaignID:  campaignID,
+		}
+		if err := s.prepareNewMessage(&msg); err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i, err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	created, err := s.repo.CreateCampaign(ctx, campaignID, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCreated(campaign.Queue)
+	return &domain.CampaignResult{CampaignID: campaignID, Created: created}, nil
+}
+
+// newCampaignID returns 32 random hex characters.
+func newCampaignID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate campaign id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// ContentTransformer rewrites message content right before it is sent, e.g. to shorten URLs.
+// The stored message keeps its original content.
+type ContentTransformer interface {
+	Transform(ctx context.Context, content string) (string, error)
+}
+
+// NoopTransformer sends content unchanged. It is the default transformer.
+type NoopTransformer struct{}
+
+func (NoopTransformer) Transform(_ context.Context, content string) (string, error) {
+	return content, nil
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// shortCodeLength is the number of hex characters of the URL hash used as the short code.
+const shortCodeLength = 8
+
+// URLShortener replaces URLs longer than MinLength with BaseURL plus a short code derived from
+// the URL. It is a stub: it does not register the codes anywhere, so resolving them is left to
+// whatever service BaseURL points at.
+type URLShortener struct {
+	BaseURL   string // e.g. "https://sho.rt"
+	MinLength int    // URLs at or below this length are left alone
+}
+
+func (s URLShortener) Transform(_ context.Context, content string) (string, error) {
+	base := strings.TrimSuffix(s.BaseURL, "/")
+
+	return urlPattern.ReplaceAllStringFunc(content, func(url string) string {
+		short := base + "/" + shortCode(url)
+		if len(url) <= s.MinLength || len(url) <= len(short) {
+			return url
+		}
+		return short
+	}), nil
+}
+
+func shortCode(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])[:shortCodeLength]
+}
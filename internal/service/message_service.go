@@ -2,29 +2,73 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/onurcolak/insider-message-service/environments"
 	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/idempotency"
 	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/onurcolak/insider-message-service/pkg/metrics"
+	"github.com/onurcolak/insider-message-service/pkg/phone"
+	"github.com/onurcolak/insider-message-service/pkg/sms"
 )
 
 // Small internal interfaces so we can test without touching real DB/Redis/webhook.
 type messageRepository interface {
-	GetUnsent(ctx context.Context, limit int) ([]domain.Message, error)
-	MarkAsSent(ctx context.Context, id int64, messageID string, sentAt time.Time) error
-	MarkAsFailed(ctx context.Context, id int64) error
+	GetUnsent(ctx context.Context, queue string, limit int) ([]domain.Message, error)
+	GetUnsentAfter(ctx context.Context, queue string, after *domain.UnsentCursor, limit int) ([]domain.Message, error)
+	HasScheduled(ctx context.Context, queue string) (bool, error)
+	MarkAsSent(ctx context.Context, id int64, messageID string, sentAt time.Time, originalLength *int) error
+	MarkAsFailed(ctx context.Context, id int64, reason string) error
+	MarkForRetry(ctx context.Context, id int64, reason string) error
+	MarkAsDeduped(ctx context.Context, id int64) error
+	MarkAsSuppressed(ctx context.Context, id int64) error
+	SuppressPending(ctx context.Context, queue string) (int64, error)
+	IsSuppressed(ctx context.Context, phoneNumber string) (bool, error)
+	AddSuppression(ctx context.Context, phoneNumber string, reason *string) (*domain.Suppression, int64, error)
+	RemoveSuppression(ctx context.Context, phoneNumber string) error
+	IsSendingEnabled(ctx context.Context) (bool, error)
+	SetSendingEnabled(ctx context.Context, enabled bool) error
 
-	GetSent(ctx context.Context, page, pageSize int) ([]domain.Message, int64, error)
-	Create(ctx context.Context, content, phoneNumber string) (*domain.Message, error)
-	GetAll(ctx context.Context, status *domain.MessageStatus, page, pageSize int) ([]domain.Message, int64, error)
+	GetSent(ctx context.Context, filter domain.MessageFilter, page, pageSize int) ([]domain.Message, int64, error)
+	Create(ctx context.Context, msg domain.NewMessage) (*domain.Message, error)
+	CreateCampaign(ctx context.Context, campaignID string, msgs []domain.NewMessage) (int64, error)
+	GetAll(ctx context.Context, filter domain.MessageFilter, page, pageSize int) ([]domain.Message, int64, error)
+	Search(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error)
+	StreamMessages(ctx context.Context, filter domain.MessageFilter, limit int, fn func(domain.Message) error) error
+	ForEachPending(ctx context.Context, fn func(phoneNumber, content string) error) error
 	GetStats(ctx context.Context) (pending, sent, failed int64, err error)
+	GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error)
+	GetChangedSince(ctx context.Context, after domain.ChangeCursor, limit int) ([]domain.Message, error)
+	FailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error)
+	SendsPerHour(ctx context.Context, window time.Duration) ([]domain.HourlySends, error)
+	SLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error)
+	DeleteArchivedSent(ctx context.Context, cutoff time.Time, maxID int64) (int64, error)
+	GetGroupedCounts(ctx context.Context) ([]domain.StatusCount, error)
+	BackfillSentAt(ctx context.Context) (int64, error)
+	NormalizePendingPhones(ctx context.Context, normalize func(string) (string, bool)) (domain.PhoneNormalizationResult, error)
+	GetByID(ctx context.Context, id int64) (*domain.Message, error)
+	GetByMessageID(ctx context.Context, messageID string) (*domain.Message, error)
+	UpdateDeliveryStatus(ctx context.Context, id int64, status domain.DeliveryStatus) error
+	CancelPending(ctx context.Context, filter domain.CancelFilter) (int64, error)
+	ForceFail(ctx context.Context, id int64, reason string) (*domain.Message, error)
+	ResetRetries(ctx context.Context, id int64) (*domain.Message, error)
+	BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error)
 
 	// new
 	ReplayFailedByID(ctx context.Context, id int64) error
-	ReplayAllFailed(ctx context.Context) (int64, error)
+	ReplayAllFailed(ctx context.Context, age domain.ReplayAge) (int64, error)
+	ReplayFailedInWindow(ctx context.Context, from, to time.Time) (int64, error)
 }
 
 type webhookClient interface {
@@ -33,7 +77,11 @@ type webhookClient interface {
 
 type redisClient interface {
 	CacheSentMessage(ctx context.Context, dbID int64, messageID string, sentAt time.Time) error
+	CacheSentMessages(ctx context.Context, entries map[int64]*domain.SentMessageCache) error
 	GetAllCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error)
+	GetCachedMessages(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error)
+	IsRecentSend(ctx context.Context, fingerprint string) (bool, error)
+	RememberSend(ctx context.Context, fingerprint string, ttl time.Duration) error
 }
 
 type MessageService struct {
@@ -41,6 +89,43 @@ type MessageService struct {
 	webhookClient webhookClient
 	redisClient   redisClient
 	config        environments.MessageConfig
+
+	// rng drives failure simulation; guarded by rngMu since *rand.Rand is not safe for concurrent use.
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	// quietHours holds messages back during a daily window (nil = always send).
+	quietHours *quietHours
+	now        func() time.Time
+
+	// onCreate is called with the queue of every newly created message, e.g. to wake idle schedulers.
+	onCreate func(queue string)
+
+	// transformer rewrites content before it is sent, ahead of the max length check.
+	transformer ContentTransformer
+
+	// batchCacheWrites collects the cache writes of a run and pipelines them when it ends.
+	batchCacheWrites bool
+
+	// activeBatches holds the queues a batch is being sent from, so scheduler-initiated and
+	// manual runs never send from the same queue at the same time.
+	batchMu       sync.Mutex
+	activeBatches map[string]bool
+
+	// archive receives archived sent messages (nil = archiving disabled).
+	archive ObjectStore
+	// archivePrefix is prepended to archive object keys.
+	archivePrefix string
+
+	// metrics receives per-run and per-message counters (nil = in-memory metrics disabled).
+	metrics *metrics.Registry
+
+	// Outcome counters reported by OutcomeCounts.
+	truncated             atomic.Int64
+	quietHoursSkippedRuns atomic.Int64
+	sendCapSkipped        atomic.Int64
+	deduped               atomic.Int64
+	suppressed            atomic.Int64
 }
 
 func NewMessageService(
@@ -49,102 +134,376 @@ func NewMessageService(
 	redisClient redisClient,
 	config environments.MessageConfig,
 ) *MessageService {
+	seed := uint64(time.Now().UnixNano())
+	if config.FailureSeed != 0 {
+		seed = uint64(config.FailureSeed)
+	}
+
+	quiet, err := parseQuietHours(config)
+	if err != nil {
+		logger.Warnf("Quiet hours disabled: %v", err)
+	}
+
 	return &MessageService{
 		repo:          repo,
 		webhookClient: webhookClient,
 		redisClient:   redisClient,
 		config:        config,
+		rng:           rand.New(rand.NewPCG(seed, 0)),
+		quietHours:    quiet,
+		now:           time.Now,
+		transformer:   NoopTransformer{},
+		activeBatches: make(map[string]bool),
 	}
 }
 
-func (s *MessageService) ProcessUnsentMessages(ctx context.Context, failureRate float64) ([]domain.SendResult, error) {
-	messages, err := s.repo.GetUnsent(ctx, s.config.BatchSize)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get unsent messages: %w", err)
+// SetCreateHook registers a callback run after each message is created. It must be set before
+// the service starts handling requests.
+func (s *MessageService) SetCreateHook(hook func(queue string)) {
+	s.onCreate = hook
+}
+
+// SetContentTransformer replaces the transformer applied to content before sending; nil restores
+// the no-op default.
+func (s *MessageService) SetContentTransformer(t ContentTransformer) {
+	if t == nil {
+		t = NoopTransformer{}
+	}
+	s.transformer = t
+}
+
+// SetBatchCacheWrites makes SendBatch cache its successful sends in one Redis pipeline at the
+// end of the run instead of one round trip per send.
+func (s *MessageService) SetBatchCacheWrites(batch bool) {
+	s.batchCacheWrites = batch
+}
+
+// SetMetrics records sends, failures, runs and provider latency in registry.
+func (s *MessageService) SetMetrics(registry *metrics.Registry) {
+	s.metrics = registry
+}
+
+// SetClock replaces the time source used for quiet hours, so tests can control it.
+func (s *MessageService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetRandSource replaces the generator used for failure simulation, e.g. with a
+// fixed-seed source so a run can be reproduced.
+func (s *MessageService) SetRandSource(src rand.Source) {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	s.rng = rand.New(src)
+}
+
+func (s *MessageService) randFloat64() float64 {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	return s.rng.Float64()
+}
+
+// unsentChunkSize bounds how many messages are loaded at once, so a large BatchSize
+// is worked through in several smaller fetches instead of one big slice.
+const unsentChunkSize = 500
+
+// BatchOptions changes how SendBatch reacts to failed messages.
+type BatchOptions struct {
+	// StopOnFirstError ends the batch at the first message that fails, returning the results so
+	// far with an error wrapping domain.ErrBatchAborted. The failed message is handled as usual,
+	// i.e. marked failed or left pending for a retry under MaxRetries; the rest stay pending.
+	// Deduped and suppressed messages are skips, not failures. Off by default, so a run is
+	// best-effort.
+	StopOnFirstError bool
+}
+
+// ProcessUnsentMessages sends up to BatchSize pending messages from the given queue, carrying on
+// past failed messages.
+func (s *MessageService) ProcessUnsentMessages(
+	ctx context.Context,
+	queue string,
+	failureRate float64,
+) ([]domain.SendResult, error) {
+	return s.SendBatch(ctx, queue, failureRate, BatchOptions{})
+}
+
+// SendBatch sends up to BatchSize pending messages from the given queue, as configured by opts.
+// Only one batch runs per queue at a time; a second caller gets domain.ErrBatchInProgress.
+// A run that sends nothing although messages are waiting returns domain.ErrQuietHours or
+// domain.ErrNotDue rather than no results, so it is not mistaken for an empty queue.
+func (s *MessageService) SendBatch(
+	ctx context.Context,
+	queue string,
+	failureRate float64,
+	opts BatchOptions,
+) ([]domain.SendResult, error) {
+	if !s.beginBatch(queue) {
+		return nil, fmt.Errorf("%w: %q", domain.ErrBatchInProgress, queue)
+	}
+	defer s.endBatch(queue)
+
+	if s.quietHours != nil && s.quietHours.contains(s.now()) {
+		logger.Infof("Quiet hours in effect, leaving queue %q pending", queue)
+		s.quietHoursSkippedRuns.Add(1)
+		return nil, fmt.Errorf("%w: queue %q", domain.ErrQuietHours, queue)
+	}
+
+	s.metrics.Inc("runs")
+
+	var results []domain.SendResult
+	budget := &retryBudget{limit: s.config.RetryBudget}
+
+	var cacheBatch sentCacheBatch
+	if s.batchCacheWrites && s.redisClient != nil {
+		cacheBatch = make(sentCacheBatch)
+		defer s.flushSentCache(ctx, cacheBatch)
 	}
+	sends := 0
+	attempted := 0
+
+	// Each chunk continues after the last message fetched, since messages that failed but are
+	// retried later, or could not be marked sent, are still pending and must not be sent twice.
+	var after *domain.UnsentCursor
+	remaining := s.config.BatchSize
+	for remaining > 0 && ctx.Err() == nil && !s.sendCapReached(sends) {
+		limit := min(remaining, unsentChunkSize)
+
+		// The kill switch is read once per chunk; a chunk under way is finished.
+		if !s.sendingEnabled(ctx) {
+			logger.Warnf("Sending is disabled by the kill switch, leaving queue %q pending", queue)
+			if len(results) == 0 {
+				return nil, fmt.Errorf("%w: queue %q", domain.ErrSendingDisabled, queue)
+			}
+			break
+		}
+		if after == nil {
+			s.suppressPending(ctx, queue)
+		}
+
+		messages, err := s.repo.GetUnsentAfter(ctx, queue, after, limit)
+		if err != nil {
+			if len(results) == 0 {
+				return nil, fmt.Errorf("failed to get unsent messages: %w", err)
+			}
+			logger.Errorf("Failed to get next chunk of unsent messages, stopping run early: %v", err)
+			break
+		}
+
+		if len(messages) == 0 {
+			break
+		}
+
+		logger.Infof("Processing %d unsent messages from queue %q", len(messages), queue)
+
+		for i, msg := range messages {
+			if s.sendCapReached(sends) {
+				logger.Infof("Reached %d sends for this run, leaving the rest pending", s.config.MaxSendsPerRun)
+				s.sendCapSkipped.Add(int64(len(messages) - i))
+				break
+			}
+
+			if attempted > 0 && !s.waitSendDelay(ctx) {
+				logger.Infof("Run cancelled between sends, leaving the rest pending")
+				break
+			}
+			attempted++
+
+			shouldFail := s.randFloat64() < failureRate
+
+			result := s.deliverMessage(ctx, &msg, shouldFail, budget, cacheBatch)
+			s.recordMetrics(result)
+			results = append(results, result)
+			if result.Success {
+				sends++
+			} else if opts.StopOnFirstError && result.Error != nil && !result.Deduped && !result.Suppressed {
+				return results, fmt.Errorf("%w: message %d: %w", domain.ErrBatchAborted, msg.ID, result.Error)
+			}
+		}
+
+		remaining -= len(messages)
+		last := messages[len(messages)-1]
+		after = &domain.UnsentCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+
+		// A short chunk means the queue is drained.
+		if len(messages) < limit {
+			break
+		}
+	}
+
+	s.metrics.Set("last_run_messages", float64(len(results)))
+
+	if len(results) == 0 {
+		scheduled, err := s.repo.HasScheduled(ctx, queue)
+		if err != nil {
+			return nil, err
+		}
+		if scheduled {
+			logger.Debugf("No messages of queue %q are due yet", queue)
+			return nil, fmt.Errorf("%w: queue %q", domain.ErrNotDue, queue)
+		}
 
-	if len(messages) == 0 {
 		logger.Debugf("No unsent messages to process")
 		return nil, nil
 	}
 
-	logger.Infof("Processing %d unsent messages", len(messages))
+	if budget.used > 0 {
+		logger.Infof("Retry budget consumed: %d (limit: %d)", budget.used, budget.limit)
+	}
 
-	results := make([]domain.SendResult, 0, len(messages))
+	return results, nil
+}
 
-	for _, msg := range messages {
-		shouldFail := rand.Float64() < failureRate
+// beginBatch marks queue as busy and reports false if a batch is already running for it.
+func (s *MessageService) beginBatch(queue string) bool {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
 
-		result := s.deliverMessage(ctx, &msg, shouldFail)
-		results = append(results, result)
+	if s.activeBatches[queue] {
+		return false
 	}
+	s.activeBatches[queue] = true
+	return true
+}
 
-	return results, nil
+func (s *MessageService) endBatch(queue string) {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	delete(s.activeBatches, queue)
+}
+
+// waitSendDelay pauses for SendDelay between two sends, so bursts are spread out for providers
+// that throttle them. It reports false if ctx is done first.
+func (s *MessageService) waitSendDelay(ctx context.Context) bool {
+	if s.config.SendDelay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(s.config.SendDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendCapReached reports whether MaxSendsPerRun successful sends have been made in this run.
+func (s *MessageService) sendCapReached(sends int) bool {
+	return s.config.MaxSendsPerRun > 0 && sends >= s.config.MaxSendsPerRun
+}
+
+// retryBudget caps the total number of webhook retries spent during a single run,
+// so a provider outage can't turn a batch into a retry storm.
+type retryBudget struct {
+	limit int // 0 = unlimited
+	used  int
+}
+
+func (b *retryBudget) take() bool {
+	if b.limit > 0 && b.used >= b.limit {
+		return false
+	}
+	b.used++
+	return true
 }
 
 func (s *MessageService) deliverMessage(
 	ctx context.Context,
 	msg *domain.Message,
 	shouldFailAll bool,
-) domain.SendResult {
-	result := domain.SendResult{
+	budget *retryBudget,
+	cacheBatch sentCacheBatch,
+) (result domain.SendResult) {
+	result = domain.SendResult{
 		MessageDBID: msg.ID,
 		SentAt:      time.Now(),
+		PhoneNumber: msg.PhoneNumber,
+		Content:     msg.Content,
+	}
+	defer func() { logResult(result) }()
+
+	// Suppressed numbers are never sent to, not even as a simulated failure. Messages to numbers
+	// suppressed before the run are not fetched at all; this catches numbers suppressed since.
+	if s.isSuppressed(ctx, msg) {
+		logger.Infof("Skipping message %d: %s is suppressed", msg.ID, msg.PhoneNumber)
+		result.Suppressed = true
+		s.suppressed.Add(1)
+
+		if markErr := s.repo.MarkAsSuppressed(ctx, msg.ID); markErr != nil {
+			logger.Errorf("Failed to mark message %d as suppressed: %v", msg.ID, markErr)
+		}
+
+		return result
 	}
 
 	// Simulated failure for testing.
 	if shouldFailAll {
+		result.Attempts = 1
 		logger.Warnf("Simulated failure for message %d (failure rate test)", msg.ID)
 
 		result.Success = false
 		result.Error = fmt.Errorf("simulated failure for testing")
+		result.Retrying = s.recordFailure(ctx, msg, result.Error)
+
+		return result
+	}
 
-		if markErr := s.repo.MarkAsFailed(ctx, msg.ID); markErr != nil {
-			logger.Errorf("Failed to mark message %d as failed: %v", msg.ID, markErr)
+	// The fingerprint covers the content as stored, before any transform.
+	fingerprint := sendFingerprint(msg.PhoneNumber, msg.Content)
+	if s.isRecentSend(ctx, fingerprint) {
+		logger.Infof("Skipping message %d: same content was sent to this number within %v", msg.ID, s.config.DedupWindow)
+		result.Deduped = true
+		s.deduped.Add(1)
+
+		if markErr := s.repo.MarkAsDeduped(ctx, msg.ID); markErr != nil {
+			logger.Errorf("Failed to mark message %d as deduped: %v", msg.ID, markErr)
 		}
 
 		return result
 	}
 
-	// Enforce max content length.
-	if len(msg.Content) > s.config.MaxContentLength {
+	// A failing transformer is not worth failing the message over; send the content as stored.
+	if content, err := s.transformer.Transform(ctx, msg.Content); err != nil {
+		logger.Warnf("Failed to transform content of message %d, sending it unchanged: %v", msg.ID, err)
+	} else {
+		msg.Content = content
+	}
+
+	// Enforce max content length, remembering the original length for the truncation audit.
+	var originalLength *int
+	if content, truncated := truncateContent(msg.Content, s.config.MaxContentLength); truncated {
 		logger.Warnf("Message %d exceeds max content length (%d > %d)",
 			msg.ID, len(msg.Content), s.config.MaxContentLength)
 
-		ellipsis := "..."
-		max := s.config.MaxContentLength
-		if max > len(ellipsis) {
-			msg.Content = msg.Content[:max-len(ellipsis)] + ellipsis
-		} else {
-			msg.Content = msg.Content[:max]
-		}
+		length := len(msg.Content)
+		originalLength = &length
+		msg.Content = content
+		s.truncated.Add(1)
 	}
 
-	resp, err := s.webhookClient.SendMessage(ctx, msg.PhoneNumber, msg.Content)
+	start := time.Now()
+	resp, attempts, err := s.sendWithRetries(ctx, msg, budget)
+	result.Attempts = attempts
+	result.Latency = time.Since(start)
 	if err != nil {
 		logger.Errorf("Failed to send message %d: %v", msg.ID, err)
 		result.Success = false
 		result.Error = err
-
-		if markErr := s.repo.MarkAsFailed(ctx, msg.ID); markErr != nil {
-			logger.Errorf("Failed to mark message %d as failed: %v", msg.ID, markErr)
-		}
+		result.Retrying = s.recordFailure(ctx, msg, err)
 
 		return result
 	}
 
-	if err := s.repo.MarkAsSent(ctx, msg.ID, resp.MessageID, result.SentAt); err != nil {
+	if err := s.repo.MarkAsSent(ctx, msg.ID, resp.MessageID, result.SentAt, originalLength); err != nil {
 		logger.Errorf("Failed to mark message %d as sent: %v", msg.ID, err)
 		result.Success = false
 		result.Error = err
 		return result
 	}
 
-	if s.redisClient != nil {
-		if err := s.redisClient.CacheSentMessage(ctx, msg.ID, resp.MessageID, result.SentAt); err != nil {
-			logger.Warnf("Failed to cache message %d to Redis: %v", msg.ID, err)
-		}
-	}
+	s.cacheSent(ctx, cacheBatch, msg.ID, resp.MessageID, result.SentAt)
+	s.rememberSend(ctx, fingerprint)
 
 	logger.Infof("Successfully sent message %d (webhookMessageId: %s)", msg.ID, resp.MessageID)
 
@@ -154,31 +513,629 @@ func (s *MessageService) deliverMessage(
 	return result
 }
 
-func (s *MessageService) GetSentMessages(ctx context.Context, page, pageSize int) ([]domain.Message, int64, error) {
-	return s.repo.GetSent(ctx, page, pageSize)
+// recordFailure counts a failed send of msg. Until it has failed in MaxRetries runs the message
+// stays pending for the next run, and recordFailure reports true; then it is marked failed.
+// Permanent rejections and sends the provider accepted without an id are never retried: the
+// first cannot succeed and the second would deliver the message twice.
+func (s *MessageService) recordFailure(ctx context.Context, msg *domain.Message, sendErr error) bool {
+	retryable := !errors.Is(sendErr, domain.ErrTerminalSend) && !errors.Is(sendErr, domain.ErrMissingMessageID)
+	if retryable && msg.RetryCount+1 < s.config.MaxRetries {
+		logger.Infof("Message %d failed in %d of %d runs, retrying in the next run", msg.ID, msg.RetryCount+1, s.config.MaxRetries)
+
+		if markErr := s.repo.MarkForRetry(ctx, msg.ID, sendErr.Error()); markErr != nil {
+			logger.Errorf("Failed to mark message %d for retry: %v", msg.ID, markErr)
+		}
+		return true
+	}
+
+	if markErr := s.repo.MarkAsFailed(ctx, msg.ID, sendErr.Error()); markErr != nil {
+		logger.Errorf("Failed to mark message %d as failed: %v", msg.ID, markErr)
+	}
+	return false
+}
+
+// logResult writes one structured line per processed message, with the phone number masked.
+func logResult(result domain.SendResult) {
+	args := []any{
+		"id", result.MessageDBID,
+		"phone", phone.Mask(result.PhoneNumber),
+		"outcome", result.Outcome(),
+		"attempts", result.Attempts,
+		"latencyMs", result.Latency.Milliseconds(),
+	}
+	if result.Error != nil {
+		args = append(args, "error", result.Error.Error())
+	}
+	logger.Structured("message_result", args...)
+}
+
+// recordMetrics counts the outcome of a message in the in-memory metrics.
+func (s *MessageService) recordMetrics(result domain.SendResult) {
+	switch {
+	case result.Suppressed:
+		s.metrics.Inc("messages_suppressed")
+	case result.Deduped:
+		s.metrics.Inc("messages_deduped")
+	case result.Success:
+		s.metrics.Inc("messages_sent")
+	default:
+		s.metrics.Inc("messages_failed")
+	}
+
+	if result.Latency > 0 {
+		s.metrics.Observe("send_latency_avg_ms", float64(result.Latency)/float64(time.Millisecond))
+	}
+}
+
+// sentCacheBatch collects the cache entries of a run's successful sends, keyed by DB id.
+type sentCacheBatch map[int64]*domain.SentMessageCache
+
+// cacheSent caches a successful send, or adds it to cacheBatch when the run batches its writes.
+func (s *MessageService) cacheSent(ctx context.Context, cacheBatch sentCacheBatch, dbID int64, messageID string, sentAt time.Time) {
+	if s.redisClient == nil {
+		return
+	}
+
+	if cacheBatch != nil {
+		cacheBatch[dbID] = &domain.SentMessageCache{MessageID: messageID, SentAt: sentAt}
+		return
+	}
+
+	if err := s.redisClient.CacheSentMessage(ctx, dbID, messageID, sentAt); err != nil {
+		logger.Warnf("Failed to cache message %d to Redis: %v", dbID, err)
+	}
+}
+
+// flushSentCache writes the collected cache entries in one pipeline. It runs even if the run was
+// cancelled, since the messages are already marked as sent.
+func (s *MessageService) flushSentCache(ctx context.Context, cacheBatch sentCacheBatch) {
+	if len(cacheBatch) == 0 {
+		return
+	}
+
+	if err := s.redisClient.CacheSentMessages(context.WithoutCancel(ctx), cacheBatch); err != nil {
+		logger.Warnf("Failed to cache sent messages to Redis: %v", err)
+	}
+}
+
+// sendFingerprint identifies a send by destination and content, for deduplication.
+func sendFingerprint(phoneNumber, content string) string {
+	sum := sha256.Sum256([]byte(phoneNumber + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// isRecentSend reports whether the same send happened within DedupWindow. Deduplication needs
+// Redis; when it is missing or failing, messages are sent rather than held back.
+func (s *MessageService) isRecentSend(ctx context.Context, fingerprint string) bool {
+	if s.config.DedupWindow <= 0 || s.redisClient == nil {
+		return false
+	}
+
+	recent, err := s.redisClient.IsRecentSend(ctx, fingerprint)
+	if err != nil {
+		logger.Warnf("Failed to check for a recent identical send, sending anyway: %v", err)
+		return false
+	}
+	return recent
+}
+
+func (s *MessageService) rememberSend(ctx context.Context, fingerprint string) {
+	if s.config.DedupWindow <= 0 || s.redisClient == nil {
+		return
+	}
+
+	if err := s.redisClient.RememberSend(ctx, fingerprint, s.config.DedupWindow); err != nil {
+		logger.Warnf("Failed to remember send for deduplication: %v", err)
+	}
+}
+
+// truncateContent cuts content to max bytes, ending in "..." when there is room for it, and
+// reports whether it had to.
+func truncateContent(content string, max int) (string, bool) {
+	if len(content) <= max {
+		return content, false
+	}
+
+	const ellipsis = "..."
+	if max > len(ellipsis) {
+		return content[:max-len(ellipsis)] + ellipsis, true
+	}
+	return content[:max], true
+}
+
+// SendPreview computes how the message will be sent: its normalized number, the number of SMS
+// segments and whether the content will be truncated.
+func (s *MessageService) SendPreview(msg *domain.Message) domain.SendPreview {
+	content, truncated := truncateContent(msg.Content, s.config.MaxContentLength)
+
+	return domain.SendPreview{
+		NormalizedPhone: phone.Normalize(msg.PhoneNumber),
+		SegmentCount:    sms.Segments(content),
+		Truncated:       truncated,
+	}
+}
+
+// sendWithRetries calls the webhook up to SendAttempts times, drawing each retry from the run's budget.
+func (s *MessageService) sendWithRetries(
+	ctx context.Context,
+	msg *domain.Message,
+	budget *retryBudget,
+) (*domain.WebhookResponse, int, error) {
+	maxAttempts := s.config.SendAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	attempts := 0
+	for {
+		attempts++
+
+		callCtx, cancel := s.webhookCallContext(ctx, msg)
+		callCtx = idempotency.NewContext(callCtx, idempotency.Attempt{
+			MessageID: msg.ID,
+			QueuedAt:  msg.UpdatedAt,
+			Number:    attempts,
+		})
+		resp, err := s.webhookClient.SendMessage(callCtx, msg.PhoneNumber, msg.Content)
+		cancel()
+		if err == nil {
+			return resp, attempts, nil
+		}
+
+		if attempts >= maxAttempts || ctx.Err() != nil {
+			return nil, attempts, err
+		}
+
+		if errors.Is(err, domain.ErrMissingMessageID) {
+			logger.Warnf("Not retrying message %d: provider accepted it without a message id", msg.ID)
+			return nil, attempts, err
+		}
+
+		if errors.Is(err, domain.ErrTerminalSend) {
+			logger.Warnf("Not retrying message %d: provider rejected it permanently: %v", msg.ID, err)
+			return nil, attempts, err
+		}
+
+		if !budget.take() {
+			logger.Warnf("Retry budget exhausted, not retrying message %d", msg.ID)
+			return nil, attempts, err
+		}
+
+		logger.Warnf("Retrying message %d (attempt %d/%d): %v", msg.ID, attempts+1, maxAttempts, err)
+	}
+}
+
+// webhookCallContext applies the message's own timeout, capped at MaxWebhookTimeout, to a single
+// webhook call. Without an override ctx is returned as is and the client's default timeout applies.
+func (s *MessageService) webhookCallContext(ctx context.Context, msg *domain.Message) (context.Context, context.CancelFunc) {
+	if msg.TimeoutSeconds == nil || *msg.TimeoutSeconds <= 0 {
+		return ctx, func() {}
+	}
+
+	timeout := time.Duration(*msg.TimeoutSeconds) * time.Second
+	if s.config.MaxWebhookTimeout > 0 && timeout > s.config.MaxWebhookTimeout {
+		timeout = s.config.MaxWebhookTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// defaultExportMaxRows caps export streams when MESSAGE_EXPORT_MAX_ROWS is not positive.
+const defaultExportMaxRows = 10000
+
+// ExportMessages streams messages matching the filter to fn, oldest first. limit caps the rows
+// emitted (0 = the server ceiling); it is itself capped by ExportMaxRows. truncated reports
+// whether matching messages were left out because of the cap.
+func (s *MessageService) ExportMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	limit int,
+	fn func(domain.Message) error,
+) (truncated bool, err error) {
+	ceiling := s.config.ExportMaxRows
+	if ceiling <= 0 {
+		ceiling = defaultExportMaxRows
+	}
+	if limit <= 0 || limit > ceiling {
+		limit = ceiling
+	}
+
+	// Ask for one extra row to find out whether the cap cut the export short.
+	emitted := 0
+	err = s.repo.StreamMessages(ctx, filter, limit+1, func(msg domain.Message) error {
+		if emitted == limit {
+			truncated = true
+			return nil
+		}
+		emitted++
+		return fn(msg)
+	})
+
+	return truncated, err
+}
+
+// PreviewNextBatch returns the messages the next run of the queue would pick up, without sending them.
+func (s *MessageService) PreviewNextBatch(ctx context.Context, queue string) ([]domain.Message, error) {
+	if s.config.BatchSize <= 0 {
+		return []domain.Message{}, nil
+	}
+	return s.repo.GetUnsent(ctx, queue, s.config.BatchSize)
+}
+
+// GetQueueHead returns up to limit due pending messages of the queue in the order the scheduler
+// picks them, without sending or changing them. An empty queue means the default queue.
+func (s *MessageService) GetQueueHead(ctx context.Context, queue string, limit int) ([]domain.Message, error) {
+	if !s.knownQueue(queue) {
+		return nil, fmt.Errorf("%w %q", domain.ErrUnknownQueue, queue)
+	}
+	if queue == "" {
+		queue = domain.DefaultQueue
+	}
+	return s.repo.GetUnsent(ctx, queue, limit)
+}
+
+// SimulateRun predicts what the next run of the queue would do with each message of its batch.
+// It makes the same read-only checks as a run, in the same order: quiet hours, the kill switch,
+// the per-run send cap, suppression and deduplication, and previews each send from its content
+// as transformed. It assumes every send succeeds and neither calls the webhook nor writes to the
+// database; the content transformer is assumed to have no side effects, like the built-in ones.
+func (s *MessageService) SimulateRun(ctx context.Context, queue string) (*domain.SimulatedRun, error) {
+	messages, err := s.PreviewNextBatch(ctx, queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next batch: %w", err)
+	}
+
+	run := &domain.SimulatedRun{
+		Queue:      queue,
+		QuietHours: s.quietHours != nil && s.quietHours.contains(s.now()),
+		Messages:   make([]domain.SimulatedSend, 0, len(messages)),
+	}
+	sendingDisabled := !run.QuietHours && len(messages) > 0 && !s.sendingEnabled(ctx)
+
+	// Fingerprints of the simulated sends, which a run would remember for deduplication.
+	dedup := s.config.DedupWindow > 0 && s.redisClient != nil
+	sent := make(map[string]bool)
+
+	sends := 0
+	for _, msg := range messages {
+		fingerprint := sendFingerprint(msg.PhoneNumber, msg.Content)
+
+		outcome := domain.SimulatedSendOutcome
+		switch {
+		case run.QuietHours:
+			outcome = domain.SimulatedQuietHoursOutcome
+		case sendingDisabled:
+			outcome = domain.SimulatedSendingDisabledOutcome
+		case s.sendCapReached(sends):
+			outcome = domain.SimulatedSendCapOutcome
+		case s.isSuppressed(ctx, &msg):
+			outcome = domain.SimulatedSuppressedOutcome
+		case dedup && (sent[fingerprint] || s.isRecentSend(ctx, fingerprint)):
+			outcome = domain.SimulatedDedupedOutcome
+		default:
+			sends++
+			sent[fingerprint] = true
+		}
+
+		if content, err := s.transformer.Transform(ctx, msg.Content); err == nil {
+			msg.Content = content
+		}
+
+		run.Messages = append(run.Messages, domain.SimulatedSend{
+			ID:          msg.ID,
+			Outcome:     outcome,
+			SendPreview: s.SendPreview(&msg),
+		})
+	}
+
+	return run, nil
+}
+
+func (s *MessageService) GetSentMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	page,
+	pageSize int,
+) ([]domain.Message, int64, error) {
+	return s.repo.GetSent(ctx, filter, page, pageSize)
 }
 
-func (s *MessageService) CreateMessage(ctx context.Context, content, phoneNumber string) (*domain.Message, error) {
-	if len(content) > s.config.MaxContentLength {
-		return nil, fmt.Errorf("content exceeds maximum length of %d characters", s.config.MaxContentLength)
+func (s *MessageService) CreateMessage(ctx context.Context, msg domain.NewMessage) (*domain.Message, error) {
+	if err := s.prepareNewMessage(&msg); err != nil {
+		return nil, err
+	}
+
+	if msg.DelaySeconds != nil {
+		if msg.SendAt != nil {
+			return nil, domain.ErrConflictingSchedule
+		}
+		sendAt := s.now().Add(time.Duration(*msg.DelaySeconds) * time.Second)
+		msg.SendAt = &sendAt
+		msg.DelaySeconds = nil
 	}
 
-	return s.repo.Create(ctx, content, phoneNumber)
+	created, err := s.repo.Create(ctx, msg)
+	if errors.Is(err, domain.ErrDuplicateClientMessageID) {
+		return created, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCreated(msg.Queue)
+	return created, nil
+}
+
+// prepareNewMessage checks a message about to be created and normalizes its phone number and tags
+// in place.
+func (s *MessageService) prepareNewMessage(msg *domain.NewMessage) error {
+	if len(msg.Content) > s.config.MaxContentLength {
+		return fmt.Errorf("%w of %d characters", domain.ErrContentTooLong, s.config.MaxContentLength)
+	}
+
+	if !s.knownQueue(msg.Queue) {
+		return fmt.Errorf("%w %q", domain.ErrUnknownQueue, msg.Queue)
+	}
+
+	msg.PhoneNumber = phone.Normalize(msg.PhoneNumber)
+	if len(s.config.AllowedCountryCodes) > 0 && !phone.HasCountryCode(msg.PhoneNumber, s.config.AllowedCountryCodes) {
+		return fmt.Errorf("%w: %s (allowed: %s)",
+			domain.ErrCountryNotAllowed, msg.PhoneNumber, strings.Join(s.config.AllowedCountryCodes, ", "))
+	}
+
+	msg.Tags = normalizeTags(msg.Tags)
+	return nil
+}
+
+// notifyCreated runs the create hook for a queue that just received messages.
+func (s *MessageService) notifyCreated(queue string) {
+	if s.onCreate == nil {
+		return
+	}
+	if queue == "" {
+		queue = domain.DefaultQueue
+	}
+	s.onCreate(queue)
+}
+
+// knownQueue reports whether a scheduler exists for the queue; empty means the default queue.
+func (s *MessageService) knownQueue(queue string) bool {
+	return queue == "" || queue == domain.DefaultQueue || slices.Contains(s.config.Queues, queue)
+}
+
+// normalizeTags trims tags and drops empty or duplicate entries, keeping the original order.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// SearchMessages finds messages by phone number prefix or content substring, phone matches first.
+func (s *MessageService) SearchMessages(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error) {
+	return s.repo.Search(ctx, strings.TrimSpace(query), page, pageSize)
 }
 
 func (s *MessageService) GetAllMessages(
 	ctx context.Context,
-	status *domain.MessageStatus,
+	filter domain.MessageFilter,
 	page,
 	pageSize int,
 ) ([]domain.Message, int64, error) {
-	return s.repo.GetAll(ctx, status, page, pageSize)
+	return s.repo.GetAll(ctx, filter, page, pageSize)
 }
 
 func (s *MessageService) GetStats(ctx context.Context) (pending, sent, failed int64, err error) {
 	return s.repo.GetStats(ctx)
 }
 
+// OutcomeCounts returns how often messages were truncated or skipped since the service started.
+func (s *MessageService) OutcomeCounts() domain.OutcomeCounts {
+	return domain.OutcomeCounts{
+		Truncated:             s.truncated.Load(),
+		QuietHoursSkippedRuns: s.quietHoursSkippedRuns.Load(),
+		SendCapSkipped:        s.sendCapSkipped.Load(),
+		Deduped:               s.deduped.Load(),
+		Suppressed:            s.suppressed.Load(),
+	}
+}
+
+// EstimateCost projects the cost of sending all pending messages: their SMS segments (after the
+// truncation sending applies) times SegmentPrice, broken down by destination calling code.
+func (s *MessageService) EstimateCost(ctx context.Context) (*domain.CostEstimate, error) {
+	byCountry := make(map[string]*domain.CountryCost)
+
+	err := s.repo.ForEachPending(ctx, func(phoneNumber, content string) error {
+		code := phone.CountryCode(phone.Normalize(phoneNumber))
+		if code == "" {
+			code = "unknown"
+		}
+
+		country, ok := byCountry[code]
+		if !ok {
+			country = &domain.CountryCost{CountryCode: code}
+			byCountry[code] = country
+		}
+
+		content, _ = truncateContent(content, s.config.MaxContentLength)
+		country.Messages++
+		country.Segments += int64(sms.Segments(content))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate cost: %w", err)
+	}
+
+	estimate := &domain.CostEstimate{
+		PricePerSegment: s.config.SegmentPrice,
+		Countries:       make([]domain.CountryCost, 0, len(byCountry)),
+	}
+	for _, country := range byCountry {
+		country.Cost = segmentCost(country.Segments, s.config.SegmentPrice)
+		estimate.Messages += country.Messages
+		estimate.Segments += country.Segments
+		estimate.Countries = append(estimate.Countries, *country)
+	}
+	estimate.TotalCost = segmentCost(estimate.Segments, s.config.SegmentPrice)
+
+	slices.SortFunc(estimate.Countries, func(a, b domain.CountryCost) int {
+		return strings.Compare(a.CountryCode, b.CountryCode)
+	})
+
+	return estimate, nil
+}
+
+// segmentCost prices a number of segments, rounded to six decimals to hide float noise.
+func segmentCost(segments int64, price float64) float64 {
+	return math.Round(float64(segments)*price*1e6) / 1e6
+}
+
+// GetOldestPendingCreatedAt returns when the oldest pending message was created (nil if none).
+func (s *MessageService) GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error) {
+	return s.repo.GetOldestPendingCreatedAt(ctx)
+}
+
+// GetChangesSince returns messages changed after the cursor along with the cursor for the next
+// call: the last message returned, or the given cursor when nothing changed.
+func (s *MessageService) GetChangesSince(
+	ctx context.Context,
+	after domain.ChangeCursor,
+	limit int,
+) ([]domain.Message, domain.ChangeCursor, error) {
+	messages, err := s.repo.GetChangedSince(ctx, after, limit)
+	if err != nil {
+		return nil, after, err
+	}
+
+	cursor := after
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		cursor = domain.ChangeCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}
+	}
+
+	return messages, cursor, nil
+}
+
+// allStatuses is used to zero-fill grouped stats so every queue reports every status.
+var allStatuses = domain.MessageStatuses()
+
+// GetGroupedStats returns message counts as a queue -> status matrix, with missing
+// combinations reported as zero.
+func (s *MessageService) GetGroupedStats(ctx context.Context) (map[string]map[domain.MessageStatus]int64, error) {
+	counts, err := s.repo.GetGroupedCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make(map[string]map[domain.MessageStatus]int64)
+	for _, c := range counts {
+		row, ok := matrix[c.Queue]
+		if !ok {
+			row = make(map[domain.MessageStatus]int64, len(allStatuses))
+			for _, status := range allStatuses {
+				row[status] = 0
+			}
+			matrix[c.Queue] = row
+		}
+		row[c.Status] += c.Count
+	}
+
+	return matrix, nil
+}
+
+// GetFailureReasons returns the most common normalized failure reasons.
+func (s *MessageService) GetFailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error) {
+	return s.repo.FailureReasons(ctx, limit)
+}
+
+// GetThroughput returns hourly send counts over the last window, oldest hour first.
+func (s *MessageService) GetThroughput(ctx context.Context, window time.Duration) ([]domain.HourlySends, error) {
+	return s.repo.SendsPerHour(ctx, window)
+}
+
+// GetSLACompliance reports how many messages sent in the last window went out within target of
+// being due, i.e. of their creation or their send_at if later.
+func (s *MessageService) GetSLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error) {
+	return s.repo.SLACompliance(ctx, window, target)
+}
+
+// BackfillSentAt fills missing sent_at values on sent messages and returns how many rows changed.
+func (s *MessageService) BackfillSentAt(ctx context.Context) (int64, error) {
+	return s.repo.BackfillSentAt(ctx)
+}
+
+// invalidPhoneReason is the failure reason recorded on messages whose number cannot be fixed.
+const invalidPhoneReason = "invalid phone number"
+
+// NormalizePendingPhones normalizes the phone numbers of pending messages created before
+// normalization was applied on create. Numbers that are still invalid afterwards are reported
+// and, with failRejected, moved to failed so the scheduler stops trying them.
+func (s *MessageService) NormalizePendingPhones(ctx context.Context, failRejected bool) (domain.PhoneNormalizationResult, error) {
+	result, err := s.repo.NormalizePendingPhones(ctx, func(number string) (string, bool) {
+		normalized := phone.Normalize(number)
+		return normalized, phone.Valid(normalized)
+	})
+	if err != nil || !failRejected {
+		return result, err
+	}
+
+	for _, id := range result.RejectedIDs {
+		if _, err := s.repo.ForceFail(ctx, id, invalidPhoneReason); err != nil {
+			// Sent or cancelled in the meantime; nothing to fail.
+			if errors.Is(err, domain.ErrMessageNotPending) || errors.Is(err, domain.ErrMessageNotFound) {
+				continue
+			}
+			return result, err
+		}
+		result.Failed++
+	}
+
+	return result, nil
+}
+
+// RecordDeliveryReceipt stores a provider delivery receipt on the message with the given
+// provider message id. Returns domain.ErrMessageNotFound if no message matches.
+func (s *MessageService) RecordDeliveryReceipt(
+	ctx context.Context,
+	messageID string,
+	status domain.DeliveryStatus,
+) (*domain.Message, error) {
+	msg, err := s.repo.GetByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, domain.ErrMessageNotFound
+	}
+
+	if err := s.repo.UpdateDeliveryStatus(ctx, msg.ID, status); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	msg.DeliveryStatus = &status
+	msg.DeliveryUpdatedAt = &now
+
+	return msg, nil
+}
+
+// CancelPendingMessages cancels pending messages matching the filter. An empty filter is
+// rejected with domain.ErrEmptyCancelFilter unless all is set.
+func (s *MessageService) CancelPendingMessages(ctx context.Context, filter domain.CancelFilter, all bool) (int64, error) {
+	if filter.IsEmpty() && !all {
+		return 0, domain.ErrEmptyCancelFilter
+	}
+	return s.repo.CancelPending(ctx, filter)
+}
+
 func (s *MessageService) GetCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error) {
 	if s.redisClient == nil {
 		return nil, fmt.Errorf("redis client not configured")
@@ -186,10 +1143,57 @@ func (s *MessageService) GetCachedMessages(ctx context.Context) (map[int64]*doma
 	return s.redisClient.GetAllCachedMessages(ctx)
 }
 
+// GetCachedMessagesByIDs returns the cached entries of the given message ids; ids that are not
+// cached are left out.
+func (s *MessageService) GetCachedMessagesByIDs(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error) {
+	if s.redisClient == nil {
+		return nil, fmt.Errorf("redis client not configured")
+	}
+	return s.redisClient.GetCachedMessages(ctx, ids)
+}
+
+// GetMessage returns a single message by its DB id.
+func (s *MessageService) GetMessage(ctx context.Context, id int64) (*domain.Message, error) {
+	msg, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, domain.ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// ForceFailMessage marks a pending message as failed with a manual reason.
+func (s *MessageService) ForceFailMessage(ctx context.Context, id int64, reason string) (*domain.Message, error) {
+	return s.repo.ForceFail(ctx, id, reason)
+}
+
+// ResetRetries gives a failed message a fresh failure age, replay count and retry count without
+// re-pending it.
+func (s *MessageService) ResetRetries(ctx context.Context, id int64) (*domain.Message, error) {
+	return s.repo.ResetRetries(ctx, id)
+}
+
+// BulkUpdateStatus moves the given messages to status in one go, for migrations and manual
+// corrections. Duplicate ids are ignored.
+func (s *MessageService) BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error) {
+	slices.Sort(ids)
+	return s.repo.BulkUpdateStatus(ctx, slices.Compact(ids), status)
+}
+
 func (s *MessageService) ReplayFailedMessage(ctx context.Context, id int64) error {
 	return s.repo.ReplayFailedByID(ctx, id)
 }
 
-func (s *MessageService) ReplayAllFailedMessages(ctx context.Context) (int64, error) {
-	return s.repo.ReplayAllFailed(ctx)
+// ReplayAllFailedMessages re-pends failed messages, optionally only those that failed within the
+// given age bounds.
+func (s *MessageService) ReplayAllFailedMessages(ctx context.Context, age domain.ReplayAge) (int64, error) {
+	return s.repo.ReplayAllFailed(ctx, age)
+}
+
+// ReplayFailedMessagesInWindow re-pends only the messages that failed in [from, to),
+// e.g. during a known provider outage.
+func (s *MessageService) ReplayFailedMessagesInWindow(ctx context.Context, from, to time.Time) (int64, error) {
+	return s.repo.ReplayFailedInWindow(ctx, from, to)
 }
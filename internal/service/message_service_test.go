@@ -1,13 +1,25 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/onurcolak/insider-message-service/environments"
 	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/metrics"
 )
 
 //
@@ -16,69 +28,283 @@ import (
 
 type fakeRepo struct {
 	unsent          []domain.Message
+	processOrder    string // Order of GetUnsent, like the repository's ProcessOrder; fifo by default
 	markSentCalls   []markSentCall
 	markFailedCalls []int64
+	retryCalls      []int64 // Messages left pending after a failed send
+	dedupedCalls    []int64
 	replayByIDCalls []int64
 	replayAllCalls  int
 	replayAllResult int64
+	created         []domain.NewMessage
+	campaignID      string
+	failReasons     []string
+	forceFailed     []int64
+	suppressed      map[string]bool // Suppressed phone numbers
+	suppressedCalls []int64         // Messages marked as suppressed
+
+	sendingDisabled bool // State of the kill switch
+	sendingChecks   int  // Reads of the kill switch
+	scheduled       bool // Pending messages with a future send_at exist
+	changes         []domain.Message
+
+	archivePurges []archivePurge
+	// events records uploads and purges in call order, shared with fakeObjectStore.
+	events *[]string
+}
+
+type archivePurge struct {
+	cutoff time.Time
+	maxID  int64
 }
 
 type markSentCall struct {
-	id        int64
-	messageID string
-	sentAt    time.Time
+	id             int64
+	messageID      string
+	sentAt         time.Time
+	originalLength *int
+}
+
+// GetUnsent returns pending messages of the queue; messages without a queue belong to the default
+// one and messages without a status count as pending.
+// GetUnsent returns the due pending messages of queue by created_at, oldest first unless
+// processOrder is lifo; messages created at the same time keep their order in r.unsent.
+func (r *fakeRepo) GetUnsent(ctx context.Context, queue string, limit int) ([]domain.Message, error) {
+	now := time.Now()
+	var matched []domain.Message
+	for _, m := range r.unsent {
+		if m.Status != "" && m.Status != domain.StatusPending {
+			continue
+		}
+		if r.suppressed[m.PhoneNumber] || (m.SendAt != nil && m.SendAt.After(now)) {
+			continue
+		}
+		if m.Queue == queue || (m.Queue == "" && queue == domain.DefaultQueue) {
+			matched = append(matched, m)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b domain.Message) int {
+		if r.processOrder == domain.ProcessOrderLIFO {
+			return b.CreatedAt.Compare(a.CreatedAt)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	if len(matched) <= limit {
+		return matched, nil
+	}
+	return matched[:limit], nil
 }
 
-func (r *fakeRepo) GetUnsent(ctx context.Context, limit int) ([]domain.Message, error) {
-	if len(r.unsent) <= limit {
-		return r.unsent, nil
+// GetUnsentAfter is GetUnsent continuing after the message with the cursor's id; the order of
+// unsent stands in for the created_at, id order.
+func (r *fakeRepo) GetUnsentAfter(ctx context.Context, queue string, after *domain.UnsentCursor, limit int) ([]domain.Message, error) {
+	messages, _ := r.GetUnsent(ctx, queue, len(r.unsent))
+	if after != nil {
+		for i, m := range messages {
+			if m.ID == after.ID {
+				messages = messages[i+1:]
+				break
+			}
+		}
 	}
-	return r.unsent[:limit], nil
+	return messages[:min(limit, len(messages))], nil
 }
 
-func (r *fakeRepo) MarkAsSent(ctx context.Context, id int64, messageID string, sentAt time.Time) error {
+func (r *fakeRepo) MarkAsSent(ctx context.Context, id int64, messageID string, sentAt time.Time, originalLength *int) error {
 	r.markSentCalls = append(r.markSentCalls, markSentCall{
-		id:        id,
-		messageID: messageID,
-		sentAt:    sentAt,
+		id:             id,
+		messageID:      messageID,
+		sentAt:         sentAt,
+		originalLength: originalLength,
 	})
 	return nil
 }
 
-func (r *fakeRepo) MarkAsFailed(ctx context.Context, id int64) error {
+func (r *fakeRepo) MarkAsDeduped(ctx context.Context, id int64) error {
+	r.dedupedCalls = append(r.dedupedCalls, id)
+	return nil
+}
+
+func (r *fakeRepo) MarkAsFailed(ctx context.Context, id int64, reason string) error {
 	r.markFailedCalls = append(r.markFailedCalls, id)
+	r.failReasons = append(r.failReasons, reason)
+	return nil
+}
+
+func (r *fakeRepo) MarkForRetry(ctx context.Context, id int64, reason string) error {
+	r.retryCalls = append(r.retryCalls, id)
+	r.failReasons = append(r.failReasons, reason)
 	return nil
 }
 
+func (r *fakeRepo) HasScheduled(ctx context.Context, queue string) (bool, error) {
+	return r.scheduled, nil
+}
+
 // The remaining methods are not used in these tests; we return neutral values.
 
-func (r *fakeRepo) GetSent(ctx context.Context, page, pageSize int) ([]domain.Message, int64, error) {
+func (r *fakeRepo) GetSent(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	page,
+	pageSize int,
+) ([]domain.Message, int64, error) {
 	return nil, 0, nil
 }
 
-func (r *fakeRepo) Create(ctx context.Context, content, phoneNumber string) (*domain.Message, error) {
-	return nil, nil
+func (r *fakeRepo) Create(ctx context.Context, msg domain.NewMessage) (*domain.Message, error) {
+	r.created = append(r.created, msg)
+	return &domain.Message{Content: msg.Content, PhoneNumber: msg.PhoneNumber, Tags: msg.Tags}, nil
+}
+
+func (r *fakeRepo) CreateCampaign(ctx context.Context, campaignID string, msgs []domain.NewMessage) (int64, error) {
+	r.campaignID = campaignID
+	r.created = append(r.created, msgs...)
+	return int64(len(msgs)), nil
+}
+
+func (r *fakeRepo) Search(ctx context.Context, query string, page, pageSize int) ([]domain.Message, int64, error) {
+	return nil, 0, nil
 }
 
 func (r *fakeRepo) GetAll(
 	ctx context.Context,
-	status *domain.MessageStatus,
+	filter domain.MessageFilter,
 	page,
 	pageSize int,
 ) ([]domain.Message, int64, error) {
 	return nil, 0, nil
 }
 
+// StreamMessages streams the unsent messages, ignoring the filter.
+func (r *fakeRepo) StreamMessages(
+	ctx context.Context,
+	filter domain.MessageFilter,
+	limit int,
+	fn func(domain.Message) error,
+) error {
+	streamed := 0
+	for _, m := range r.unsent {
+		if streamed == limit {
+			break
+		}
+		if filter.SentBefore != nil && !sentBefore(m, *filter.SentBefore) {
+			continue
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+		streamed++
+	}
+	return nil
+}
+
+// sentBefore reports whether m is a sent message sent before cutoff, falling back to updated_at
+// like the repository.
+func sentBefore(m domain.Message, cutoff time.Time) bool {
+	sentAt := m.UpdatedAt
+	if m.SentAt != nil {
+		sentAt = *m.SentAt
+	}
+	return m.Status == domain.StatusSent && sentAt.Before(cutoff)
+}
+
+func (r *fakeRepo) ForEachPending(ctx context.Context, fn func(phoneNumber, content string) error) error {
+	for _, m := range r.unsent {
+		if err := fn(m.PhoneNumber, m.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *fakeRepo) GetStats(ctx context.Context) (pending, sent, failed int64, err error) {
 	return 0, 0, 0, nil
 }
 
+func (r *fakeRepo) GetOldestPendingCreatedAt(ctx context.Context) (*time.Time, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) GetChangedSince(ctx context.Context, after domain.ChangeCursor, limit int) ([]domain.Message, error) {
+	return r.changes, nil
+}
+
+func (r *fakeRepo) FailureReasons(ctx context.Context, limit int) ([]domain.FailureReason, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) DeleteArchivedSent(ctx context.Context, cutoff time.Time, maxID int64) (int64, error) {
+	r.archivePurges = append(r.archivePurges, archivePurge{cutoff: cutoff, maxID: maxID})
+	if r.events != nil {
+		*r.events = append(*r.events, "purge")
+	}
+
+	var deleted int64
+	for _, m := range r.unsent {
+		if m.ID <= maxID && sentBefore(m, cutoff) {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *fakeRepo) SendsPerHour(ctx context.Context, window time.Duration) ([]domain.HourlySends, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) SLACompliance(ctx context.Context, window, target time.Duration) (domain.SLACompliance, error) {
+	return domain.SLACompliance{}, nil
+}
+
+func (r *fakeRepo) BackfillSentAt(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// NormalizePendingPhones applies normalize to the unsent messages.
+func (r *fakeRepo) NormalizePendingPhones(
+	ctx context.Context,
+	normalize func(string) (string, bool),
+) (domain.PhoneNormalizationResult, error) {
+	var result domain.PhoneNormalizationResult
+	for i, m := range r.unsent {
+		result.Scanned++
+		normalized, ok := normalize(m.PhoneNumber)
+		if !ok {
+			result.RejectedIDs = append(result.RejectedIDs, m.ID)
+			continue
+		}
+		if normalized != m.PhoneNumber {
+			r.unsent[i].PhoneNumber = normalized
+			result.Changed++
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRepo) CancelPending(ctx context.Context, filter domain.CancelFilter) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeRepo) GetByMessageID(ctx context.Context, messageID string) (*domain.Message, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) UpdateDeliveryStatus(ctx context.Context, id int64, status domain.DeliveryStatus) error {
+	return nil
+}
+
 type fakeWebhookClient struct {
 	shouldFail        bool
+	failWith          error // Returned on failure instead of a generic error
 	responseMessageID string
 
-	lastPhone   string
-	lastContent string
+	lastPhone    string
+	lastContent  string
+	lastDeadline time.Time
+	calls        int
+	callTimes    []time.Time
 }
 
 func (c *fakeWebhookClient) SendMessage(
@@ -88,8 +314,14 @@ func (c *fakeWebhookClient) SendMessage(
 ) (*domain.WebhookResponse, error) {
 	c.lastPhone = phoneNumber
 	c.lastContent = content
+	c.lastDeadline, _ = ctx.Deadline()
+	c.calls++
+	c.callTimes = append(c.callTimes, time.Now())
 
 	if c.shouldFail {
+		if c.failWith != nil {
+			return nil, c.failWith
+		}
 		return nil, fmt.Errorf("simulated webhook error")
 	}
 
@@ -105,10 +337,13 @@ func (c *fakeWebhookClient) SendMessage(
 }
 
 type fakeRedisClient struct {
-	cache map[int64]*domain.SentMessageCache
+	cache        map[int64]*domain.SentMessageCache
+	fingerprints map[string]time.Duration // Remembered sends and their TTL
+	cacheWrites  int                      // Round trips made to write cache entries
 }
 
 func (c *fakeRedisClient) CacheSentMessage(ctx context.Context, dbID int64, messageID string, sentAt time.Time) error {
+	c.cacheWrites++
 	if c.cache == nil {
 		c.cache = make(map[int64]*domain.SentMessageCache)
 	}
@@ -119,10 +354,42 @@ func (c *fakeRedisClient) CacheSentMessage(ctx context.Context, dbID int64, mess
 	return nil
 }
 
+func (c *fakeRedisClient) CacheSentMessages(ctx context.Context, entries map[int64]*domain.SentMessageCache) error {
+	c.cacheWrites++
+	if c.cache == nil {
+		c.cache = make(map[int64]*domain.SentMessageCache)
+	}
+	maps.Copy(c.cache, entries)
+	return nil
+}
+
 func (c *fakeRedisClient) GetAllCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error) {
 	return c.cache, nil
 }
 
+func (c *fakeRedisClient) IsRecentSend(ctx context.Context, fingerprint string) (bool, error) {
+	_, ok := c.fingerprints[fingerprint]
+	return ok, nil
+}
+
+func (c *fakeRedisClient) RememberSend(ctx context.Context, fingerprint string, ttl time.Duration) error {
+	if c.fingerprints == nil {
+		c.fingerprints = make(map[string]time.Duration)
+	}
+	c.fingerprints[fingerprint] = ttl
+	return nil
+}
+
+func (c *fakeRedisClient) GetCachedMessages(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error) {
+	result := make(map[int64]*domain.SentMessageCache)
+	for _, id := range ids {
+		if cached, ok := c.cache[id]; ok {
+			result[id] = cached
+		}
+	}
+	return result, nil
+}
+
 //
 // Tests
 //
@@ -156,7 +423,7 @@ func TestProcessUnsentMessages_SuccessFlow(t *testing.T) {
 
 	svc := NewMessageService(repo, webhook, redisClient, cfg)
 
-	results, err := svc.ProcessUnsentMessages(ctx, 0.0)
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0)
 	if err != nil {
 		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
 	}
@@ -185,6 +452,9 @@ func TestProcessUnsentMessages_SuccessFlow(t *testing.T) {
 	if call.messageID != "msg-123" {
 		t.Errorf("expected MarkAsSent messageID=%q, got %q", "msg-123", call.messageID)
 	}
+	if call.originalLength != nil {
+		t.Errorf("expected no truncation to be recorded, got original length %d", *call.originalLength)
+	}
 
 	if redisClient.cache == nil {
 		t.Fatalf("expected Redis cache to be initialized")
@@ -194,6 +464,63 @@ func TestProcessUnsentMessages_SuccessFlow(t *testing.T) {
 	}
 }
 
+func TestProcessUnsentMessages_FailureKeepsMessagePendingUntilMaxRetries(t *testing.T) {
+	repo := &fakeRepo{
+		unsent: []domain.Message{
+			{ID: 1, Content: "first failure", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+			{ID: 2, Content: "last retry", PhoneNumber: "+905551234568", Status: domain.StatusPending, RetryCount: 2},
+		},
+	}
+	cfg := environments.MessageConfig{BatchSize: 2, MaxContentLength: 1000, MaxRetries: 3}
+	svc := NewMessageService(repo, &fakeWebhookClient{shouldFail: true}, &fakeRedisClient{}, cfg)
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	// Message 1 has failed once of three and stays pending; message 2 used its last retry.
+	if !slices.Equal(repo.retryCalls, []int64{1}) || !slices.Equal(repo.markFailedCalls, []int64{2}) {
+		t.Fatalf("expected message 1 kept for retry and 2 failed, got retries %v and failed %v",
+			repo.retryCalls, repo.markFailedCalls)
+	}
+	if results[0].Outcome() != domain.OutcomeRetrying || results[1].Outcome() != domain.OutcomeFailed {
+		t.Fatalf("unexpected outcomes %q and %q", results[0].Outcome(), results[1].Outcome())
+	}
+}
+
+func TestProcessUnsentMessages_PermanentFailuresAreNotRetried(t *testing.T) {
+	for name, sendErr := range map[string]error{
+		"terminal":           &domain.WebhookStatusError{StatusCode: 400},
+		"missing message id": fmt.Errorf("%w (status: 202)", domain.ErrMissingMessageID),
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeRepo{
+				unsent: []domain.Message{{ID: 7, Content: "hi", PhoneNumber: "+905551234567", Status: domain.StatusPending}},
+			}
+			cfg := environments.MessageConfig{BatchSize: 1, MaxContentLength: 1000, MaxRetries: 3}
+			webhook := &fakeWebhookClient{shouldFail: true, failWith: sendErr}
+			svc := NewMessageService(repo, webhook, &fakeRedisClient{}, cfg)
+
+			results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0)
+			if err != nil {
+				t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+			}
+
+			if len(repo.retryCalls) != 0 || !slices.Equal(repo.markFailedCalls, []int64{7}) {
+				t.Fatalf("expected message 7 failed without a retry, got retries %v and failed %v",
+					repo.retryCalls, repo.markFailedCalls)
+			}
+			if len(results) != 1 || results[0].Outcome() != domain.OutcomeFailed {
+				t.Fatalf("expected one failed result, got %+v", results)
+			}
+		})
+	}
+}
+
 func TestProcessUnsentMessages_WebhookFailureMarksFailed(t *testing.T) {
 	ctx := context.Background()
 
@@ -221,7 +548,7 @@ func TestProcessUnsentMessages_WebhookFailureMarksFailed(t *testing.T) {
 
 	svc := NewMessageService(repo, webhook, redisClient, cfg)
 
-	results, err := svc.ProcessUnsentMessages(ctx, 0.0)
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0)
 	if err != nil {
 		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
 	}
@@ -242,6 +569,9 @@ func TestProcessUnsentMessages_WebhookFailureMarksFailed(t *testing.T) {
 	if repo.markFailedCalls[0] != 42 {
 		t.Errorf("expected MarkAsFailed to be called with id=42, got %d", repo.markFailedCalls[0])
 	}
+	if repo.failReasons[0] != "simulated webhook error" {
+		t.Errorf("expected failure reason to be recorded, got %q", repo.failReasons[0])
+	}
 
 	// On failure, Redis should not be updated
 	if len(redisClient.cache) > 0 {
@@ -249,6 +579,146 @@ func TestProcessUnsentMessages_WebhookFailureMarksFailed(t *testing.T) {
 	}
 }
 
+func TestProcessUnsentMessages_BatchedCacheWritesUseOnePipeline(t *testing.T) {
+	newRepo := func() *fakeRepo {
+		return &fakeRepo{unsent: []domain.Message{
+			{ID: 1, Content: "first", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+			{ID: 2, Content: "second", PhoneNumber: "+905551234568", Status: domain.StatusPending},
+			{ID: 3, Content: "third", PhoneNumber: "+905551234569", Status: domain.StatusPending},
+		}}
+	}
+	cfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 1000}
+
+	unbatched := &fakeRedisClient{}
+	svc := NewMessageService(newRepo(), &fakeWebhookClient{responseMessageID: "msg"}, unbatched, cfg)
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if unbatched.cacheWrites != 3 {
+		t.Fatalf("expected one cache write per send without batching, got %d", unbatched.cacheWrites)
+	}
+
+	batched := &fakeRedisClient{}
+	svc = NewMessageService(newRepo(), &fakeWebhookClient{responseMessageID: "msg"}, batched, cfg)
+	svc.SetBatchCacheWrites(true)
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if batched.cacheWrites != 1 {
+		t.Fatalf("expected a single pipelined cache write, got %d", batched.cacheWrites)
+	}
+	if len(batched.cache) != 3 || batched.cache[2] == nil || batched.cache[2].MessageID != "msg" {
+		t.Fatalf("expected all three sends to be cached, got %+v", batched.cache)
+	}
+}
+
+func TestProcessUnsentMessages_RecordsInMemoryMetrics(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "first", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+		{ID: 2, Content: "second", PhoneNumber: "+905551234568", Status: domain.StatusPending},
+	}}
+	webhook := &fakeWebhookClient{responseMessageID: "msg"}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000})
+	registry := metrics.NewRegistry()
+	svc.SetMetrics(registry)
+
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	repo.unsent = []domain.Message{{ID: 3, Content: "third", PhoneNumber: "+905551234569", Status: domain.StatusPending}}
+	webhook.shouldFail = true
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	raw, err := json.Marshal(registry.Snapshot())
+	if err != nil {
+		t.Fatalf("failed to marshal snapshot: %v", err)
+	}
+	var got struct {
+		Counters map[string]int64   `json:"counters"`
+		Gauges   map[string]float64 `json:"gauges"`
+	}
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("failed to unmarshal snapshot: %v", err)
+	}
+
+	if got.Counters["runs"] != 2 || got.Counters["messages_sent"] != 2 || got.Counters["messages_failed"] != 1 {
+		t.Fatalf("unexpected counters: %s", raw)
+	}
+	if got.Gauges["last_run_messages"] != 1 {
+		t.Fatalf("expected the last run to report 1 message, got %s", raw)
+	}
+	if _, ok := got.Gauges["send_latency_avg_ms"]; !ok {
+		t.Fatalf("expected an average send latency, got %s", raw)
+	}
+}
+
+func TestProcessUnsentMessages_LogsStructuredResultPerMessage(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "first", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+		{ID: 2, Content: "second", PhoneNumber: "+905551234568", Status: domain.StatusPending},
+	}}
+	webhook := &fakeWebhookClient{responseMessageID: "msg"}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{
+		BatchSize:        10,
+		MaxContentLength: 1000,
+		SendAttempts:     2,
+	})
+
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	webhook.shouldFail = true
+	repo.unsent = []domain.Message{{ID: 3, Content: "third", PhoneNumber: "+905551234569", Status: domain.StatusPending}}
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	type resultLine struct {
+		Msg       string  `json:"msg"`
+		ID        int64   `json:"id"`
+		Phone     string  `json:"phone"`
+		Outcome   string  `json:"outcome"`
+		Attempts  int     `json:"attempts"`
+		LatencyMs *int64  `json:"latencyMs"`
+		Error     *string `json:"error"`
+	}
+	var lines []resultLine
+	for _, raw := range strings.Split(logs.String(), "\n") {
+		if !strings.HasPrefix(raw, "{") {
+			continue // Free-form log lines
+		}
+		var line resultLine
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("structured line is not valid JSON: %v: %s", err, raw)
+		}
+		if line.Msg == "message_result" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("expected one result line per message, got %d: %s", len(lines), logs.String())
+	}
+	if got := lines[0]; got.ID != 1 || got.Phone != "+90********67" || got.Outcome != "sent" ||
+		got.Attempts != 1 || got.LatencyMs == nil || got.Error != nil {
+		t.Fatalf("unexpected line for the sent message: %+v", got)
+	}
+	if got := lines[2]; got.ID != 3 || got.Outcome != "failed" || got.Attempts != 2 ||
+		got.Error == nil || *got.Error != "simulated webhook error" {
+		t.Fatalf("unexpected line for the failed message: %+v", got)
+	}
+	if strings.Contains(logs.String(), `"phone":"+905551234567"`) {
+		t.Fatalf("expected phone numbers to be masked: %s", logs.String())
+	}
+}
+
 func TestProcessUnsentMessages_ContentTruncation(t *testing.T) {
 	ctx := context.Background()
 
@@ -282,7 +752,7 @@ func TestProcessUnsentMessages_ContentTruncation(t *testing.T) {
 
 	svc := NewMessageService(repo, webhook, redisClient, cfg)
 
-	_, err := svc.ProcessUnsentMessages(ctx, 0.0)
+	_, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0)
 	if err != nil {
 		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
 	}
@@ -294,6 +764,13 @@ func TestProcessUnsentMessages_ContentTruncation(t *testing.T) {
 	if webhook.lastContent != expected {
 		t.Fatalf("expected truncated content %q, got %q", expected, webhook.lastContent)
 	}
+
+	if len(repo.markSentCalls) != 1 {
+		t.Fatalf("expected MarkAsSent to be called once, got %d calls", len(repo.markSentCalls))
+	}
+	if got := repo.markSentCalls[0].originalLength; got == nil || *got != len(longContent) {
+		t.Fatalf("expected the original length %d to be recorded, got %v", len(longContent), got)
+	}
 }
 
 func TestCreateMessage_ContentTooLong(t *testing.T) {
@@ -312,7 +789,7 @@ func TestCreateMessage_ContentTooLong(t *testing.T) {
 	svc := NewMessageService(repo, webhook, redisClient, cfg)
 
 	longContent := "0123456789ABC" // 13 > 10
-	_, err := svc.CreateMessage(ctx, longContent, "+905551234567")
+	_, err := svc.CreateMessage(ctx, domain.NewMessage{Content: longContent, PhoneNumber: "+905551234567"})
 	if err == nil {
 		t.Fatalf("expected error for too-long content, got nil")
 	}
@@ -323,6 +800,102 @@ func TestCreateMessage_ContentTooLong(t *testing.T) {
 	}
 }
 
+func TestSendBatch_StopOnFirstErrorReturnsEarly(t *testing.T) {
+	newRepo := func() *fakeRepo {
+		return &fakeRepo{unsent: []domain.Message{
+			{ID: 1, Content: "one", PhoneNumber: "+905551234567"},
+			{ID: 2, Content: "two", PhoneNumber: "+905551234567"},
+			{ID: 3, Content: "three", PhoneNumber: "+905551234567"},
+		}}
+	}
+	cfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 1000}
+
+	repo := newRepo()
+	webhook := &fakeWebhookClient{shouldFail: true}
+	svc := NewMessageService(repo, webhook, nil, cfg)
+
+	results, err := svc.SendBatch(context.Background(), domain.DefaultQueue, 0, BatchOptions{StopOnFirstError: true})
+	if !errors.Is(err, domain.ErrBatchAborted) {
+		t.Fatalf("expected ErrBatchAborted, got %v", err)
+	}
+	if len(results) != 1 || results[0].MessageDBID != 1 || results[0].Success {
+		t.Fatalf("expected only the failed first result, got %+v", results)
+	}
+	if webhook.calls != 1 {
+		t.Errorf("expected the webhook to be called once, got %d", webhook.calls)
+	}
+	if !slices.Equal(repo.markFailedCalls, []int64{1}) {
+		t.Errorf("expected only message 1 to be marked failed, got %v", repo.markFailedCalls)
+	}
+
+	// The default stays best-effort: every message is tried and marked failed.
+	repo = newRepo()
+	svc = NewMessageService(repo, &fakeWebhookClient{shouldFail: true}, nil, cfg)
+
+	results, err = svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if len(results) != 3 || len(repo.markFailedCalls) != 3 {
+		t.Fatalf("expected all 3 messages tried and failed, got %d results and %v", len(results), repo.markFailedCalls)
+	}
+}
+
+// suppressedAfterFetchRepo reports numbers as suppressed only once their messages were fetched,
+// as if they were suppressed while the run was under way.
+type suppressedAfterFetchRepo struct {
+	*fakeRepo
+	numbers map[string]bool
+}
+
+func (r suppressedAfterFetchRepo) IsSuppressed(ctx context.Context, phoneNumber string) (bool, error) {
+	return r.numbers[phoneNumber], nil
+}
+
+func TestSendBatch_StopOnFirstErrorCarriesOnPastSkips(t *testing.T) {
+	repo := suppressedAfterFetchRepo{
+		fakeRepo: &fakeRepo{unsent: []domain.Message{
+			{ID: 1, Content: "one", PhoneNumber: "+905550000001"},
+			{ID: 2, Content: "two", PhoneNumber: "+905551234567"},
+		}},
+		numbers: map[string]bool{"+905550000001": true},
+	}
+	cfg := environments.MessageConfig{BatchSize: 2, MaxContentLength: 1000}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	results, err := svc.SendBatch(context.Background(), domain.DefaultQueue, 0, BatchOptions{StopOnFirstError: true})
+	if err != nil {
+		t.Fatalf("expected a suppressed message not to abort the batch, got %v", err)
+	}
+	if len(results) != 2 || !results[0].Suppressed || !results[1].Success {
+		t.Fatalf("expected the suppressed skip and then a send, got %+v", results)
+	}
+	if !slices.Equal(repo.suppressedCalls, []int64{1}) {
+		t.Errorf("expected message 1 marked suppressed, got %v", repo.suppressedCalls)
+	}
+}
+
+func TestGetCachedMessagesByIDs_ReturnsOnlyRequestedPresentEntries(t *testing.T) {
+	redis := &fakeRedisClient{cache: map[int64]*domain.SentMessageCache{
+		1: {MessageID: "msg-1"},
+		2: {MessageID: "msg-2"},
+		3: {MessageID: "msg-3"},
+	}}
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, redis, environments.MessageConfig{})
+
+	cached, err := svc.GetCachedMessagesByIDs(context.Background(), []int64{1, 3, 99})
+	if err != nil {
+		t.Fatalf("GetCachedMessagesByIDs returned error: %v", err)
+	}
+
+	if len(cached) != 2 || cached[1].MessageID != "msg-1" || cached[3].MessageID != "msg-3" {
+		t.Fatalf("expected entries for ids 1 and 3 only, got %+v", cached)
+	}
+	if _, ok := cached[99]; ok {
+		t.Errorf("expected the uncached id to be absent")
+	}
+}
+
 func TestGetCachedMessages_NoRedisConfigured(t *testing.T) {
 	ctx := context.Background()
 
@@ -359,12 +932,101 @@ func (r *fakeRepo) ReplayFailedByID(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *fakeRepo) ReplayAllFailed(ctx context.Context) (int64, error) {
+func (r *fakeRepo) ReplayAllFailed(ctx context.Context, age domain.ReplayAge) (int64, error) {
 	r.replayAllCalls++
 
 	return r.replayAllResult, nil
 }
 
+func (r *fakeRepo) GetByID(ctx context.Context, id int64) (*domain.Message, error) {
+	for i := range r.unsent {
+		if r.unsent[i].ID == id {
+			return &r.unsent[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) MarkAsSuppressed(ctx context.Context, id int64) error {
+	r.suppressedCalls = append(r.suppressedCalls, id)
+	return nil
+}
+
+func (r *fakeRepo) IsSuppressed(ctx context.Context, phoneNumber string) (bool, error) {
+	return r.suppressed[phoneNumber], nil
+}
+
+// SuppressPending moves pending messages of queue in r.unsent whose number is suppressed to
+// suppressed.
+func (r *fakeRepo) SuppressPending(ctx context.Context, queue string) (int64, error) {
+	var moved int64
+	for i := range r.unsent {
+		m := &r.unsent[i]
+		inQueue := m.Queue == queue || (m.Queue == "" && queue == domain.DefaultQueue)
+		if inQueue && r.suppressed[m.PhoneNumber] && (m.Status == "" || m.Status == domain.StatusPending) {
+			m.Status = domain.StatusSuppressed
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// AddSuppression suppresses the number and moves its pending messages in r.unsent to suppressed.
+func (r *fakeRepo) AddSuppression(ctx context.Context, phoneNumber string, reason *string) (*domain.Suppression, int64, error) {
+	if r.suppressed == nil {
+		r.suppressed = make(map[string]bool)
+	}
+	r.suppressed[phoneNumber] = true
+
+	var moved int64
+	for i := range r.unsent {
+		if r.unsent[i].PhoneNumber == phoneNumber && r.unsent[i].Status == domain.StatusPending {
+			r.unsent[i].Status = domain.StatusSuppressed
+			moved++
+		}
+	}
+	return &domain.Suppression{PhoneNumber: phoneNumber, Reason: reason}, moved, nil
+}
+
+func (r *fakeRepo) RemoveSuppression(ctx context.Context, phoneNumber string) error {
+	if !r.suppressed[phoneNumber] {
+		return domain.ErrSuppressionNotFound
+	}
+	delete(r.suppressed, phoneNumber)
+	return nil
+}
+
+func (r *fakeRepo) IsSendingEnabled(ctx context.Context) (bool, error) {
+	r.sendingChecks++
+	return !r.sendingDisabled, nil
+}
+
+func (r *fakeRepo) SetSendingEnabled(ctx context.Context, enabled bool) error {
+	r.sendingDisabled = !enabled
+	return nil
+}
+
+func (r *fakeRepo) ForceFail(ctx context.Context, id int64, reason string) (*domain.Message, error) {
+	r.forceFailed = append(r.forceFailed, id)
+	return nil, nil
+}
+
+func (r *fakeRepo) ResetRetries(ctx context.Context, id int64) (*domain.Message, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) BulkUpdateStatus(ctx context.Context, ids []int64, status domain.MessageStatus) (int64, error) {
+	return int64(len(ids)), nil
+}
+
+func (r *fakeRepo) GetGroupedCounts(ctx context.Context) ([]domain.StatusCount, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) ReplayFailedInWindow(ctx context.Context, from, to time.Time) (int64, error) {
+	return 0, nil
+}
+
 func TestReplayAllFailedMessages_DelegatesToRepo(t *testing.T) {
 	ctx := context.Background()
 
@@ -382,7 +1044,7 @@ func TestReplayAllFailedMessages_DelegatesToRepo(t *testing.T) {
 
 	svc := NewMessageService(repo, webhook, redisClient, cfg)
 
-	count, err := svc.ReplayAllFailedMessages(ctx)
+	count, err := svc.ReplayAllFailedMessages(ctx, domain.ReplayAge{})
 	if err != nil {
 		t.Fatalf("ReplayAllFailedMessages returned error: %v", err)
 	}
@@ -425,3 +1087,1303 @@ func TestReplayFailedMessage_DelegatesToRepo(t *testing.T) {
 		t.Fatalf("expected ReplayFailedByID to be called with id=%d, got %d", id, repo.replayByIDCalls[0])
 	}
 }
+
+func TestProcessUnsentMessages_RetryBudgetCapsAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 5; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{
+			ID:          i,
+			Content:     "Provider is down",
+			PhoneNumber: "+905551234567",
+			Status:      domain.StatusPending,
+		})
+	}
+
+	webhook := &fakeWebhookClient{shouldFail: true}
+
+	cfg := environments.MessageConfig{
+		BatchSize:        5,
+		SendInterval:     2 * time.Minute,
+		MaxContentLength: 1000,
+		SendAttempts:     3,
+		RetryBudget:      4,
+	}
+
+	svc := NewMessageService(repo, webhook, &fakeRedisClient{}, cfg)
+
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	// 5 first attempts + 4 budgeted retries; without the budget this would be 15.
+	if webhook.calls != 9 {
+		t.Fatalf("expected 9 webhook calls, got %d", webhook.calls)
+	}
+
+	for _, r := range results {
+		if r.Success {
+			t.Errorf("expected message %d to fail", r.MessageDBID)
+		}
+	}
+	if used := domain.RetryBudgetUsed(domain.NewRunResults(results)); used != 4 {
+		t.Fatalf("expected the run results to report 4 retries consumed, got %d", used)
+	}
+
+	if len(repo.markFailedCalls) != 5 {
+		t.Fatalf("expected all 5 messages to be marked failed, got %d", len(repo.markFailedCalls))
+	}
+}
+
+// pagingRepo serves pending messages in order, the way repeated GetUnsentAfter calls would
+// once earlier messages have left the pending state.
+type pagingRepo struct {
+	*fakeRepo
+	next   int
+	limits []int
+}
+
+func (r *pagingRepo) GetUnsentAfter(ctx context.Context, queue string, after *domain.UnsentCursor, limit int) ([]domain.Message, error) {
+	r.limits = append(r.limits, limit)
+
+	end := min(r.next+limit, len(r.unsent))
+	batch := r.unsent[r.next:end]
+	r.next = end
+
+	return batch, nil
+}
+
+func TestProcessUnsentMessages_LargeBatchIsChunked(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &pagingRepo{fakeRepo: &fakeRepo{}}
+	for i := int64(1); i <= 1300; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{
+			ID:          i,
+			Content:     "bulk",
+			PhoneNumber: "+905551234567",
+			Status:      domain.StatusPending,
+		})
+	}
+
+	cfg := environments.MessageConfig{
+		BatchSize:        1200,
+		SendInterval:     2 * time.Minute,
+		MaxContentLength: 1000,
+	}
+
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(results) != 1200 {
+		t.Fatalf("expected 1200 results (BatchSize), got %d", len(results))
+	}
+	if len(repo.markSentCalls) != 1200 {
+		t.Fatalf("expected 1200 messages marked sent, got %d", len(repo.markSentCalls))
+	}
+
+	expectedLimits := []int{500, 500, 200}
+	if len(repo.limits) != len(expectedLimits) {
+		t.Fatalf("expected %d GetUnsent calls, got %d (%v)", len(expectedLimits), len(repo.limits), repo.limits)
+	}
+	for i, limit := range expectedLimits {
+		if repo.limits[i] != limit {
+			t.Errorf("expected chunk %d limit %d, got %d", i, limit, repo.limits[i])
+		}
+	}
+}
+
+func TestProcessUnsentMessages_RetriedMessagesAreSentOncePerRun(t *testing.T) {
+	// Failed messages stay pending for a retry, so every chunk would fetch them again without
+	// the cursor.
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 1200; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{
+			ID:          i,
+			Content:     "bulk",
+			PhoneNumber: "+905551234567",
+			Status:      domain.StatusPending,
+		})
+	}
+	webhook := &fakeWebhookClient{shouldFail: true}
+	cfg := environments.MessageConfig{BatchSize: 1200, MaxContentLength: 1000, MaxRetries: 5}
+
+	svc := NewMessageService(repo, webhook, nil, cfg)
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(results) != 1200 || webhook.calls != 1200 {
+		t.Fatalf("expected 1200 results and webhook calls, got %d and %d", len(results), webhook.calls)
+	}
+	seen := make(map[int64]bool, len(repo.retryCalls))
+	for _, id := range repo.retryCalls {
+		if seen[id] {
+			t.Fatalf("message %d was retried twice in one run", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 1200 {
+		t.Fatalf("expected every message kept for retry once, got %d", len(seen))
+	}
+}
+
+func TestProcessUnsentMessages_FixedSeedIsReproducible(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := environments.MessageConfig{
+		BatchSize:        10,
+		SendInterval:     2 * time.Minute,
+		MaxContentLength: 1000,
+	}
+
+	run := func() []int64 {
+		repo := &fakeRepo{}
+		for i := int64(1); i <= 10; i++ {
+			repo.unsent = append(repo.unsent, domain.Message{
+				ID:          i,
+				Content:     "campaign",
+				PhoneNumber: "+905551234567",
+				Status:      domain.StatusPending,
+			})
+		}
+
+		svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+		svc.SetRandSource(rand.NewPCG(42, 0))
+
+		if _, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.5); err != nil {
+			t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+		}
+		return repo.markFailedCalls
+	}
+
+	// With seed (42, 0), draws 1, 3, 4, 6, 7, 9 and 10 fall below 0.5.
+	expected := []int64{1, 3, 4, 6, 7, 9, 10}
+
+	first := run()
+	if !slices.Equal(first, expected) {
+		t.Fatalf("expected simulated failures %v, got %v", expected, first)
+	}
+	if second := run(); !slices.Equal(second, first) {
+		t.Fatalf("expected the same failures on a second run, got %v then %v", first, second)
+	}
+}
+
+func TestCancelPendingMessages_EmptyFilterRequiresAll(t *testing.T) {
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	if _, err := svc.CancelPendingMessages(context.Background(), domain.CancelFilter{}, false); !errors.Is(err, domain.ErrEmptyCancelFilter) {
+		t.Fatalf("expected ErrEmptyCancelFilter, got %v", err)
+	}
+	if _, err := svc.CancelPendingMessages(context.Background(), domain.CancelFilter{}, true); err != nil {
+		t.Fatalf("expected all=true to be accepted, got %v", err)
+	}
+}
+
+func TestProcessUnsentMessages_QueuesAreDisjoint(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &fakeRepo{
+		unsent: []domain.Message{
+			{ID: 1, Content: "OTP 1234", PhoneNumber: "+905551234567", Status: domain.StatusPending, Queue: "transactional"},
+			{ID: 2, Content: "Summer sale", PhoneNumber: "+905551234568", Status: domain.StatusPending, Queue: "promotional"},
+			{ID: 3, Content: "OTP 5678", PhoneNumber: "+905551234569", Status: domain.StatusPending, Queue: "transactional"},
+		},
+	}
+
+	cfg := environments.MessageConfig{
+		BatchSize:        10,
+		SendInterval:     2 * time.Minute,
+		MaxContentLength: 1000,
+	}
+	svc := NewMessageService(repo, &fakeWebhookClient{responseMessageID: "msg"}, nil, cfg)
+
+	sentIDs := func(results []domain.SendResult) []int64 {
+		var ids []int64
+		for _, r := range results {
+			ids = append(ids, r.MessageDBID)
+		}
+		return ids
+	}
+
+	transactional, err := svc.ProcessUnsentMessages(ctx, "transactional", 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	promotional, err := svc.ProcessUnsentMessages(ctx, "promotional", 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if got := sentIDs(transactional); !slices.Equal(got, []int64{1, 3}) {
+		t.Errorf("expected transactional queue to send [1 3], got %v", got)
+	}
+	if got := sentIDs(promotional); !slices.Equal(got, []int64{2}) {
+		t.Errorf("expected promotional queue to send [2], got %v", got)
+	}
+}
+
+func TestCreateMessage_RejectsUnknownQueue(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := environments.MessageConfig{MaxContentLength: 1000, Queues: []string{"transactional"}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	_, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "+905551234567", Queue: "promotional",
+	})
+	if !errors.Is(err, domain.ErrUnknownQueue) {
+		t.Fatalf("expected ErrUnknownQueue, got %v", err)
+	}
+
+	if _, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "+905551234567", Queue: "transactional",
+	}); err != nil {
+		t.Fatalf("expected configured queue to be accepted, got %v", err)
+	}
+}
+
+func TestCreateMessage_AllowsListedCountryCode(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := environments.MessageConfig{MaxContentLength: 1000, AllowedCountryCodes: []string{"+90", "44"}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	msg, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "0090 555 123 45 67",
+	})
+	if err != nil {
+		t.Fatalf("expected allowed country code to be accepted, got %v", err)
+	}
+	if msg.PhoneNumber != "+905551234567" {
+		t.Fatalf("expected normalized phone number, got %q", msg.PhoneNumber)
+	}
+}
+
+func TestCreateMessage_RejectsDisallowedCountryCode(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := environments.MessageConfig{MaxContentLength: 1000, AllowedCountryCodes: []string{"+90", "44"}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	_, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "+1 555 123 4567",
+	})
+	if !errors.Is(err, domain.ErrCountryNotAllowed) {
+		t.Fatalf("expected ErrCountryNotAllowed, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("expected nothing to be stored, got %d messages", len(repo.created))
+	}
+}
+
+func TestCreateMessage_DelaySetsSendAtFromNow(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{MaxContentLength: 1000})
+	now := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	svc.SetClock(func() time.Time { return now })
+
+	delay := 300
+	if _, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "+905551234567", DelaySeconds: &delay,
+	}); err != nil {
+		t.Fatalf("CreateMessage returned error: %v", err)
+	}
+
+	if len(repo.created) != 1 {
+		t.Fatalf("expected one stored message, got %d", len(repo.created))
+	}
+	stored := repo.created[0]
+	if stored.SendAt == nil || !stored.SendAt.Equal(now.Add(5*time.Minute)) {
+		t.Fatalf("expected sendAt %v, got %v", now.Add(5*time.Minute), stored.SendAt)
+	}
+	if stored.DelaySeconds != nil {
+		t.Fatalf("expected the delay to be resolved into sendAt, got %d", *stored.DelaySeconds)
+	}
+}
+
+func TestCreateMessage_RejectsSendAtWithDelay(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{MaxContentLength: 1000})
+
+	sendAt := time.Now().Add(time.Hour)
+	delay := 60
+	_, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+		Content: "hi", PhoneNumber: "+905551234567", SendAt: &sendAt, DelaySeconds: &delay,
+	})
+	if !errors.Is(err, domain.ErrConflictingSchedule) {
+		t.Fatalf("expected ErrConflictingSchedule, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("expected nothing to be stored, got %d messages", len(repo.created))
+	}
+}
+
+// blockingWebhookClient holds every send until release is closed, signalling started first.
+type blockingWebhookClient struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingWebhookClient) SendMessage(ctx context.Context, phoneNumber, content string) (*domain.WebhookResponse, error) {
+	c.started <- struct{}{}
+	<-c.release
+	return &domain.WebhookResponse{Message: "Accepted", MessageID: "abc"}, nil
+}
+
+func TestSendBatch_OnlyOneBatchPerQueueAtATime(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "hello", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+	}}
+	webhook := &blockingWebhookClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 1, MaxContentLength: 1000})
+
+	type outcome struct {
+		results []domain.SendResult
+		err     error
+	}
+	first := make(chan outcome, 1)
+	go func() {
+		results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+		first <- outcome{results, err}
+	}()
+	<-webhook.started
+
+	// The first batch is mid-send: a second run on the same queue is turned away without sending.
+	if _, err := svc.SendBatch(context.Background(), domain.DefaultQueue, 0, BatchOptions{}); !errors.Is(err, domain.ErrBatchInProgress) {
+		t.Fatalf("expected ErrBatchInProgress, got %v", err)
+	}
+
+	close(webhook.release)
+	got := <-first
+	if got.err != nil || len(got.results) != 1 || !got.results[0].Success {
+		t.Fatalf("expected the first batch to send its message, got %+v, %v", got.results, got.err)
+	}
+
+	// Once the first batch is done the queue is free again.
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); errors.Is(err, domain.ErrBatchInProgress) {
+		t.Fatalf("expected the queue to be released after the batch, got %v", err)
+	}
+}
+
+func TestCreateCampaign_RendersTemplatePerRecipient(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{MaxContentLength: 1000})
+
+	result, err := svc.CreateCampaign(context.Background(), domain.NewCampaign{
+		Template: "Hi {{.name}}, your code is {{.code}}",
+		Recipients: []domain.CampaignRecipient{
+			{PhoneNumber: "0090 555 123 45 67", Variables: map[string]string{"name": "Ada", "code": "1234"}},
+			{PhoneNumber: "+905559876543", Variables: map[string]string{"name": "Alan", "code": "5678"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateCampaign returned error: %v", err)
+	}
+
+	if result.Created != 2 || len(result.CampaignID) != 32 || repo.campaignID != result.CampaignID {
+		t.Fatalf("unexpected result %+v (stored under %q)", result, repo.campaignID)
+	}
+	if repo.created[0].Content != "Hi Ada, your code is 1234" || repo.created[1].Content != "Hi Alan, your code is 5678" {
+		t.Fatalf("expected rendered content per recipient, got %+v", repo.created)
+	}
+	if repo.created[0].PhoneNumber != "+905551234567" {
+		t.Fatalf("expected normalized phone number, got %q", repo.created[0].PhoneNumber)
+	}
+}
+
+func TestCreateCampaign_MissingVariableStoresNothing(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{MaxContentLength: 1000})
+
+	_, err := svc.CreateCampaign(context.Background(), domain.NewCampaign{
+		Template: "Hi {{.name}}",
+		Recipients: []domain.CampaignRecipient{
+			{PhoneNumber: "+905551234567", Variables: map[string]string{"name": "Ada"}},
+			{PhoneNumber: "+905559876543", Variables: map[string]string{"first": "Alan"}},
+		},
+	})
+	if !errors.Is(err, domain.ErrInvalidTemplate) {
+		t.Fatalf("expected ErrInvalidTemplate, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("expected nothing to be stored, got %d messages", len(repo.created))
+	}
+}
+
+// missingIDWebhookClient accepts every message but never returns a message id.
+type missingIDWebhookClient struct {
+	calls int
+}
+
+func (c *missingIDWebhookClient) SendMessage(ctx context.Context, phoneNumber, content string) (*domain.WebhookResponse, error) {
+	c.calls++
+	return nil, domain.ErrMissingMessageID
+}
+
+func TestProcessUnsentMessages_MissingMessageIDIsNotRetried(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 9, Content: "hello", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+	}}
+	webhook := &missingIDWebhookClient{}
+
+	cfg := environments.MessageConfig{BatchSize: 1, MaxContentLength: 1000, SendAttempts: 3}
+	svc := NewMessageService(repo, webhook, nil, cfg)
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if webhook.calls != 1 {
+		t.Fatalf("expected a single webhook call, got %d", webhook.calls)
+	}
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("expected one failed result, got %+v", results)
+	}
+	if len(repo.markSentCalls) != 0 || len(repo.markFailedCalls) != 1 {
+		t.Fatalf("expected message marked failed, not sent (sent=%d failed=%d)", len(repo.markSentCalls), len(repo.markFailedCalls))
+	}
+}
+
+func TestProcessUnsentMessages_RetriesOnlyRetryableStatuses(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{"invalid number is terminal", &domain.WebhookStatusError{StatusCode: 400}, 1},
+		{"unavailable is retried", &domain.WebhookStatusError{StatusCode: 503, Retryable: true}, 3},
+		{"transport error is retried", errors.New("connection reset"), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{unsent: []domain.Message{
+				{ID: 9, Content: "hello", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+			}}
+			webhook := &fakeWebhookClient{shouldFail: true, failWith: tt.err}
+
+			cfg := environments.MessageConfig{BatchSize: 1, MaxContentLength: 1000, SendAttempts: 3}
+			svc := NewMessageService(repo, webhook, nil, cfg)
+
+			results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0.0)
+			if err != nil {
+				t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+			}
+
+			if webhook.calls != tt.wantCalls {
+				t.Fatalf("expected %d webhook calls, got %d", tt.wantCalls, webhook.calls)
+			}
+			if len(results) != 1 || results[0].Attempts != tt.wantCalls || len(repo.markFailedCalls) != 1 {
+				t.Fatalf("expected the message marked failed after %d attempts, got %+v", tt.wantCalls, results)
+			}
+		})
+	}
+}
+
+func TestProcessUnsentMessages_HeldDuringQuietHours(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, PhoneNumber: "+905551234567", Content: "hello"},
+	}}
+	cfg := environments.MessageConfig{
+		BatchSize:          10,
+		MaxContentLength:   1000,
+		QuietHoursStart:    "22:00",
+		QuietHoursEnd:      "08:00",
+		QuietHoursTimezone: "Europe/Istanbul",
+	}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	now := time.Date(2025, 3, 1, 23, 30, 0, 0, istanbul)
+	svc.SetClock(func() time.Time { return now })
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if !errors.Is(err, domain.ErrQuietHours) {
+		t.Fatalf("expected ErrQuietHours, got %v", err)
+	}
+	if len(results) != 0 || len(repo.markSentCalls) != 0 || len(repo.markFailedCalls) != 0 {
+		t.Fatalf("expected nothing to be sent during quiet hours, got %d results", len(results))
+	}
+
+	// 08:00 local is the first minute outside the window.
+	now = time.Date(2025, 3, 2, 8, 0, 0, 0, istanbul)
+
+	results, err = svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if len(results) != 1 || len(repo.markSentCalls) != 1 {
+		t.Fatalf("expected the held message to be sent after quiet hours, got %d results", len(results))
+	}
+}
+
+func TestProcessUnsentMessages_OnlyScheduledMessagesIsNotDue(t *testing.T) {
+	cfg := environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000}
+
+	svc := NewMessageService(&fakeRepo{scheduled: true}, &fakeWebhookClient{}, nil, cfg)
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); !errors.Is(err, domain.ErrNotDue) {
+		t.Fatalf("expected ErrNotDue with only future messages pending, got %v", err)
+	}
+
+	svc = NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, cfg)
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil || results != nil {
+		t.Fatalf("expected no results and no error for an empty queue, got %v, %v", results, err)
+	}
+}
+
+func TestGetChangesSince_CursorIsLastMessageReturned(t *testing.T) {
+	second := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{changes: []domain.Message{{ID: 4, UpdatedAt: second}, {ID: 9, UpdatedAt: second}}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	after := domain.ChangeCursor{UpdatedAt: second.Add(-time.Minute)}
+	_, cursor, err := svc.GetChangesSince(context.Background(), after, 2)
+	if err != nil {
+		t.Fatalf("GetChangesSince returned error: %v", err)
+	}
+	// Both rows share a second; the id lets the next page resume after row 9 within it.
+	if cursor != (domain.ChangeCursor{UpdatedAt: second, ID: 9}) {
+		t.Fatalf("expected the cursor at message 9, got %+v", cursor)
+	}
+
+	repo.changes = nil
+	if _, cursor, _ := svc.GetChangesSince(context.Background(), after, 2); cursor != after {
+		t.Fatalf("expected the cursor to stay put without changes, got %+v", cursor)
+	}
+}
+
+func TestQuietHours_Contains(t *testing.T) {
+	cases := []struct {
+		start, end string
+		at         string
+		want       bool
+	}{
+		{"22:00", "08:00", "23:59", true},
+		{"22:00", "08:00", "03:00", true},
+		{"22:00", "08:00", "08:00", false},
+		{"22:00", "08:00", "12:00", false},
+		{"12:00", "14:00", "13:00", true},
+		{"12:00", "14:00", "14:30", false},
+	}
+
+	for _, tc := range cases {
+		q, err := parseQuietHours(environments.MessageConfig{QuietHoursStart: tc.start, QuietHoursEnd: tc.end})
+		if err != nil {
+			t.Fatalf("parseQuietHours returned error: %v", err)
+		}
+
+		at, _ := time.Parse("15:04", tc.at)
+		if got := q.contains(at); got != tc.want {
+			t.Errorf("window %s-%s at %s: got %v, want %v", tc.start, tc.end, tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestProcessUnsentMessages_StopsAtMaxSendsPerRun(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 5; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{ID: i, PhoneNumber: "+905551234567", Content: "hello"})
+	}
+	cfg := environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000, MaxSendsPerRun: 3}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(results) != 3 || len(repo.markSentCalls) != 3 {
+		t.Fatalf("expected processing to stop after 3 sends, got %d results and %d sends",
+			len(results), len(repo.markSentCalls))
+	}
+	if len(repo.markFailedCalls) != 0 {
+		t.Fatalf("expected the remaining messages to stay pending, got %d marked failed", len(repo.markFailedCalls))
+	}
+}
+
+func TestProcessUnsentMessages_AppliesPerMessageTimeout(t *testing.T) {
+	timeout := 300
+	cases := []struct {
+		name    string
+		timeout *int
+		want    time.Duration
+	}{
+		{name: "no override", timeout: nil, want: 0},
+		{name: "capped override", timeout: &timeout, want: 120 * time.Second},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepo{unsent: []domain.Message{
+				{ID: 1, PhoneNumber: "+905551234567", Content: "hello", TimeoutSeconds: tc.timeout},
+			}}
+			client := &fakeWebhookClient{}
+			cfg := environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000, MaxWebhookTimeout: 120 * time.Second}
+			svc := NewMessageService(repo, client, nil, cfg)
+
+			start := time.Now()
+			if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); err != nil {
+				t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+			}
+
+			if tc.want == 0 {
+				if !client.lastDeadline.IsZero() {
+					t.Fatalf("expected no deadline, got %v", client.lastDeadline)
+				}
+				return
+			}
+			got := client.lastDeadline.Sub(start)
+			if got < tc.want-time.Second || got > tc.want+time.Second {
+				t.Fatalf("expected deadline about %v from now, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExportMessages_StopsAtCapAndReportsTruncation(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 5; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{ID: i})
+	}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{ExportMaxRows: 4})
+
+	cases := []struct {
+		name          string
+		limit         int
+		wantRows      int
+		wantTruncated bool
+	}{
+		{name: "client limit", limit: 3, wantRows: 3, wantTruncated: true},
+		{name: "server ceiling", limit: 100, wantRows: 4, wantTruncated: true},
+		{name: "default is the ceiling", limit: 0, wantRows: 4, wantTruncated: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rows int
+			truncated, err := svc.ExportMessages(context.Background(), domain.MessageFilter{}, tc.limit,
+				func(domain.Message) error { rows++; return nil })
+			if err != nil {
+				t.Fatalf("ExportMessages returned error: %v", err)
+			}
+			if rows != tc.wantRows || truncated != tc.wantTruncated {
+				t.Fatalf("expected %d rows (truncated=%t), got %d (truncated=%t)",
+					tc.wantRows, tc.wantTruncated, rows, truncated)
+			}
+		})
+	}
+
+	// Everything fits: no truncation.
+	repo.unsent = repo.unsent[:2]
+	truncated, err := svc.ExportMessages(context.Background(), domain.MessageFilter{}, 2,
+		func(domain.Message) error { return nil })
+	if err != nil || truncated {
+		t.Fatalf("expected a complete export, got truncated=%t err=%v", truncated, err)
+	}
+}
+
+func TestSendPreview_ComputesWhatWillBeSent(t *testing.T) {
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, environments.MessageConfig{MaxContentLength: 200})
+
+	preview := svc.SendPreview(&domain.Message{PhoneNumber: "0090 555 123 45 67", Content: strings.Repeat("a", 161)})
+	if preview.NormalizedPhone != "+905551234567" || preview.SegmentCount != 2 || preview.Truncated {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+
+	// Content over the limit is previewed as it will be sent, after truncation.
+	preview = svc.SendPreview(&domain.Message{PhoneNumber: "+905551234567", Content: strings.Repeat("a", 400)})
+	if !preview.Truncated || preview.SegmentCount != 2 {
+		t.Fatalf("expected a truncated 2-segment preview, got %+v", preview)
+	}
+}
+
+func TestNormalizePendingPhones_FailsRejectedWhenAsked(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, PhoneNumber: "0090 555 123 45 67"},
+		{ID: 2, PhoneNumber: "+905551234567"},
+		{ID: 3, PhoneNumber: "call me"},
+	}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	result, err := svc.NormalizePendingPhones(context.Background(), true)
+	if err != nil {
+		t.Fatalf("NormalizePendingPhones returned error: %v", err)
+	}
+
+	if result.Scanned != 3 || result.Changed != 1 || result.Failed != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if repo.unsent[0].PhoneNumber != "+905551234567" {
+		t.Errorf("expected the messy number to be normalized, got %q", repo.unsent[0].PhoneNumber)
+	}
+	if !slices.Equal(repo.forceFailed, []int64{3}) {
+		t.Errorf("expected only the unfixable message to be failed, got %v", repo.forceFailed)
+	}
+}
+
+func TestCreateMessage_CallsCreateHookWithQueue(t *testing.T) {
+	cfg := environments.MessageConfig{MaxContentLength: 1000, Queues: []string{"transactional"}}
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, cfg)
+
+	var woken []string
+	svc.SetCreateHook(func(queue string) { woken = append(woken, queue) })
+
+	for _, queue := range []string{"", "transactional"} {
+		if _, err := svc.CreateMessage(context.Background(), domain.NewMessage{
+			Content: "hi", PhoneNumber: "+905551234567", Queue: queue,
+		}); err != nil {
+			t.Fatalf("CreateMessage returned error: %v", err)
+		}
+	}
+
+	if !slices.Equal(woken, []string{domain.DefaultQueue, "transactional"}) {
+		t.Fatalf("unexpected hook calls: %v", woken)
+	}
+}
+
+// groupedCountsRepo returns fixed grouped counts.
+type groupedCountsRepo struct {
+	fakeRepo
+	counts []domain.StatusCount
+}
+
+func (r *groupedCountsRepo) GetGroupedCounts(ctx context.Context) ([]domain.StatusCount, error) {
+	return r.counts, nil
+}
+
+func TestGetGroupedStats_ZeroFillsMissingStatuses(t *testing.T) {
+	repo := &groupedCountsRepo{counts: []domain.StatusCount{
+		{Queue: "default", Status: domain.StatusPending, Count: 3},
+		{Queue: "transactional", Status: domain.StatusFailed, Count: 1},
+	}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	matrix, err := svc.GetGroupedStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetGroupedStats returned error: %v", err)
+	}
+
+	statuses := len(domain.MessageStatuses())
+	if len(matrix) != 2 || len(matrix["default"]) != statuses || len(matrix["transactional"]) != statuses {
+		t.Fatalf("expected both queues with all %d statuses, got %v", statuses, matrix)
+	}
+	if matrix["default"][domain.StatusPending] != 3 || matrix["default"][domain.StatusFailed] != 0 ||
+		matrix["transactional"][domain.StatusFailed] != 1 {
+		t.Fatalf("unexpected matrix: %v", matrix)
+	}
+}
+
+func TestOutcomeCounts_CountTruncatedAndSkippedMessages(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, PhoneNumber: "+905551234567", Content: "0123456789ABCDEFGHIJ"},
+		{ID: 2, PhoneNumber: "+905551234567", Content: "short"},
+		{ID: 3, PhoneNumber: "+905551234567", Content: "short"},
+	}}
+	cfg := environments.MessageConfig{
+		BatchSize:        10,
+		MaxContentLength: 10,
+		MaxSendsPerRun:   2,
+		QuietHoursStart:  "22:00",
+		QuietHoursEnd:    "08:00",
+	}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	now := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+	svc.SetClock(func() time.Time { return now })
+
+	// Quiet hours: the run is skipped.
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); !errors.Is(err, domain.ErrQuietHours) {
+		t.Fatalf("expected ErrQuietHours, got %v", err)
+	}
+	if got := svc.OutcomeCounts(); got != (domain.OutcomeCounts{QuietHoursSkippedRuns: 1}) {
+		t.Fatalf("after a quiet-hours run: unexpected counts %+v", got)
+	}
+
+	// Outside quiet hours: message 1 is truncated, and message 3 is left pending by the send cap.
+	now = time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	want := domain.OutcomeCounts{Truncated: 1, QuietHoursSkippedRuns: 1, SendCapSkipped: 1}
+	if got := svc.OutcomeCounts(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestEstimateCost_PricesSegmentsPerCountry(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{PhoneNumber: "+905551234567", Content: "hello"},                       // 1 segment
+		{PhoneNumber: "0090 555 123 45 67", Content: strings.Repeat("a", 161)}, // 2 GSM-7 segments
+		{PhoneNumber: "+442079460958", Content: "Grüße 👋"},                     // 1 UCS-2 segment
+		{PhoneNumber: "5551234567", Content: strings.Repeat("b", 400)},         // truncated to 200: 2 segments
+	}}
+	cfg := environments.MessageConfig{MaxContentLength: 200, SegmentPrice: 0.05}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	estimate, err := svc.EstimateCost(context.Background())
+	if err != nil {
+		t.Fatalf("EstimateCost returned error: %v", err)
+	}
+
+	want := &domain.CostEstimate{
+		PricePerSegment: 0.05,
+		Messages:        4,
+		Segments:        6,
+		TotalCost:       0.3,
+		Countries: []domain.CountryCost{
+			{CountryCode: "+44", Messages: 1, Segments: 1, Cost: 0.05},
+			{CountryCode: "+90", Messages: 2, Segments: 3, Cost: 0.15},
+			{CountryCode: "unknown", Messages: 1, Segments: 2, Cost: 0.1},
+		},
+	}
+	if !reflect.DeepEqual(estimate, want) {
+		t.Fatalf("expected %+v, got %+v", want, estimate)
+	}
+}
+
+type fakeTransformer struct {
+	seen []string
+}
+
+func (f *fakeTransformer) Transform(ctx context.Context, content string) (string, error) {
+	f.seen = append(f.seen, content)
+	return strings.ToUpper(content), nil
+}
+
+func TestProcessUnsentMessages_TransformsContentBeforeSending(t *testing.T) {
+	repo := &fakeRepo{
+		unsent: []domain.Message{{ID: 1, PhoneNumber: "+905551234567", Content: "hello"}},
+	}
+	webhook := &fakeWebhookClient{responseMessageID: "msg-1"}
+	transformer := &fakeTransformer{}
+
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 1, MaxContentLength: 1000})
+	svc.SetContentTransformer(transformer)
+
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if !slices.Equal(transformer.seen, []string{"hello"}) {
+		t.Fatalf("expected the transformer to see the stored content once, got %v", transformer.seen)
+	}
+	if webhook.lastContent != "HELLO" {
+		t.Fatalf("expected the webhook to receive the transformed content, got %q", webhook.lastContent)
+	}
+}
+
+func TestURLShortener_ShortensOnlyLongLinks(t *testing.T) {
+	shortener := URLShortener{BaseURL: "https://sho.rt/", MinLength: 30}
+	long := "https://example.com/campaigns/summer-sale?utm_source=sms"
+
+	got, err := shortener.Transform(context.Background(), "Sale: "+long+" or https://ex.co/a")
+	if err != nil {
+		t.Fatalf("Transform returned error: %v", err)
+	}
+
+	want := "Sale: https://sho.rt/" + shortCode(long) + " or https://ex.co/a"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestProcessUnsentMessages_SpacesSendsBySendDelay(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 3; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{ID: i, PhoneNumber: "+905551234567", Content: "hello"})
+	}
+	webhook := &fakeWebhookClient{}
+
+	const delay = 20 * time.Millisecond
+	cfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 1000, SendDelay: delay}
+	svc := NewMessageService(repo, webhook, nil, cfg)
+
+	if _, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(webhook.callTimes) != 3 {
+		t.Fatalf("expected 3 sends, got %d", len(webhook.callTimes))
+	}
+	for i := 1; i < len(webhook.callTimes); i++ {
+		if gap := webhook.callTimes[i].Sub(webhook.callTimes[i-1]); gap < delay {
+			t.Fatalf("expected sends %d and %d to be at least %v apart, got %v", i, i+1, delay, gap)
+		}
+	}
+}
+
+func TestProcessUnsentMessages_CancelDuringSendDelayLeavesRestPending(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := int64(1); i <= 3; i++ {
+		repo.unsent = append(repo.unsent, domain.Message{ID: i, PhoneNumber: "+905551234567", Content: "hello"})
+	}
+
+	cfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 1000, SendDelay: time.Hour}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(results) != 1 || len(repo.markSentCalls) != 1 || len(repo.markFailedCalls) != 0 {
+		t.Fatalf("expected only the first message to be sent before cancellation, got %d results", len(results))
+	}
+}
+
+type fakeObjectStore struct {
+	err     error
+	key     string
+	content string
+	size    int64
+	events  *[]string
+}
+
+func (s *fakeObjectStore) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.key, s.content, s.size = key, string(data), size
+	if s.events != nil {
+		*s.events = append(*s.events, "upload")
+	}
+	return s.err
+}
+
+func TestArchiveSentMessages_UploadsJSONLinesThenPurges(t *testing.T) {
+	var events []string
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	oldSend := now.Add(-40 * 24 * time.Hour)
+	recentSend := now.Add(-time.Hour)
+	repo := &fakeRepo{
+		unsent: []domain.Message{
+			{ID: 4, Content: "old one", PhoneNumber: "+905551234567", Status: domain.StatusSent, SentAt: &oldSend},
+			// Sent before sent_at was recorded; archived by updated_at.
+			{ID: 9, Content: "old two", PhoneNumber: "+905551234568", Status: domain.StatusSent, UpdatedAt: oldSend},
+			{ID: 10, Content: "recent", PhoneNumber: "+905551234569", Status: domain.StatusSent, SentAt: &recentSend},
+			{ID: 11, Content: "pending", PhoneNumber: "+905551234569", Status: domain.StatusPending},
+		},
+		events: &events,
+	}
+	store := &fakeObjectStore{events: &events}
+
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+	svc.SetClock(func() time.Time { return now })
+	svc.SetArchiveStore(store, "archive/")
+
+	result, err := svc.ArchiveSentMessages(context.Background(), 30*24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("ArchiveSentMessages returned error: %v", err)
+	}
+
+	cutoff := now.Add(-30 * 24 * time.Hour)
+	if store.key != "archive/messages-sent-before-20250502T120000Z.jsonl" || result.Key != store.key {
+		t.Errorf("unexpected archive key %q (result %q)", store.key, result.Key)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(store.content, "\n"), "\n")
+	if len(lines) != 2 || int64(len(store.content)) != store.size {
+		t.Fatalf("expected 2 lines of %d bytes, got %q", store.size, store.content)
+	}
+	var first domain.Message
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("archive line is not JSON: %v", err)
+	}
+	if first.ID != 4 || first.Content != "old one" {
+		t.Errorf("unexpected first archived message %+v", first)
+	}
+
+	if !slices.Equal(events, []string{"upload", "purge"}) {
+		t.Fatalf("expected the purge to follow the upload, got %v", events)
+	}
+	if len(repo.archivePurges) != 1 || !repo.archivePurges[0].cutoff.Equal(cutoff) || repo.archivePurges[0].maxID != 9 {
+		t.Errorf("expected a purge up to id 9 before %v, got %+v", cutoff, repo.archivePurges)
+	}
+	if result.Archived != 2 || result.Purged != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestArchiveSentMessages_FailedUploadDoesNotPurge(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{{ID: 1, Content: "old", Status: domain.StatusSent}}}
+	store := &fakeObjectStore{err: errors.New("bucket unavailable")}
+
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+	svc.SetArchiveStore(store, "")
+
+	if _, err := svc.ArchiveSentMessages(context.Background(), 24*time.Hour, true); err == nil {
+		t.Fatal("expected the upload error to be returned")
+	}
+	if len(repo.archivePurges) != 0 {
+		t.Fatalf("expected no purge after a failed upload, got %+v", repo.archivePurges)
+	}
+}
+
+func TestArchiveSentMessages_RequiresStore(t *testing.T) {
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	if _, err := svc.ArchiveSentMessages(context.Background(), time.Hour, false); !errors.Is(err, domain.ErrArchiveNotConfigured) {
+		t.Fatalf("expected ErrArchiveNotConfigured, got %v", err)
+	}
+}
+
+func TestProcessUnsentMessages_SkipsIdenticalSendWithinDedupWindow(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "Your code is 1234", PhoneNumber: "+905551234567"},
+		{ID: 2, Content: "Your code is 1234", PhoneNumber: "+905551234567"},
+		{ID: 3, Content: "Your code is 1234", PhoneNumber: "+905559999999"},
+	}}
+	webhook := &fakeWebhookClient{}
+	redis := &fakeRedisClient{}
+
+	cfg := environments.MessageConfig{BatchSize: 3, MaxContentLength: 1000, DedupWindow: 10 * time.Minute}
+	svc := NewMessageService(repo, webhook, redis, cfg)
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+
+	if len(results) != 3 || !results[0].Success || results[2].Deduped || !results[2].Success {
+		t.Fatalf("expected the first send and the other number to go through, got %+v", results)
+	}
+	if second := results[1]; !second.Deduped || second.Success || second.Error != nil {
+		t.Fatalf("expected the second identical send to be deduped, got %+v", second)
+	}
+	if webhook.calls != 2 {
+		t.Errorf("expected the provider to be called twice, got %d", webhook.calls)
+	}
+	if !slices.Equal(repo.dedupedCalls, []int64{2}) || len(repo.markFailedCalls) != 0 {
+		t.Errorf("expected only message 2 marked deduped, got deduped=%v failed=%v", repo.dedupedCalls, repo.markFailedCalls)
+	}
+	for _, ttl := range redis.fingerprints {
+		if ttl != 10*time.Minute {
+			t.Errorf("expected fingerprints kept for the dedup window, got %v", ttl)
+		}
+	}
+	if got := svc.OutcomeCounts().Deduped; got != 1 {
+		t.Errorf("expected 1 deduped outcome, got %d", got)
+	}
+}
+
+func TestProcessUnsentMessages_KillSwitchLeavesMessagesPending(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "first", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+		{ID: 2, Content: "second", PhoneNumber: "+905551234568", Status: domain.StatusPending},
+	}}
+	webhook := &fakeWebhookClient{responseMessageID: "msg"}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000})
+
+	if err := svc.SetSendingEnabled(ctx, false); err != nil {
+		t.Fatalf("SetSendingEnabled returned error: %v", err)
+	}
+
+	// Even a forced failure rate must not touch the messages.
+	results, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 1.0)
+	if !errors.Is(err, domain.ErrSendingDisabled) {
+		t.Fatalf("expected ErrSendingDisabled, got %v", err)
+	}
+	if results != nil || webhook.calls != 0 {
+		t.Fatalf("expected no results and no webhook calls, got %d results and %d calls", len(results), webhook.calls)
+	}
+	if len(repo.markSentCalls) != 0 || len(repo.markFailedCalls) != 0 {
+		t.Fatalf("expected messages to stay pending, got sent=%v failed=%v", repo.markSentCalls, repo.markFailedCalls)
+	}
+
+	if err := svc.SetSendingEnabled(ctx, true); err != nil {
+		t.Fatalf("SetSendingEnabled returned error: %v", err)
+	}
+	if _, err := svc.ProcessUnsentMessages(ctx, domain.DefaultQueue, 0.0); err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if webhook.calls != 2 {
+		t.Fatalf("expected both messages to be sent once enabled, got %d calls", webhook.calls)
+	}
+}
+
+func TestProcessUnsentMessages_ReadsKillSwitchOncePerChunk(t *testing.T) {
+	repo := &fakeRepo{}
+	for i := range 3 {
+		repo.unsent = append(repo.unsent, domain.Message{ID: int64(i + 1), Content: "hello", PhoneNumber: fmt.Sprintf("+90555123456%d", i)})
+	}
+	webhook := &fakeWebhookClient{responseMessageID: "msg"}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000})
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if repo.sendingChecks != 1 {
+		t.Errorf("expected the kill switch to be read once for the single chunk, got %d reads", repo.sendingChecks)
+	}
+}
+
+func TestSuppressPhone_StopsPendingAndLaterMessagesToTheNumber(t *testing.T) {
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Content: "Sale!", PhoneNumber: "+905551234567", Status: domain.StatusPending},
+		{ID: 2, Content: "Sale!", PhoneNumber: "+905559999999", Status: domain.StatusPending},
+	}}
+	webhook := &fakeWebhookClient{responseMessageID: "msg"}
+	svc := NewMessageService(repo, webhook, nil, environments.MessageConfig{BatchSize: 10, MaxContentLength: 1000})
+
+	result, err := svc.SuppressPhone(context.Background(), "+90 555 123 45 67", " complained ")
+	if err != nil {
+		t.Fatalf("SuppressPhone returned error: %v", err)
+	}
+	if result.Suppression.PhoneNumber != "+905551234567" || result.Suppressed != 1 {
+		t.Fatalf("expected the normalized number and one stopped message, got %+v", result)
+	}
+	if reason := result.Suppression.Reason; reason == nil || *reason != "complained" {
+		t.Fatalf("expected the trimmed reason, got %v", reason)
+	}
+
+	// A message created after the suppression is still pending; the run suppresses it instead
+	// of fetching it.
+	repo.unsent = append(repo.unsent, domain.Message{ID: 3, Content: "Last chance", PhoneNumber: "+905551234567", Status: domain.StatusPending})
+
+	results, err := svc.ProcessUnsentMessages(context.Background(), domain.DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("ProcessUnsentMessages returned error: %v", err)
+	}
+	if len(results) != 1 || !results[0].Success || results[0].MessageDBID != 2 {
+		t.Fatalf("expected only message 2 sent, got %+v", results)
+	}
+	if webhook.calls != 1 || webhook.lastPhone != "+905559999999" {
+		t.Errorf("expected the provider to be called only for the other number, got %d calls (last %s)", webhook.calls, webhook.lastPhone)
+	}
+	if status := repo.unsent[2].Status; status != domain.StatusSuppressed {
+		t.Errorf("expected message 3 suppressed, got %s", status)
+	}
+	if got := svc.OutcomeCounts().Suppressed; got != 1 {
+		t.Errorf("expected 1 suppressed outcome, got %d", got)
+	}
+}
+
+func TestUnsuppressPhone_UnknownNumber(t *testing.T) {
+	svc := NewMessageService(&fakeRepo{}, &fakeWebhookClient{}, nil, environments.MessageConfig{})
+
+	if err := svc.UnsuppressPhone(context.Background(), "+905551234567"); !errors.Is(err, domain.ErrSuppressionNotFound) {
+		t.Fatalf("expected ErrSuppressionNotFound, got %v", err)
+	}
+}
+
+func TestGetQueueHead_FollowsUnsentOrderAndSendAt(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+	repo := &fakeRepo{unsent: []domain.Message{
+		{ID: 1, Status: domain.StatusPending, CreatedAt: base.Add(2 * time.Minute)},
+		{ID: 2, Status: domain.StatusSent, CreatedAt: base},
+		{ID: 3, Status: domain.StatusPending, CreatedAt: base.Add(time.Minute)},
+		{ID: 4, Status: domain.StatusPending, CreatedAt: base, SendAt: &later},
+		{ID: 5, Status: domain.StatusPending, CreatedAt: base.Add(3 * time.Minute)},
+		{ID: 6, Status: domain.StatusPending, CreatedAt: base, Queue: "bulk"},
+	}}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, nil, environments.MessageConfig{Queues: []string{"bulk"}})
+
+	ids := func(messages []domain.Message) []int64 {
+		var got []int64
+		for _, m := range messages {
+			got = append(got, m.ID)
+		}
+		return got
+	}
+
+	// Message 4 is the oldest but not due yet; message 2 is sent and message 6 is in another queue.
+	head, err := svc.GetQueueHead(context.Background(), "", 2)
+	if err != nil {
+		t.Fatalf("GetQueueHead returned error: %v", err)
+	}
+	if got := ids(head); !slices.Equal(got, []int64{3, 1}) {
+		t.Fatalf("expected the two oldest due messages [3 1], got %v", got)
+	}
+
+	repo.processOrder = domain.ProcessOrderLIFO
+	head, err = svc.GetQueueHead(context.Background(), domain.DefaultQueue, 10)
+	if err != nil {
+		t.Fatalf("GetQueueHead returned error: %v", err)
+	}
+	if got := ids(head); !slices.Equal(got, []int64{5, 1, 3}) {
+		t.Fatalf("expected due messages newest first [5 1 3], got %v", got)
+	}
+
+	if _, err := svc.GetQueueHead(context.Background(), "unknown", 10); !errors.Is(err, domain.ErrUnknownQueue) {
+		t.Fatalf("expected ErrUnknownQueue, got %v", err)
+	}
+}
+
+func TestSimulateRun_AppliesTheRunsPreSendChecks(t *testing.T) {
+	link := "Sale: https://example.com/" + strings.Repeat("x", 40)
+	repo := suppressedAfterFetchRepo{
+		fakeRepo: &fakeRepo{unsent: []domain.Message{
+			{ID: 1, Content: link, PhoneNumber: "+905551234567"},
+			{ID: 2, Content: link, PhoneNumber: "+905551234567"},
+			{ID: 3, Content: "hi", PhoneNumber: "+905550000001"},
+			{ID: 4, Content: "code 1234", PhoneNumber: "+905551234568"},
+		}},
+		numbers: map[string]bool{"+905550000001": true},
+	}
+	redis := &fakeRedisClient{fingerprints: map[string]time.Duration{
+		sendFingerprint("+905551234568", "code 1234"): time.Minute,
+	}}
+	cfg := environments.MessageConfig{BatchSize: 10, MaxContentLength: 40, DedupWindow: time.Minute}
+	svc := NewMessageService(repo, &fakeWebhookClient{}, redis, cfg)
+	svc.SetContentTransformer(URLShortener{BaseURL: "https://sho.rt", MinLength: 20})
+
+	run, err := svc.SimulateRun(context.Background(), domain.DefaultQueue)
+	if err != nil {
+		t.Fatalf("SimulateRun returned error: %v", err)
+	}
+
+	expected := []domain.SimulatedOutcome{
+		domain.SimulatedSendOutcome,
+		domain.SimulatedDedupedOutcome, // Identical to message 1, which the run would send first
+		domain.SimulatedSuppressedOutcome,
+		domain.SimulatedDedupedOutcome, // Sent within the dedup window before the run
+	}
+	if len(run.Messages) != len(expected) {
+		t.Fatalf("expected %d predictions, got %+v", len(expected), run.Messages)
+	}
+	for i, want := range expected {
+		if got := run.Messages[i].Outcome; got != want {
+			t.Errorf("message %d: expected %s, got %s", run.Messages[i].ID, want, got)
+		}
+	}
+	// The shortened link fits; the content as stored would have been truncated.
+	if run.Messages[0].Truncated {
+		t.Errorf("expected the preview to use the transformed content, got %+v", run.Messages[0])
+	}
+	if len(repo.suppressedCalls) != 0 || len(repo.dedupedCalls) != 0 || len(redis.fingerprints) != 1 {
+		t.Errorf("expected no writes, got suppressed=%v deduped=%v fingerprints=%d",
+			repo.suppressedCalls, repo.dedupedCalls, len(redis.fingerprints))
+	}
+
+	repo.sendingDisabled = true
+	run, err = svc.SimulateRun(context.Background(), domain.DefaultQueue)
+	if err != nil {
+		t.Fatalf("SimulateRun returned error: %v", err)
+	}
+	for _, msg := range run.Messages {
+		if msg.Outcome != domain.SimulatedSendingDisabledOutcome {
+			t.Errorf("message %d: expected %s with the kill switch off, got %s", msg.ID, domain.SimulatedSendingDisabledOutcome, msg.Outcome)
+		}
+	}
+}
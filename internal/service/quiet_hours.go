@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onurcolak/insider-message-service/environments"
+)
+
+// quietHours is a daily window, in a fixed timezone, during which nothing is sent.
+// A window whose end is before its start wraps past midnight (e.g. 22:00-08:00).
+type quietHours struct {
+	start, end time.Duration // offsets from local midnight
+	loc        *time.Location
+}
+
+// parseQuietHours builds the window from config; it returns nil when quiet hours are not configured.
+func parseQuietHours(cfg environments.MessageConfig) (*quietHours, error) {
+	if cfg.QuietHoursStart == "" && cfg.QuietHoursEnd == "" {
+		return nil, nil
+	}
+
+	start, err := parseClock(cfg.QuietHoursStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	end, err := parseClock(cfg.QuietHoursEnd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+
+	loc := time.UTC
+	if cfg.QuietHoursTimezone != "" {
+		if loc, err = time.LoadLocation(cfg.QuietHoursTimezone); err != nil {
+			return nil, fmt.Errorf("invalid quiet hours timezone: %w", err)
+		}
+	}
+
+	return &quietHours{start: start, end: end, loc: loc}, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight.
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether t falls inside the window.
+func (q *quietHours) contains(t time.Time) bool {
+	local := t.In(q.loc)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	if q.start <= q.end {
+		return offset >= q.start && offset < q.end
+	}
+	return offset >= q.start || offset < q.end
+}
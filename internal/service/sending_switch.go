@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+)
+
+// SetSendingEnabled turns the global kill switch on or off. While it is off, runs leave every
+// message pending and the schedulers keep running, so sending resumes as soon as it is turned on.
+func (s *MessageService) SetSendingEnabled(ctx context.Context, enabled bool) error {
+	if err := s.repo.SetSendingEnabled(ctx, enabled); err != nil {
+		return err
+	}
+
+	if enabled {
+		logger.Infof("Sending enabled by the kill switch")
+	} else {
+		logger.Warnf("Sending disabled by the kill switch, messages stay pending until it is turned on")
+	}
+	return nil
+}
+
+// sendingEnabled reports whether the kill switch allows sending. Unlike the suppression check, a
+// failed lookup holds the message: the switch exists to stop sends when in doubt.
+func (s *MessageService) sendingEnabled(ctx context.Context) bool {
+	enabled, err := s.repo.IsSendingEnabled(ctx)
+	if err != nil {
+		logger.Errorf("Failed to read the sending kill switch, holding messages: %v", err)
+		return false
+	}
+	return enabled
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/onurcolak/insider-message-service/pkg/phone"
+)
+
+// SuppressPhone stops all messages to phoneNumber: its pending messages are moved to suppressed
+// right away and messages created later are suppressed when the scheduler picks them up.
+func (s *MessageService) SuppressPhone(ctx context.Context, phoneNumber, reason string) (*domain.SuppressionResult, error) {
+	var r *string
+	if reason = strings.TrimSpace(reason); reason != "" {
+		r = &reason
+	}
+
+	suppression, suppressed, err := s.repo.AddSuppression(ctx, phone.Normalize(phoneNumber), r)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Suppressed %s, %d pending messages stopped", suppression.PhoneNumber, suppressed)
+	return &domain.SuppressionResult{Suppression: suppression, Suppressed: suppressed}, nil
+}
+
+// UnsuppressPhone lets messages to phoneNumber be sent again. Messages suppressed so far stay
+// suppressed.
+func (s *MessageService) UnsuppressPhone(ctx context.Context, phoneNumber string) error {
+	return s.repo.RemoveSuppression(ctx, phone.Normalize(phoneNumber))
+}
+
+// suppressPending moves the queue's pending messages to suppressed numbers out of the way before a
+// run fetches any. A failure is only logged: such messages are not fetched either way.
+func (s *MessageService) suppressPending(ctx context.Context, queue string) {
+	suppressed, err := s.repo.SuppressPending(ctx, queue)
+	if err != nil {
+		logger.Warnf("Failed to suppress pending messages of queue %q: %v", queue, err)
+		return
+	}
+	if suppressed > 0 {
+		logger.Infof("Suppressed %d pending messages of queue %q", suppressed, queue)
+		s.suppressed.Add(suppressed)
+		s.metrics.Add("messages_suppressed", suppressed)
+	}
+}
+
+// isSuppressed reports whether the message's number is suppressed. A failed lookup is logged and
+// treated as not suppressed, so an outage of the check does not stop all sending.
+func (s *MessageService) isSuppressed(ctx context.Context, msg *domain.Message) bool {
+	suppressed, err := s.repo.IsSuppressed(ctx, msg.PhoneNumber)
+	if err != nil {
+		logger.Warnf("Failed to check suppression for message %d, sending it: %v", msg.ID, err)
+		return false
+	}
+	return suppressed
+}
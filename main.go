@@ -6,19 +6,27 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"text/template"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/onurcolak/insider-message-service/environments"
 	"github.com/onurcolak/insider-message-service/handlers"
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/internal/middlewares"
 	"github.com/onurcolak/insider-message-service/internal/repository"
 	"github.com/onurcolak/insider-message-service/internal/scheduler"
 	"github.com/onurcolak/insider-message-service/internal/service"
 	"github.com/onurcolak/insider-message-service/pkg/database"
 	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/onurcolak/insider-message-service/pkg/metrics"
+	"github.com/onurcolak/insider-message-service/pkg/objectstore"
 	"github.com/onurcolak/insider-message-service/pkg/redis"
+	"github.com/onurcolak/insider-message-service/pkg/requestid"
+	"github.com/onurcolak/insider-message-service/pkg/response"
 	"github.com/onurcolak/insider-message-service/pkg/validator"
 	"github.com/onurcolak/insider-message-service/pkg/webhook"
 	"github.com/onurcolak/insider-message-service/routes"
@@ -49,7 +57,10 @@ func main() {
 
 	// Hard-fail if required secrets are missing
 	if cfg.Webhook.AuthKey == "" {
-		logger.Fatalf("WEBHOOK_AUTH_KEY is required but not set")
+		if cfg.Webhook.AuthRequired {
+			logger.Fatalf("WEBHOOK_AUTH_KEY is required but not set (set WEBHOOK_AUTH_REQUIRED=false for providers without auth)")
+		}
+		logger.Warnf("WEBHOOK_AUTH_KEY is not set; webhook calls are sent without an auth header")
 	}
 	if cfg.Auth.MessagesAPIKey == "" {
 		logger.Fatalf("MESSAGES_API_KEY is required but not set")
@@ -57,6 +68,18 @@ func main() {
 	if cfg.Auth.SchedulerAPIKey == "" {
 		logger.Fatalf("SCHEDULER_API_KEY is required but not set")
 	}
+	if cfg.Auth.DLRAPIKey == "" {
+		logger.Warnf("DLR_API_KEY is not set; delivery receipt webhooks will be rejected")
+	}
+
+	var alertTemplate *template.Template
+	if cfg.Alert.MessageTemplate != "" {
+		tmpl, err := scheduler.ParseAlertTemplate(cfg.Alert.MessageTemplate)
+		if err != nil {
+			logger.Fatalf("ALERT_MESSAGE_TEMPLATE is invalid: %v", err)
+		}
+		alertTemplate = tmpl
+	}
 
 	logger.Infof("Starting Insider Message Service...")
 
@@ -66,6 +89,15 @@ func main() {
 		logger.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Read replica for list and stats queries, if configured
+	var replica *sqlx.DB
+	if cfg.Database.ReplicaDSN != "" {
+		replica, err = database.NewReplicaDB(cfg.Database.ReplicaDSN)
+		if err != nil {
+			logger.Fatalf("Failed to connect to read replica: %v", err)
+		}
+	}
+
 	// Run migrations
 	if err := database.RunMigrations(db); err != nil {
 		logger.Fatalf("Failed to run migrations: %v", err)
@@ -79,11 +111,9 @@ func main() {
 	}
 
 	// Init redis
-	var redisClient *redis.Client
-	redisClient, err = redis.NewRedisClient(cfg.Redis)
+	redisClient, err := connectRedis(cfg.Redis, redis.NewRedisClient)
 	if err != nil {
-		logger.Warnf("Redis not available, caching disabled: %v", err)
-		redisClient = nil
+		logger.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
 	// Initialize webhook client
@@ -92,57 +122,178 @@ func main() {
 
 	// Initialize repository
 	messageRepo := repository.NewMessageRepository(db)
+	messageRepo.ReadAttempts = cfg.Database.ReadRetryAttempts
+	if replica != nil {
+		messageRepo.SetReplica(replica)
+	}
+	if order := cfg.Message.ProcessOrder; order != domain.ProcessOrderFIFO && order != domain.ProcessOrderLIFO {
+		logger.Warnf("Unknown MESSAGE_PROCESS_ORDER %q, using %s", order, domain.ProcessOrderFIFO)
+	}
+	messageRepo.ProcessOrder = cfg.Message.ProcessOrder
+	messageRepo.MaxReplays = cfg.Message.MaxReplays
+	if err := messageRepo.SetContentKey(cfg.Database.ContentEncryptionKey); err != nil {
+		logger.Fatalf("Failed to set up content encryption: %v", err)
+	}
+	schedulerStateRepo := repository.NewSchedulerStateRepository(db)
+	runResultRepo := repository.NewRunResultRepository(db)
+	runResultRepo.Keep = cfg.Server.SchedulerRunsKept
 
-	// Initialize service
-	messageService := service.NewMessageService(
-		messageRepo,
-		webhookClient,
-		redisClient,
-		cfg.Message,
-	)
+	// Initialize service. Without Redis pass an untyped nil: a nil *redis.Client would reach the
+	// service as a non-nil interface and caching would not be treated as disabled.
+	var messageService *service.MessageService
+	if redisClient != nil {
+		messageService = service.NewMessageService(messageRepo, webhookClient, redisClient, cfg.Message)
+	} else {
+		messageService = service.NewMessageService(messageRepo, webhookClient, nil, cfg.Message)
+	}
+	if cfg.Redis.BatchCacheWrites {
+		messageService.SetBatchCacheWrites(true)
+	}
+	if cfg.Message.URLShortenerBase != "" {
+		messageService.SetContentTransformer(service.URLShortener{
+			BaseURL:   cfg.Message.URLShortenerBase,
+			MinLength: cfg.Message.URLShortenerMinLength,
+		})
+	}
+	if cfg.Archive.Endpoint != "" && cfg.Archive.Bucket != "" {
+		store, err := objectstore.NewS3Client(cfg.Archive)
+		if err != nil {
+			logger.Fatalf("Failed to configure archive store: %v", err)
+		}
+		messageService.SetArchiveStore(store, cfg.Archive.Prefix)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize scheduler
+	// Initialize schedulers: the default queue plus one per configured named queue
 	sched := scheduler.NewScheduler(messageService, cfg.Message.SendInterval)
+	schedulers := []*scheduler.Scheduler{sched}
+	for _, queue := range cfg.Message.Queues {
+		if queue == domain.DefaultQueue {
+			continue
+		}
+		schedulers = append(schedulers, scheduler.NewQueueScheduler(queue, messageService, cfg.Message.SendInterval))
+	}
+	for _, s := range schedulers {
+		s.MinInterval = cfg.Message.MinSendInterval
+		s.AlertTimeout = cfg.Alert.Timeout
+		s.AlertRetries = cfg.Alert.Retries
+		s.AlertRetryBackoff = cfg.Alert.RetryBackoff
+		s.DeadLetterURL = cfg.Alert.DeadLetterWebhookURL
+		s.AlertTemplate = alertTemplate
+		s.StartDelay = cfg.Server.SchedulerStartDelay
+		s.IdleStopRuns = cfg.Server.SchedulerIdleStopRuns
+		s.FailureRetryInterval = cfg.Server.SchedulerFailureRetry
+		s.Store = schedulerStateRepo
+		s.Runs = runResultRepo
+	}
+
+	// Restart a queue's scheduler if it stopped itself for being idle.
+	messageService.SetCreateHook(func(queue string) {
+		for _, s := range schedulers {
+			if s.Queue() != queue {
+				continue
+			}
+			if err := s.Wake(ctx); err != nil {
+				logger.Errorf("Failed to wake scheduler for queue %q: %v", queue, err)
+			}
+		}
+	})
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(db, redisClient)
+	healthHandler.SetBacklogCheck(messageService, cfg.Server.HealthPendingBacklog)
 	messageHandler := handlers.NewMessageHandler(messageService)
-	schedulerHandler := handlers.NewSchedulerHandler(sched, ctx, cfg)
+	messageHandler.SetTrustedMaxPageSize(cfg.Auth.TrustedMaxPageSize)
+	if mode := cfg.Message.ControlChars; mode != validator.ControlCharsReject && mode != validator.ControlCharsStrip {
+		logger.Warnf("Unknown MESSAGE_CONTROL_CHARS %q, using %s", mode, validator.ControlCharsReject)
+	}
+	messageHandler.SetStripControlChars(cfg.Message.ControlChars == validator.ControlCharsStrip)
+	schedulerHandler := handlers.NewSchedulerHandler(schedulers, messageService, ctx, cfg)
+	adminHandler := handlers.NewAdminHandler(messageService, sched, healthHandler)
+	webhookHandler := handlers.NewWebhookHandler(messageService)
+	suppressionHandler := handlers.NewSuppressionHandler(messageService)
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(cfg, db.DriverName(), redisClient != nil)
+	metricsHandler := handlers.NewMetricsHandler(messageService)
+
+	// Recent error responses are only recorded when RECENT_ERRORS_SIZE is set.
+	var recentErrors *middlewares.RecentErrors
+	recentErrorsHandler := handlers.NewRecentErrorsHandler(nil)
+	if cfg.Server.RecentErrorsSize > 0 {
+		recentErrors = middlewares.NewRecentErrors(cfg.Server.RecentErrorsSize)
+		recentErrorsHandler = handlers.NewRecentErrorsHandler(recentErrors)
+	}
 
-	// Auto-start scheduler
-	if os.Getenv("AUTO_START_SCHEDULER") != "false" {
-		logger.Infof("Auto-starting scheduler...")
-		if err := sched.Start(ctx); err != nil {
-			logger.Warnf("Failed to auto-start scheduler: %v", err)
+	// In-memory metrics are only recorded when IN_MEMORY_METRICS_ENABLED is set.
+	inMemoryMetricsHandler := handlers.NewInMemoryMetricsHandler(nil)
+	if cfg.Server.InMemoryMetrics {
+		metricsRegistry := metrics.NewRegistry()
+		messageService.SetMetrics(metricsRegistry)
+		inMemoryMetricsHandler = handlers.NewInMemoryMetricsHandler(metricsRegistry)
+	}
+
+	// Start schedulers: a saved state wins, so a deliberately stopped scheduler stays stopped;
+	// queues without one follow AUTO_START_SCHEDULER.
+	autoStart := os.Getenv("AUTO_START_SCHEDULER") != "false"
+	for _, s := range schedulers {
+		start := autoStart
+		state, err := s.Restore(ctx)
+		if err != nil {
+			logger.Warnf("Failed to restore scheduler for queue %q: %v", s.Queue(), err)
+		} else if state != nil {
+			start = state.Running
+		}
+		if !start {
+			logger.Infof("Scheduler for queue %q left stopped", s.Queue())
+			continue
+		}
+
+		logger.Infof("Auto-starting scheduler for queue %q...", s.Queue())
+		if err := s.Start(ctx); err != nil {
+			logger.Warnf("Failed to auto-start scheduler for queue %q: %v", s.Queue(), err)
 		}
 	}
 
 	e := echo.New()
 	e.HideBanner = true
 	e.Validator = validator.New()
+	if naming := cfg.Server.JSONFieldNaming; naming != response.FieldNamingCamelCase && naming != response.FieldNamingSnakeCase {
+		logger.Warnf("Unknown API_JSON_FIELD_NAMING %q, using %s", naming, response.FieldNamingCamelCase)
+	}
+	e.JSONSerializer = response.JSONSerializer(cfg.Server.JSONFieldNaming)
 
 	// Middleware
 	e.Use(middleware.Logger())
-	e.Use(middleware.RequestID())
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		// Make the id available to outgoing calls, e.g. the webhook client.
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.SetRequest(c.Request().WithContext(requestid.NewContext(c.Request().Context(), id)))
+		},
+	}))
+	if recentErrors != nil {
+		e.Use(recentErrors.Middleware())
+	}
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
 		AllowHeaders: []string{
 			echo.HeaderOrigin,
 			echo.HeaderContentType,
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
 			"x-ins-auth-key",
+			response.EnvelopeHeader,
+			"If-None-Match",
 		},
+		// Lets browsers read the ETag of GET /messages/{id} to revalidate it.
+		ExposeHeaders: []string{"ETag"},
 	}))
 
 	// Setup routes
-	routes.RegisterRoutes(e, healthHandler, messageHandler, schedulerHandler, cfg)
+	routes.RegisterRoutes(e, healthHandler, messageHandler, schedulerHandler, adminHandler, webhookHandler, suppressionHandler, diagnosticsHandler, metricsHandler, recentErrorsHandler, inMemoryMetricsHandler, cfg)
 
 	// Start server in goroutine
 	go func() {
@@ -161,56 +312,52 @@ func main() {
 
 	logger.Infof("Shutting down gracefully...")
 
-	// Cancel context to signal all goroutines to stop
-	cancel()
-
-	// Stop scheduler first (with timeout)
-	if sched.IsRunning() {
-		logger.Infof("Stopping scheduler...")
-		stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer stopCancel()
-
-		done := make(chan error, 1)
-		go func() {
-			done <- sched.Stop()
-		}()
-
-		select {
-		case err := <-done:
-			if err != nil {
-				logger.Errorf("Error stopping scheduler: %v", err)
-			} else {
-				logger.Infof("Scheduler stopped successfully")
-			}
-		case <-stopCtx.Done():
-			logger.Warnf("Scheduler stop timeout, forcing shutdown")
-		}
-	}
-
-	// Shutdown HTTP server (with timeout)
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 
-	logger.Infof("Shutting down HTTP server...")
-	if err := e.Shutdown(shutdownCtx); err != nil {
-		logger.Errorf("Server forced to shutdown: %v", err)
-	} else {
-		logger.Infof("HTTP server stopped successfully")
-	}
-
-	// Close database connection
-	logger.Infof("Closing database connection...")
-	if err := db.Close(); err != nil {
-		logger.Errorf("Error closing database: %v", err)
-	}
+	shutdownSequence{
+		stopHTTP: func() error {
+			return e.Shutdown(shutdownCtx)
+		},
+		drainSchedulers: func() {
+			for _, s := range schedulers {
+				if !s.IsRunning() {
+					continue
+				}
+				logger.Infof("Stopping scheduler for queue %q...", s.Queue())
+
+				timedOut, err := stopWithTimeout(s.Shutdown, cfg.Server.SchedulerStopTimeout, time.After)
+				switch {
+				case timedOut:
+					logger.Warnf("Scheduler stop timeout for queue %q, forcing shutdown", s.Queue())
+				case err != nil:
+					logger.Errorf("Error stopping scheduler for queue %q: %v", s.Queue(), err)
+				default:
+					logger.Infof("Scheduler for queue %q stopped successfully", s.Queue())
+				}
+			}
+		},
+		cancel: cancel,
+		closeConnections: func() {
+			logger.Infof("Closing database connection...")
+			if err := db.Close(); err != nil {
+				logger.Errorf("Error closing database: %v", err)
+			}
+			if replica != nil {
+				if err := replica.Close(); err != nil {
+					logger.Errorf("Error closing read replica: %v", err)
+				}
+			}
 
-	// Close Redis connection
-	if redisClient != nil {
-		logger.Infof("Closing Redis connection...")
-		if err := redisClient.Close(); err != nil {
-			logger.Errorf("Error closing Redis: %v", err)
-		}
-	}
+			if redisClient != nil {
+				// Close ends pub/sub subscriptions and waits for in-flight publishes first.
+				logger.Infof("Closing Redis connection...")
+				if err := redisClient.Close(); err != nil {
+					logger.Errorf("Error closing Redis: %v", err)
+				}
+			}
+		},
+	}.run()
 
 	logger.Infof("Graceful shutdown completed")
 }
@@ -16,6 +16,28 @@ func NewMySQLDB(cfg environments.DatabaseConfig) (*sqlx.DB, error) {
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName,
 	)
 
+	db, err := connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Connected to MySQL database")
+	return db, nil
+}
+
+// NewReplicaDB connects to a read replica. dsn is a go-sql-driver/mysql DSN and needs
+// parseTime=true, like the primary connection.
+func NewReplicaDB(dsn string) (*sqlx.DB, error) {
+	db, err := connect(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+
+	logger.Infof("Connected to MySQL read replica")
+	return db, nil
+}
+
+func connect(dsn string) (*sqlx.DB, error) {
 	db, err := sqlx.Connect("mysql", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -27,10 +49,10 @@ func NewMySQLDB(cfg environments.DatabaseConfig) (*sqlx.DB, error) {
 
 	// Verify connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Infof("Connected to MySQL database")
 	return db, nil
 }
 
@@ -43,11 +65,31 @@ func RunMigrations(db *sqlx.DB) error {
 		status VARCHAR(20) NOT NULL DEFAULT 'pending',
 		message_id VARCHAR(100),
 		sent_at DATETIME,
+		tags VARCHAR(512),
+		queue VARCHAR(50) NOT NULL DEFAULT 'default',
+		timeout_seconds INT,
+		send_at DATETIME,
+		campaign_id VARCHAR(32),
+		client VARCHAR(20) NOT NULL DEFAULT 'default',
+		client_message_id VARCHAR(100),
+		truncated BOOLEAN NOT NULL DEFAULT FALSE,
+		original_length INT,
+		last_error VARCHAR(1000),
+		first_failed_at DATETIME,
+		replay_count INT NOT NULL DEFAULT 0,
+		retry_count INT NOT NULL DEFAULT 0,
+		delivery_status VARCHAR(20),
+		delivery_updated_at DATETIME,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 		INDEX idx_messages_status (status),
 		INDEX idx_messages_created_at (created_at),
-		INDEX idx_messages_sent_at (sent_at)
+		INDEX idx_messages_sent_at (sent_at),
+		INDEX idx_messages_updated_at (updated_at),
+		UNIQUE INDEX uq_messages_message_id (message_id),
+		INDEX idx_messages_queue_status (queue, status, created_at),
+		INDEX idx_messages_campaign_id (campaign_id),
+		UNIQUE INDEX uq_messages_client_client_message_id (client, client_message_id)
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 	`
 
@@ -56,11 +98,211 @@ func RunMigrations(db *sqlx.DB) error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Tables created by older versions need newer columns and indexes added explicitly.
+	if err := ensureColumn(db, "messages", "tags", "VARCHAR(512) AFTER sent_at"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "last_error", "VARCHAR(1000) AFTER tags"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureIndex(db, "messages", "idx_messages_updated_at", "updated_at"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "delivery_status", "VARCHAR(20) AFTER last_error"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "delivery_updated_at", "DATETIME AFTER delivery_status"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	// message_id used to have a plain index. The unique one cannot be added while duplicates are
+	// stored; then the plain index is kept and startup continues.
+	if err := ensureUniqueIndex(db, "messages", "uq_messages_message_id", "message_id"); err != nil {
+		logger.Warnf("Keeping the non-unique message_id index: %v", err)
+		if err := ensureIndex(db, "messages", "idx_messages_message_id", "message_id"); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+	} else if err := dropIndex(db, "messages", "idx_messages_message_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "first_failed_at", "DATETIME AFTER last_error"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "queue", "VARCHAR(50) NOT NULL DEFAULT 'default' AFTER tags"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureIndex(db, "messages", "idx_messages_queue_status", "queue, status, created_at"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "timeout_seconds", "INT AFTER queue"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "truncated", "BOOLEAN NOT NULL DEFAULT FALSE AFTER timeout_seconds"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "original_length", "INT AFTER truncated"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "send_at", "DATETIME AFTER timeout_seconds"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "campaign_id", "VARCHAR(32) AFTER send_at"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureIndex(db, "messages", "idx_messages_campaign_id", "campaign_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "client_message_id", "VARCHAR(100) AFTER campaign_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "client", "VARCHAR(20) NOT NULL DEFAULT 'default' AFTER campaign_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	// Client message ids are unique per client, not across all of them.
+	if err := ensureUniqueIndex(db, "messages", "uq_messages_client_client_message_id", "client, client_message_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := dropIndex(db, "messages", "uq_messages_client_message_id"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "replay_count", "INT NOT NULL DEFAULT 0 AFTER first_failed_at"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if err := ensureColumn(db, "messages", "retry_count", "INT NOT NULL DEFAULT 0 AFTER replay_count"); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Scheduler state per queue, restored on boot.
+	schedulerState := `
+	CREATE TABLE IF NOT EXISTS scheduler_state (
+		queue VARCHAR(50) PRIMARY KEY,
+		running BOOLEAN NOT NULL,
+		interval_seconds BIGINT NOT NULL,
+		failure_rate DOUBLE NOT NULL DEFAULT 0,
+		runs_count BIGINT NOT NULL DEFAULT 0,
+		messages_sent BIGINT NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+	if _, err := db.Exec(schedulerState); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Per-message results of recent scheduler runs, pruned on every save.
+	schedulerRuns := `
+	CREATE TABLE IF NOT EXISTS scheduler_runs (
+		queue VARCHAR(50) NOT NULL,
+		run_number BIGINT NOT NULL,
+		started_at DATETIME NOT NULL,
+		results MEDIUMTEXT NOT NULL,
+		PRIMARY KEY (queue, run_number)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+	if _, err := db.Exec(schedulerRuns); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Phone numbers no message is sent to, e.g. after a complaint.
+	suppressions := `
+	CREATE TABLE IF NOT EXISTS suppressions (
+		phone_number VARCHAR(20) PRIMARY KEY,
+		reason VARCHAR(255),
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+	if _, err := db.Exec(suppressions); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Global kill switch for sending, one row with id 1; no row means sending is enabled.
+	sendingSwitch := `
+	CREATE TABLE IF NOT EXISTS sending_switch (
+		id TINYINT PRIMARY KEY,
+		enabled BOOLEAN NOT NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+	if _, err := db.Exec(sendingSwitch); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
 	logger.Infof("Database migrations completed")
 
 	return nil
 }
 
+// ensureColumn adds the column with the given definition if it does not exist yet.
+func ensureColumn(db *sqlx.DB, table, column, definition string) error {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+	`
+	if err := db.Get(&count, query, table, column); err != nil {
+		return fmt.Errorf("failed to check column %s: %w", column, err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s: %w", column, err)
+	}
+
+	return nil
+}
+
+// ensureIndex creates the index if it does not exist yet.
+func ensureIndex(db *sqlx.DB, table, index, columns string) error {
+	return createIndexIfMissing(db, "INDEX", table, index, columns)
+}
+
+// ensureUniqueIndex creates the unique index if it does not exist yet. It fails if the stored
+// rows already violate it.
+func ensureUniqueIndex(db *sqlx.DB, table, index, columns string) error {
+	return createIndexIfMissing(db, "UNIQUE INDEX", table, index, columns)
+}
+
+func createIndexIfMissing(db *sqlx.DB, kind, table, index, columns string) error {
+	exists, err := indexExists(db, table, index)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, index, table, columns)); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", index, err)
+	}
+
+	return nil
+}
+
+// dropIndex removes the index if it exists.
+func dropIndex(db *sqlx.DB, table, index string) error {
+	exists, err := indexExists(db, table, index)
+	if err != nil || !exists {
+		return err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("DROP INDEX %s ON %s", index, table)); err != nil {
+		return fmt.Errorf("failed to drop index %s: %w", index, err)
+	}
+
+	return nil
+}
+
+func indexExists(db *sqlx.DB, table, index string) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?
+	`
+	if err := db.Get(&count, query, table, index); err != nil {
+		return false, fmt.Errorf("failed to check index %s: %w", index, err)
+	}
+
+	return count > 0, nil
+}
+
 func SeedTestData(db *sqlx.DB) error {
 	var count int
 
@@ -0,0 +1,32 @@
+// Package idempotency carries the identity of a webhook send attempt through a context, so
+// the webhook client can send an Idempotency-Key the provider can dedupe on.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Header is the header the idempotency key is sent in.
+const Header = "Idempotency-Key"
+
+// Attempt identifies one send attempt of a message. QueuedAt is when the message was last
+// (re)queued, so a replayed message starts over with new keys instead of reusing old ones.
+type Attempt struct {
+	MessageID int64
+	QueuedAt  time.Time
+	Number    int
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the attempt.
+func NewContext(ctx context.Context, attempt Attempt) context.Context {
+	return context.WithValue(ctx, contextKey{}, attempt)
+}
+
+// FromContext returns the attempt stored in ctx, if any.
+func FromContext(ctx context.Context) (Attempt, bool) {
+	attempt, ok := ctx.Value(contextKey{}).(Attempt)
+	return attempt, ok
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"log"
+	"log/slog"
 )
 
 // Initialize logging flags (called once from main)
@@ -28,3 +29,19 @@ func Debugf(format string, v ...any) {
 func Fatalf(format string, v ...any) {
 	log.Fatalf("[FATAL] "+format, v...)
 }
+
+// stdWriter writes to the standard logger's current output, so structured lines go wherever the
+// other log lines go, even if the output is changed after startup.
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	return log.Writer().Write(p)
+}
+
+var structured = slog.New(slog.NewJSONHandler(stdWriter{}, nil))
+
+// Structured logs msg as a single JSON object with the given key-value pairs, for lines meant
+// to be parsed by log pipelines rather than read.
+func Structured(msg string, args ...any) {
+	structured.Info(msg, args...)
+}
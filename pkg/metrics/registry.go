@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"maps"
+	"sync"
+)
+
+// Registry keeps named counters and gauges in memory, for deployments without a Prometheus
+// scraper. It is safe for concurrent use. All methods are no-ops on a nil Registry, so callers
+// can record unconditionally when it is disabled.
+type Registry struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+	averages map[string]*average
+}
+
+// average is a gauge reported as the mean of all observed samples.
+type average struct {
+	sum   float64
+	count int64
+}
+
+// Snapshot is a point-in-time copy of a registry's values.
+type Snapshot struct {
+	Counters map[string]int64   `json:"counters"`
+	Gauges   map[string]float64 `json:"gauges"`
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		counters: make(map[string]int64),
+		gauges:   make(map[string]float64),
+		averages: make(map[string]*average),
+	}
+}
+
+// Add increases the counter name by delta.
+func (r *Registry) Add(name string, delta int64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name] += delta
+}
+
+// Inc increases the counter name by one.
+func (r *Registry) Inc(name string) {
+	r.Add(name, 1)
+}
+
+// Set sets the gauge name to value.
+func (r *Registry) Set(name string, value float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[name] = value
+}
+
+// Observe adds a sample to the gauge name, which is reported as the average of all samples.
+func (r *Registry) Observe(name string, value float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	avg, ok := r.averages[name]
+	if !ok {
+		avg = &average{}
+		r.averages[name] = avg
+	}
+	avg.sum += value
+	avg.count++
+}
+
+// Snapshot returns a copy of the current values; averaged gauges are included with the others.
+func (r *Registry) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Counters: make(map[string]int64),
+		Gauges:   make(map[string]float64),
+	}
+	if r == nil {
+		return snapshot
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maps.Copy(snapshot.Counters, r.counters)
+	maps.Copy(snapshot.Gauges, r.gauges)
+	for name, avg := range r.averages {
+		snapshot.Gauges[name] = avg.sum / float64(avg.count)
+	}
+	return snapshot
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRegistry_ConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Inc("sent")
+			r.Observe("latency_ms", float64(i%2)*10) // Half 0, half 10
+			r.Snapshot()
+		}()
+	}
+	wg.Wait()
+	r.Set("last_run", 3)
+
+	snapshot := r.Snapshot()
+	if snapshot.Counters["sent"] != 50 {
+		t.Fatalf("expected 50 sent, got %d", snapshot.Counters["sent"])
+	}
+	if snapshot.Gauges["latency_ms"] != 5 || snapshot.Gauges["last_run"] != 3 {
+		t.Fatalf("unexpected gauges: %v", snapshot.Gauges)
+	}
+}
+
+func TestRegistry_NilIsNoop(t *testing.T) {
+	var r *Registry
+
+	r.Inc("sent")
+	r.Set("last_run", 1)
+	r.Observe("latency_ms", 1)
+
+	snapshot := r.Snapshot()
+	if len(snapshot.Counters) != 0 || len(snapshot.Gauges) != 0 {
+		t.Fatalf("expected an empty snapshot, got %+v", snapshot)
+	}
+}
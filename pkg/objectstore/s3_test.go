@@ -0,0 +1,72 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onurcolak/insider-message-service/environments"
+)
+
+func TestPutObject_SendsSignedPathStyleUpload(t *testing.T) {
+	var (
+		gotPath, gotAuth, gotBody string
+		gotLength                 int64
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotAuth, gotBody, gotLength = r.URL.Path, r.Header.Get("Authorization"), string(body), r.ContentLength
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewS3Client(environments.ArchiveConfig{
+		Endpoint:  server.URL,
+		Bucket:    "archives",
+		Region:    "eu-west-1",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "secret",
+		Timeout:   time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Client returned error: %v", err)
+	}
+	client.now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+
+	content := "{\"id\":1}\n"
+	if err := client.PutObject(context.Background(), "archive/a.jsonl", strings.NewReader(content), int64(len(content)), "application/x-ndjson"); err != nil {
+		t.Fatalf("PutObject returned error: %v", err)
+	}
+
+	if gotPath != "/archives/archive/a.jsonl" {
+		t.Errorf("expected a path-style key, got %q", gotPath)
+	}
+	if gotBody != content || gotLength != int64(len(content)) {
+		t.Errorf("expected the body with its length, got %q (%d)", gotBody, gotLength)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20250601/eu-west-1/s3/aws4_request, ") ||
+		strings.Contains(gotAuth, "secret") {
+		t.Errorf("unexpected Authorization header %q", gotAuth)
+	}
+}
+
+func TestPutObject_ReportsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := NewS3Client(environments.ArchiveConfig{Endpoint: server.URL, Bucket: "archives", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewS3Client returned error: %v", err)
+	}
+
+	err = client.PutObject(context.Background(), "a.jsonl", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected a 403 error, got %v", err)
+	}
+}
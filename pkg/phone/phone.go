@@ -0,0 +1,96 @@
+// Package phone holds the small amount of phone number handling the service needs.
+package phone
+
+import "strings"
+
+// Normalize strips common formatting characters and rewrites a leading "00"
+// international prefix to "+", e.g. "0090 (555) 123-45-67" becomes "+905551234567".
+func Normalize(number string) string {
+	var b strings.Builder
+	b.Grow(len(number))
+
+	for _, r := range strings.TrimSpace(number) {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	normalized := b.String()
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + strings.TrimPrefix(normalized, "00")
+	}
+	return normalized
+}
+
+// HasCountryCode reports whether a normalized number starts with one of the given
+// country codes. Codes may be written with or without the leading "+".
+func HasCountryCode(number string, codes []string) bool {
+	if !strings.HasPrefix(number, "+") {
+		return false
+	}
+
+	for _, code := range codes {
+		code = strings.TrimPrefix(strings.TrimSpace(code), "+")
+		if code != "" && strings.HasPrefix(number[1:], code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether a normalized number is plausible: an optional leading "+" followed by
+// 7 to 15 digits (the E.164 maximum).
+func Valid(number string) bool {
+	digits := strings.TrimPrefix(number, "+")
+	if len(digits) < 7 || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// twoDigitCountryCodes are the ITU calling codes that are two digits long. Calling codes are
+// prefix-free, so together with the one-digit codes 1 and 7 they determine a code's length:
+// everything else is three digits.
+var twoDigitCountryCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true, "36": true,
+	"39": true, "40": true, "41": true, "43": true, "44": true, "45": true, "46": true, "47": true,
+	"48": true, "49": true, "51": true, "52": true, "53": true, "54": true, "55": true, "56": true,
+	"57": true, "58": true, "60": true, "61": true, "62": true, "63": true, "64": true, "65": true,
+	"66": true, "81": true, "82": true, "84": true, "86": true, "90": true, "91": true, "92": true,
+	"93": true, "94": true, "95": true, "98": true,
+}
+
+// CountryCode returns the calling code of a normalized international number, e.g. "+90" for
+// "+905551234567", or "" if the number has no leading "+" or is too short to tell.
+func CountryCode(number string) string {
+	digits, ok := strings.CutPrefix(number, "+")
+	if !ok || len(digits) < 3 {
+		return ""
+	}
+
+	switch {
+	case digits[0] == '1' || digits[0] == '7':
+		return "+" + digits[:1]
+	case twoDigitCountryCodes[digits[:2]]:
+		return "+" + digits[:2]
+	default:
+		return "+" + digits[:3]
+	}
+}
+
+// Mask hides all but the first three and last two characters of a number for logging, e.g.
+// "+905551234567" becomes "+90********67". Numbers too short to keep anything are masked entirely.
+func Mask(number string) string {
+	const keepStart, keepEnd = 3, 2
+	if len(number) <= keepStart+keepEnd {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:keepStart] + strings.Repeat("*", len(number)-keepStart-keepEnd) + number[len(number)-keepEnd:]
+}
@@ -0,0 +1,86 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"+905551234567":        "+905551234567",
+		" +90 555 123 45 67 ":  "+905551234567",
+		"0090 (555) 123-45-67": "+905551234567",
+		"+44.20.7946.0958":     "+442079460958",
+		"5551234567":           "5551234567",
+	}
+
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHasCountryCode(t *testing.T) {
+	codes := []string{"+90", "44"}
+
+	if !HasCountryCode("+905551234567", codes) {
+		t.Error("expected +90 number to match")
+	}
+	if !HasCountryCode("+442079460958", codes) {
+		t.Error("expected +44 number to match a code written without +")
+	}
+	if HasCountryCode("+15551234567", codes) {
+		t.Error("expected +1 number not to match")
+	}
+	if HasCountryCode("905551234567", codes) {
+		t.Error("expected a number without + not to match")
+	}
+}
+
+func TestValid(t *testing.T) {
+	cases := map[string]bool{
+		"+905551234567":     true,
+		"5551234567":        true,
+		"+90555":            false,
+		"+9055512345678901": false,
+		"+90555abc4567":     false,
+		"":                  false,
+	}
+
+	for in, want := range cases {
+		if got := Valid(in); got != want {
+			t.Errorf("Valid(%q) = %t, want %t", in, got, want)
+		}
+	}
+}
+
+func TestCountryCode(t *testing.T) {
+	cases := map[string]string{
+		"+905551234567": "+90",
+		"+442079460958": "+44",
+		"+15551234567":  "+1",
+		"+79161234567":  "+7",
+		"+35312345678":  "+353",
+		"905551234567":  "",
+		"+9":            "",
+	}
+
+	for in, want := range cases {
+		if got := CountryCode(in); got != want {
+			t.Errorf("CountryCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	cases := map[string]string{
+		"+905551234567": "+90********67",
+		"5551234":       "555**34",
+		"+9055":         "*****",
+		"":              "",
+	}
+
+	for in, want := range cases {
+		if got := Mask(in); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/onurcolak/insider-message-service/environments"
@@ -14,11 +16,18 @@ import (
 
 type Client struct {
 	client valkey.Client
+
+	mu            sync.Mutex
+	closed        bool
+	subscriptions map[*Subscription]struct{}
+	inFlight      sync.WaitGroup // Publishes that Close waits for
 }
 
 const (
 	sentMessageKeyPrefix = "sent_message:"
 	sentMessageTTL       = 24 * time.Hour
+
+	sendFingerprintKeyPrefix = "send_fingerprint:"
 )
 
 func NewRedisClient(cfg environments.RedisConfig) (*Client, error) {
@@ -70,6 +79,44 @@ func (c *Client) CacheSentMessage(ctx context.Context, dbID int64, messageID str
 	return nil
 }
 
+// CacheSentMessages caches several sent messages, keyed by their DB id, in a single pipeline.
+func (c *Client) CacheSentMessages(ctx context.Context, entries map[int64]*domain.SentMessageCache) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(entries))
+	cmds := make(valkey.Commands, 0, len(entries))
+	for dbID, cache := range entries {
+		data, err := json.Marshal(cache)
+		if err != nil {
+			return fmt.Errorf("failed to marshal cache data: %w", err)
+		}
+
+		key := fmt.Sprintf("%s%d", sentMessageKeyPrefix, dbID)
+		ids = append(ids, dbID)
+		cmds = append(cmds, c.client.B().Set().Key(key).Value(string(data)).Ex(sentMessageTTL).Build())
+	}
+
+	var failed []int64
+	var firstErr error
+	for i, resp := range c.client.DoMulti(ctx, cmds...) {
+		if err := resp.Error(); err != nil {
+			failed = append(failed, ids[i])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to cache %d of %d sent messages %v: %w", len(failed), len(entries), failed, firstErr)
+	}
+
+	logger.Debugf("Cached %d sent messages in Redis", len(entries))
+
+	return nil
+}
+
 func (c *Client) GetCachedMessage(ctx context.Context, dbID int64) (*domain.SentMessageCache, error) {
 	key := fmt.Sprintf("%s%d", sentMessageKeyPrefix, dbID)
 
@@ -94,29 +141,53 @@ func (c *Client) GetCachedMessage(ctx context.Context, dbID int64) (*domain.Sent
 	return &cache, nil
 }
 
-func (c *Client) GetAllCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error) {
-	pattern := fmt.Sprintf("%s*", sentMessageKeyPrefix)
+// scanPage fetches one page of keys for a SCAN cursor and returns the next cursor.
+type scanPage func(ctx context.Context, cursor uint64) ([]string, uint64, error)
 
+// scanKeys walks a SCAN cursor to the end. If a page fails, the keys collected so far are
+// returned together with the error.
+func scanKeys(ctx context.Context, scan scanPage) ([]string, error) {
 	var keys []string
 	var cursor uint64
 	for {
-		result := c.client.Do(ctx, c.client.B().Scan().Cursor(cursor).Match(pattern).Count(100).Build())
-		if result.Error() != nil {
-			return nil, fmt.Errorf("failed to scan cache keys: %w", result.Error())
-		}
-
-		scanResult, err := result.AsScanEntry()
+		page, next, err := scan(ctx, cursor)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse scan result: %w", err)
+			return keys, err
 		}
 
-		keys = append(keys, scanResult.Elements...)
-		cursor = scanResult.Cursor
+		keys = append(keys, page...)
+		cursor = next
 
 		if cursor == 0 {
-			break
+			return keys, nil
 		}
 	}
+}
+
+func (c *Client) scanSentMessageKeys(ctx context.Context, cursor uint64) ([]string, uint64, error) {
+	pattern := fmt.Sprintf("%s*", sentMessageKeyPrefix)
+
+	result := c.client.Do(ctx, c.client.B().Scan().Cursor(cursor).Match(pattern).Count(100).Build())
+	if result.Error() != nil {
+		return nil, 0, fmt.Errorf("failed to scan cache keys: %w", result.Error())
+	}
+
+	scanResult, err := result.AsScanEntry()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse scan result: %w", err)
+	}
+
+	return scanResult.Elements, scanResult.Cursor, nil
+}
+
+// GetAllCachedMessages returns every cached sent message. If the key scan fails after some keys
+// were found, the entries for those keys are returned with an error wrapping
+// domain.ErrPartialCacheRead.
+func (c *Client) GetAllCachedMessages(ctx context.Context) (map[int64]*domain.SentMessageCache, error) {
+	keys, scanErr := scanKeys(ctx, c.scanSentMessageKeys)
+	if scanErr != nil && len(keys) == 0 {
+		return nil, scanErr
+	}
 
 	result := make(map[int64]*domain.SentMessageCache)
 
@@ -146,10 +217,94 @@ func (c *Client) GetAllCachedMessages(ctx context.Context) (map[int64]*domain.Se
 		result[dbID] = &cache
 	}
 
+	if scanErr != nil {
+		logger.Warnf("Returning %d cached messages after a failed key scan: %v", len(result), scanErr)
+		return result, fmt.Errorf("%w: %w", domain.ErrPartialCacheRead, scanErr)
+	}
+
+	return result, nil
+}
+
+// IsRecentSend reports whether fingerprint was remembered by RememberSend and has not expired.
+func (c *Client) IsRecentSend(ctx context.Context, fingerprint string) (bool, error) {
+	n, err := c.client.Do(ctx, c.client.B().Exists().Key(sendFingerprintKeyPrefix+fingerprint).Build()).AsInt64()
+	if err != nil {
+		return false, fmt.Errorf("failed to check send fingerprint: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RememberSend stores fingerprint for ttl, so IsRecentSend reports it until then.
+func (c *Client) RememberSend(ctx context.Context, fingerprint string, ttl time.Duration) error {
+	key := sendFingerprintKeyPrefix + fingerprint
+	if err := c.client.Do(ctx, c.client.B().Set().Key(key).Value("1").Ex(ttl).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to store send fingerprint: %w", err)
+	}
+	return nil
+}
+
+// mgetChunkSize bounds the keys per MGET; larger id lists are split into pipelined MGETs.
+const mgetChunkSize = 100
+
+// GetCachedMessages returns the cached entries for the given message ids. Ids without a cache
+// entry are absent from the result.
+func (c *Client) GetCachedMessages(ctx context.Context, ids []int64) (map[int64]*domain.SentMessageCache, error) {
+	result := make(map[int64]*domain.SentMessageCache, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var cmds valkey.Commands
+	for chunk := range slices.Chunk(ids, mgetChunkSize) {
+		keys := make([]string, len(chunk))
+		for i, id := range chunk {
+			keys[i] = fmt.Sprintf("%s%d", sentMessageKeyPrefix, id)
+		}
+		cmds = append(cmds, c.client.B().Mget().Key(keys...).Build())
+	}
+
+	// A nil value means the key does not exist.
+	values := make([]*string, 0, len(ids))
+	for _, resp := range c.client.DoMulti(ctx, cmds...) {
+		entries, err := resp.ToArray()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cached messages: %w", err)
+		}
+
+		for _, entry := range entries {
+			data, err := entry.ToString()
+			if err != nil {
+				values = append(values, nil)
+				continue
+			}
+			values = append(values, &data)
+		}
+	}
+
+	decodeCachedMessages(result, ids, values)
 	return result, nil
 }
 
+// decodeCachedMessages adds the entry of ids[i] for every values[i] that is present and valid.
+func decodeCachedMessages(result map[int64]*domain.SentMessageCache, ids []int64, values []*string) {
+	for i, value := range values {
+		if i >= len(ids) || value == nil {
+			continue
+		}
+
+		var cache domain.SentMessageCache
+		if err := json.Unmarshal([]byte(*value), &cache); err != nil {
+			logger.Warnf("failed to unmarshal cached message %d: %v", ids[i], err)
+			continue
+		}
+		result[ids[i]] = &cache
+	}
+}
+
+// Close closes the open subscriptions, waits for in-flight publishes and then closes the
+// connection. Publish and Subscribe return ErrClientClosed afterwards.
 func (c *Client) Close() error {
+	c.drain()
 	c.client.Close()
 	return nil
 }
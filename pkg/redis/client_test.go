@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/onurcolak/insider-message-service/internal/domain"
+)
+
+func TestScanKeys_ReturnsKeysCollectedBeforeMidScanError(t *testing.T) {
+	scanErr := errors.New("connection reset")
+	pages := map[uint64]struct {
+		keys []string
+		next uint64
+	}{
+		0:  {keys: []string{"sent_message:1", "sent_message:2"}, next: 10},
+		10: {keys: []string{"sent_message:3"}, next: 20},
+	}
+
+	scan := func(_ context.Context, cursor uint64) ([]string, uint64, error) {
+		page, ok := pages[cursor]
+		if !ok {
+			return nil, 0, scanErr
+		}
+		return page.keys, page.next, nil
+	}
+
+	keys, err := scanKeys(context.Background(), scan)
+	if !errors.Is(err, scanErr) {
+		t.Fatalf("expected the scan error, got %v", err)
+	}
+	if want := []string{"sent_message:1", "sent_message:2", "sent_message:3"}; !slices.Equal(keys, want) {
+		t.Fatalf("expected keys %v, got %v", want, keys)
+	}
+}
+
+func TestScanKeys_StopsAtCursorZero(t *testing.T) {
+	calls := 0
+	scan := func(_ context.Context, cursor uint64) ([]string, uint64, error) {
+		calls++
+		return []string{"sent_message:1"}, 0, nil
+	}
+
+	keys, err := scanKeys(context.Background(), scan)
+	if err != nil {
+		t.Fatalf("scanKeys returned error: %v", err)
+	}
+	if calls != 1 || len(keys) != 1 {
+		t.Fatalf("expected a single page with one key, got %d calls and %v", calls, keys)
+	}
+}
+
+func TestDecodeCachedMessages_SkipsMissingAndInvalidValues(t *testing.T) {
+	present := `{"messageId":"msg-1","sentAt":"2025-03-01T10:00:00Z"}`
+	invalid := `not json`
+
+	result := make(map[int64]*domain.SentMessageCache)
+	decodeCachedMessages(result, []int64{1, 2, 3}, []*string{&present, nil, &invalid})
+
+	if len(result) != 1 {
+		t.Fatalf("expected only the present entry, got %+v", result)
+	}
+	want := domain.SentMessageCache{MessageID: "msg-1", SentAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)}
+	if got := result[1]; got == nil || got.MessageID != want.MessageID || !got.SentAt.Equal(want.SentAt) {
+		t.Fatalf("expected %+v for id 1, got %+v", want, got)
+	}
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/valkey-io/valkey-go"
+)
+
+// ErrClientClosed is returned by Publish and Subscribe once Close has been called.
+var ErrClientClosed = errors.New("redis client is closed")
+
+// Subscription receives the messages published to one channel until it is closed.
+type Subscription struct {
+	channel string
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// Channel returns the subscribed channel.
+func (s *Subscription) Channel() string {
+	return s.channel
+}
+
+// Close unsubscribes and waits for the handler to return. It is safe to call more than once.
+func (s *Subscription) Close() error {
+	s.cancel()
+	<-s.done
+	return s.err
+}
+
+// Publish sends payload to channel. Close waits for publishes that are in flight.
+func (c *Client) Publish(ctx context.Context, channel, payload string) error {
+	return c.track(func() error {
+		if err := c.client.Do(ctx, c.client.B().Publish().Channel(channel).Message(payload).Build()).Error(); err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", channel, err)
+		}
+		return nil
+	})
+}
+
+// Subscribe calls handler for every message published to channel, one at a time, until the
+// returned subscription or the client is closed, or ctx is cancelled.
+func (c *Client) Subscribe(ctx context.Context, channel string, handler func(payload string)) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{channel: channel, cancel: cancel, done: make(chan struct{})}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		cancel()
+		return nil, ErrClientClosed
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[*Subscription]struct{})
+	}
+	c.subscriptions[sub] = struct{}{}
+
+	go func() {
+		defer close(sub.done)
+		defer c.forget(sub)
+
+		err := c.client.Receive(ctx, c.client.B().Subscribe().Channel(channel).Build(), func(msg valkey.PubSubMessage) {
+			handler(msg.Message)
+		})
+		// Cancellation is how a subscription ends normally.
+		if err != nil && ctx.Err() == nil {
+			sub.err = fmt.Errorf("subscription to %s ended: %w", channel, err)
+			logger.Errorf("%v", sub.err)
+		}
+	}()
+
+	return sub, nil
+}
+
+// forget drops a finished subscription from the set closed by Close.
+func (c *Client) forget(sub *Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subscriptions, sub)
+}
+
+// track runs op as an in-flight operation that Close waits for, unless the client is closed.
+func (c *Client) track(op func() error) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	c.inFlight.Add(1)
+	c.mu.Unlock()
+
+	defer c.inFlight.Done()
+	return op()
+}
+
+// drain rejects new publishes and subscriptions, closes the open subscriptions and waits for
+// in-flight publishes to finish.
+func (c *Client) drain() {
+	c.mu.Lock()
+	c.closed = true
+	subscriptions := make([]*Subscription, 0, len(c.subscriptions))
+	for sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	c.mu.Unlock()
+
+	if len(subscriptions) > 0 {
+		logger.Infof("Closing %d Redis subscription(s)...", len(subscriptions))
+	}
+	for _, sub := range subscriptions {
+		if err := sub.Close(); err != nil {
+			logger.Warnf("Error closing subscription to %s: %v", sub.channel, err)
+		}
+	}
+
+	c.inFlight.Wait()
+}
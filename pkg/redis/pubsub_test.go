@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// closeRecorder is a valkey client that only records Close; other methods must not be called.
+type closeRecorder struct {
+	valkey.Client
+	closed atomic.Bool
+}
+
+func (r *closeRecorder) Close() {
+	r.closed.Store(true)
+}
+
+func TestClose_WaitsForInFlightPublish(t *testing.T) {
+	conn := &closeRecorder{}
+	client := &Client{client: conn}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	publishDone := make(chan error)
+	go func() {
+		publishDone <- client.track(func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	closeDone := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closeDone)
+	}()
+
+	select {
+	case <-closeDone:
+		t.Fatal("Close returned while a publish was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if conn.closed.Load() {
+		t.Fatal("connection closed while a publish was in flight")
+	}
+
+	close(release)
+	if err := <-publishDone; err != nil {
+		t.Fatalf("in-flight publish returned error: %v", err)
+	}
+	select {
+	case <-closeDone:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after the publish finished")
+	}
+	if !conn.closed.Load() {
+		t.Fatal("expected the connection to be closed")
+	}
+
+	if err := client.track(func() error { return nil }); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed after Close, got %v", err)
+	}
+}
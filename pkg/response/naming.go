@@ -0,0 +1,218 @@
+package response
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Supported JSON field naming styles for response bodies.
+const (
+	FieldNamingCamelCase = "camelCase"
+	FieldNamingSnakeCase = "snake_case"
+)
+
+// JSONSerializer returns the Echo serializer for the given field naming. Struct tags stay
+// camelCase; snake_case responses are produced by renaming the field names of the encoded body.
+// Field names are the keys that come from struct fields, map[string]any (used for ad-hoc
+// objects) and FieldMap; keys of other maps are data, e.g. queue names, and are kept.
+// Request bodies are always decoded as-is.
+func JSONSerializer(naming string) echo.JSONSerializer {
+	if naming == FieldNamingSnakeCase {
+		return snakeCaseSerializer{}
+	}
+	return &echo.DefaultJSONSerializer{}
+}
+
+// FieldMap is an object whose keys are field names rather than data, such as a message reduced to
+// some of its fields; snake_case responses rename its keys like struct fields.
+type FieldMap map[string]json.RawMessage
+
+type snakeCaseSerializer struct {
+	echo.DefaultJSONSerializer
+}
+
+func (s snakeCaseSerializer) Serialize(c echo.Context, i any, indent string) error {
+	raw, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	// UseNumber keeps int64 ids exact instead of round-tripping them through float64.
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(c.Response())
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	return enc.Encode(snakeCaseFields(reflect.ValueOf(i), body))
+}
+
+var (
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+	fieldMapType  = reflect.TypeFor[FieldMap]()
+	anyMapType    = reflect.TypeFor[map[string]any]()
+)
+
+// snakeCaseFields renames the field names in decoded, the JSON form of v, walking v alongside it
+// to tell field names from data keys. Where v is unknown, e.g. below a custom marshaler, every
+// key is taken for a field name.
+func snakeCaseFields(v reflect.Value, decoded any) any {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+	if v.IsValid() && v.Type().Implements(marshalerType) {
+		return snakeCaseKeys(decoded)
+	}
+
+	switch value := decoded.(type) {
+	case map[string]any:
+		switch {
+		case !v.IsValid():
+			return snakeCaseKeys(value)
+		case v.Kind() == reflect.Struct:
+			fields := jsonFields(v)
+			renamed := make(map[string]any, len(value))
+			for key, item := range value {
+				renamed[toSnakeCase(key)] = snakeCaseFields(fields[key], item)
+			}
+			return renamed
+		case v.Kind() == reflect.Map:
+			entries := mapEntries(v)
+			fieldNames := v.Type() == fieldMapType || v.Type() == anyMapType
+			renamed := make(map[string]any, len(value))
+			for key, item := range value {
+				name := key
+				if fieldNames {
+					name = toSnakeCase(key)
+				}
+				renamed[name] = snakeCaseFields(entries[key], item)
+			}
+			return renamed
+		default:
+			return snakeCaseKeys(value)
+		}
+	case []any:
+		sequence := v.IsValid() && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array)
+		for i, item := range value {
+			var element reflect.Value
+			if sequence && i < v.Len() {
+				element = v.Index(i)
+			}
+			value[i] = snakeCaseFields(element, item)
+		}
+		return value
+	default:
+		return decoded
+	}
+}
+
+// jsonFields maps the JSON names of a struct's encoded fields, promoted ones included, to
+// their values.
+func jsonFields(v reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			for value.Kind() == reflect.Pointer && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				for promoted, promotedValue := range jsonFields(value) {
+					if _, shadowed := fields[promoted]; !shadowed {
+						fields[promoted] = promotedValue
+					}
+				}
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// mapEntries indexes a map's values by their JSON object key.
+func mapEntries(v reflect.Value) map[string]reflect.Value {
+	entries := make(map[string]reflect.Value, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key := iter.Key()
+		switch {
+		case key.Kind() == reflect.String:
+			entries[key.String()] = iter.Value()
+		case key.CanInt():
+			entries[strconv.FormatInt(key.Int(), 10)] = iter.Value()
+		case key.CanUint():
+			entries[strconv.FormatUint(key.Uint(), 10)] = iter.Value()
+		default:
+			if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+				if text, err := marshaler.MarshalText(); err == nil {
+					entries[string(text)] = iter.Value()
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// snakeCaseKeys renames every object key in v, recursively.
+func snakeCaseKeys(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		renamed := make(map[string]any, len(value))
+		for key, item := range value {
+			renamed[toSnakeCase(key)] = snakeCaseKeys(item)
+		}
+		return renamed
+	case []any:
+		for i, item := range value {
+			value[i] = snakeCaseKeys(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// toSnakeCase converts camelCase to snake_case, keeping acronyms together
+// (e.g. "messageId" -> "message_id", "lastRunAt" -> "last_run_at", "sentViaURL" -> "sent_via_url").
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
@@ -2,13 +2,31 @@ package response
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
 
+// EnvelopeHeader lets clients opt out of the {success, data} envelope: with
+// "X-Response-Envelope: false", Ok and Paginated write the bare payload.
+const EnvelopeHeader = "X-Response-Envelope"
+
+// WarningHeader carries the warning of OkWithWarning when the envelope is disabled.
+const WarningHeader = "X-Warning"
+
+// Pagination headers sent instead of the envelope fields when the envelope is disabled.
+const (
+	PageHeader       = "X-Page"
+	PageSizeHeader   = "X-Page-Size"
+	TotalCountHeader = "X-Total-Count"
+	TotalPagesHeader = "X-Total-Pages"
+	OutOfRangeHeader = "X-Page-Out-Of-Range"
+)
+
 type SuccessResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
+	Warning string `json:"warning,omitempty"`
 	Data    any    `json:"data,omitempty"`
 }
 
@@ -24,11 +42,39 @@ type PaginatedResponse struct {
 	PageSize   int   `json:"pageSize"`
 	TotalCount int64 `json:"totalCount"`
 	TotalPages int   `json:"totalPages"`
+	// PageOutOfRange is set when page is past the last page, so clients paging until an empty
+	// data array can tell the end of the list from an empty page.
+	PageOutOfRange bool `json:"pageOutOfRange,omitempty"`
+}
+
+// envelopeDisabled reports whether the client asked for the bare payload via EnvelopeHeader.
+func envelopeDisabled(c echo.Context) bool {
+	enabled, err := strconv.ParseBool(c.Request().Header.Get(EnvelopeHeader))
+	return err == nil && !enabled
 }
 
 func Ok(c echo.Context, data any) error {
+	if envelopeDisabled(c) {
+		return c.JSON(http.StatusOK, data)
+	}
+
+	return c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// OkWithWarning reports success with data that may be incomplete, e.g. when a dependency failed
+// partway through a read.
+func OkWithWarning(c echo.Context, warning string, data any) error {
+	if envelopeDisabled(c) {
+		c.Response().Header().Set(WarningHeader, warning)
+		return c.JSON(http.StatusOK, data)
+	}
+
 	return c.JSON(http.StatusOK, SuccessResponse{
 		Success: true,
+		Warning: warning,
 		Data:    data,
 	})
 }
@@ -81,6 +127,14 @@ func NotFound(c echo.Context, message string) error {
 	})
 }
 
+// Conflict reports that the request is valid but clashes with the resource's current state.
+func Conflict(c echo.Context, err error) error {
+	return c.JSON(http.StatusConflict, ErrorResponse{
+		Success: false,
+		Error:   err.Error(),
+	})
+}
+
 func InternalServerError(c echo.Context, err error) error {
 	return c.JSON(http.StatusInternalServerError, ErrorResponse{
 		Success: false,
@@ -88,6 +142,15 @@ func InternalServerError(c echo.Context, err error) error {
 	})
 }
 
+// GatewayTimeout reports that a downstream dependency (e.g. the database) did not answer in time.
+// The underlying error is not exposed since it usually carries raw driver details.
+func GatewayTimeout(c echo.Context) error {
+	return c.JSON(http.StatusGatewayTimeout, ErrorResponse{
+		Success: false,
+		Error:   "The request timed out, please retry",
+	})
+}
+
 func UnprocessableEntity(c echo.Context, err error) error {
 	return c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
 		Success: false,
@@ -100,13 +163,28 @@ func Paginated(c echo.Context, data any, page, pageSize int, totalCount int64) e
 	if int(totalCount)%pageSize > 0 {
 		totalPages++
 	}
+	// Page 1 of an empty list is not out of range: it is the (empty) first page.
+	outOfRange := page > max(totalPages, 1)
+
+	if envelopeDisabled(c) {
+		header := c.Response().Header()
+		header.Set(PageHeader, strconv.Itoa(page))
+		header.Set(PageSizeHeader, strconv.Itoa(pageSize))
+		header.Set(TotalCountHeader, strconv.FormatInt(totalCount, 10))
+		header.Set(TotalPagesHeader, strconv.Itoa(totalPages))
+		if outOfRange {
+			header.Set(OutOfRangeHeader, "true")
+		}
+		return c.JSON(http.StatusOK, data)
+	}
 
 	return c.JSON(http.StatusOK, PaginatedResponse{
-		Success:    true,
-		Data:       data,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalCount: totalCount,
-		TotalPages: totalPages,
+		Success:        true,
+		Data:           data,
+		Page:           page,
+		PageSize:       pageSize,
+		TotalCount:     totalCount,
+		TotalPages:     totalPages,
+		PageOutOfRange: outOfRange,
 	})
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -50,3 +51,52 @@ func TestPaginated_ComputesTotalPagesCorrectly(t *testing.T) {
 		t.Errorf("expected TotalPages=3, got %d", body.TotalPages)
 	}
 }
+
+func TestToSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"phoneNumber":   "phone_number",
+		"messageId":     "message_id",
+		"totalPages":    "total_pages",
+		"success":       "success",
+		"sentViaURL":    "sent_via_url",
+		"URLPath":       "url_path",
+		"already_snake": "already_snake",
+	}
+
+	for in, want := range cases {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJSONSerializer_SnakeCaseKeepsDataKeys(t *testing.T) {
+	type stats struct {
+		QueueName string                      `json:"queueName"`
+		ByQueue   map[string]map[string]int64 `json:"byQueue"`
+	}
+	type failure struct {
+		ErrorCode string            `json:"errorCode"`
+		Details   map[string]string `json:"details"`
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/test", nil), rec)
+
+	body := map[string]any{
+		"stats":   stats{QueueName: "bulkSms", ByQueue: map[string]map[string]int64{"bulkSms": {"sentOk": 3}}},
+		"failure": &failure{ErrorCode: "invalid", Details: map[string]string{"phoneNumber": "required"}},
+		"fields":  []FieldMap{{"phoneNumber": json.RawMessage(`"+905551234567"`)}},
+	}
+	if err := JSONSerializer(FieldNamingSnakeCase).Serialize(c, body, ""); err != nil {
+		t.Fatalf("Serialize returned error: %v", err)
+	}
+
+	want := `{"failure":{"details":{"phoneNumber":"required"},"error_code":"invalid"},` +
+		`"fields":[{"phone_number":"+905551234567"}],` +
+		`"stats":{"by_queue":{"bulkSms":{"sentOk":3}},"queue_name":"bulkSms"}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("unexpected body:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Package sms estimates how providers split message content into SMS segments.
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// gsm7Basic is the GSM 03.38 basic character set; each character takes one septet.
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension characters are sent as an escape plus the character, taking two septets.
+const gsm7Extension = "\f^{}\\[~]|€"
+
+const (
+	gsm7SingleSegment = 160 // Septets in a message that fits one segment
+	gsm7MultiSegment  = 153 // Septets per segment once the message is split (UDH takes the rest)
+	ucs2SingleSegment = 70  // UTF-16 code units in a single UCS-2 segment
+	ucs2MultiSegment  = 67
+)
+
+// Segments returns the number of SMS segments content is sent as. Content that fits the GSM-7
+// alphabet is counted in septets, anything else as UCS-2. Empty content counts as no segments.
+func Segments(content string) int {
+	if content == "" {
+		return 0
+	}
+
+	if septets, ok := gsm7Length(content); ok {
+		return segmentCount(septets, gsm7SingleSegment, gsm7MultiSegment)
+	}
+
+	units := 0
+	for _, r := range content {
+		units += utf16.RuneLen(r)
+	}
+	return segmentCount(units, ucs2SingleSegment, ucs2MultiSegment)
+}
+
+// gsm7Length returns the length of content in septets, or false if it needs UCS-2.
+func gsm7Length(content string) (int, bool) {
+	septets := 0
+	for _, r := range content {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extension, r):
+			septets += 2
+		default:
+			return 0, false
+		}
+	}
+	return septets, true
+}
+
+func segmentCount(length, single, multi int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
@@ -0,0 +1,30 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{name: "empty", content: "", want: 0},
+		{name: "short gsm", content: "Hello, your code is 1234", want: 1},
+		{name: "gsm single limit", content: strings.Repeat("a", 160), want: 1},
+		{name: "gsm split", content: strings.Repeat("a", 161), want: 2},
+		{name: "gsm extension counts twice", content: strings.Repeat("€", 81), want: 2},
+		{name: "ucs2 single limit", content: strings.Repeat("ş", 70), want: 1},
+		{name: "ucs2 split", content: strings.Repeat("ş", 71), want: 2},
+		{name: "emoji uses two code units", content: strings.Repeat("😀", 35), want: 1},
+		{name: "emoji split", content: strings.Repeat("😀", 36), want: 2},
+	}
+
+	for _, tc := range cases {
+		if got := Segments(tc.content); got != tc.want {
+			t.Errorf("%s: Segments() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"unicode"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -43,17 +44,81 @@ func New() *CustomValidator {
 		panic("failed to register validator default translations: " + err.Error())
 	}
 
+	registerNoControl(validate, trans)
+
 	return &CustomValidator{
 		validator:  validate,
 		translator: trans,
 	}
 }
 
+// How content with control characters is handled (see NoControlTag).
+const (
+	ControlCharsReject = "reject" // Fail validation (default)
+	ControlCharsStrip  = "strip"  // Remove them before validating
+)
+
+// NoControlTag rejects strings containing control characters other than newline, CRLF line
+// breaks and tab, such as null bytes, which can break the provider or log lines.
+const NoControlTag = "nocontrol"
+
+func registerNoControl(validate *validator.Validate, trans ut.Translator) {
+	if err := validate.RegisterValidation(NoControlTag, func(fl validator.FieldLevel) bool {
+		return !HasControlChars(fl.Field().String())
+	}); err != nil {
+		panic("failed to register " + NoControlTag + " validation: " + err.Error())
+	}
+
+	if err := validate.RegisterTranslation(NoControlTag, trans,
+		func(ut ut.Translator) error {
+			return ut.Add(NoControlTag, "{0} must not contain control characters other than line breaks and tab", true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			msg, _ := ut.T(NoControlTag, fe.Field())
+			return msg
+		},
+	); err != nil {
+		panic("failed to register " + NoControlTag + " translation: " + err.Error())
+	}
+}
+
+// disallowedControl reports whether the rune r at byte offset i of s is a disallowed control
+// character. A carriage return is allowed only as part of a CRLF line break.
+func disallowedControl(s string, i int, r rune) bool {
+	if r == '\r' {
+		return !strings.HasPrefix(s[i+1:], "\n")
+	}
+	return unicode.IsControl(r) && r != '\n' && r != '\t'
+}
+
+// HasControlChars reports whether s contains a control character other than newline, CRLF or tab.
+func HasControlChars(s string) bool {
+	for i, r := range s {
+		if disallowedControl(s, i, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripControlChars removes every control character other than newline, CRLF and tab from s.
+func StripControlChars(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range s {
+		if !disallowedControl(s, i, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
 func (cv *CustomValidator) Validate(i any) error {
 	if err := cv.validator.Struct(i); err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
 			return &ValidationError{
 				Errors: cv.translateErrors(validationErrors),
+				Rules:  failedRules(validationErrors),
 			}
 		}
 		return err
@@ -70,8 +135,19 @@ func (cv *CustomValidator) translateErrors(errs validator.ValidationErrors) map[
 	return errors
 }
 
+// failedRules maps each field to the validation tag it failed (e.g. "required", "max"),
+// so clients can localize messages themselves.
+func failedRules(errs validator.ValidationErrors) map[string]string {
+	rules := make(map[string]string, len(errs))
+	for _, err := range errs {
+		rules[err.Field()] = err.Tag()
+	}
+	return rules
+}
+
 type ValidationError struct {
 	Errors map[string]string `json:"errors"`
+	Rules  map[string]string `json:"rules"`
 }
 
 func (e *ValidationError) Error() string {
@@ -86,6 +162,7 @@ type ValidationErrorResponse struct {
 	Success bool              `json:"success"`
 	Error   string            `json:"error"`
 	Details map[string]string `json:"details,omitempty"`
+	Rules   map[string]string `json:"rules,omitempty"`
 }
 
 func HandleValidationError(c echo.Context, err error) error {
@@ -94,6 +171,7 @@ func HandleValidationError(c echo.Context, err error) error {
 			Success: false,
 			Error:   "Validation failed",
 			Details: ve.Errors,
+			Rules:   ve.Rules,
 		})
 	}
 	return c.JSON(http.StatusBadRequest, ValidationErrorResponse{
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -79,3 +80,80 @@ func TestHandleValidationError_Returns422WithDetails(t *testing.T) {
 		t.Fatalf("expected details in validation response, got none")
 	}
 }
+
+func TestHandleValidationError_IncludesRuleName(t *testing.T) {
+	type limitedRequest struct {
+		Content string `json:"content" validate:"required,max=5"`
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/test", nil), rec)
+
+	err := New().Validate(limitedRequest{Content: "too long"})
+	if err := HandleValidationError(c, err); err != nil {
+		t.Fatalf("HandleValidationError returned error: %v", err)
+	}
+
+	var body ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if body.Rules["content"] != "max" {
+		t.Fatalf("expected rule 'max' for content, got %q (rules: %v)", body.Rules["content"], body.Rules)
+	}
+	if body.Details["content"] == "" {
+		t.Fatal("expected the translated message to still be present")
+	}
+}
+
+func TestNoControl_AllowsCleanContentAndNewlines(t *testing.T) {
+	type contentRequest struct {
+		Content string `json:"content" validate:"nocontrol"`
+	}
+
+	for _, content := range []string{"Your code is 1234", "Line one\nLine two\twith a tab"} {
+		if err := New().Validate(contentRequest{Content: content}); err != nil {
+			t.Errorf("expected %q to pass, got %v", content, err)
+		}
+	}
+}
+
+func TestNoControl_RejectsNullByte(t *testing.T) {
+	type contentRequest struct {
+		Content string `json:"content" validate:"nocontrol"`
+	}
+
+	err := New().Validate(contentRequest{Content: "Hello\x00world"})
+
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T (%v)", err, err)
+	}
+	if ve.Rules["content"] != NoControlTag {
+		t.Errorf("expected rule %q for content, got %q", NoControlTag, ve.Rules["content"])
+	}
+	if !strings.Contains(ve.Errors["content"], "control characters") {
+		t.Errorf("expected a translated message, got %q", ve.Errors["content"])
+	}
+}
+
+func TestStripControlChars_KeepsNewlineAndTab(t *testing.T) {
+	got := StripControlChars("a\x00b\x1bc\nd\te\u0085")
+	if got != "abc\nd\te" {
+		t.Fatalf("unexpected stripped content %q", got)
+	}
+}
+
+func TestControlChars_AllowCRLFButNotLoneCarriageReturn(t *testing.T) {
+	if HasControlChars("line one\r\nline two\r\n") {
+		t.Error("expected CRLF line breaks to be allowed")
+	}
+	if !HasControlChars("line one\rline two") {
+		t.Error("expected a lone carriage return to be rejected")
+	}
+	if got := StripControlChars("a\r\nb\rc\r"); got != "a\r\nbc" {
+		t.Fatalf("unexpected stripped content %q", got)
+	}
+}
@@ -0,0 +1,27 @@
+// Package version holds build metadata injected at link time, e.g.:
+//
+//	go build -ldflags "-X github.com/onurcolak/insider-message-service/pkg/version.Version=v1.2.0 \
+//	  -X github.com/onurcolak/insider-message-service/pkg/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/onurcolak/insider-message-service/pkg/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+// Placeholders used when the values are not injected at build time.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+}
+
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+	}
+}
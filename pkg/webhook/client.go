@@ -2,6 +2,9 @@ package webhook
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -9,46 +12,122 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/onurcolak/insider-message-service/environments"
 	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/idempotency"
 	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/onurcolak/insider-message-service/pkg/requestid"
 )
 
 type Client struct {
-	httpClient *resty.Client
-	webhookURL string
+	httpClient   *resty.Client
+	webhookURL   string
+	successField string
+	successValue string
+	timeout      time.Duration
+
+	// retryable holds the status codes reported as transient; other unexpected ones are terminal.
+	retryable map[int]bool
+
+	// sem bounds concurrent SendMessage calls; nil when unlimited.
+	sem chan struct{}
+}
+
+// DefaultRetryableStatusCodes are the status codes treated as transient when none are configured:
+// timeouts, rate limiting and server-side failures.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
 }
 
 func NewWebhookClient(cfg environments.WebhookConfig) *Client {
 	client := resty.New().
-		SetTimeout(cfg.Timeout).
 		SetRetryCount(3).
 		SetRetryWaitTime(500*time.Millisecond).
 		SetRetryMaxWaitTime(2*time.Second).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Accept", "application/json").
-		SetHeader("x-ins-auth-key", cfg.AuthKey)
+		SetHeader("Accept", "application/json")
+
+	// Providers without auth get no auth header at all rather than an empty one.
+	if cfg.AuthKey != "" {
+		client.SetHeader("x-ins-auth-key", cfg.AuthKey)
+	}
+
+	var sem chan struct{}
+	if cfg.MaxConcurrency > 0 {
+		sem = make(chan struct{}, cfg.MaxConcurrency)
+	}
+
+	codes := cfg.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryableStatusCodes
+	}
+	retryable := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		retryable[code] = true
+	}
 
 	return &Client{
-		httpClient: client,
-		webhookURL: cfg.URL,
+		httpClient:   client,
+		webhookURL:   cfg.URL,
+		successField: cfg.SuccessField,
+		successValue: cfg.SuccessValue,
+		timeout:      cfg.Timeout,
+		retryable:    retryable,
+		sem:          sem,
 	}
 }
 
+// SendMessage posts a message to the webhook. When a concurrency cap is configured, it waits
+// for a free slot and gives up if ctx is done first. The request id from ctx is forwarded as
+// X-Request-ID; calls without one (e.g. from the scheduler) get a fresh id. If ctx carries a
+// send attempt, an Idempotency-Key derived from it is sent, so resty's retries of that attempt
+// reuse the key while the next attempt gets a new one.
+//
+// The timeout is a total budget for the call: it covers resty's retries and the waits between
+// them, not each HTTP request. The ctx deadline, if there is one, takes the place of the
+// configured timeout, so callers can override it per message.
 func (c *Client) SendMessage(ctx context.Context, phoneNumber, content string) (*domain.WebhookResponse, error) {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a free webhook slot: %w", ctx.Err())
+		}
+	}
+
 	// Prepare request payload
 	payload := domain.WebhookRequest{
 		To:      phoneNumber,
 		Content: content,
 	}
 
-	var webhookResp domain.WebhookResponse
+	requestID := requestid.FromContext(ctx)
+	if requestID == "" {
+		requestID = requestid.New()
+	}
 
 	startTime := time.Now()
 
-	resp, err := c.httpClient.R().
+	req := c.httpClient.R().
 		SetContext(ctx).
-		SetBody(payload).
-		SetResult(&webhookResp).
-		Post(c.webhookURL)
+		SetHeader(requestid.Header, requestID).
+		SetBody(payload)
+	if attempt, ok := idempotency.FromContext(ctx); ok {
+		req.SetHeader(idempotency.Header, IdempotencyKey(attempt))
+	}
+
+	resp, err := req.Post(c.webhookURL)
 
 	duration := time.Since(startTime)
 
@@ -56,15 +135,73 @@ func (c *Client) SendMessage(ctx context.Context, phoneNumber, content string) (
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	logger.Infof("Webhook request to %s completed in %v (status: %d)", c.webhookURL, duration, resp.StatusCode())
+	logger.Infof("Webhook request to %s completed in %v (status: %d, request id: %s)",
+		c.webhookURL, duration, resp.StatusCode(), requestID)
+
+	// Providers that signal success in the body are judged by it instead of the exact status code.
+	if c.successField != "" {
+		if !resp.IsSuccess() {
+			return nil, c.statusError(resp)
+		}
+		if err := c.checkBodySuccess(resp.Body()); err != nil {
+			return nil, err
+		}
+		return decodeAccepted(resp)
+	}
 
 	if resp.StatusCode() != http.StatusAccepted {
-		return nil, fmt.Errorf("unexpected status code: %d (expected 202), body: %s", resp.StatusCode(), resp.String())
+		return nil, c.statusError(resp)
+	}
+
+	return decodeAccepted(resp)
+}
+
+// statusError classifies an unexpected response as retryable or terminal by its status code.
+func (c *Client) statusError(resp *resty.Response) error {
+	return &domain.WebhookStatusError{
+		StatusCode: resp.StatusCode(),
+		Body:       resp.String(),
+		Retryable:  c.retryable[resp.StatusCode()],
 	}
+}
 
+// IdempotencyKey derives the Idempotency-Key for a send attempt: the same attempt always maps to
+// the same key and different attempts to different keys.
+func IdempotencyKey(attempt idempotency.Attempt) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d:%d:%d", attempt.MessageID, attempt.QueuedAt.UnixNano(), attempt.Number))
+	return hex.EncodeToString(sum[:16])
+}
+
+// decodeAccepted reads the message id from a success response. The body is decoded here rather
+// than by resty so that an empty, non-JSON or id-less body is reported instead of leaving the
+// message marked sent with a blank message id.
+func decodeAccepted(resp *resty.Response) (*domain.WebhookResponse, error) {
+	var webhookResp domain.WebhookResponse
+	if err := json.Unmarshal(resp.Body(), &webhookResp); err != nil || webhookResp.MessageID == "" {
+		return nil, fmt.Errorf("%w (status: %d, body: %q)", domain.ErrMissingMessageID, resp.StatusCode(), resp.String())
+	}
 	return &webhookResp, nil
 }
 
+// checkBodySuccess verifies that the configured top-level field in the response body has the expected value.
+func (c *Client) checkBodySuccess(body []byte) error {
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return fmt.Errorf("failed to parse webhook response body: %w", err)
+	}
+
+	value, ok := fields[c.successField]
+	if !ok {
+		return fmt.Errorf("webhook response is missing %q, body: %s", c.successField, string(body))
+	}
+
+	if fmt.Sprint(value) != c.successValue {
+		return fmt.Errorf("webhook response %q is %v (expected %s)", c.successField, value, c.successValue)
+	}
+
+	return nil
+}
+
 func (c *Client) GetURL() string {
 	return c.webhookURL
 }
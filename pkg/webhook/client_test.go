@@ -0,0 +1,375 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/internal/domain"
+	"github.com/onurcolak/insider-message-service/pkg/idempotency"
+	"github.com/onurcolak/insider-message-service/pkg/requestid"
+)
+
+func newTestServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestSendMessage_BodySuccessWith200(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"status":"accepted","messageId":"abc-123"}`)
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:          srv.URL,
+		Timeout:      time.Second,
+		SuccessField: "status",
+		SuccessValue: "accepted",
+	})
+
+	resp, err := client.SendMessage(context.Background(), "+905551234567", "hello")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if resp.MessageID != "abc-123" {
+		t.Errorf("expected messageId %q, got %q", "abc-123", resp.MessageID)
+	}
+}
+
+func TestSendMessage_BodyFailureWith200(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"status":"rejected","messageId":"abc-123"}`)
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:          srv.URL,
+		Timeout:      time.Second,
+		SuccessField: "status",
+		SuccessValue: "accepted",
+	})
+
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err == nil {
+		t.Fatalf("expected error for rejected body status, got nil")
+	}
+}
+
+func TestSendMessage_StatusCodeCheckWithoutBodyCriteria(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"status":"accepted"}`)
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:     srv.URL,
+		Timeout: time.Second,
+	})
+
+	// Without body criteria only 202 counts as success.
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err == nil {
+		t.Fatalf("expected error for 200 without body criteria, got nil")
+	}
+}
+
+func TestSendMessage_ClassifiesUnexpectedStatusCodes(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable []int
+		terminal  bool
+	}{
+		{http.StatusBadRequest, nil, true},
+		{http.StatusUnauthorized, nil, true},
+		{http.StatusUnprocessableEntity, nil, true},
+		{http.StatusOK, nil, true},
+		{http.StatusTooManyRequests, nil, false},
+		{http.StatusInternalServerError, nil, false},
+		{http.StatusServiceUnavailable, nil, false},
+		{http.StatusServiceUnavailable, []int{http.StatusTooManyRequests}, true},
+		{http.StatusBadRequest, []int{http.StatusBadRequest}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d retryable %v", tt.status, tt.retryable), func(t *testing.T) {
+			srv := newTestServer(t, tt.status, `{"error":"nope"}`)
+
+			client := NewWebhookClient(environments.WebhookConfig{
+				URL:                  srv.URL,
+				Timeout:              time.Second,
+				RetryableStatusCodes: tt.retryable,
+			})
+
+			_, err := client.SendMessage(context.Background(), "+905551234567", "hello")
+
+			var statusErr *domain.WebhookStatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+				t.Fatalf("expected a status error for %d, got %v", tt.status, err)
+			}
+			if got := errors.Is(err, domain.ErrTerminalSend); got != tt.terminal {
+				t.Fatalf("expected terminal=%v for %d, got %v", tt.terminal, tt.status, got)
+			}
+		})
+	}
+}
+
+func TestSendMessage_ConcurrencyCapIsRespected(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	const maxConcurrency = 3
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:            srv.URL,
+		Timeout:        5 * time.Second,
+		MaxConcurrency: maxConcurrency,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err != nil {
+				t.Errorf("SendMessage returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got > maxConcurrency {
+		t.Fatalf("expected at most %d concurrent calls, observed %d", maxConcurrency, got)
+	}
+}
+
+func TestSendMessage_SaturatedCapHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:            srv.URL,
+		Timeout:        5 * time.Second,
+		MaxConcurrency: 1,
+	})
+
+	// Occupy the only slot.
+	go func() { _, _ = client.SendMessage(context.Background(), "+905551234567", "first") }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.SendMessage(ctx, "+905551234567", "second"); err == nil {
+		t.Fatalf("expected error while waiting for a slot, got nil")
+	}
+}
+
+func TestSendMessage_202WithEmptyBodyIsAnError(t *testing.T) {
+	srv := newTestServer(t, http.StatusAccepted, "")
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:     srv.URL,
+		Timeout: time.Second,
+	})
+
+	resp, err := client.SendMessage(context.Background(), "+905551234567", "hello")
+	if !errors.Is(err, domain.ErrMissingMessageID) {
+		t.Fatalf("expected ErrMissingMessageID, got resp=%+v err=%v", resp, err)
+	}
+}
+
+func TestSendMessage_202WithNonJSONBodyIsAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("queued"))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewWebhookClient(environments.WebhookConfig{
+		URL:     srv.URL,
+		Timeout: time.Second,
+	})
+
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); !errors.Is(err, domain.ErrMissingMessageID) {
+		t.Fatalf("expected ErrMissingMessageID, got %v", err)
+	}
+}
+
+func TestSendMessage_ForwardsRequestID(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get(requestid.Header))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc-123"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: time.Second})
+
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	if _, err := client.SendMessage(ctx, "+905551234567", "hello"); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if got.Load() != "req-42" {
+		t.Fatalf("expected X-Request-ID %q, got %q", "req-42", got.Load())
+	}
+
+	// Without a request-scoped id a fresh one is generated.
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if id, _ := got.Load().(string); id == "" || id == "req-42" {
+		t.Fatalf("expected a generated X-Request-ID, got %q", id)
+	}
+}
+
+func TestSendMessage_ContextDeadlineOverridesTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc-123"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: 50 * time.Millisecond})
+
+	// The configured timeout applies when the caller sets no deadline.
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the configured timeout to apply, got %v", err)
+	}
+
+	// A longer caller deadline takes precedence.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := client.SendMessage(ctx, "+905551234567", "hello"); err != nil {
+		t.Fatalf("expected the caller deadline to override the timeout, got %v", err)
+	}
+}
+
+func TestSendMessage_IdempotencyKeyStableAcrossRetriesOfOneAttempt(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(idempotency.Header))
+		first := len(keys)%2 == 1
+		mu.Unlock()
+
+		// Every attempt's first request drops the connection, so resty retries it.
+		if first {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc-123"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: 5 * time.Second})
+
+	queuedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for attempt := 1; attempt <= 2; attempt++ {
+		ctx := idempotency.NewContext(context.Background(), idempotency.Attempt{MessageID: 42, QueuedAt: queuedAt, Number: attempt})
+		if _, err := client.SendMessage(ctx, "+905551234567", "hello"); err != nil {
+			t.Fatalf("attempt %d: expected success, got error: %v", attempt, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 4 {
+		t.Fatalf("expected 4 requests (each attempt retried once), got %d", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Fatalf("expected retries of one attempt to reuse its key, got %q", keys)
+	}
+	if keys[1] == keys[2] {
+		t.Fatalf("expected a new key for the next attempt, got %q", keys)
+	}
+}
+
+func TestNewWebhookClient_OmitsAuthHeaderWithoutKey(t *testing.T) {
+	var present atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Header[http.CanonicalHeaderKey("x-ins-auth-key")]
+		present.Store(ok)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc-123"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: time.Second, AuthRequired: false})
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if present.Load() {
+		t.Fatalf("expected no auth header when no key is configured")
+	}
+
+	client = NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: time.Second, AuthKey: "secret"})
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if !present.Load() {
+		t.Fatalf("expected the auth header when a key is configured")
+	}
+}
+
+func TestSendMessage_TimeoutIsATotalBudgetAcrossRetries(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request drops the connection; the retry would succeed at once.
+		if requests.Add(1) == 1 {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc-123"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	// Each request is fast, but the wait before the retry alone exceeds the budget.
+	client := NewWebhookClient(environments.WebhookConfig{URL: srv.URL, Timeout: 200 * time.Millisecond})
+
+	if _, err := client.SendMessage(context.Background(), "+905551234567", "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the retry to run out of the call's budget, got %v", err)
+	}
+}
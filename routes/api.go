@@ -15,31 +15,98 @@ func RegisterRoutes(
 	healthHandler *handlers.HealthHandler,
 	messageHandler *handlers.MessageHandler,
 	schedulerHandler *handlers.SchedulerHandler,
+	adminHandler *handlers.AdminHandler,
+	webhookHandler *handlers.WebhookHandler,
+	suppressionHandler *handlers.SuppressionHandler,
+	diagnosticsHandler *handlers.DiagnosticsHandler,
+	metricsHandler *handlers.MetricsHandler,
+	recentErrorsHandler *handlers.RecentErrorsHandler,
+	inMemoryMetricsHandler *handlers.InMemoryMetricsHandler,
 	cfg *environments.Config,
 ) {
 	e.GET("/health", healthHandler.Health)
+	e.GET("/version", handlers.Version)
+	e.GET("/metrics", metricsHandler.GetMetrics)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	// API v1 base group
 	v1 := e.Group("/api/v1")
 
 	// Message routes with their own API key
-	messages := v1.Group("/messages", middlewares.APIKeyAuth(cfg.Auth.MessagesAPIKey))
+	messages := v1.Group("/messages", middlewares.APIKeyAuth(cfg.Auth.MessagesAPIKey, cfg.Auth.TrustedAPIKey))
 
 	messages.GET("", messageHandler.GetAllMessages)
 	messages.POST("", messageHandler.CreateMessage)
+	messages.POST("/campaign", messageHandler.CreateCampaign)
+	messages.GET("/campaign/:campaignId", messageHandler.GetCampaignMessages)
 	messages.GET("/sent", messageHandler.GetSentMessages)
+	messages.GET("/truncated", messageHandler.GetTruncatedMessages)
+	messages.GET("/search", messageHandler.SearchMessages)
+	messages.GET("/export.jsonl", messageHandler.ExportMessages)
 	messages.GET("/stats", messageHandler.GetStats)
+	messages.GET("/stats/grouped", messageHandler.GetGroupedStats)
+	messages.GET("/cost-estimate", messageHandler.GetCostEstimate)
 	messages.GET("/cached", messageHandler.GetCachedMessages)
+	messages.POST("/cached/batch", messageHandler.GetCachedMessagesBatch)
+	messages.GET("/changes", messageHandler.GetChanges)
+	messages.GET("/failure-reasons", messageHandler.GetFailureReasons)
+	messages.GET("/throughput", messageHandler.GetThroughput)
+	messages.GET("/sla", messageHandler.GetSLACompliance)
+	messages.GET("/head", messageHandler.GetQueueHead)
+	messages.POST("/cancel", messageHandler.CancelMessages)
+	messages.GET("/:id", messageHandler.GetMessage)
+	messages.POST("/:id/fail", messageHandler.ForceFailMessage)
+	messages.POST("/:id/reset-retries", messageHandler.ResetRetries)
 
 	// new replay endpoints
 	messages.POST("/replay", messageHandler.ReplayAllFailedMessages)
 	messages.POST("/:id/replay", messageHandler.ReplayFailedMessage)
 
+	// Suppression list, managed with the messages API key
+	suppressions := v1.Group("/suppressions", middlewares.APIKeyAuth(cfg.Auth.MessagesAPIKey, cfg.Auth.TrustedAPIKey))
+
+	suppressions.POST("", suppressionHandler.AddSuppression)
+	suppressions.DELETE("/:phone", suppressionHandler.RemoveSuppression)
+
 	// Scheduler routes with their own API key
 	schedulerGroup := v1.Group("/scheduler", middlewares.APIKeyAuth(cfg.Auth.SchedulerAPIKey))
 
 	schedulerGroup.POST("/start", schedulerHandler.StartScheduler)
 	schedulerGroup.POST("/stop", schedulerHandler.StopScheduler)
 	schedulerGroup.GET("/status", schedulerHandler.GetSchedulerStatus)
+	schedulerGroup.GET("/next-batch", schedulerHandler.GetNextBatch)
+	schedulerGroup.POST("/simulate", schedulerHandler.SimulateRun)
+	schedulerGroup.POST("/test-alert", schedulerHandler.TestAlert)
+	schedulerGroup.GET("/history.csv", schedulerHandler.GetHistoryCSV)
+	schedulerGroup.GET("/runs/:runNumber", schedulerHandler.GetRunResults)
+
+	// Same endpoints for a named queue's scheduler; the unnamed ones above use the default queue
+	schedulerGroup.POST("/:name/start", schedulerHandler.StartScheduler)
+	schedulerGroup.POST("/:name/stop", schedulerHandler.StopScheduler)
+	schedulerGroup.GET("/:name/status", schedulerHandler.GetSchedulerStatus)
+	schedulerGroup.GET("/:name/next-batch", schedulerHandler.GetNextBatch)
+	schedulerGroup.POST("/:name/simulate", schedulerHandler.SimulateRun)
+	schedulerGroup.GET("/:name/history.csv", schedulerHandler.GetHistoryCSV)
+	schedulerGroup.GET("/:name/runs/:runNumber", schedulerHandler.GetRunResults)
+
+	// Admin routes share the scheduler API key
+	admin := v1.Group("/admin", middlewares.APIKeyAuth(cfg.Auth.SchedulerAPIKey))
+
+	admin.GET("/overview", adminHandler.GetOverview)
+	admin.POST("/backfill-sent-at", adminHandler.BackfillSentAt)
+	admin.POST("/normalize-phones", adminHandler.NormalizePhones)
+	admin.POST("/archive", adminHandler.ArchiveSentMessages)
+	admin.POST("/sending", adminHandler.SetSending)
+	admin.GET("/diagnostics", diagnosticsHandler.GetDiagnostics)
+	admin.GET("/config", diagnosticsHandler.GetConfig)
+	admin.GET("/recent-errors", recentErrorsHandler.GetRecentErrors)
+	admin.GET("/metrics.json", inMemoryMetricsHandler.GetMetrics)
+
+	// Bulk status changes are an operator action, so they need the scheduler key rather than the messages key
+	v1.PATCH("/messages", adminHandler.BulkUpdateStatus, middlewares.APIKeyAuth(cfg.Auth.SchedulerAPIKey))
+
+	// Inbound provider webhooks with their own API key
+	webhooks := v1.Group("/webhooks", middlewares.APIKeyAuth(cfg.Auth.DLRAPIKey))
+
+	webhooks.POST("/dlr", webhookHandler.ReceiveDeliveryReceipt)
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+)
+
+// stopWithTimeout runs stop and waits for it to return, giving up after timeout.
+// after is the clock used for the deadline (time.After in production) so tests
+// can trigger the timeout without waiting for it.
+func stopWithTimeout(
+	stop func() error,
+	timeout time.Duration,
+	after func(time.Duration) <-chan time.Time,
+) (timedOut bool, err error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- stop()
+	}()
+
+	select {
+	case err := <-done:
+		return false, err
+	case <-after(timeout):
+		return true, nil
+	}
+}
+
+// shutdownSequence holds the steps of a graceful shutdown. run executes them in a fixed order:
+// the HTTP server stops accepting first so no request can start a send while the schedulers
+// drain, the root context is cancelled only once nothing is running on it, and connections
+// close last.
+type shutdownSequence struct {
+	stopHTTP         func() error // Stops accepting new requests and waits for in-flight ones
+	drainSchedulers  func()       // Stops every running scheduler, letting its current run finish
+	cancel           func()       // Cancels the root context
+	closeConnections func()       // Closes the database and Redis clients
+}
+
+func (s shutdownSequence) run() {
+	logger.Infof("Shutting down HTTP server...")
+	if err := s.stopHTTP(); err != nil {
+		logger.Errorf("Server forced to shutdown: %v", err)
+	} else {
+		logger.Infof("HTTP server stopped successfully")
+	}
+
+	s.drainSchedulers()
+	s.cancel()
+	s.closeConnections()
+}
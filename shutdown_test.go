@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStopWithTimeout_GivesUpAfterProvidedTimeout(t *testing.T) {
+	var requested time.Duration
+	fakeAfter := func(d time.Duration) <-chan time.Time {
+		requested = d
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+
+	timedOut, err := stopWithTimeout(func() error {
+		<-block
+		return nil
+	}, 50*time.Millisecond, fakeAfter)
+
+	if !timedOut {
+		t.Fatalf("expected stop to time out")
+	}
+	if err != nil {
+		t.Fatalf("expected no error on timeout, got %v", err)
+	}
+	if requested != 50*time.Millisecond {
+		t.Fatalf("expected deadline of 50ms, got %v", requested)
+	}
+}
+
+func TestStopWithTimeout_ReturnsStopError(t *testing.T) {
+	never := func(time.Duration) <-chan time.Time { return nil }
+	stopErr := errors.New("scheduler is not running")
+
+	timedOut, err := stopWithTimeout(func() error { return stopErr }, time.Second, never)
+
+	if timedOut {
+		t.Fatalf("expected stop to finish before the deadline")
+	}
+	if !errors.Is(err, stopErr) {
+		t.Fatalf("expected %v, got %v", stopErr, err)
+	}
+}
+
+func TestShutdownSequence_StopsHTTPBeforeDrainingSchedulers(t *testing.T) {
+	var steps []string
+	record := func(step string) func() {
+		return func() { steps = append(steps, step) }
+	}
+
+	shutdownSequence{
+		stopHTTP: func() error {
+			steps = append(steps, "http")
+			return errors.New("deadline exceeded")
+		},
+		drainSchedulers:  record("schedulers"),
+		cancel:           record("cancel"),
+		closeConnections: record("connections"),
+	}.run()
+
+	want := []string{"http", "schedulers", "cancel", "connections"}
+	if !slices.Equal(steps, want) {
+		t.Fatalf("expected steps %v, got %v", want, steps)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/pkg/logger"
+	"github.com/onurcolak/insider-message-service/pkg/redis"
+)
+
+// connectRedis connects with connect (redis.NewRedisClient in production). If Redis is
+// unavailable, caching is disabled and a nil client is returned, unless cfg.Required is set,
+// in which case the error is returned so startup can fail fast.
+func connectRedis(
+	cfg environments.RedisConfig,
+	connect func(environments.RedisConfig) (*redis.Client, error),
+) (*redis.Client, error) {
+	client, err := connect(cfg)
+	if err == nil {
+		return client, nil
+	}
+
+	if cfg.Required {
+		return nil, fmt.Errorf("redis is required but not available: %w", err)
+	}
+
+	logger.Warnf("Redis not available, caching disabled: %v", err)
+	return nil, nil
+}
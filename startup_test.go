@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onurcolak/insider-message-service/environments"
+	"github.com/onurcolak/insider-message-service/pkg/redis"
+)
+
+func TestConnectRedis_UnavailableDisablesCachingUnlessRequired(t *testing.T) {
+	unavailable := func(environments.RedisConfig) (*redis.Client, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	client, err := connectRedis(environments.RedisConfig{}, unavailable)
+	if err != nil || client != nil {
+		t.Fatalf("expected caching to be disabled without error, got client=%v err=%v", client, err)
+	}
+
+	if _, err := connectRedis(environments.RedisConfig{Required: true}, unavailable); err == nil {
+		t.Fatal("expected an error when Redis is required")
+	}
+}
+
+func TestConnectRedis_ReturnsConnectedClient(t *testing.T) {
+	want := &redis.Client{}
+	connected := func(environments.RedisConfig) (*redis.Client, error) {
+		return want, nil
+	}
+
+	client, err := connectRedis(environments.RedisConfig{Required: true}, connected)
+	if err != nil || client != want {
+		t.Fatalf("expected the connected client, got client=%v err=%v", client, err)
+	}
+}